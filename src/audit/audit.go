@@ -0,0 +1,188 @@
+// Package audit registra o histórico de alterações de despesas, receitas e
+// categorias: quem alterou, quando, por qual origem e o que mudou em cada campo.
+//
+// O uso é sempre o mesmo, dentro da transação da alteração:
+//
+//	t, err := audit.Track(tx, models.AuditExpenses, "id = $1", id) // antes
+//	// ... UPDATE/DELETE ...
+//	err = t.Record(tx, models.AuditEntry{Acao: models.AuditUpdate, Origem: models.AuditSourceAPI})
+package audit
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"reflect"
+	"time"
+
+	"finance/src/models"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// Executor é satisfeito por *sql.DB e *sql.Tx
+type Executor interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// snapshotSQL é a linha da entidade como JSON, com os campos que moram em
+// outras tabelas (tags, campos personalizados, divisão, total pago e estornado)
+// para que também entrem na diferença
+var snapshotSQL = map[string]string{
+	models.AuditExpenses: `to_jsonb(expenses) || jsonb_build_object(
+		'tags', COALESCE((SELECT jsonb_agg(g.nome ORDER BY g.nome) FROM expense_tags et JOIN tags g ON g.id = et.tag_id WHERE et.expense_id = expenses.id), '[]'::jsonb),
+		'valor_pago', (SELECT COALESCE(SUM(p.valor), 0) FROM expense_payments p WHERE p.expense_id = expenses.id),
+		'valor_estornado', (SELECT COALESCE(SUM(rf.valor), 0) FROM expense_refunds rf WHERE rf.expense_id = expenses.id),
+		'campos', COALESCE((SELECT jsonb_object_agg(f.nome, v.valor) FROM expense_field_values v JOIN custom_fields f ON f.id = v.field_id WHERE v.expense_id = expenses.id), '{}'::jsonb),
+		'divisao', COALESCE((SELECT jsonb_agg(jsonb_build_object('categoria', s.categoria, 'valor', s.valor, 'observacao', s.observacao) ORDER BY s.posicao) FROM expense_splits s WHERE s.expense_id = expenses.id), '[]'::jsonb))`,
+	models.AuditIncomes: `to_jsonb(incomes) || jsonb_build_object(
+		'tags', COALESCE((SELECT jsonb_agg(g.nome ORDER BY g.nome) FROM income_tags it JOIN tags g ON g.id = it.tag_id WHERE it.income_id = incomes.id), '[]'::jsonb),
+		'campos', COALESCE((SELECT jsonb_object_agg(f.nome, v.valor) FROM income_field_values v JOIN custom_fields f ON f.id = v.field_id WHERE v.income_id = incomes.id), '{}'::jsonb),
+		'divisao', COALESCE((SELECT jsonb_agg(jsonb_build_object('categoria', s.categoria, 'valor', s.valor, 'observacao', s.observacao) ORDER BY s.posicao) FROM income_splits s WHERE s.income_id = incomes.id), '[]'::jsonb))`,
+	models.AuditCategories: `to_jsonb(categories)`,
+}
+
+// ignoredFields já estão no próprio registro do histórico
+var ignoredFields = map[string]bool{"id": true, "user_id": true}
+
+type snapshot map[string]interface{}
+
+// Tracker guarda o estado das linhas antes de uma alteração
+type Tracker struct {
+	entidade string
+	ids      []uuid.UUID
+	before   map[uuid.UUID]snapshot
+}
+
+// Track lê as linhas da entidade que where seleciona, antes de alterá-las.
+// where é escrito sobre a própria tabela, sem apelido (ex: "recorrencia_id = $1").
+func Track(q Executor, entidade, where string, args ...interface{}) (*Tracker, error) {
+	before, err := load(q, entidade, where, args...)
+	if err != nil {
+		return nil, err
+	}
+	t := &Tracker{entidade: entidade, before: before}
+	for id := range before {
+		t.ids = append(t.ids, id)
+	}
+	return t, nil
+}
+
+// TrackNew acompanha linhas que ainda vão ser criadas
+func TrackNew(entidade string, ids ...uuid.UUID) *Tracker {
+	return &Tracker{entidade: entidade, ids: ids, before: map[uuid.UUID]snapshot{}}
+}
+
+// Record lê as linhas depois da alteração e grava um registro por linha com
+// a diferença campo a campo. Linhas que não mudaram não geram registro e as
+// que deixaram de existir são registradas como exclusão.
+// Ação, origem e ator vêm de e; o restante é preenchido aqui.
+func (t *Tracker) Record(q Executor, e models.AuditEntry) error {
+	if len(t.ids) == 0 {
+		return nil
+	}
+	after, err := load(q, t.entidade, "id = ANY($1::uuid[])", uuidArray(t.ids))
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for _, id := range t.ids {
+		before, after := t.before[id], after[id]
+		changes := diff(before, after)
+		if len(changes) == 0 {
+			continue
+		}
+		entry := e
+		row := after
+		if row == nil {
+			row = before
+			entry.Acao = models.AuditDelete
+		}
+		userID, err := uuid.Parse(toString(row["user_id"]))
+		if err != nil {
+			return err
+		}
+
+		entry.ID = uuid.New()
+		entry.UserID = userID
+		entry.Entidade = t.entidade
+		entry.EntidadeID = id
+		entry.Alteracoes = changes
+		entry.CreatedAt = now
+
+		alteracoes, err := json.Marshal(entry.Alteracoes)
+		if err != nil {
+			return err
+		}
+		if _, err := q.Exec(`
+			INSERT INTO audit_log (id, user_id, ator_id, entidade, entidade_id, acao, origem, alteracoes, created_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		`, entry.ID, entry.UserID, entry.AtorID, entry.Entidade, entry.EntidadeID, entry.Acao, entry.Origem, alteracoes, entry.CreatedAt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// load busca os snapshots das linhas selecionadas, por ID
+func load(q Executor, entidade, where string, args ...interface{}) (map[uuid.UUID]snapshot, error) {
+	rows, err := q.Query(`SELECT id, `+snapshotSQL[entidade]+` FROM `+entidade+` WHERE `+where, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := map[uuid.UUID]snapshot{}
+	for rows.Next() {
+		var id uuid.UUID
+		var raw []byte
+		if err := rows.Scan(&id, &raw); err != nil {
+			return nil, err
+		}
+		// json.Number mantém os valores em NUMERIC exatamente como estão no banco
+		dec := json.NewDecoder(bytes.NewReader(raw))
+		dec.UseNumber()
+		var s snapshot
+		if err := dec.Decode(&s); err != nil {
+			return nil, err
+		}
+		out[id] = s
+	}
+	return out, rows.Err()
+}
+
+// diff compara os campos de dois snapshots; nil representa a linha inexistente
+func diff(before, after snapshot) map[string]models.AuditChange {
+	changes := map[string]models.AuditChange{}
+	for field, de := range before {
+		if ignoredFields[field] {
+			continue
+		}
+		if para := after[field]; !reflect.DeepEqual(de, para) {
+			changes[field] = models.AuditChange{De: de, Para: para}
+		}
+	}
+	for field, para := range after {
+		if _, seen := before[field]; seen || ignoredFields[field] || para == nil {
+			continue
+		}
+		changes[field] = models.AuditChange{De: nil, Para: para}
+	}
+	return changes
+}
+
+func toString(v interface{}) string {
+	s, _ := v.(string)
+	return s
+}
+
+func uuidArray(ids []uuid.UUID) interface{} {
+	out := make([]string, len(ids))
+	for i, id := range ids {
+		out[i] = id.String()
+	}
+	return pq.Array(out)
+}
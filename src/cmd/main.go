@@ -14,7 +14,10 @@ import (
 
 	"finance/src/config"
 	"finance/src/db"
+	"finance/src/jobs"
+	"finance/src/notify"
 	"finance/src/routes"
+	"finance/src/storage"
 
 	_ "finance/src/controllers"
 	_ "finance/src/docs" // Importando os docs gerados pelo Swag
@@ -26,6 +29,9 @@ import (
 func main() {
 	config.LoadEnv()
 	db.Init()
+	storage.Init()
+	notify.Init(db.DB)
+	jobs.Start() // Inicia os jobs em segundo plano (ex: despesas recorrentes)
 	router := routes.SetupRoutes()
 	log.Println("Servidor iniciado na porta 8080")
 	log.Fatal(http.ListenAndServe(":8080", router))
@@ -0,0 +1,61 @@
+// Comando networth-backfill reconstrói as fotografias mensais do patrimônio
+// a partir dos lançamentos já registrados.
+//
+// Uso:
+//
+//	go run ./src/cmd/networth-backfill [-user <id>] [-from YYYY-MM]
+//
+// Sem -user processa todos os usuários; sem -from começa no mês do
+// lançamento mais antigo de cada usuário.
+package main
+
+import (
+	"flag"
+	"log"
+	"time"
+
+	"finance/src/config"
+	"finance/src/db"
+	"finance/src/jobs"
+
+	"github.com/google/uuid"
+)
+
+func main() {
+	userFlag := flag.String("user", "", "ID do usuário (padrão: todos)")
+	fromFlag := flag.String("from", "", "primeiro mês, no formato YYYY-MM (padrão: primeiro lançamento)")
+	flag.Parse()
+
+	var from time.Time
+	if *fromFlag != "" {
+		var err error
+		if from, err = time.Parse("2006-01", *fromFlag); err != nil {
+			log.Fatalf("Mês inicial inválido: use YYYY-MM")
+		}
+	}
+
+	config.LoadEnv()
+	db.Init()
+
+	var users []uuid.UUID
+	if *userFlag != "" {
+		id, err := uuid.Parse(*userFlag)
+		if err != nil {
+			log.Fatalf("ID de usuário inválido")
+		}
+		users = append(users, id)
+	} else {
+		var err error
+		if users, err = jobs.UserIDs(); err != nil {
+			log.Fatalf("Erro ao buscar usuários: %v", err)
+		}
+	}
+
+	for _, id := range users {
+		n, err := jobs.BackfillNetWorth(id, from)
+		if err != nil {
+			log.Fatalf("Usuário %s: %v", id, err)
+		}
+		log.Printf("Usuário %s: %d meses registrados", id, n)
+	}
+}
@@ -0,0 +1,224 @@
+package controllers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"finance/src/db"
+	"finance/src/models"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+// Tipos de conta aceitos
+var accountTypes = map[string]bool{
+	"corrente":       true,
+	"poupanca":       true,
+	"cartao_credito": true,
+	"carteira":       true,
+	"investimento":   true,
+}
+
+var errAccountNotFound = errors.New("Conta não encontrada")
+
+// checkAccount confere se a conta existe e pertence ao usuário
+func checkAccount(q dbExecutor, userID string, id uuid.UUID) error {
+	var exists bool
+	err := q.QueryRow(`SELECT EXISTS (SELECT 1 FROM accounts WHERE user_id = $1 AND id = $2)`, userID, id).Scan(&exists)
+	if err == nil && !exists {
+		return errAccountNotFound
+	}
+	return err
+}
+
+// CreateAccount cria uma conta para o usuário
+//
+// @Summary Criar conta
+// @Tags Accounts
+// @Security BearerAuth
+// @Param userId path string true "ID do usuário"
+// @Param account body models.Account true "Dados da conta"
+// @Success 201 {object} models.Account
+// @Failure 400,401,500 {string} string
+// @Router /users/{userId}/accounts [post]
+func CreateAccount(w http.ResponseWriter, r *http.Request) {
+	userID, err := uuid.Parse(mux.Vars(r)["userId"])
+	if err != nil {
+		http.Error(w, "ID de usuário inválido", http.StatusBadRequest)
+		return
+	}
+
+	var acc models.Account
+	if err := json.NewDecoder(r.Body).Decode(&acc); err != nil {
+		http.Error(w, "Erro ao decodificar JSON", http.StatusBadRequest)
+		return
+	}
+
+	if acc.Nome == "" {
+		http.Error(w, "Nome da conta é obrigatório", http.StatusBadRequest)
+		return
+	}
+	if acc.Tipo == "" {
+		acc.Tipo = "corrente"
+	}
+	if !accountTypes[acc.Tipo] {
+		http.Error(w, "Tipo de conta inválido", http.StatusBadRequest)
+		return
+	}
+
+	if acc.Moeda, err = resolveCurrency(db.DB, userID.String(), acc.Moeda); err != nil {
+		writeCurrencyError(w, err)
+		return
+	}
+
+	acc.ID = uuid.New()
+	acc.UserID = userID
+	acc.CreatedAt = time.Now()
+
+	_, err = db.DB.Exec(`
+		INSERT INTO accounts (id, user_id, nome, tipo, moeda, saldo_inicial, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`, acc.ID, acc.UserID, acc.Nome, acc.Tipo, acc.Moeda, acc.SaldoInicial, acc.CreatedAt)
+	if err != nil {
+		http.Error(w, "Erro ao criar conta: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(acc)
+}
+
+// ListAccounts lista as contas do usuário
+//
+// @Summary Listar contas
+// @Tags Accounts
+// @Security BearerAuth
+// @Param userId path string true "ID do usuário"
+// @Success 200 {array} models.Account
+// @Failure 400,401,500 {string} string
+// @Router /users/{userId}/accounts [get]
+func ListAccounts(w http.ResponseWriter, r *http.Request) {
+	userID := mux.Vars(r)["userId"]
+
+	rows, err := db.DB.Query(`
+		SELECT id, user_id, nome, tipo, moeda, saldo_inicial, created_at
+		FROM accounts
+		WHERE user_id = $1
+		ORDER BY nome ASC
+	`, userID)
+	if err != nil {
+		http.Error(w, "Erro ao buscar contas: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	var accounts []models.Account
+	for rows.Next() {
+		var acc models.Account
+		if err := rows.Scan(&acc.ID, &acc.UserID, &acc.Nome, &acc.Tipo, &acc.Moeda, &acc.SaldoInicial, &acc.CreatedAt); err != nil {
+			http.Error(w, "Erro ao ler conta: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		accounts = append(accounts, acc)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(accounts)
+}
+
+// UpdateAccount altera o nome e o saldo inicial de uma conta
+//
+// @Summary Atualizar conta
+// @Tags Accounts
+// @Security BearerAuth
+// @Param userId path string true "ID do usuário"
+// @Param id path string true "ID da conta"
+// @Param account body object{nome=string,saldo_inicial=string} true "Nome e saldo inicial"
+// @Success 200 {object} models.Account
+// @Failure 400,401,404,500 {string} string
+// @Router /users/{userId}/accounts/{id} [put]
+func UpdateAccount(w http.ResponseWriter, r *http.Request) {
+	p := mux.Vars(r)
+
+	var in struct {
+		Nome         string       `json:"nome"`
+		SaldoInicial models.Money `json:"saldo_inicial"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		http.Error(w, "Erro ao decodificar JSON", http.StatusBadRequest)
+		return
+	}
+	if in.Nome == "" {
+		http.Error(w, "Nome da conta é obrigatório", http.StatusBadRequest)
+		return
+	}
+
+	var acc models.Account
+	err := db.DB.QueryRow(`
+		UPDATE accounts
+		SET nome = $1, saldo_inicial = $2
+		WHERE user_id = $3 AND id = $4
+		RETURNING id, user_id, nome, tipo, moeda, saldo_inicial, created_at
+	`, in.Nome, in.SaldoInicial, p["userId"], p["id"]).Scan(&acc.ID, &acc.UserID, &acc.Nome, &acc.Tipo, &acc.Moeda, &acc.SaldoInicial, &acc.CreatedAt)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Conta não encontrada", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Erro ao atualizar conta: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(acc)
+}
+
+// DeleteAccount exclui uma conta; pagamentos vinculados ficam sem conta.
+// Contas com operações de investimento não podem ser excluídas.
+//
+// @Summary Excluir conta
+// @Tags Accounts
+// @Security BearerAuth
+// @Param userId path string true "ID do usuário"
+// @Param id path string true "ID da conta"
+// @Success 200 {object} Message
+// @Failure 400,401,404,409,500 {string} string
+// @Router /users/{userId}/accounts/{id} [delete]
+func DeleteAccount(w http.ResponseWriter, r *http.Request) {
+	p := mux.Vars(r)
+
+	var invested bool
+	err := db.DB.QueryRow(`
+		SELECT EXISTS (SELECT 1 FROM investment_transactions WHERE user_id = $1 AND conta_id = $2)
+	`, p["userId"], p["id"]).Scan(&invested)
+	if err != nil {
+		http.Error(w, "Erro ao verificar investimentos: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if invested {
+		http.Error(w, "A conta tem operações de investimento: exclua-as antes de excluir a conta", http.StatusConflict)
+		return
+	}
+
+	result, err := db.DB.Exec(`
+		DELETE FROM accounts
+		WHERE user_id = $1 AND id = $2
+	`, p["userId"], p["id"])
+	if err != nil {
+		http.Error(w, "Erro ao excluir conta: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if rowsAffected, _ := result.RowsAffected(); rowsAffected == 0 {
+		http.Error(w, "Conta não encontrada", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(Message{Message: "Conta excluída com sucesso"})
+}
@@ -0,0 +1,352 @@
+package controllers
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"finance/src/db"
+	"finance/src/jobs"
+	"finance/src/models"
+	"finance/src/storage"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+// defaultAttachmentMaxBytes é o tamanho máximo de um anexo quando
+// ATTACHMENT_MAX_BYTES não está definido (10 MiB)
+const defaultAttachmentMaxBytes = 10 << 20
+
+// expenseWithoutAttachments filtra despesas sem nenhum anexo
+const expenseWithoutAttachments = `NOT EXISTS (SELECT 1 FROM expense_attachments a WHERE a.expense_id = expenses.id)`
+
+const attachmentColumns = `id, expense_id, user_id, nome, content_type, tamanho, sha256, created_at`
+
+func scanAttachment(row rowScanner, a *models.Attachment) error {
+	return row.Scan(&a.ID, &a.ExpenseID, &a.UserID, &a.Nome, &a.ContentType, &a.Tamanho, &a.SHA256, &a.CreatedAt)
+}
+
+func attachmentMaxBytes() int64 {
+	if v, err := strconv.ParseInt(os.Getenv("ATTACHMENT_MAX_BYTES"), 10, 64); err == nil && v > 0 {
+		return v
+	}
+	return defaultAttachmentMaxBytes
+}
+
+// attachmentKey é a chave do arquivo no armazenamento: o conteúdo define a
+// chave, então anexos iguais do mesmo usuário compartilham o mesmo blob
+func attachmentKey(userID uuid.UUID, sum string) string {
+	return "attachments/" + userID.String() + "/" + sum
+}
+
+// UploadExpenseAttachment anexa um comprovante (PDF, JPEG ou PNG) à despesa
+//
+// O arquivo vai no campo "file" de um formulário multipart. O tipo é
+// detectado pelo conteúdo, não pela extensão. Enviar o mesmo arquivo de novo
+// para a mesma despesa devolve o anexo existente.
+//
+// @Summary Anexar comprovante à despesa
+// @Tags Expenses
+// @Security BearerAuth
+// @Accept multipart/form-data
+// @Param userId path string true "ID do usuário"
+// @Param id path string true "ID da despesa"
+// @Param file formData file true "Arquivo PDF, JPEG ou PNG"
+// @Success 200,201 {object} models.Attachment
+// @Failure 400,401,404,413,415,500 {string} string
+// @Router /users/{userId}/expenses/{id}/attachments [post]
+func UploadExpenseAttachment(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	userID, err := uuid.Parse(params["userId"])
+	if err != nil {
+		http.Error(w, "ID de usuário inválido", http.StatusBadRequest)
+		return
+	}
+	expenseID, err := uuid.Parse(params["id"])
+	if err != nil {
+		http.Error(w, "ID de despesa inválido", http.StatusBadRequest)
+		return
+	}
+
+	var exists bool
+	if err := db.DB.QueryRow(`
+		SELECT EXISTS (SELECT 1 FROM expenses WHERE user_id = $1 AND id = $2 AND deleted_at IS NULL)
+	`, userID, expenseID).Scan(&exists); err != nil {
+		http.Error(w, "Erro ao buscar despesa: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if !exists {
+		http.Error(w, "Despesa não encontrada", http.StatusNotFound)
+		return
+	}
+
+	maxBytes := attachmentMaxBytes()
+	// folga para os cabeçalhos do multipart
+	r.Body = http.MaxBytesReader(w, r.Body, maxBytes+1<<20)
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			http.Error(w, "Arquivo maior que o permitido ("+strconv.FormatInt(maxBytes, 10)+" bytes)", http.StatusRequestEntityTooLarge)
+			return
+		}
+		http.Error(w, "Envie o arquivo no campo 'file' (multipart/form-data)", http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(io.LimitReader(file, maxBytes+1))
+	if err != nil {
+		http.Error(w, "Erro ao ler arquivo: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if int64(len(data)) > maxBytes {
+		http.Error(w, "Arquivo maior que o permitido ("+strconv.FormatInt(maxBytes, 10)+" bytes)", http.StatusRequestEntityTooLarge)
+		return
+	}
+	if len(data) == 0 {
+		http.Error(w, "Arquivo vazio", http.StatusBadRequest)
+		return
+	}
+
+	contentType := http.DetectContentType(data)
+	if !models.ValidAttachmentType(contentType) {
+		http.Error(w, "Tipo de arquivo não suportado: "+contentType+" (aceitos: "+strings.Join(models.AttachmentContentTypes, ", ")+")", http.StatusUnsupportedMediaType)
+		return
+	}
+
+	sum := sha256.Sum256(data)
+	a := models.Attachment{
+		ID:          uuid.New(),
+		ExpenseID:   expenseID,
+		UserID:      userID,
+		Nome:        filepath.Base(header.Filename),
+		ContentType: contentType,
+		Tamanho:     int64(len(data)),
+		SHA256:      hex.EncodeToString(sum[:]),
+	}
+	if a.Nome == "." || a.Nome == "/" {
+		a.Nome = "anexo"
+	}
+
+	// mesmo arquivo já anexado a esta despesa: devolve o existente
+	err = scanAttachment(db.DB.QueryRow(`
+		SELECT `+attachmentColumns+` FROM expense_attachments WHERE expense_id = $1 AND sha256 = $2
+	`, expenseID, a.SHA256), &a)
+	if err == nil {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(a)
+		return
+	}
+	if err != sql.ErrNoRows {
+		http.Error(w, "Erro ao buscar anexo: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// o blob é gravado com a chave travada e só é liberado depois do INSERT,
+	// para que a remoção de um blob sem uso não o apague no meio do caminho
+	key := attachmentKey(userID, a.SHA256)
+	tx, err := db.DB.Begin()
+	if err != nil {
+		http.Error(w, "Erro ao iniciar transação: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+	if err := jobs.LockBlob(tx, key); err != nil {
+		http.Error(w, "Erro ao travar anexo: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if err := storage.Blobs.Put(r.Context(), key, data, contentType); err != nil {
+		http.Error(w, "Erro ao armazenar arquivo: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	created := true
+	err = scanAttachment(tx.QueryRow(`
+		INSERT INTO expense_attachments (id, expense_id, user_id, nome, content_type, tamanho, sha256, chave, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, NOW())
+		ON CONFLICT (expense_id, sha256) DO NOTHING
+		RETURNING `+attachmentColumns+`
+	`, a.ID, a.ExpenseID, a.UserID, a.Nome, a.ContentType, a.Tamanho, a.SHA256, key), &a)
+	if err == sql.ErrNoRows {
+		// outro envio simultâneo do mesmo arquivo venceu a corrida
+		created = false
+		err = scanAttachment(tx.QueryRow(`
+			SELECT `+attachmentColumns+` FROM expense_attachments WHERE expense_id = $1 AND sha256 = $2
+		`, expenseID, a.SHA256), &a)
+	}
+	if err == nil {
+		err = tx.Commit()
+	}
+	if err != nil {
+		tx.Rollback()
+		jobs.RemoveUnreferencedBlobs(r.Context(), []string{key})
+		http.Error(w, "Erro ao salvar anexo: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if created {
+		w.WriteHeader(http.StatusCreated)
+	}
+	json.NewEncoder(w).Encode(a)
+}
+
+// ListExpenseAttachments lista os anexos de uma despesa
+//
+// @Summary Listar anexos da despesa
+// @Tags Expenses
+// @Security BearerAuth
+// @Param userId path string true "ID do usuário"
+// @Param id path string true "ID da despesa"
+// @Success 200 {array} models.Attachment
+// @Failure 400,401,404,500 {string} string
+// @Router /users/{userId}/expenses/{id}/attachments [get]
+func ListExpenseAttachments(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	if _, err := uuid.Parse(params["id"]); err != nil {
+		http.Error(w, "ID de despesa inválido", http.StatusBadRequest)
+		return
+	}
+
+	var exists bool
+	if err := db.DB.QueryRow(`
+		SELECT EXISTS (SELECT 1 FROM expenses WHERE user_id = $1 AND id = $2 AND deleted_at IS NULL)
+	`, params["userId"], params["id"]).Scan(&exists); err != nil {
+		http.Error(w, "Erro ao buscar despesa: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if !exists {
+		http.Error(w, "Despesa não encontrada", http.StatusNotFound)
+		return
+	}
+
+	rows, err := db.DB.Query(`
+		SELECT `+attachmentColumns+`
+		FROM expense_attachments
+		WHERE user_id = $1 AND expense_id = $2
+		ORDER BY created_at
+	`, params["userId"], params["id"])
+	if err != nil {
+		http.Error(w, "Erro ao buscar anexos: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	attachments := []models.Attachment{}
+	for rows.Next() {
+		var a models.Attachment
+		if err := scanAttachment(rows, &a); err != nil {
+			http.Error(w, "Erro ao ler anexo: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		attachments = append(attachments, a)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(attachments)
+}
+
+// DownloadExpenseAttachment devolve o conteúdo do anexo
+//
+// @Summary Baixar anexo da despesa
+// @Tags Expenses
+// @Security BearerAuth
+// @Produce application/pdf,image/jpeg,image/png
+// @Param userId path string true "ID do usuário"
+// @Param id path string true "ID da despesa"
+// @Param attachmentId path string true "ID do anexo"
+// @Success 200 {file} file
+// @Failure 400,401,404,500 {string} string
+// @Router /users/{userId}/expenses/{id}/attachments/{attachmentId} [get]
+func DownloadExpenseAttachment(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	if _, err := uuid.Parse(params["attachmentId"]); err != nil {
+		http.Error(w, "ID de anexo inválido", http.StatusBadRequest)
+		return
+	}
+
+	var a models.Attachment
+	var key string
+	err := db.DB.QueryRow(`
+		SELECT nome, content_type, tamanho, chave
+		FROM expense_attachments
+		WHERE user_id = $1 AND expense_id = $2 AND id = $3
+	`, params["userId"], params["id"], params["attachmentId"]).Scan(&a.Nome, &a.ContentType, &a.Tamanho, &key)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Anexo não encontrado", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Erro ao buscar anexo: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	body, err := storage.Blobs.Get(r.Context(), key)
+	if errors.Is(err, storage.ErrNotFound) {
+		http.Error(w, "Arquivo do anexo não encontrado no armazenamento", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Erro ao ler anexo: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer body.Close()
+
+	w.Header().Set("Content-Type", a.ContentType)
+	w.Header().Set("Content-Length", strconv.FormatInt(a.Tamanho, 10))
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": a.Nome}))
+	if _, err := io.Copy(w, body); err != nil {
+		log.Println("Erro ao enviar anexo:", err)
+	}
+}
+
+// DeleteExpenseAttachment exclui um anexo da despesa
+//
+// @Summary Excluir anexo da despesa
+// @Tags Expenses
+// @Security BearerAuth
+// @Param userId path string true "ID do usuário"
+// @Param id path string true "ID da despesa"
+// @Param attachmentId path string true "ID do anexo"
+// @Success 200 {object} Message
+// @Failure 400,401,404,500 {string} string
+// @Router /users/{userId}/expenses/{id}/attachments/{attachmentId} [delete]
+func DeleteExpenseAttachment(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	if _, err := uuid.Parse(params["attachmentId"]); err != nil {
+		http.Error(w, "ID de anexo inválido", http.StatusBadRequest)
+		return
+	}
+
+	var key string
+	err := db.DB.QueryRow(`
+		DELETE FROM expense_attachments
+		WHERE user_id = $1 AND expense_id = $2 AND id = $3
+		RETURNING chave
+	`, params["userId"], params["id"], params["attachmentId"]).Scan(&key)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Anexo não encontrado", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Erro ao excluir anexo: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	jobs.RemoveUnreferencedBlobs(r.Context(), []string{key})
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(Message{Message: "Anexo excluído com sucesso"})
+}
@@ -0,0 +1,181 @@
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"finance/src/db"
+	"finance/src/middlewares"
+	"finance/src/models"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+// defaultAuditLimit e maxAuditLimit limitam quantos registros do histórico voltam por consulta
+const (
+	defaultAuditLimit = 100
+	maxAuditLimit     = 1000
+)
+
+// apiAudit é o registro do histórico de uma alteração feita pela API, pelo usuário autenticado
+func apiAudit(r *http.Request, acao string) models.AuditEntry {
+	e := models.AuditEntry{Acao: acao, Origem: models.AuditSourceAPI}
+	if raw, ok := r.Context().Value(middlewares.UserIDKey).(string); ok {
+		if id, err := uuid.Parse(raw); err == nil {
+			e.AtorID = &id
+		}
+	}
+	return e
+}
+
+// queryAuditLog busca o histórico com os filtros já montados em where, do mais recente ao mais antigo
+func queryAuditLog(w http.ResponseWriter, where string, args []interface{}, limit int) {
+	args = append(args, limit)
+	rows, err := db.DB.Query(`
+		SELECT id, user_id, ator_id, entidade, entidade_id, acao, origem, alteracoes, created_at
+		FROM audit_log
+		WHERE `+where+`
+		ORDER BY created_at DESC, id
+		LIMIT $`+strconv.Itoa(len(args)), args...)
+	if err != nil {
+		http.Error(w, "Erro ao buscar histórico: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	entries := []models.AuditEntry{}
+	for rows.Next() {
+		var e models.AuditEntry
+		var alteracoes []byte
+		if err := rows.Scan(&e.ID, &e.UserID, &e.AtorID, &e.Entidade, &e.EntidadeID, &e.Acao, &e.Origem, &alteracoes, &e.CreatedAt); err != nil {
+			http.Error(w, "Erro ao ler histórico: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if err := json.Unmarshal(alteracoes, &e.Alteracoes); err != nil {
+			http.Error(w, "Erro ao ler histórico: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		entries = append(entries, e)
+	}
+	if err := rows.Err(); err != nil {
+		http.Error(w, "Erro ao ler histórico: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(entries)
+}
+
+// auditLimit lê o parâmetro limit (padrão 100, máximo 1000)
+func auditLimit(r *http.Request) (int, bool) {
+	raw := r.URL.Query().Get("limit")
+	if raw == "" {
+		return defaultAuditLimit, true
+	}
+	limit, err := strconv.Atoi(raw)
+	if err != nil || limit < 1 || limit > maxAuditLimit {
+		return 0, false
+	}
+	return limit, true
+}
+
+// ListAuditLog lista o histórico de alterações dos lançamentos e categorias do usuário
+//
+// @Summary Histórico de alterações do usuário
+// @Description Cada registro traz quem alterou (ator_id, vazio nos jobs), a origem (api, importacao ou recorrencia)
+// @Description e os campos alterados com os valores de antes e depois.
+// @Tags History
+// @Security BearerAuth
+// @Param userId path string true "ID do usuário"
+// @Param entidade query string false "expenses, incomes ou categories"
+// @Param acao query string false "criacao, alteracao, exclusao, restauracao, pagamento ou estorno_pagamento"
+// @Param de query string false "A partir da data (YYYY-MM-DD)"
+// @Param ate query string false "Até a data, inclusive (YYYY-MM-DD)"
+// @Param limit query int false "Quantidade máxima de registros (padrão 100, máximo 1000)"
+// @Success 200 {array} models.AuditEntry
+// @Failure 400,401,500 {string} string
+// @Router /users/{userId}/history [get]
+func ListAuditLog(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	where := "user_id = $1"
+	args := []interface{}{mux.Vars(r)["userId"]}
+
+	if entidade := q.Get("entidade"); entidade != "" {
+		if !models.ValidAuditEntity(entidade) {
+			http.Error(w, "Entidade inválida: use expenses, incomes ou categories", http.StatusBadRequest)
+			return
+		}
+		args = append(args, entidade)
+		where += fmt.Sprintf(" AND entidade = $%d", len(args))
+	}
+	if acao := q.Get("acao"); acao != "" {
+		if !models.ValidAuditAction(acao) {
+			http.Error(w, "Ação inválida", http.StatusBadRequest)
+			return
+		}
+		args = append(args, acao)
+		where += fmt.Sprintf(" AND acao = $%d", len(args))
+	}
+	if raw := q.Get("de"); raw != "" {
+		de, err := time.Parse("2006-01-02", raw)
+		if err != nil {
+			http.Error(w, "Data inicial inválida: use o formato YYYY-MM-DD", http.StatusBadRequest)
+			return
+		}
+		args = append(args, de)
+		where += fmt.Sprintf(" AND created_at >= $%d", len(args))
+	}
+	if raw := q.Get("ate"); raw != "" {
+		ate, err := time.Parse("2006-01-02", raw)
+		if err != nil {
+			http.Error(w, "Data final inválida: use o formato YYYY-MM-DD", http.StatusBadRequest)
+			return
+		}
+		args = append(args, ate.AddDate(0, 0, 1))
+		where += fmt.Sprintf(" AND created_at < $%d", len(args))
+	}
+
+	limit, ok := auditLimit(r)
+	if !ok {
+		http.Error(w, "limit inválido: use um número entre 1 e 1000", http.StatusBadRequest)
+		return
+	}
+	queryAuditLog(w, where, args, limit)
+}
+
+// GetEntityHistory lista o histórico de alterações de uma despesa, receita ou categoria
+//
+// @Summary Histórico de alterações do lançamento
+// @Description O histórico continua disponível depois que o lançamento é apagado de vez da lixeira.
+// @Tags History
+// @Security BearerAuth
+// @Param userId path string true "ID do usuário"
+// @Param entidade path string true "expenses, incomes ou categories"
+// @Param id path string true "ID do lançamento ou categoria"
+// @Param limit query int false "Quantidade máxima de registros (padrão 100, máximo 1000)"
+// @Success 200 {array} models.AuditEntry
+// @Failure 400,401,500 {string} string
+// @Router /users/{userId}/history/{entidade}/{id} [get]
+func GetEntityHistory(w http.ResponseWriter, r *http.Request) {
+	p := mux.Vars(r)
+	if !models.ValidAuditEntity(p["entidade"]) {
+		http.Error(w, "Entidade inválida: use expenses, incomes ou categories", http.StatusBadRequest)
+		return
+	}
+	id, err := uuid.Parse(p["id"])
+	if err != nil {
+		http.Error(w, "ID inválido", http.StatusBadRequest)
+		return
+	}
+
+	limit, ok := auditLimit(r)
+	if !ok {
+		http.Error(w, "limit inválido: use um número entre 1 e 1000", http.StatusBadRequest)
+		return
+	}
+	queryAuditLog(w, "user_id = $1 AND entidade = $2 AND entidade_id = $3", []interface{}{p["userId"], p["entidade"], id}, limit)
+}
@@ -0,0 +1,437 @@
+package controllers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"finance/src/db"
+	"finance/src/models"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/lib/pq"
+)
+
+const budgetColumns = `b.id, b.user_id, b.category_id, c.name, b.valor, b.recorrente, b.mes, b.fim, b.rollover, b.created_at`
+
+func scanBudget(row rowScanner, b *models.Budget) error {
+	var mes time.Time
+	var fim *time.Time
+	if err := row.Scan(&b.ID, &b.UserID, &b.CategoriaID, &b.Categoria, &b.Valor, &b.Recorrente, &mes, &fim, &b.Rollover, &b.CreatedAt); err != nil {
+		return err
+	}
+	b.Mes = mes.Format(models.BudgetMonthLayout)
+	b.Fim = nil
+	if fim != nil {
+		s := fim.Format(models.BudgetMonthLayout)
+		b.Fim = &s
+	}
+	return nil
+}
+
+// budgetPeriod valida o mês inicial e o fim (só para recorrentes) do orçamento
+func budgetPeriod(b *models.Budget) (time.Time, *time.Time, string) {
+	mes, err := models.ParseBudgetMonth(b.Mes)
+	if err != nil {
+		return mes, nil, "Mês inválido: use o formato YYYY-MM"
+	}
+	if b.Fim == nil {
+		return mes, nil, ""
+	}
+	if !b.Recorrente {
+		return mes, nil, "Só orçamentos recorrentes têm mês final"
+	}
+	fim, err := models.ParseBudgetMonth(*b.Fim)
+	if err != nil {
+		return mes, nil, "Mês final inválido: use o formato YYYY-MM"
+	}
+	if fim.Before(mes) {
+		return mes, nil, "O mês final deve ser igual ou posterior ao mês inicial"
+	}
+	return mes, &fim, ""
+}
+
+// parseMonthYear lê os parâmetros month e year e devolve o início e o fim do mês
+func parseMonthYear(r *http.Request) (time.Time, time.Time, bool) {
+	month, err1 := strconv.Atoi(r.URL.Query().Get("month"))
+	year, err2 := strconv.Atoi(r.URL.Query().Get("year"))
+	if err1 != nil || err2 != nil || month < 1 || month > 12 {
+		return time.Time{}, time.Time{}, false
+	}
+	start := time.Date(year, time.Month(month), 1, 0, 0, 0, 0, time.UTC)
+	return start, start.AddDate(0, 1, 0), true
+}
+
+// CreateBudget cria um orçamento para uma categoria de despesas
+//
+// @Summary Criar orçamento
+// @Description O orçamento vale para a categoria e todas as suas subcategorias, na moeda base do usuário.
+// @Description Com recorrente=true vale a partir de "mes" até "fim" (ou sem fim); um orçamento só do mês tem prioridade.
+// @Tags Budgets
+// @Security BearerAuth
+// @Param userId path string true "ID do usuário"
+// @Param budget body models.Budget true "Dados do orçamento"
+// @Success 201 {object} models.Budget
+// @Failure 400,401,409,500 {string} string
+// @Router /users/{userId}/budgets [post]
+func CreateBudget(w http.ResponseWriter, r *http.Request) {
+	userID, err := uuid.Parse(mux.Vars(r)["userId"])
+	if err != nil {
+		http.Error(w, "ID de usuário inválido", http.StatusBadRequest)
+		return
+	}
+
+	var b models.Budget
+	if err := json.NewDecoder(r.Body).Decode(&b); err != nil {
+		http.Error(w, "Erro ao decodificar JSON", http.StatusBadRequest)
+		return
+	}
+	if b.Valor < 0 {
+		http.Error(w, "Valor não pode ser negativo", http.StatusBadRequest)
+		return
+	}
+	mes, fim, msg := budgetPeriod(&b)
+	if msg != "" {
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
+
+	var kind string
+	err = db.DB.QueryRow(`
+		SELECT name, kind FROM categories WHERE user_id = $1 AND id = $2 AND deleted_at IS NULL
+	`, userID, b.CategoriaID).Scan(&b.Categoria, &kind)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Categoria não encontrada", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Erro ao buscar categoria: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if !models.CategoryAppliesTo(kind, models.CategoryExpense) {
+		http.Error(w, "Orçamentos só podem ser criados para categorias de despesa", http.StatusBadRequest)
+		return
+	}
+
+	b.ID = uuid.New()
+	b.UserID = userID
+	b.CreatedAt = time.Now()
+
+	_, err = db.DB.Exec(`
+		INSERT INTO budgets (id, user_id, category_id, valor, recorrente, mes, fim, rollover, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+	`, b.ID, b.UserID, b.CategoriaID, b.Valor, b.Recorrente, mes, fim, b.Rollover, b.CreatedAt)
+	if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+		http.Error(w, "Já existe um orçamento para essa categoria nesse mês", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Erro ao criar orçamento: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(b)
+}
+
+// ListBudgets lista os orçamentos do usuário
+//
+// @Summary Listar orçamentos
+// @Tags Budgets
+// @Security BearerAuth
+// @Param userId path string true "ID do usuário"
+// @Param mes query string false "Só os orçamentos em vigor no mês (YYYY-MM)"
+// @Success 200 {array} models.Budget
+// @Failure 400,401,500 {string} string
+// @Router /users/{userId}/budgets [get]
+func ListBudgets(w http.ResponseWriter, r *http.Request) {
+	userID := mux.Vars(r)["userId"]
+
+	var month *time.Time
+	if raw := r.URL.Query().Get("mes"); raw != "" {
+		m, err := models.ParseBudgetMonth(raw)
+		if err != nil {
+			http.Error(w, "Mês inválido: use o formato YYYY-MM", http.StatusBadRequest)
+			return
+		}
+		month = &m
+	}
+
+	rows, err := db.DB.Query(`
+		SELECT `+budgetColumns+`
+		FROM budgets b
+		JOIN categories c ON c.id = b.category_id
+		WHERE b.user_id = $1 AND c.deleted_at IS NULL
+		  AND ($2::date IS NULL
+		    OR (NOT b.recorrente AND b.mes = $2)
+		    OR (b.recorrente AND b.mes <= $2 AND (b.fim IS NULL OR b.fim >= $2)))
+		ORDER BY c.name, b.mes
+	`, userID, month)
+	if err != nil {
+		http.Error(w, "Erro ao buscar orçamentos: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	budgets := []models.Budget{}
+	for rows.Next() {
+		var b models.Budget
+		if err := scanBudget(rows, &b); err != nil {
+			http.Error(w, "Erro ao ler orçamento: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		budgets = append(budgets, b)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(budgets)
+}
+
+// UpdateBudget altera o valor, o mês final e o rollover de um orçamento
+//
+// @Summary Atualizar orçamento
+// @Tags Budgets
+// @Security BearerAuth
+// @Param userId path string true "ID do usuário"
+// @Param id path string true "ID do orçamento"
+// @Param budget body models.Budget true "Valor, fim e rollover"
+// @Success 200 {object} models.Budget
+// @Failure 400,401,404,500 {string} string
+// @Router /users/{userId}/budgets/{id} [put]
+func UpdateBudget(w http.ResponseWriter, r *http.Request) {
+	p := mux.Vars(r)
+
+	var in models.Budget
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		http.Error(w, "Erro ao decodificar JSON", http.StatusBadRequest)
+		return
+	}
+	if in.Valor < 0 {
+		http.Error(w, "Valor não pode ser negativo", http.StatusBadRequest)
+		return
+	}
+
+	var current models.Budget
+	err := scanBudget(db.DB.QueryRow(`
+		SELECT `+budgetColumns+`
+		FROM budgets b
+		JOIN categories c ON c.id = b.category_id
+		WHERE b.user_id = $1 AND b.id = $2
+	`, p["userId"], p["id"]), &current)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Orçamento não encontrado", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Erro ao buscar orçamento: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// categoria, mês inicial e tipo não mudam; para isso crie outro orçamento
+	current.Valor = in.Valor
+	current.Fim = in.Fim
+	current.Rollover = in.Rollover
+	_, fim, msg := budgetPeriod(&current)
+	if msg != "" {
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
+
+	if _, err := db.DB.Exec(`
+		UPDATE budgets SET valor = $1, fim = $2, rollover = $3
+		WHERE user_id = $4 AND id = $5
+	`, current.Valor, fim, current.Rollover, p["userId"], current.ID); err != nil {
+		http.Error(w, "Erro ao atualizar orçamento: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(current)
+}
+
+// DeleteBudget exclui um orçamento
+//
+// @Summary Excluir orçamento
+// @Tags Budgets
+// @Security BearerAuth
+// @Param userId path string true "ID do usuário"
+// @Param id path string true "ID do orçamento"
+// @Success 200 {object} Message
+// @Failure 400,401,404,500 {string} string
+// @Router /users/{userId}/budgets/{id} [delete]
+func DeleteBudget(w http.ResponseWriter, r *http.Request) {
+	p := mux.Vars(r)
+
+	result, err := db.DB.Exec(`
+		DELETE FROM budgets
+		WHERE user_id = $1 AND id = $2
+	`, p["userId"], p["id"])
+	if err != nil {
+		http.Error(w, "Erro ao excluir orçamento: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if rowsAffected, _ := result.RowsAffected(); rowsAffected == 0 {
+		http.Error(w, "Orçamento não encontrado", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(Message{Message: "Orçamento excluído com sucesso"})
+}
+
+// budgetSpending é o gasto de uma categoria (com subcategorias) num mês
+type budgetSpending struct {
+	total   models.Money
+	rates   models.RatesUsed
+	missing []string
+}
+
+// GetBudgetProgress compara, por categoria, o orçado, o gasto e o restante no mês
+//
+// O gasto é calculado como em GetExpensesByCategory: despesas pelo vencimento,
+// convertidas para a moeda base e somando as subcategorias. No mês corrente,
+// categorias cujo ritmo de gasto passaria do orçamento aparecem como "em_risco".
+//
+// @Summary Progresso dos orçamentos
+// @Tags Budgets
+// @Security BearerAuth
+// @Param userId path string true "ID do usuário"
+// @Param month query string true "Mês (1-12)"
+// @Param year query string true "Ano (YYYY)"
+// @Success 200 {array} models.BudgetProgress
+// @Failure 400,401,500 {string} string
+// @Router /users/{userId}/budgets/progress [get]
+func GetBudgetProgress(w http.ResponseWriter, r *http.Request) {
+	userID := mux.Vars(r)["userId"]
+	start, end, ok := parseMonthYear(r)
+	if !ok {
+		http.Error(w, "Parâmetros de mês/ano inválidos", http.StatusBadRequest)
+		return
+	}
+
+	rows, err := db.DB.Query(`
+		SELECT `+budgetColumns+`
+		FROM budgets b
+		JOIN categories c ON c.id = b.category_id
+		WHERE b.user_id = $1 AND b.mes <= $2 AND c.deleted_at IS NULL
+		ORDER BY b.mes
+	`, userID, start)
+	if err != nil {
+		http.Error(w, "Erro ao buscar orçamentos: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	byCategory := map[uuid.UUID]models.CategoryBudgets{}
+	for rows.Next() {
+		var b models.Budget
+		if err := scanBudget(rows, &b); err != nil {
+			rows.Close()
+			http.Error(w, "Erro ao ler orçamento: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		byCategory[b.CategoriaID] = append(byCategory[b.CategoriaID], b)
+	}
+	rows.Close()
+
+	// só interessam as categorias com orçamento no mês; com rollover, o saldo
+	// é acumulado desde o primeiro orçamento da categoria
+	var ids []string
+	from := start
+	for id, cb := range byCategory {
+		if cb.Effective(start) == nil {
+			delete(byCategory, id)
+			continue
+		}
+		ids = append(ids, id.String())
+		if first := cb.FirstMonth(); cb.HasRollover() && first.Before(from) {
+			from = first
+		}
+	}
+
+	spending := map[uuid.UUID]map[string]budgetSpending{}
+	if len(ids) > 0 {
+		rows, err := db.DB.Query(`
+			WITH RECURSIVE tree AS (
+				SELECT id, id AS ancestral FROM categories WHERE id = ANY($4::uuid[])
+				UNION ALL
+				SELECT c.id, t.ancestral FROM categories c JOIN tree t ON c.parent_id = t.id
+			)
+			SELECT t.ancestral, date_trunc('month', x.vencimento)::date AS mes,
+				COALESCE(SUM(x.valor_base), 0), `+ratesUsedSQL+`, `+missingRatesSQL+`
+			FROM `+netExpenseLinesSQL+` x
+			JOIN tree t ON t.id = x.category_id
+			WHERE x.user_id = $1 AND x.vencimento >= $2 AND x.vencimento < $3
+			GROUP BY t.ancestral, mes
+		`, userID, from, end, pq.Array(ids))
+		if err != nil {
+			http.Error(w, "Erro ao buscar gastos: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var id uuid.UUID
+			var mes time.Time
+			var s budgetSpending
+			if err := rows.Scan(&id, &mes, &s.total, &s.rates, pq.Array(&s.missing)); err != nil {
+				http.Error(w, "Erro ao processar gastos: "+err.Error(), http.StatusInternalServerError)
+				return
+			}
+			if spending[id] == nil {
+				spending[id] = map[string]budgetSpending{}
+			}
+			spending[id][mes.Format(models.BudgetMonthLayout)] = s
+		}
+	}
+
+	now := time.Now()
+	current := !now.Before(start) && now.Before(end)
+
+	result := []models.BudgetProgress{}
+	for id, cb := range byCategory {
+		spent := spending[id]
+
+		totals := make(map[string]models.Money, len(spent))
+		for mes, s := range spent {
+			totals[mes] = s.total
+		}
+		carry := cb.Carry(start, totals)
+
+		b := cb.Effective(start)
+		s := spent[start.Format(models.BudgetMonthLayout)]
+		p := models.BudgetProgress{
+			BudgetID:        b.ID,
+			CategoriaID:     id,
+			Categoria:       b.Categoria,
+			Orcado:          b.Valor,
+			Transportado:    carry,
+			Disponivel:      b.Valor + carry,
+			Gasto:           s.total,
+			TaxasUtilizadas: s.rates,
+			MoedasSemTaxa:   s.missing,
+		}
+		p.Restante = p.Disponivel - p.Gasto
+		if p.Disponivel > 0 {
+			p.Percentual = math.Round(float64(p.Gasto)/float64(p.Disponivel)*1000) / 10
+		}
+		if current {
+			// projeta o gasto até o fim do mês no ritmo dos dias já decorridos
+			elapsed := int64(now.Day())
+			days := int64(end.AddDate(0, 0, -1).Day())
+			projected := models.Money(int64(p.Gasto) * days / elapsed)
+			p.Projetado = &projected
+		}
+		p.Status = models.BudgetStatus(p.Disponivel, p.Gasto, p.Projetado)
+		result = append(result, p)
+	}
+
+	sort.Slice(result, func(i, j int) bool { return result[i].Categoria < result[j].Categoria })
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
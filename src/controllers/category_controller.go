@@ -1,19 +1,269 @@
 package controllers
 
 import (
+	"database/sql"
 	"encoding/json"
+	"errors"
+	"finance/src/audit"
 	"finance/src/db"
 	"finance/src/models"
+	"fmt"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
+	"github.com/lib/pq"
 )
 
+var (
+	errCategoryNotFound = errors.New("Categoria não encontrada")
+	errCategoryKind     = errors.New("Categoria não se aplica a este tipo de lançamento")
+)
+
+const categoryColumns = `id, user_id, parent_id, name, posicao, kind, color, icon, archived, created_at`
+
+func scanCategory(row rowScanner, cat *models.Category, extra ...interface{}) error {
+	dest := []interface{}{&cat.ID, &cat.UserID, &cat.ParentID, &cat.Name, &cat.Posicao, &cat.Kind, &cat.Color, &cat.Icon, &cat.Archived, &cat.CreatedAt}
+	return row.Scan(append(dest, extra...)...)
+}
+
+// categorySplitLink devolve a divisão cuja tabela de linhas é table, se for uma
+func categorySplitLink(table string) (splitLink, bool) {
+	for _, l := range splitLinks {
+		if l.table == table {
+			return l, true
+		}
+	}
+	return splitLink{}, false
+}
+
+// resolveCategory identifica a categoria pelo ID ou, na falta dele, pelo nome, criando
+// uma categoria raiz do tipo kind quando o nome ainda não existe. Devolve o ID e o nome atual.
+func resolveCategory(q dbExecutor, userID, kind string, id *uuid.UUID, name string) (*uuid.UUID, string, error) {
+	var catKind string
+	if id != nil {
+		err := q.QueryRow(`SELECT name, kind FROM categories WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL`, *id, userID).Scan(&name, &catKind)
+		if err == sql.ErrNoRows {
+			return nil, "", errCategoryNotFound
+		}
+		if err != nil {
+			return nil, "", err
+		}
+		if !models.CategoryAppliesTo(catKind, kind) {
+			return nil, "", errCategoryKind
+		}
+		return id, name, nil
+	}
+
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, "", nil
+	}
+
+	var catID uuid.UUID
+	err := q.QueryRow(`
+		INSERT INTO categories (id, user_id, name, kind, created_at)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (user_id, name) WHERE deleted_at IS NULL DO UPDATE SET name = EXCLUDED.name
+		RETURNING id, kind
+	`, uuid.New(), userID, name, kind, time.Now()).Scan(&catID, &catKind)
+	if err != nil {
+		return nil, "", err
+	}
+	if !models.CategoryAppliesTo(catKind, kind) {
+		return nil, "", errCategoryKind
+	}
+	return &catID, name, nil
+}
+
+// writeCategoryError responde 400 para categoria inexistente e 500 para falhas do banco
+func writeCategoryError(w http.ResponseWriter, err error) {
+	if err == errCategoryNotFound || err == errCategoryKind {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	http.Error(w, "Erro ao buscar categoria: "+err.Error(), http.StatusInternalServerError)
+}
+
+// reassignCategory move os lançamentos das categorias from para a categoria to,
+// registrando a mudança no histórico das despesas e receitas afetadas
+func reassignCategory(q dbExecutor, e models.AuditEntry, from []string, to uuid.UUID, toName string) error {
+	for _, table := range models.CategorizedTables {
+		var history *audit.Tracker
+		var err error
+		if models.ValidAuditEntity(table) {
+			history, err = audit.Track(q, table, "category_id = ANY($1::uuid[])", pq.Array(from))
+		} else if l, ok := categorySplitLink(table); ok {
+			// a mudança nas linhas aparece no histórico do lançamento dividido
+			history, err = audit.Track(q, l.owner, "id IN (SELECT "+l.column+" FROM "+l.table+" WHERE category_id = ANY($1::uuid[]))", pq.Array(from))
+		}
+		if err != nil {
+			return err
+		}
+		if _, err := q.Exec(`
+			UPDATE `+table+`
+			SET category_id = $1, categoria = $2
+			WHERE category_id = ANY($3::uuid[])
+		`, to, toName, pq.Array(from)); err != nil {
+			return err
+		}
+		if history != nil {
+			if err := history.Record(q, e); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// categoryInUse indica se alguma das categorias tem lançamentos vinculados fora da lixeira
+func categoryInUse(q dbExecutor, ids []string) (bool, error) {
+	for _, table := range models.CategorizedTables {
+		live := ""
+		if table == "expenses" || table == "incomes" {
+			live = " AND deleted_at IS NULL"
+		} else if l, ok := categorySplitLink(table); ok {
+			live = " AND " + l.column + " IN (SELECT id FROM " + l.owner + " WHERE deleted_at IS NULL)"
+		}
+		var used bool
+		err := q.QueryRow(`SELECT EXISTS (SELECT 1 FROM `+table+` WHERE category_id = ANY($1::uuid[])`+live+`)`, pq.Array(ids)).Scan(&used)
+		if err != nil || used {
+			return used, err
+		}
+	}
+	return false, nil
+}
+
+// categoryKindConflict indica se as categorias têm lançamentos que uma categoria do tipo kind não aceita
+func categoryKindConflict(q dbExecutor, ids []string, kind string) (bool, error) {
+	var tables []string
+	switch kind {
+	case models.CategoryExpense:
+		tables = []string{"incomes", "recurring_incomes", "income_splits"}
+	case models.CategoryIncome:
+		tables = []string{"expenses", "recurring_expenses", "expense_splits", "loans"}
+	}
+	for _, table := range tables {
+		var used bool
+		err := q.QueryRow(`SELECT EXISTS (SELECT 1 FROM `+table+` WHERE category_id = ANY($1::uuid[]))`, pq.Array(ids)).Scan(&used)
+		if err != nil || used {
+			return used, err
+		}
+	}
+	return false, nil
+}
+
+// validateParentCategory confere se a categoria pai existe e pertence ao usuário
+func validateParentCategory(q dbExecutor, userID uuid.UUID, parentID *uuid.UUID) (bool, error) {
+	if parentID == nil {
+		return true, nil
+	}
+	var ok bool
+	err := q.QueryRow(`SELECT EXISTS (SELECT 1 FROM categories WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL)`, *parentID, userID).Scan(&ok)
+	return ok, err
+}
+
+// isInSubtree indica se candidate é a própria categoria root ou uma de suas descendentes
+func isInSubtree(q dbExecutor, root, candidate uuid.UUID) (bool, error) {
+	rows, err := q.Query(`
+		SELECT id, parent_id FROM categories
+		WHERE user_id = (SELECT user_id FROM categories WHERE id = $1) AND parent_id IS NOT NULL
+	`, root)
+	if err != nil {
+		return false, err
+	}
+	defer rows.Close()
+
+	parents := make(map[uuid.UUID]uuid.UUID)
+	for rows.Next() {
+		var id, parent uuid.UUID
+		if err := rows.Scan(&id, &parent); err != nil {
+			return false, err
+		}
+		parents[id] = parent
+	}
+	if err := rows.Err(); err != nil {
+		return false, err
+	}
+	return models.IsInSubtree(parents, root, candidate), nil
+}
+
+// buildCategoryTree aninha as categorias (já ordenadas). Categorias cujo pai
+// ficou fora da lista (ex: pelos filtros) aparecem na raiz.
+func buildCategoryTree(categories []models.Category) []models.Category {
+	present := make(map[uuid.UUID]bool, len(categories))
+	children := make(map[uuid.UUID][]models.Category)
+	for _, cat := range categories {
+		present[cat.ID] = true
+		if cat.ParentID != nil {
+			children[*cat.ParentID] = append(children[*cat.ParentID], cat)
+		}
+	}
+
+	var attach func(cat models.Category) models.Category
+	attach = func(cat models.Category) models.Category {
+		for _, child := range children[cat.ID] {
+			cat.Filhos = append(cat.Filhos, attach(child))
+		}
+		return cat
+	}
+
+	var roots []models.Category
+	for _, cat := range categories {
+		if cat.ParentID == nil || !present[*cat.ParentID] {
+			roots = append(roots, attach(cat))
+		}
+	}
+	return roots
+}
+
+// validateCategoryFields normaliza e valida tipo e cor da categoria
+func validateCategoryFields(cat *models.Category) string {
+	if cat.Kind == "" {
+		cat.Kind = models.CategoryBoth
+	}
+	if !models.ValidCategoryKind(cat.Kind) {
+		return "Tipo inválido: use expense, income ou both"
+	}
+	if cat.Color != nil && !models.ValidCategoryColor(*cat.Color) {
+		return "Cor inválida: use o formato #RRGGBB"
+	}
+	return ""
+}
+
+// seedDefaultCategories cria as categorias padrão do idioma para um novo usuário
+func seedDefaultCategories(q dbExecutor, userID uuid.UUID, locale string) error {
+	now := time.Now()
+	insert := func(parentID *uuid.UUID, name string, posicao int, def models.DefaultCategory) (uuid.UUID, error) {
+		id := uuid.New()
+		_, err := q.Exec(`
+			INSERT INTO categories (id, user_id, parent_id, name, posicao, kind, color, icon, created_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+			ON CONFLICT (user_id, name) WHERE deleted_at IS NULL DO NOTHING
+		`, id, userID, parentID, name, posicao, def.Kind, def.Color, def.Icon, now)
+		return id, err
+	}
+
+	for i, def := range models.DefaultCategories(locale) {
+		parentID, err := insert(nil, def.Name, i, def)
+		if err != nil {
+			return err
+		}
+		for j, child := range def.Children {
+			if _, err := insert(&parentID, child, j, def); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
 // CreateCategory cria uma nova categoria para um usuário
 //
 // @Summary Criar categoria
+// @Description Informe parent_id para criar uma subcategoria (ex: Moradia > Aluguel).
 // @Tags Categories
 // @Security BearerAuth
 // @Param category body models.Category true "Dados da categoria"
@@ -31,20 +281,55 @@ func CreateCategory(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Nome e ID do usuário são obrigatórios", http.StatusBadRequest)
 		return
 	}
+	if cat.Posicao < 0 {
+		http.Error(w, "Posição não pode ser negativa", http.StatusBadRequest)
+		return
+	}
+	if msg := validateCategoryFields(&cat); msg != "" {
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
+
+	tx, err := db.DB.Begin()
+	if err != nil {
+		http.Error(w, "Erro ao iniciar transação: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	ok, err := validateParentCategory(tx, cat.UserID, cat.ParentID)
+	if err != nil {
+		http.Error(w, "Erro ao buscar categoria pai: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if !ok {
+		http.Error(w, "Categoria pai não encontrada", http.StatusBadRequest)
+		return
+	}
 
 	cat.ID = uuid.New()
 	cat.CreatedAt = time.Now()
 
-	_, err := db.DB.Exec(`
-		INSERT INTO categories (id, user_id, name, created_at)
-		VALUES ($1, $2, $3, $4)
-	`, cat.ID, cat.UserID, cat.Name, cat.CreatedAt)
+	_, err = tx.Exec(`
+		INSERT INTO categories (id, user_id, parent_id, name, posicao, kind, color, icon, archived, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+	`, cat.ID, cat.UserID, cat.ParentID, cat.Name, cat.Posicao, cat.Kind, cat.Color, cat.Icon, cat.Archived, cat.CreatedAt)
 
 	if err != nil {
 		http.Error(w, "Erro ao criar categoria: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
 
+	if err := audit.TrackNew(models.AuditCategories, cat.ID).Record(tx, apiAudit(r, models.AuditCreate)); err != nil {
+		http.Error(w, "Erro ao registrar histórico: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		http.Error(w, "Erro ao criar categoria: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
 	_ = json.NewEncoder(w).Encode(cat)
@@ -53,21 +338,62 @@ func CreateCategory(w http.ResponseWriter, r *http.Request) {
 // GetCategories retorna todas as categorias de um usuário
 //
 // @Summary Listar categorias
+// @Description Por padrão retorna a lista plana em ordem de árvore, com caminho e nível; com tree=true retorna as categorias aninhadas.
+// @Description kind=expense ou kind=income também inclui as categorias do tipo both.
 // @Tags Categories
 // @Security BearerAuth
 // @Param userId path string true "ID do usuário"
+// @Param tree query bool false "Retornar como árvore"
+// @Param kind query string false "expense, income ou both"
+// @Param archived query string false "false (padrão), true ou all"
 // @Success 200 {array} models.Category
 // @Failure 400,401,500 {string} string
 // @Router /categories/{userId} [get]
 func GetCategories(w http.ResponseWriter, r *http.Request) {
 	userID := mux.Vars(r)["userId"]
 
+	filters := []interface{}{userID}
+	where := ""
+
+	kind := r.URL.Query().Get("kind")
+	if kind != "" {
+		if !models.ValidCategoryKind(kind) {
+			http.Error(w, "Tipo inválido: use expense, income ou both", http.StatusBadRequest)
+			return
+		}
+		filters = append(filters, kind)
+		where += fmt.Sprintf(" AND (kind = $%d OR kind = 'both')", len(filters))
+	}
+
+	switch r.URL.Query().Get("archived") {
+	case "", "false":
+		where += " AND NOT archived"
+	case "true":
+		where += " AND archived"
+	case "all":
+	default:
+		http.Error(w, "archived inválido: use true, false ou all", http.StatusBadRequest)
+		return
+	}
+
 	rows, err := db.DB.Query(`
-		SELECT id, user_id, name, created_at
-		FROM categories
-		WHERE user_id = $1
-		ORDER BY name ASC
-	`, userID)
+		WITH RECURSIVE t AS (
+			SELECT `+categoryColumns+`,
+				name AS caminho, 0 AS nivel, ARRAY[to_char(posicao, 'FM0000000000') || name] AS ordem
+			FROM categories
+			WHERE user_id = $1 AND parent_id IS NULL AND deleted_at IS NULL
+			UNION ALL
+			SELECT c.id, c.user_id, c.parent_id, c.name, c.posicao, c.kind, c.color, c.icon, c.archived, c.created_at,
+				t.caminho || ' > ' || c.name, t.nivel + 1, t.ordem || (to_char(c.posicao, 'FM0000000000') || c.name)
+			FROM categories c
+			JOIN t ON c.parent_id = t.id
+			WHERE c.deleted_at IS NULL
+		)
+		SELECT `+categoryColumns+`, caminho, nivel
+		FROM t
+		WHERE true`+where+`
+		ORDER BY ordem
+	`, filters...)
 	if err != nil {
 		http.Error(w, "Erro ao buscar categorias: "+err.Error(), http.StatusInternalServerError)
 		return
@@ -77,44 +403,628 @@ func GetCategories(w http.ResponseWriter, r *http.Request) {
 	var categories []models.Category
 	for rows.Next() {
 		var cat models.Category
-		if err := rows.Scan(&cat.ID, &cat.UserID, &cat.Name, &cat.CreatedAt); err != nil {
+		if err := scanCategory(rows, &cat, &cat.Caminho, &cat.Nivel); err != nil {
 			http.Error(w, "Erro ao ler categoria: "+err.Error(), http.StatusInternalServerError)
 			return
 		}
 		categories = append(categories, cat)
 	}
 
+	if r.URL.Query().Get("tree") == "true" {
+		categories = buildCategoryTree(categories)
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	_ = json.NewEncoder(w).Encode(categories)
 }
 
-// DeleteCategory exclui uma categoria pelo ID
+// MoveCategoryInput define o novo pai e a posição de uma categoria
+type MoveCategoryInput struct {
+	ParentID *uuid.UUID `json:"parent_id"` // null move para a raiz
+	Posicao  *int       `json:"posicao"`   // omitido mantém a posição atual
+}
+
+// MoveCategory move uma categoria (e suas subcategorias) para outro pai e/ou posição
+//
+// @Summary Mover categoria
+// @Tags Categories
+// @Security BearerAuth
+// @Param id path string true "ID da categoria"
+// @Param move body MoveCategoryInput true "Novo pai e posição"
+// @Success 200 {object} models.Category
+// @Failure 400,401,404,409,500 {string} string
+// @Router /categories/{id}/move [patch]
+func MoveCategory(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "ID de categoria inválido", http.StatusBadRequest)
+		return
+	}
+
+	var in MoveCategoryInput
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		http.Error(w, "Erro ao decodificar JSON", http.StatusBadRequest)
+		return
+	}
+	if in.Posicao != nil && *in.Posicao < 0 {
+		http.Error(w, "Posição não pode ser negativa", http.StatusBadRequest)
+		return
+	}
+
+	tx, err := db.DB.Begin()
+	if err != nil {
+		http.Error(w, "Erro ao iniciar transação: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	var cat models.Category
+	err = scanCategory(tx.QueryRow(`
+		SELECT `+categoryColumns+`
+		FROM categories
+		WHERE id = $1 AND deleted_at IS NULL
+		FOR UPDATE
+	`, id), &cat)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Categoria não encontrada", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Erro ao buscar categoria: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	ok, err := validateParentCategory(tx, cat.UserID, in.ParentID)
+	if err != nil {
+		http.Error(w, "Erro ao buscar categoria pai: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if !ok {
+		http.Error(w, "Categoria pai não encontrada", http.StatusBadRequest)
+		return
+	}
+	if in.ParentID != nil {
+		cycle, err := isInSubtree(tx, cat.ID, *in.ParentID)
+		if err != nil {
+			http.Error(w, "Erro ao validar hierarquia: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if cycle {
+			http.Error(w, "Uma categoria não pode ser movida para dentro de si mesma ou de uma subcategoria", http.StatusConflict)
+			return
+		}
+	}
+
+	cat.ParentID = in.ParentID
+	if in.Posicao != nil {
+		cat.Posicao = *in.Posicao
+	}
+
+	history, err := audit.Track(tx, models.AuditCategories, "id = $1", cat.ID)
+	if err != nil {
+		http.Error(w, "Erro ao mover categoria: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if _, err := tx.Exec(`
+		UPDATE categories SET parent_id = $1, posicao = $2
+		WHERE id = $3
+	`, cat.ParentID, cat.Posicao, cat.ID); err != nil {
+		http.Error(w, "Erro ao mover categoria: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if err := history.Record(tx, apiAudit(r, models.AuditUpdate)); err != nil {
+		http.Error(w, "Erro ao registrar histórico: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		http.Error(w, "Erro ao mover categoria: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(cat)
+}
+
+// UpdateCategoryInput traz os atributos visuais e de uso da categoria; campos omitidos não mudam
+type UpdateCategoryInput struct {
+	Kind     *string `json:"kind"`
+	Color    *string `json:"color"`
+	Icon     *string `json:"icon"`
+	Archived *bool   `json:"archived"`
+}
+
+// UpdateCategory altera tipo, cor, ícone ou arquivamento de uma categoria
+//
+// @Summary Atualizar categoria
+// @Tags Categories
+// @Security BearerAuth
+// @Param id path string true "ID da categoria"
+// @Param category body UpdateCategoryInput true "Campos a alterar"
+// @Success 200 {object} models.Category
+// @Failure 400,401,404,409,500 {string} string
+// @Router /categories/{id} [patch]
+func UpdateCategory(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	var in UpdateCategoryInput
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		http.Error(w, "Erro ao decodificar JSON", http.StatusBadRequest)
+		return
+	}
+	if in.Kind != nil && !models.ValidCategoryKind(*in.Kind) {
+		http.Error(w, "Tipo inválido: use expense, income ou both", http.StatusBadRequest)
+		return
+	}
+	if in.Color != nil && *in.Color != "" && !models.ValidCategoryColor(*in.Color) {
+		http.Error(w, "Cor inválida: use o formato #RRGGBB", http.StatusBadRequest)
+		return
+	}
+
+	tx, err := db.DB.Begin()
+	if err != nil {
+		http.Error(w, "Erro ao iniciar transação: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	// restringir o tipo não pode deixar lançamentos do outro tipo na categoria
+	if in.Kind != nil {
+		conflict, err := categoryKindConflict(tx, []string{id}, *in.Kind)
+		if err != nil {
+			http.Error(w, "Erro ao verificar lançamentos: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if conflict {
+			http.Error(w, "Categoria possui lançamentos do outro tipo: mova-os antes de alterar o tipo", http.StatusConflict)
+			return
+		}
+	}
+
+	history, err := audit.Track(tx, models.AuditCategories, "id = $1 AND deleted_at IS NULL", id)
+	if err != nil {
+		http.Error(w, "Erro ao buscar categoria: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	var cat models.Category
+	err = scanCategory(tx.QueryRow(`
+		UPDATE categories
+		SET kind = COALESCE($1, kind),
+			color = CASE WHEN $2::text IS NULL THEN color ELSE NULLIF($2, '') END,
+			icon = CASE WHEN $3::text IS NULL THEN icon ELSE NULLIF($3, '') END,
+			archived = COALESCE($4, archived)
+		WHERE id = $5 AND deleted_at IS NULL
+		RETURNING `+categoryColumns+`
+	`, in.Kind, in.Color, in.Icon, in.Archived, id), &cat)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Categoria não encontrada", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Erro ao atualizar categoria: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if err := history.Record(tx, apiAudit(r, models.AuditUpdate)); err != nil {
+		http.Error(w, "Erro ao registrar histórico: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		http.Error(w, "Erro ao atualizar categoria: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(cat)
+}
+
+// RenameCategory renomeia uma categoria, mantendo os lançamentos vinculados
+//
+// @Summary Renomear categoria
+// @Tags Categories
+// @Security BearerAuth
+// @Param id path string true "ID da categoria"
+// @Param body body object{name=string} true "Novo nome"
+// @Success 200 {object} models.Category
+// @Failure 400,401,404,409,500 {string} string
+// @Router /categories/{id}/rename [patch]
+func RenameCategory(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "ID de categoria inválido", http.StatusBadRequest)
+		return
+	}
+
+	var in struct {
+		Name string `json:"name"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		http.Error(w, "Erro ao decodificar JSON", http.StatusBadRequest)
+		return
+	}
+	in.Name = strings.TrimSpace(in.Name)
+	if in.Name == "" {
+		http.Error(w, "Nome é obrigatório", http.StatusBadRequest)
+		return
+	}
+
+	tx, err := db.DB.Begin()
+	if err != nil {
+		http.Error(w, "Erro ao iniciar transação: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	history, err := audit.Track(tx, models.AuditCategories, "id = $1 AND deleted_at IS NULL", id)
+	if err != nil {
+		http.Error(w, "Erro ao buscar categoria: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	var cat models.Category
+	err = scanCategory(tx.QueryRow(`
+		UPDATE categories SET name = $1
+		WHERE id = $2 AND deleted_at IS NULL
+		RETURNING `+categoryColumns+`
+	`, in.Name, id), &cat)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Categoria não encontrada", http.StatusNotFound)
+		return
+	}
+	if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+		http.Error(w, "Já existe uma categoria com esse nome: use o merge para unificá-las", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Erro ao renomear categoria: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if err := history.Record(tx, apiAudit(r, models.AuditUpdate)); err != nil {
+		http.Error(w, "Erro ao registrar histórico: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if err := reassignCategory(tx, apiAudit(r, models.AuditUpdate), []string{id.String()}, cat.ID, cat.Name); err != nil {
+		http.Error(w, "Erro ao atualizar lançamentos: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		http.Error(w, "Erro ao renomear categoria: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(cat)
+}
+
+// MergeCategory une a categoria {id} à categoria destino: lançamentos,
+// subcategorias, orçamentos e movimentos de envelope passam para o destino e
+// a categoria {id}, já vazia, vai para a lixeira
+//
+// @Summary Unificar categorias
+// @Description Retorna 409 se as duas categorias tiverem orçamento para o mesmo mês.
+// @Description A categoria absorvida vai para a lixeira (GET /users/{userId}/trash).
+// @Tags Categories
+// @Security BearerAuth
+// @Param id path string true "ID da categoria a ser absorvida"
+// @Param body body object{target_id=string} true "Categoria destino"
+// @Success 200 {object} models.Category
+// @Failure 400,401,404,409,500 {string} string
+// @Router /categories/{id}/merge [post]
+func MergeCategory(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "ID de categoria inválido", http.StatusBadRequest)
+		return
+	}
+
+	var in struct {
+		TargetID uuid.UUID `json:"target_id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		http.Error(w, "Erro ao decodificar JSON", http.StatusBadRequest)
+		return
+	}
+
+	tx, err := db.DB.Begin()
+	if err != nil {
+		http.Error(w, "Erro ao iniciar transação: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	var userID uuid.UUID
+	err = tx.QueryRow(`SELECT user_id FROM categories WHERE id = $1 AND deleted_at IS NULL FOR UPDATE`, id).Scan(&userID)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Categoria não encontrada", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Erro ao buscar categoria: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	var target models.Category
+	err = scanCategory(tx.QueryRow(`
+		SELECT `+categoryColumns+`
+		FROM categories
+		WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL
+	`, in.TargetID, userID), &target)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Categoria destino não encontrada", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Erro ao buscar categoria destino: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	inside, err := isInSubtree(tx, id, target.ID)
+	if err != nil {
+		http.Error(w, "Erro ao validar hierarquia: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if inside {
+		http.Error(w, "A categoria destino não pode ser a própria categoria nem uma de suas subcategorias", http.StatusConflict)
+		return
+	}
+
+	conflict, err := categoryKindConflict(tx, []string{id.String()}, target.Kind)
+	if err != nil {
+		http.Error(w, "Erro ao verificar lançamentos: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if conflict {
+		http.Error(w, "A categoria destino não aceita o tipo dos lançamentos desta categoria", http.StatusConflict)
+		return
+	}
+
+	children, err := audit.Track(tx, models.AuditCategories, "parent_id = $1", id)
+	if err != nil {
+		http.Error(w, "Erro ao buscar subcategorias: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if _, err := tx.Exec(`UPDATE categories SET parent_id = $1 WHERE parent_id = $2`, target.ID, id); err != nil {
+		http.Error(w, "Erro ao mover subcategorias: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if err := children.Record(tx, apiAudit(r, models.AuditUpdate)); err != nil {
+		http.Error(w, "Erro ao registrar histórico: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if err := reassignCategory(tx, apiAudit(r, models.AuditUpdate), []string{id.String()}, target.ID, target.Name); err != nil {
+		http.Error(w, "Erro ao mover lançamentos: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// os orçamentos passam para o destino; dois orçamentos do mesmo mês (e
+	// recorrência) não têm como ser unidos sem perder um deles
+	var budgetClash bool
+	err = tx.QueryRow(`
+		SELECT EXISTS (
+			SELECT 1 FROM budgets b JOIN budgets t ON t.category_id = $1 AND t.recorrente = b.recorrente AND t.mes = b.mes
+			WHERE b.category_id = $2
+		)
+	`, target.ID, id).Scan(&budgetClash)
+	if err != nil {
+		http.Error(w, "Erro ao verificar orçamentos: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if budgetClash {
+		http.Error(w, "As duas categorias têm orçamento para o mesmo mês: exclua um deles antes de unificar", http.StatusConflict)
+		return
+	}
+	budgets, err := tx.Exec(`UPDATE budgets SET category_id = $1 WHERE category_id = $2`, target.ID, id)
+	if err != nil {
+		http.Error(w, "Erro ao mover orçamentos: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if n, _ := budgets.RowsAffected(); n > 0 && !models.CategoryAppliesTo(target.Kind, models.CategoryExpense) {
+		http.Error(w, "A categoria destino não aceita os orçamentos desta categoria", http.StatusConflict)
+		return
+	}
+
+	// atribuições e transferências do envelope são movimentos de dinheiro: passam
+	// para o destino em vez de sumirem com a categoria (ON DELETE CASCADE)
+	moved, err := tx.Exec(`UPDATE envelope_movements SET category_id = $1 WHERE category_id = $2`, target.ID, id)
+	if err != nil {
+		http.Error(w, "Erro ao mover envelope: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if n, _ := moved.RowsAffected(); n > 0 && !models.CategoryAppliesTo(target.Kind, models.CategoryExpense) {
+		http.Error(w, "A categoria destino não aceita o envelope desta categoria", http.StatusConflict)
+		return
+	}
+
+	merged, err := audit.Track(tx, models.AuditCategories, "id = $1", id)
+	if err != nil {
+		http.Error(w, "Erro ao buscar categoria: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if _, err := tx.Exec(`UPDATE categories SET deleted_at = $2 WHERE id = $1`, id, time.Now()); err != nil {
+		http.Error(w, "Erro ao excluir categoria: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if err := merged.Record(tx, apiAudit(r, models.AuditDelete)); err != nil {
+		http.Error(w, "Erro ao registrar histórico: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		http.Error(w, "Erro ao unificar categorias: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(target)
+}
+
+// DeleteCategory move uma categoria para a lixeira, de onde pode ser restaurada
+// até ser apagada de vez pelo job de limpeza
 //
 // @Summary Excluir categoria
+// @Description children define o que fazer com as subcategorias: restrict (padrão) recusa a exclusão,
+// @Description promote sobe as subcategorias para o pai da categoria excluída e cascade exclui toda a subárvore.
+// @Description Se houver lançamentos nas categorias excluídas, reassign_to é obrigatório e indica a categoria que os recebe.
+// @Description As categorias excluídas vão para a lixeira (GET /users/{userId}/trash).
 // @Tags Categories
 // @Security BearerAuth
 // @Param id path string true "ID da categoria"
+// @Param children query string false "Política para subcategorias: restrict, promote ou cascade"
+// @Param reassign_to query string false "Categoria que recebe os lançamentos"
 // @Success 204 {string} string "Categoria excluída com sucesso"
-// @Failure 400,401,404,500 {string} string
+// @Failure 400,401,404,409,500 {string} string
 // @Router /categories/{id} [delete]
 func DeleteCategory(w http.ResponseWriter, r *http.Request) {
 	id := mux.Vars(r)["id"]
 
-	result, err := db.DB.Exec(`
-		DELETE FROM categories
-		WHERE id = $1
-	`, id)
+	policy := r.URL.Query().Get("children")
+	if policy == "" {
+		policy = models.ChildrenRestrict
+	}
+	if policy != models.ChildrenRestrict && policy != models.ChildrenPromote && policy != models.ChildrenCascade {
+		http.Error(w, "Política de subcategorias inválida: use restrict, promote ou cascade", http.StatusBadRequest)
+		return
+	}
+
+	var reassignTo *uuid.UUID
+	if raw := r.URL.Query().Get("reassign_to"); raw != "" {
+		target, err := uuid.Parse(raw)
+		if err != nil {
+			http.Error(w, "reassign_to inválido", http.StatusBadRequest)
+			return
+		}
+		reassignTo = &target
+	}
+
+	tx, err := db.DB.Begin()
 	if err != nil {
-		http.Error(w, "Erro ao excluir categoria: "+err.Error(), http.StatusInternalServerError)
+		http.Error(w, "Erro ao iniciar transação: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
+	defer tx.Rollback()
 
-	rowsAffected, _ := result.RowsAffected()
-	if rowsAffected == 0 {
+	var userID uuid.UUID
+	var parentID *uuid.UUID
+	err = tx.QueryRow(`SELECT user_id, parent_id FROM categories WHERE id = $1 AND deleted_at IS NULL FOR UPDATE`, id).Scan(&userID, &parentID)
+	if err == sql.ErrNoRows {
 		http.Error(w, "Nenhuma categoria encontrada com esse ID", http.StatusNotFound)
 		return
 	}
+	if err != nil {
+		http.Error(w, "Erro ao buscar categoria: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	switch policy {
+	case models.ChildrenRestrict:
+		var hasChildren bool
+		if err := tx.QueryRow(`SELECT EXISTS (SELECT 1 FROM categories WHERE parent_id = $1 AND deleted_at IS NULL)`, id).Scan(&hasChildren); err != nil {
+			http.Error(w, "Erro ao buscar subcategorias: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if hasChildren {
+			http.Error(w, "Categoria possui subcategorias: use children=promote ou children=cascade", http.StatusConflict)
+			return
+		}
+	case models.ChildrenPromote:
+		children, err := audit.Track(tx, models.AuditCategories, "parent_id = $1 AND deleted_at IS NULL", id)
+		if err != nil {
+			http.Error(w, "Erro ao buscar subcategorias: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if _, err := tx.Exec(`UPDATE categories SET parent_id = $1 WHERE parent_id = $2 AND deleted_at IS NULL`, parentID, id); err != nil {
+			http.Error(w, "Erro ao mover subcategorias: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if err := children.Record(tx, apiAudit(r, models.AuditUpdate)); err != nil {
+			http.Error(w, "Erro ao registrar histórico: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	// com cascade a subárvore inteira é excluída; nos demais casos sobra só a própria categoria
+	var ids []string
+	err = tx.QueryRow(`
+		WITH RECURSIVE sub AS (
+			SELECT id FROM categories WHERE id = $1
+			UNION ALL
+			SELECT c.id FROM categories c JOIN sub s ON c.parent_id = s.id WHERE c.deleted_at IS NULL
+		)
+		SELECT array_agg(id::text) FROM sub
+	`, id).Scan(pq.Array(&ids))
+	if err != nil {
+		http.Error(w, "Erro ao buscar subcategorias: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if reassignTo != nil {
+		var targetName, targetKind string
+		err := tx.QueryRow(`
+			SELECT name, kind FROM categories
+			WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL AND NOT (id::text = ANY($3))
+		`, *reassignTo, userID, pq.Array(ids)).Scan(&targetName, &targetKind)
+		if err == sql.ErrNoRows {
+			http.Error(w, "Categoria de destino não encontrada ou incluída na exclusão", http.StatusBadRequest)
+			return
+		}
+		if err != nil {
+			http.Error(w, "Erro ao buscar categoria de destino: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		conflict, err := categoryKindConflict(tx, ids, targetKind)
+		if err != nil {
+			http.Error(w, "Erro ao verificar lançamentos: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if conflict {
+			http.Error(w, "A categoria de destino não aceita o tipo dos lançamentos", http.StatusConflict)
+			return
+		}
+		if err := reassignCategory(tx, apiAudit(r, models.AuditUpdate), ids, *reassignTo, targetName); err != nil {
+			http.Error(w, "Erro ao mover lançamentos: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+	} else {
+		used, err := categoryInUse(tx, ids)
+		if err != nil {
+			http.Error(w, "Erro ao verificar lançamentos: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if used {
+			http.Error(w, "Categoria possui lançamentos: informe reassign_to com a categoria que vai recebê-los", http.StatusConflict)
+			return
+		}
+	}
+
+	history, err := audit.Track(tx, models.AuditCategories, "id::text = ANY($1)", pq.Array(ids))
+	if err != nil {
+		http.Error(w, "Erro ao buscar categoria: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// a subárvore vai para a lixeira com o mesmo deleted_at, que a restauração usa para trazê-la de volta junta
+	if _, err := tx.Exec(`UPDATE categories SET deleted_at = $2 WHERE id::text = ANY($1)`, pq.Array(ids), time.Now()); err != nil {
+		http.Error(w, "Erro ao excluir categoria: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if err := history.Record(tx, apiAudit(r, models.AuditDelete)); err != nil {
+		http.Error(w, "Erro ao registrar histórico: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		http.Error(w, "Erro ao excluir categoria: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
 
 	w.WriteHeader(http.StatusNoContent)
 	w.Write([]byte("Categoria excluída com sucesso"))
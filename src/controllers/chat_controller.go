@@ -3,40 +3,103 @@ package controllers
 import (
 	"encoding/json"
 	"finance/src/db"
+	"finance/src/models"
 	"net/http"
 	"strconv"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/gorilla/mux"
+	"github.com/lib/pq"
 )
 
+// Os totais dos gráficos estão na moeda base do usuário. TaxasUtilizadas lista
+// as taxas aplicadas e MoedasSemTaxa as moedas que ficaram fora por falta de taxa.
+
 type CategoryChart struct {
-	Categoria string  `json:"categoria"`
-	Total     float64 `json:"total"`
+	CategoriaID     *uuid.UUID       `json:"categoria_id,omitempty"`
+	Categoria       string           `json:"categoria"`
+	Direto          bool             `json:"direto,omitempty"` // lançamentos na própria categoria pai
+	TemFilhos       bool             `json:"tem_filhos"`       // permite drill-down via parent_id
+	Total           models.Money     `json:"total"`
+	TaxasUtilizadas models.RatesUsed `json:"taxas_utilizadas,omitempty"`
+	MoedasSemTaxa   []string         `json:"moedas_sem_taxa,omitempty"`
 }
 
 type StatusChart struct {
-	Status string  `json:"status"`
-	Total  float64 `json:"total"`
+	Status          string           `json:"status"`
+	Total           models.Money     `json:"total"`
+	TaxasUtilizadas models.RatesUsed `json:"taxas_utilizadas,omitempty"`
+	MoedasSemTaxa   []string         `json:"moedas_sem_taxa,omitempty"`
 }
 
 type MonthChart struct {
-	Mes   string  `json:"mes"`
-	Total float64 `json:"total"`
+	Mes             string           `json:"mes"`
+	Total           models.Money     `json:"total"`
+	TaxasUtilizadas models.RatesUsed `json:"taxas_utilizadas,omitempty"`
+	MoedasSemTaxa   []string         `json:"moedas_sem_taxa,omitempty"`
 }
 
 type IncomeCategoryChart struct {
-	Categoria string  `json:"categoria"`
-	Total     float64 `json:"total"`
+	CategoriaID     *uuid.UUID       `json:"categoria_id,omitempty"`
+	Categoria       string           `json:"categoria"`
+	Direto          bool             `json:"direto,omitempty"` // lançamentos na própria categoria pai
+	TemFilhos       bool             `json:"tem_filhos"`       // permite drill-down via parent_id
+	Total           models.Money     `json:"total"`
+	TaxasUtilizadas models.RatesUsed `json:"taxas_utilizadas,omitempty"`
+	MoedasSemTaxa   []string         `json:"moedas_sem_taxa,omitempty"`
+}
+
+// categoryRollupSQL agrupa os lançamentos de source pelos filhos diretos da
+// categoria $4 (raiz quando NULL), somando toda a subárvore de cada filho.
+// Lançamentos feitos na própria categoria $4 aparecem numa linha "direto"; na
+// raiz, lançamentos sem categoria aparecem agrupados pelo texto de categoria.
+func categoryRollupSQL(source, where string) string {
+	return `
+		WITH RECURSIVE sub AS (
+			SELECT id, id AS raiz FROM categories WHERE user_id = $1 AND parent_id IS NOT DISTINCT FROM $4::uuid
+			UNION ALL
+			SELECT c.id, s.raiz FROM categories c JOIN sub s ON c.parent_id = s.id
+		), tx AS (
+			SELECT x.*, s.raiz, x.category_id AS cat_id
+			FROM ` + source + ` x
+			LEFT JOIN sub s ON s.id = x.category_id
+			WHERE ` + where + `
+		)
+		SELECT k.id, COALESCE(k.name, x.categoria) AS categoria,
+			$4::uuid IS NOT NULL AND bool_and(x.raiz IS NULL) AS direto,
+			bool_or(x.raiz IS NOT NULL) AND EXISTS (SELECT 1 FROM categories f WHERE f.parent_id = k.id) AS tem_filhos,
+			COALESCE(SUM(x.valor_base), 0) AS total, ` + ratesUsedSQL + `, ` + missingRatesSQL + `
+		FROM tx x
+		LEFT JOIN categories k ON k.id = COALESCE(x.raiz, x.cat_id)
+		WHERE x.raiz IS NOT NULL OR x.cat_id IS NOT DISTINCT FROM $4::uuid
+		GROUP BY k.id, COALESCE(k.name, x.categoria), x.raiz IS NULL
+		ORDER BY total DESC
+	`
+}
+
+// parseParentCategory lê o parâmetro opcional parent_id usado no drill-down
+func parseParentCategory(r *http.Request) (*uuid.UUID, error) {
+	raw := r.URL.Query().Get("parent_id")
+	if raw == "" {
+		return nil, nil
+	}
+	id, err := uuid.Parse(raw)
+	if err != nil {
+		return nil, err
+	}
+	return &id, nil
 }
 
-// GetExpensesByCategory retorna soma das despesas agrupadas por categoria
+// GetExpensesByCategory retorna soma das despesas agrupadas por categoria, somando as subcategorias
 //
 // @Summary Despesas por categoria
+// @Description Despesas divididas entram em cada categoria com o valor da linha da divisão.
 // @Tags Charts
 // @Param userId path string true "ID do usuário"
 // @Param month query string true "Mês (1-12)"
 // @Param year query string true "Ano (YYYY)"
+// @Param parent_id query string false "Categoria pai para drill-down (padrão: raiz)"
 // @Success 200 {array} CategoryChart
 // @Failure 400,401,500 {string} string
 // @Router /charts/expenses-by-category/{userId} [get]
@@ -57,13 +120,14 @@ func GetExpensesByCategory(w http.ResponseWriter, r *http.Request) {
 	start := time.Date(year, time.Month(month), 1, 0, 0, 0, 0, time.UTC) // Define o início do mês
 	end := start.AddDate(0, 1, 0)                                        // Adiciona um mês para definir o final do mês
 
-	rows, err := db.DB.Query(`
-		SELECT categoria, COALESCE(SUM(valor), 0) AS total
-		FROM expenses
-		WHERE user_id = $1 AND vencimento >= $2 AND vencimento < $3
-		GROUP BY categoria
-		ORDER BY total DESC
-	`, userID, start, end)
+	parentID, err := parseParentCategory(r)
+	if err != nil {
+		http.Error(w, "parent_id inválido", http.StatusBadRequest)
+		return
+	}
+
+	rows, err := db.DB.Query(categoryRollupSQL(netExpenseLinesSQL,
+		`x.user_id = $1 AND x.vencimento >= $2 AND x.vencimento < $3`), userID, start, end, parentID)
 	if err != nil {
 		http.Error(w, "Erro ao buscar dados", http.StatusInternalServerError)
 		return
@@ -73,7 +137,7 @@ func GetExpensesByCategory(w http.ResponseWriter, r *http.Request) {
 	var result []CategoryChart
 	for rows.Next() {
 		var row CategoryChart
-		if err := rows.Scan(&row.Categoria, &row.Total); err != nil {
+		if err := rows.Scan(&row.CategoriaID, &row.Categoria, &row.Direto, &row.TemFilhos, &row.Total, &row.TaxasUtilizadas, pq.Array(&row.MoedasSemTaxa)); err != nil {
 			http.Error(w, "Erro ao processar dados", http.StatusInternalServerError)
 			return
 		}
@@ -107,18 +171,25 @@ func GetExpensesByStatus(w http.ResponseWriter, r *http.Request) {
 	start := time.Date(year, time.Month(month), 1, 0, 0, 0, 0, time.UTC) // Define o início do mês
 	end := start.AddDate(0, 1, 0)                                        // Adiciona um mês para definir o final do mês
 
+	// Valores pagos (inclusive parciais) entram em "Paga"; o saldo em aberto
+	// de cada despesa entra em "Vencida" ou "A Vencer"
 	query := `
-		SELECT
-			CASE
-				WHEN paga = true THEN 'Paga'
-				WHEN paga = false AND vencimento < CURRENT_DATE THEN 'Vencida'
-				ELSE 'A Vencer'
-			END AS status,
-			COALESCE(SUM(valor), 0) AS total
-		FROM expenses
-		WHERE user_id = $1 AND vencimento >= $2 AND vencimento < $3
-		GROUP BY status
-		ORDER BY status
+		SELECT s.status, COALESCE(SUM(s.total * x.fator), 0) AS total, ` + ratesUsedSQL + `, ` + missingRatesSQL + `
+		FROM ` + convertedSQL(liveExpenses, "vencimento") + ` x
+		LEFT JOIN LATERAL (
+			SELECT SUM(valor) AS pago, SUM(valor - multa - juros + desconto) AS principal
+			FROM expense_payments
+			WHERE expense_id = x.id
+		) p ON true,
+		LATERAL (VALUES
+			('Paga', COALESCE(p.pago, 0)),
+			(CASE WHEN x.vencimento < CURRENT_DATE THEN 'Vencida' ELSE 'A Vencer' END,
+			 CASE WHEN x.paga THEN 0 ELSE GREATEST(x.valor - COALESCE(p.principal, 0), 0) END)
+		) AS s(status, total)
+		WHERE x.user_id = $1 AND x.vencimento >= $2 AND x.vencimento < $3
+		GROUP BY s.status
+		HAVING SUM(s.total) > 0
+		ORDER BY s.status
 	`
 	rows, err := db.DB.Query(query, uid, start, end)
 	if err != nil {
@@ -130,7 +201,7 @@ func GetExpensesByStatus(w http.ResponseWriter, r *http.Request) {
 	var result []StatusChart
 	for rows.Next() {
 		var s StatusChart
-		if err := rows.Scan(&s.Status, &s.Total); err != nil {
+		if err := rows.Scan(&s.Status, &s.Total, &s.TaxasUtilizadas, pq.Array(&s.MoedasSemTaxa)); err != nil {
 			http.Error(w, "Erro ao processar dados", http.StatusInternalServerError)
 			return
 		}
@@ -163,9 +234,9 @@ func GetMonthlySummaryChart(w http.ResponseWriter, r *http.Request) {
 	end := start.AddDate(1, 0, 0)                        // Adiciona um ano para definir o final do ano
 
 	query := `
-		SELECT EXTRACT(MONTH FROM vencimento)::INT As mes, COALESCE(SUM(valor), 0) AS total
-		FROM expenses
-		WHERE user_id = $1 AND vencimento >= $2 AND vencimento < $3
+		SELECT EXTRACT(MONTH FROM x.vencimento)::INT As mes, COALESCE(SUM(x.valor_base), 0) AS total, ` + ratesUsedSQL + `, ` + missingRatesSQL + `
+		FROM ` + netExpensesSQL + ` x
+		WHERE x.user_id = $1 AND x.vencimento >= $2 AND x.vencimento < $3
 		GROUP BY mes
 		ORDER BY mes
 	`
@@ -179,7 +250,7 @@ func GetMonthlySummaryChart(w http.ResponseWriter, r *http.Request) {
 	var result []MonthChart
 	for rows.Next() {
 		var m MonthChart
-		if err := rows.Scan(&m.Mes, &m.Total); err != nil {
+		if err := rows.Scan(&m.Mes, &m.Total, &m.TaxasUtilizadas, pq.Array(&m.MoedasSemTaxa)); err != nil {
 			http.Error(w, "Erro ao processar dados", http.StatusInternalServerError)
 			return
 		}
@@ -190,14 +261,16 @@ func GetMonthlySummaryChart(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(result)
 }
 
-// GetIncomeByCategory retorna soma das receitas agrupadas por categoria
+// GetIncomeByCategory retorna soma das receitas agrupadas por categoria, somando as subcategorias
 //
 // @Summary Receitas por categoria
+// @Description Receitas divididas entram em cada categoria com o valor da linha da divisão.
 // @Tags Charts
 // @Security BearerAuth
 // @Param userId path string true "ID do usuário"
 // @Param month query string true "Mês (1-12)"
 // @Param year query string true "Ano (YYYY)"
+// @Param parent_id query string false "Categoria pai para drill-down (padrão: raiz)"
 // @Success 200 {array} IncomeCategoryChart
 // @Failure 400,401,500 {string} string
 // @Router /charts/incomes-by-category/{userId} [get]
@@ -217,13 +290,14 @@ func GetIncomeByCategory(w http.ResponseWriter, r *http.Request) {
 	start := time.Date(year, time.Month(month), 1, 0, 0, 0, 0, time.UTC) // Define o início do mês
 	end := start.AddDate(0, 1, 0)                                        // Adiciona um mês para definir o final do mês
 
-	rows, err := db.DB.Query(`
-		SELECT categoria, COALESCE(SUM(valor), 0) AS total
-		FROM incomes
-		WHERE user_id = $1 AND data_recebimento >= $2 AND data_recebimento < $3
-		GROUP BY categoria
-		ORDER BY total DESC
-	`, userID, start, end)
+	parentID, err := parseParentCategory(r)
+	if err != nil {
+		http.Error(w, "parent_id inválido", http.StatusBadRequest)
+		return
+	}
+
+	rows, err := db.DB.Query(categoryRollupSQL(incomeLinesSQL,
+		`x.user_id = $1 AND x.status = 'recebida' AND x.data_recebimento >= $2 AND x.data_recebimento < $3`), userID, start, end, parentID)
 	if err != nil {
 		http.Error(w, "Erro ao buscar dados", http.StatusInternalServerError)
 		return
@@ -233,7 +307,7 @@ func GetIncomeByCategory(w http.ResponseWriter, r *http.Request) {
 	var result []IncomeCategoryChart
 	for rows.Next() {
 		var row IncomeCategoryChart
-		if err := rows.Scan(&row.Categoria, &row.Total); err != nil {
+		if err := rows.Scan(&row.CategoriaID, &row.Categoria, &row.Direto, &row.TemFilhos, &row.Total, &row.TaxasUtilizadas, pq.Array(&row.MoedasSemTaxa)); err != nil {
 			http.Error(w, "Erro ao processar dados", http.StatusInternalServerError)
 			return
 		}
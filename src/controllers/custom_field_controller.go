@@ -0,0 +1,502 @@
+package controllers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"finance/src/db"
+	"finance/src/models"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/lib/pq"
+)
+
+// fieldLink descreve a tabela com os valores dos campos personalizados de um tipo de lançamento
+type fieldLink struct {
+	table  string // tabela de valores
+	column string // coluna com o ID do lançamento
+	owner  string // tabela do lançamento
+	kind   string // expense ou income, para conferir se o campo se aplica
+}
+
+var (
+	expenseFieldLink = fieldLink{table: "expense_field_values", column: "expense_id", owner: "expenses", kind: models.CategoryExpense}
+	incomeFieldLink  = fieldLink{table: "income_field_values", column: "income_id", owner: "incomes", kind: models.CategoryIncome}
+)
+
+// fieldValueError é um valor de campo personalizado recusado na validação
+type fieldValueError struct{ msg string }
+
+func (e *fieldValueError) Error() string { return e.msg }
+
+// writeFieldError responde 400 para valores inválidos e 500 para o resto
+func writeFieldError(w http.ResponseWriter, err error) {
+	var invalid *fieldValueError
+	if errors.As(err, &invalid) {
+		http.Error(w, invalid.Error(), http.StatusBadRequest)
+		return
+	}
+	http.Error(w, "Erro ao salvar campos personalizados: "+err.Error(), http.StatusInternalServerError)
+}
+
+// valuesColumn devolve os campos do lançamento corrente como objeto JSON, para uso nas listas de colunas
+func (l fieldLink) valuesColumn() string {
+	return `COALESCE((SELECT jsonb_object_agg(f.nome, v.valor) FROM ` + l.table + ` v JOIN custom_fields f ON f.id = v.field_id
+		WHERE v.` + l.column + ` = ` + l.owner + `.id), '{}') AS campos`
+}
+
+// filter exige que o lançamento tenha cada campo com o valor pedido, comparado como texto
+func (l fieldLink) filter(ownerRef string, filters []fieldFilterValue, args []interface{}) (string, []interface{}) {
+	var out string
+	for _, f := range filters {
+		args = append(args, f.nome, f.valor)
+		out += fmt.Sprintf(` AND EXISTS (
+			SELECT 1 FROM %s v JOIN custom_fields f ON f.id = v.field_id
+			WHERE v.%s = %s AND f.nome = $%d AND v.valor #>> '{}' = $%d
+		)`, l.table, l.column, ownerRef, len(args)-1, len(args))
+	}
+	return out, args
+}
+
+// set substitui os valores dos campos personalizados de um lançamento. Os
+// campos são identificados pelo nome; valores null são ignorados.
+func (l fieldLink) set(q dbExecutor, userID string, ownerID uuid.UUID, campos models.CustomValues) (models.CustomValues, error) {
+	fields := map[string]models.CustomField{}
+	rows, err := q.Query(`SELECT id, nome, tipo, kind, opcoes FROM custom_fields WHERE user_id = $1`, userID)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var f models.CustomField
+		if err := rows.Scan(&f.ID, &f.Nome, &f.Tipo, &f.Kind, pq.Array(&f.Opcoes)); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		fields[f.Nome] = f
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	out := models.CustomValues{}
+	for nome, v := range campos {
+		if v == nil {
+			continue
+		}
+		f, ok := fields[nome]
+		if !ok {
+			return nil, &fieldValueError{fmt.Sprintf("Campo personalizado não encontrado: %s", nome)}
+		}
+		if !models.CategoryAppliesTo(f.Kind, l.kind) {
+			return nil, &fieldValueError{fmt.Sprintf("Campo %q não se aplica a este tipo de lançamento", nome)}
+		}
+		if out[nome], err = f.Normalize(v); err != nil {
+			return nil, &fieldValueError{err.Error()}
+		}
+	}
+
+	if _, err := q.Exec(`DELETE FROM `+l.table+` WHERE `+l.column+` = $1`, ownerID); err != nil {
+		return nil, err
+	}
+	for nome, v := range out {
+		valor, err := json.Marshal(v)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := q.Exec(`INSERT INTO `+l.table+` (`+l.column+`, field_id, valor) VALUES ($1, $2, $3)`, ownerID, fields[nome].ID, valor); err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+// fieldFilterValue é um filtro ?campo[nome]=valor
+type fieldFilterValue struct {
+	nome  string
+	valor string
+}
+
+// fieldFilter lê os parâmetros ?campo[nome]=valor; o lançamento precisa atender a todos
+func fieldFilter(r *http.Request) []fieldFilterValue {
+	var out []fieldFilterValue
+	for key, values := range r.URL.Query() {
+		if !strings.HasPrefix(key, "campo[") || !strings.HasSuffix(key, "]") || len(values) == 0 {
+			continue
+		}
+		out = append(out, fieldFilterValue{nome: key[len("campo[") : len(key)-1], valor: values[0]})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].nome < out[j].nome })
+	return out
+}
+
+const customFieldColumns = `id, user_id, nome, tipo, kind, opcoes, created_at,
+	(SELECT COUNT(*) FROM expense_field_values v JOIN expenses e ON e.id = v.expense_id WHERE v.field_id = custom_fields.id AND e.deleted_at IS NULL) +
+	(SELECT COUNT(*) FROM income_field_values v JOIN incomes i ON i.id = v.income_id WHERE v.field_id = custom_fields.id AND i.deleted_at IS NULL) AS usos`
+
+func scanCustomField(row rowScanner, f *models.CustomField) error {
+	return row.Scan(&f.ID, &f.UserID, &f.Nome, &f.Tipo, &f.Kind, pq.Array(&f.Opcoes), &f.CreatedAt, &f.Usos)
+}
+
+// customFieldConflict indica por que a nova definição do campo invalidaria
+// valores já gravados (outro tipo, opção removida ou tipo de lançamento excluído)
+func customFieldConflict(q dbExecutor, f models.CustomField) (string, error) {
+	var tipo string
+	if err := q.QueryRow(`SELECT tipo FROM custom_fields WHERE id = $1`, f.ID).Scan(&tipo); err != nil {
+		return "", err
+	}
+
+	var removedOption, onExpenses, onIncomes bool
+	err := q.QueryRow(`
+		WITH valores AS (
+			SELECT 'expense' AS kind, v.valor FROM expense_field_values v WHERE v.field_id = $1
+			UNION ALL
+			SELECT 'income', v.valor FROM income_field_values v WHERE v.field_id = $1
+		)
+		SELECT
+			EXISTS (SELECT 1 FROM valores WHERE NOT (valor #>> '{}' = ANY($2::text[]))),
+			EXISTS (SELECT 1 FROM valores WHERE kind = 'expense'),
+			EXISTS (SELECT 1 FROM valores WHERE kind = 'income')
+	`, f.ID, pq.Array(f.Opcoes)).Scan(&removedOption, &onExpenses, &onIncomes)
+	if err != nil {
+		return "", err
+	}
+
+	switch {
+	case tipo != f.Tipo && (onExpenses || onIncomes):
+		return "O tipo de um campo em uso não pode ser alterado", nil
+	case f.Tipo == models.FieldSelect && removedOption:
+		return "Não é possível remover uma opção em uso", nil
+	case onExpenses && !models.CategoryAppliesTo(f.Kind, models.CategoryExpense):
+		return "O campo está preenchido em despesas", nil
+	case onIncomes && !models.CategoryAppliesTo(f.Kind, models.CategoryIncome):
+		return "O campo está preenchido em receitas", nil
+	}
+	return "", nil
+}
+
+// CreateCustomField cria um campo personalizado
+//
+// @Summary Criar campo personalizado
+// @Description Os valores são informados em "campos" nas despesas e receitas, pelo nome do campo.
+// @Tags CustomFields
+// @Security BearerAuth
+// @Param userId path string true "ID do usuário"
+// @Param field body models.CustomField true "Nome, tipo (texto, numero, data, selecao ou booleano), kind e opções"
+// @Success 201 {object} models.CustomField
+// @Failure 400,401,409,500 {string} string
+// @Router /users/{userId}/custom-fields [post]
+func CreateCustomField(w http.ResponseWriter, r *http.Request) {
+	userID, err := uuid.Parse(mux.Vars(r)["userId"])
+	if err != nil {
+		http.Error(w, "ID de usuário inválido", http.StatusBadRequest)
+		return
+	}
+
+	var f models.CustomField
+	if err := json.NewDecoder(r.Body).Decode(&f); err != nil {
+		http.Error(w, "Erro ao decodificar JSON", http.StatusBadRequest)
+		return
+	}
+	if msg := f.Validate(); msg != "" {
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
+
+	f.ID = uuid.New()
+	f.UserID = userID
+	f.Usos = 0
+	f.CreatedAt = time.Now()
+
+	_, err = db.DB.Exec(`
+		INSERT INTO custom_fields (id, user_id, nome, tipo, kind, opcoes, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`, f.ID, f.UserID, f.Nome, f.Tipo, f.Kind, pq.Array(f.Opcoes), f.CreatedAt)
+	if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+		http.Error(w, "Já existe um campo com esse nome", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Erro ao criar campo: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(f)
+}
+
+// ListCustomFields lista os campos personalizados do usuário com a quantidade de lançamentos preenchidos
+//
+// @Summary Listar campos personalizados
+// @Tags CustomFields
+// @Security BearerAuth
+// @Param userId path string true "ID do usuário"
+// @Param kind query string false "expense ou income: somente os campos que se aplicam"
+// @Success 200 {array} models.CustomField
+// @Failure 400,401,500 {string} string
+// @Router /users/{userId}/custom-fields [get]
+func ListCustomFields(w http.ResponseWriter, r *http.Request) {
+	userID := mux.Vars(r)["userId"]
+
+	kind := r.URL.Query().Get("kind")
+	if kind != "" && kind != models.CategoryExpense && kind != models.CategoryIncome {
+		http.Error(w, "Kind inválido: use expense ou income", http.StatusBadRequest)
+		return
+	}
+
+	rows, err := db.DB.Query(`
+		SELECT `+customFieldColumns+`
+		FROM custom_fields
+		WHERE user_id = $1 AND ($2 = '' OR kind IN ($2, 'both'))
+		ORDER BY nome
+	`, userID, kind)
+	if err != nil {
+		http.Error(w, "Erro ao buscar campos: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	fields := []models.CustomField{}
+	for rows.Next() {
+		var f models.CustomField
+		if err := scanCustomField(rows, &f); err != nil {
+			http.Error(w, "Erro ao ler campo: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		fields = append(fields, f)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(fields)
+}
+
+// UpdateCustomField renomeia ou altera a definição de um campo personalizado
+//
+// @Summary Atualizar campo personalizado
+// @Description Campos em uso não podem mudar de tipo, perder opções usadas nem deixar de se aplicar a lançamentos preenchidos.
+// @Tags CustomFields
+// @Security BearerAuth
+// @Param userId path string true "ID do usuário"
+// @Param id path string true "ID do campo"
+// @Param field body models.CustomField true "Nova definição do campo"
+// @Success 200 {object} models.CustomField
+// @Failure 400,401,404,409,500 {string} string
+// @Router /users/{userId}/custom-fields/{id} [put]
+func UpdateCustomField(w http.ResponseWriter, r *http.Request) {
+	p := mux.Vars(r)
+
+	id, err := uuid.Parse(p["id"])
+	if err != nil {
+		http.Error(w, "ID inválido", http.StatusBadRequest)
+		return
+	}
+
+	var in models.CustomField
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		http.Error(w, "Erro ao decodificar JSON", http.StatusBadRequest)
+		return
+	}
+	if msg := in.Validate(); msg != "" {
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
+	in.ID = id
+
+	tx, err := db.DB.Begin()
+	if err != nil {
+		http.Error(w, "Erro ao iniciar transação: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	var exists bool
+	if err := tx.QueryRow(`
+		SELECT EXISTS (SELECT 1 FROM custom_fields WHERE user_id = $1 AND id = $2)
+	`, p["userId"], id).Scan(&exists); err != nil {
+		http.Error(w, "Erro ao buscar campo: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if !exists {
+		http.Error(w, "Campo não encontrado", http.StatusNotFound)
+		return
+	}
+
+	msg, err := customFieldConflict(tx, in)
+	if err != nil {
+		http.Error(w, "Erro ao buscar campo: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if msg != "" {
+		http.Error(w, msg, http.StatusConflict)
+		return
+	}
+
+	var f models.CustomField
+	err = scanCustomField(tx.QueryRow(`
+		UPDATE custom_fields SET nome = $1, tipo = $2, kind = $3, opcoes = $4
+		WHERE user_id = $5 AND id = $6
+		RETURNING `+customFieldColumns+`
+	`, in.Nome, in.Tipo, in.Kind, pq.Array(in.Opcoes), p["userId"], id), &f)
+	if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+		http.Error(w, "Já existe um campo com esse nome", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Erro ao atualizar campo: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		http.Error(w, "Erro ao atualizar campo: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(f)
+}
+
+// DeleteCustomField exclui um campo personalizado e os valores preenchidos nos lançamentos
+//
+// @Summary Excluir campo personalizado
+// @Tags CustomFields
+// @Security BearerAuth
+// @Param userId path string true "ID do usuário"
+// @Param id path string true "ID do campo"
+// @Success 200 {object} Message
+// @Failure 400,401,404,500 {string} string
+// @Router /users/{userId}/custom-fields/{id} [delete]
+func DeleteCustomField(w http.ResponseWriter, r *http.Request) {
+	p := mux.Vars(r)
+
+	result, err := db.DB.Exec(`
+		DELETE FROM custom_fields
+		WHERE user_id = $1 AND id = $2
+	`, p["userId"], p["id"])
+	if err != nil {
+		http.Error(w, "Erro ao excluir campo: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if rowsAffected, _ := result.RowsAffected(); rowsAffected == 0 {
+		http.Error(w, "Campo não encontrado", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(Message{Message: "Campo excluído com sucesso"})
+}
+
+// FieldChart é o total dos lançamentos com um valor do campo no período, na
+// moeda base do usuário. Valor vazio agrupa os lançamentos sem o campo preenchido.
+type FieldChart struct {
+	Valor           *string          `json:"valor"`
+	Total           models.Money     `json:"total"`
+	TaxasUtilizadas models.RatesUsed `json:"taxas_utilizadas,omitempty"`
+	MoedasSemTaxa   []string         `json:"moedas_sem_taxa,omitempty"`
+}
+
+// fieldChart soma os lançamentos de source pelo valor do campo ?agrupar=
+func fieldChart(w http.ResponseWriter, r *http.Request, link fieldLink, source, where string) {
+	userID := mux.Vars(r)["userId"]
+
+	month, err1 := strconv.Atoi(r.URL.Query().Get("month"))
+	year, err2 := strconv.Atoi(r.URL.Query().Get("year"))
+	if err1 != nil || err2 != nil || month < 1 || month > 12 {
+		http.Error(w, "Parâmetros de mês/ano inválidos", http.StatusBadRequest)
+		return
+	}
+
+	var fieldID uuid.UUID
+	var kind string
+	err := db.DB.QueryRow(`
+		SELECT id, kind FROM custom_fields WHERE user_id = $1 AND nome = $2
+	`, userID, r.URL.Query().Get("agrupar")).Scan(&fieldID, &kind)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Campo personalizado não encontrado: informe o nome em agrupar", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Erro ao buscar campo: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if !models.CategoryAppliesTo(kind, link.kind) {
+		http.Error(w, "O campo não se aplica a este tipo de lançamento", http.StatusBadRequest)
+		return
+	}
+
+	start := time.Date(year, time.Month(month), 1, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(0, 1, 0)
+
+	filters, args := link.filter("x.id", fieldFilter(r), []interface{}{userID, start, end, fieldID})
+	rows, err := db.DB.Query(`
+		SELECT v.valor #>> '{}' AS valor, COALESCE(SUM(x.valor_base), 0) AS total, `+ratesUsedSQL+`, `+missingRatesSQL+`
+		FROM `+source+` x
+		LEFT JOIN `+link.table+` v ON v.`+link.column+` = x.id AND v.field_id = $4
+		WHERE `+where+filters+`
+		GROUP BY 1
+		ORDER BY total DESC
+	`, args...)
+	if err != nil {
+		http.Error(w, "Erro ao buscar dados", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	var result []FieldChart
+	for rows.Next() {
+		var row FieldChart
+		if err := rows.Scan(&row.Valor, &row.Total, &row.TaxasUtilizadas, pq.Array(&row.MoedasSemTaxa)); err != nil {
+			http.Error(w, "Erro ao processar dados", http.StatusInternalServerError)
+			return
+		}
+		result = append(result, row)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// GetExpensesByField retorna soma das despesas agrupadas pelo valor de um campo personalizado
+//
+// @Summary Despesas por campo personalizado
+// @Tags Charts
+// @Security BearerAuth
+// @Param userId path string true "ID do usuário"
+// @Param month query string true "Mês (1-12)"
+// @Param year query string true "Ano (YYYY)"
+// @Param agrupar query string true "Nome do campo personalizado"
+// @Param campo[nome] query string false "Filtra pelo valor de um campo personalizado; repita com outros nomes para vários"
+// @Success 200 {array} FieldChart
+// @Failure 400,401,404,500 {string} string
+// @Router /charts/expenses-by-field/{userId} [get]
+func GetExpensesByField(w http.ResponseWriter, r *http.Request) {
+	fieldChart(w, r, expenseFieldLink, netExpensesSQL,
+		`x.user_id = $1 AND x.vencimento >= $2 AND x.vencimento < $3`)
+}
+
+// GetIncomesByField retorna soma das receitas recebidas agrupadas pelo valor de um campo personalizado
+//
+// @Summary Receitas por campo personalizado
+// @Tags Charts
+// @Security BearerAuth
+// @Param userId path string true "ID do usuário"
+// @Param month query string true "Mês (1-12)"
+// @Param year query string true "Ano (YYYY)"
+// @Param agrupar query string true "Nome do campo personalizado"
+// @Param campo[nome] query string false "Filtra pelo valor de um campo personalizado; repita com outros nomes para vários"
+// @Success 200 {array} FieldChart
+// @Failure 400,401,404,500 {string} string
+// @Router /charts/incomes-by-field/{userId} [get]
+func GetIncomesByField(w http.ResponseWriter, r *http.Request) {
+	fieldChart(w, r, incomeFieldLink, convertedSQL(liveIncomes, "data_recebimento"),
+		`x.user_id = $1 AND x.status = 'recebida' AND x.data_recebimento >= $2 AND x.data_recebimento < $3`)
+}
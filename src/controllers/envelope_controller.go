@@ -0,0 +1,550 @@
+package controllers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"slices"
+	"sort"
+	"time"
+
+	"finance/src/db"
+	"finance/src/models"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/lib/pq"
+)
+
+const envelopeMovementColumns = `m.id, m.user_id, m.category_id, c.name, to_char(m.mes, 'YYYY-MM'), m.valor, m.tipo, m.transferencia_id, m.observacoes, m.created_at`
+
+func scanEnvelopeMovement(row rowScanner, m *models.EnvelopeMovement) error {
+	return row.Scan(&m.ID, &m.UserID, &m.CategoriaID, &m.Categoria, &m.Mes, &m.Valor, &m.Tipo, &m.TransferenciaID, &m.Observacoes, &m.CreatedAt)
+}
+
+// envelopeStart devolve o primeiro mês do modo envelope, ou status e mensagem
+// de erro se o usuário não usa envelopes
+func envelopeStart(q dbExecutor, userID string) (time.Time, int, string) {
+	var modo string
+	var inicio sql.NullTime
+	err := q.QueryRow(`SELECT modo_orcamento, envelope_inicio FROM users WHERE id = $1`, userID).Scan(&modo, &inicio)
+	if err == sql.ErrNoRows {
+		return time.Time{}, http.StatusNotFound, "Usuário não encontrado"
+	}
+	if err != nil {
+		return time.Time{}, http.StatusInternalServerError, "Erro ao buscar usuário: " + err.Error()
+	}
+	if modo != models.BudgetModeEnvelopes || !inicio.Valid {
+		return time.Time{}, http.StatusConflict, "O modo envelope não está ativo; ative-o em /users/{id}/budget-mode"
+	}
+	return inicio.Time, 0, ""
+}
+
+// envelopeMonthParam lê ?mes=YYYY-MM (padrão: mês corrente)
+func envelopeMonthParam(r *http.Request) (time.Time, bool) {
+	if s := r.URL.Query().Get("mes"); s != "" {
+		mes, err := models.ParseBudgetMonth(s)
+		return mes, err == nil
+	}
+	now := time.Now()
+	return time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC), true
+}
+
+// buildEnvelopeMonth monta o orçamento base zero do mês.
+//
+// Os envelopes são as categorias de despesa que já receberam dinheiro. Uma
+// despesa sai do envelope da sua categoria ou, se ela não tiver envelope, do
+// envelope da categoria ancestral mais próxima; sem nenhum, conta como gasto
+// sem envelope. Despesas divididas saem, linha a linha, dos envelopes das
+// categorias das linhas. Receitas recebidas e despesas (pelo vencimento)
+// entram convertidas para a moeda base.
+func buildEnvelopeMonth(q dbExecutor, userID string, inicio, month time.Time) (models.EnvelopeMonth, error) {
+	end := month.AddDate(0, 1, 0)
+	key := month.Format(models.BudgetMonthLayout)
+	ledger := models.NewEnvelopeLedger(inicio)
+
+	out := models.EnvelopeMonth{Mes: key, Envelopes: []models.Envelope{}}
+	var err error
+	if out.MoedaBase, err = resolveCurrency(q, userID, ""); err != nil {
+		return out, err
+	}
+
+	type category struct {
+		nome   string
+		parent *uuid.UUID
+	}
+	categories := map[uuid.UUID]category{}
+	rows, err := q.Query(`SELECT id, name, parent_id FROM categories WHERE user_id = $1`, userID)
+	if err != nil {
+		return out, err
+	}
+	for rows.Next() {
+		var id uuid.UUID
+		var c category
+		if err := rows.Scan(&id, &c.nome, &c.parent); err != nil {
+			rows.Close()
+			return out, err
+		}
+		categories[id] = c
+	}
+	rows.Close()
+
+	// todo envelope com movimentação desde o início existe em qualquer mês
+	envelopes := map[uuid.UUID]bool{}
+	rows, err = q.Query(`
+		SELECT category_id, to_char(mes, 'YYYY-MM'), SUM(valor)
+		FROM envelope_movements
+		WHERE user_id = $1 AND mes >= $2
+		GROUP BY category_id, mes
+	`, userID, inicio)
+	if err != nil {
+		return out, err
+	}
+	for rows.Next() {
+		var id uuid.UUID
+		var mes string
+		var valor models.Money
+		if err := rows.Scan(&id, &mes, &valor); err != nil {
+			rows.Close()
+			return out, err
+		}
+		envelopes[id] = true
+		if mes <= key {
+			ledger.Assign(id, mes, valor)
+		}
+	}
+	rows.Close()
+
+	envelopeOf := func(id *uuid.UUID) *uuid.UUID {
+		for seen := 0; id != nil && seen < len(categories); seen++ {
+			if envelopes[*id] {
+				return id
+			}
+			id = categories[*id].parent
+		}
+		return nil
+	}
+
+	var missing []string
+	rows, err = q.Query(`
+		SELECT to_char(x.data_recebimento, 'YYYY-MM') AS mes, COALESCE(SUM(x.valor_base), 0), `+missingRatesSQL+`
+		FROM `+convertedSQL(liveIncomes, "data_recebimento")+` x
+		WHERE x.user_id = $1 AND x.status = $2 AND x.data_recebimento >= $3 AND x.data_recebimento < $4
+		GROUP BY mes
+	`, userID, models.IncomeReceived, inicio, end)
+	if err != nil {
+		return out, err
+	}
+	for rows.Next() {
+		var mes string
+		var valor models.Money
+		var sem []string
+		if err := rows.Scan(&mes, &valor, pq.Array(&sem)); err != nil {
+			rows.Close()
+			return out, err
+		}
+		ledger.Receitas[mes] += valor
+		missing = append(missing, sem...)
+	}
+	rows.Close()
+
+	rows, err = q.Query(`
+		SELECT x.category_id, to_char(x.vencimento, 'YYYY-MM') AS mes, COALESCE(SUM(x.valor_base), 0), `+missingRatesSQL+`
+		FROM `+netExpenseLinesSQL+` x
+		WHERE x.user_id = $1 AND x.vencimento >= $2 AND x.vencimento < $3
+		GROUP BY x.category_id, mes
+	`, userID, inicio, end)
+	if err != nil {
+		return out, err
+	}
+	for rows.Next() {
+		var id *uuid.UUID
+		var mes string
+		var valor models.Money
+		var sem []string
+		if err := rows.Scan(&id, &mes, &valor, pq.Array(&sem)); err != nil {
+			rows.Close()
+			return out, err
+		}
+		if env := envelopeOf(id); env != nil {
+			ledger.Spend(*env, mes, valor)
+		} else {
+			ledger.SemEnvelope[mes] += valor
+		}
+		missing = append(missing, sem...)
+	}
+	rows.Close()
+
+	var byEnvelope map[uuid.UUID]models.Envelope
+	out.AAtribuir, byEnvelope = ledger.Month(month)
+	out.Receitas = ledger.Receitas[key]
+	out.GastoSemEnvelope = ledger.SemEnvelope[key]
+	for id, e := range byEnvelope {
+		e.Categoria = categories[id].nome
+		out.Atribuido += e.Atribuido
+		out.Gasto += e.Gasto
+		out.Envelopes = append(out.Envelopes, e)
+	}
+	sort.Slice(out.Envelopes, func(i, j int) bool { return out.Envelopes[i].Categoria < out.Envelopes[j].Categoria })
+
+	sort.Strings(missing)
+	out.MoedasSemTaxa = slices.Compact(missing)
+	return out, nil
+}
+
+// GetEnvelopes mostra o orçamento base zero do mês: quanto falta atribuir e
+// o saldo de cada envelope
+//
+// @Summary Envelopes do mês
+// @Description a_atribuir = receitas recebidas desde o início do modo envelope - dinheiro atribuído
+// @Description - gasto sem envelope - estouros de meses anteriores. O saldo positivo de cada envelope
+// @Description passa para o mês seguinte.
+// @Tags Envelopes
+// @Security BearerAuth
+// @Param userId path string true "ID do usuário"
+// @Param mes query string false "Mês (YYYY-MM); padrão: mês corrente"
+// @Success 200 {object} models.EnvelopeMonth
+// @Failure 400,401,404,409,500 {string} string
+// @Router /users/{userId}/envelopes [get]
+func GetEnvelopes(w http.ResponseWriter, r *http.Request) {
+	userID := mux.Vars(r)["userId"]
+	month, ok := envelopeMonthParam(r)
+	if !ok {
+		http.Error(w, "Mês inválido: use o formato YYYY-MM", http.StatusBadRequest)
+		return
+	}
+
+	inicio, status, msg := envelopeStart(db.DB, userID)
+	if status != 0 {
+		http.Error(w, msg, status)
+		return
+	}
+	if month.Before(inicio) {
+		http.Error(w, "Mês anterior ao início do modo envelope ("+inicio.Format(models.BudgetMonthLayout)+")", http.StatusBadRequest)
+		return
+	}
+
+	out, err := buildEnvelopeMonth(db.DB, userID, inicio, month)
+	if err != nil {
+		http.Error(w, "Erro ao calcular envelopes: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(out)
+}
+
+// envelopeTx abre uma transação travando o usuário, para que movimentações
+// simultâneas não usem o mesmo saldo, e valida o mês da movimentação
+func envelopeTx(w http.ResponseWriter, userID, mes string) (*sql.Tx, time.Time, time.Time, bool) {
+	month, err := models.ParseBudgetMonth(mes)
+	if err != nil {
+		http.Error(w, "Mês inválido: use o formato YYYY-MM", http.StatusBadRequest)
+		return nil, time.Time{}, time.Time{}, false
+	}
+
+	tx, err := db.DB.Begin()
+	if err != nil {
+		http.Error(w, "Erro ao iniciar transação: "+err.Error(), http.StatusInternalServerError)
+		return nil, time.Time{}, time.Time{}, false
+	}
+	if _, err := tx.Exec(`SELECT 1 FROM users WHERE id = $1 FOR UPDATE`, userID); err != nil {
+		tx.Rollback()
+		http.Error(w, "Erro ao buscar usuário: "+err.Error(), http.StatusInternalServerError)
+		return nil, time.Time{}, time.Time{}, false
+	}
+
+	inicio, status, msg := envelopeStart(tx, userID)
+	if status == 0 && month.Before(inicio) {
+		status, msg = http.StatusBadRequest, "Mês anterior ao início do modo envelope ("+inicio.Format(models.BudgetMonthLayout)+")"
+	}
+	if status != 0 {
+		tx.Rollback()
+		http.Error(w, msg, status)
+		return nil, time.Time{}, time.Time{}, false
+	}
+	return tx, inicio, month, true
+}
+
+// envelopeBalance devolve o disponível de um envelope no mês (zero se ainda não existe)
+func envelopeBalance(m models.EnvelopeMonth, id uuid.UUID) models.Money {
+	for _, e := range m.Envelopes {
+		if e.CategoriaID == id {
+			return e.Disponivel
+		}
+	}
+	return 0
+}
+
+func insertEnvelopeMovement(q dbExecutor, m *models.EnvelopeMovement, month time.Time) error {
+	_, err := q.Exec(`
+		INSERT INTO envelope_movements (id, user_id, category_id, mes, valor, tipo, transferencia_id, observacoes, created_at)
+		VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9)
+	`, m.ID, m.UserID, m.CategoriaID, month, m.Valor, m.Tipo, m.TransferenciaID, m.Observacoes, m.CreatedAt)
+	return err
+}
+
+// AssignEnvelope atribui dinheiro do "a atribuir" a um envelope; valor
+// negativo devolve dinheiro do envelope para o "a atribuir"
+//
+// @Summary Atribuir dinheiro a um envelope
+// @Description O a atribuir pode ficar negativo (mais dinheiro atribuído do que recebido); o envelope não.
+// @Tags Envelopes
+// @Security BearerAuth
+// @Param userId path string true "ID do usuário"
+// @Param movement body object{categoria_id=string,mes=string,valor=string,observacoes=string} true "Envelope, mês (YYYY-MM) e valor"
+// @Success 201 {object} models.EnvelopeMonth
+// @Failure 400,401,404,409,500 {string} string
+// @Router /users/{userId}/envelopes/assign [post]
+func AssignEnvelope(w http.ResponseWriter, r *http.Request) {
+	userIDStr := mux.Vars(r)["userId"]
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		http.Error(w, "ID de usuário inválido", http.StatusBadRequest)
+		return
+	}
+
+	var in struct {
+		CategoriaID uuid.UUID    `json:"categoria_id"`
+		Mes         string       `json:"mes"`
+		Valor       models.Money `json:"valor"`
+		Observacoes *string      `json:"observacoes"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		http.Error(w, "JSON inválido: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if in.Valor == 0 {
+		http.Error(w, "Valor deve ser diferente de zero", http.StatusBadRequest)
+		return
+	}
+
+	tx, inicio, month, ok := envelopeTx(w, userIDStr, in.Mes)
+	if !ok {
+		return
+	}
+	defer tx.Rollback()
+
+	if _, _, err := resolveCategory(tx, userIDStr, models.CategoryExpense, &in.CategoriaID, ""); err != nil {
+		writeCategoryError(w, err)
+		return
+	}
+
+	if in.Valor < 0 {
+		before, err := buildEnvelopeMonth(tx, userIDStr, inicio, month)
+		if err != nil {
+			http.Error(w, "Erro ao calcular envelopes: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if envelopeBalance(before, in.CategoriaID)+in.Valor < 0 {
+			http.Error(w, "O envelope não tem saldo suficiente para devolver esse valor", http.StatusConflict)
+			return
+		}
+	}
+
+	m := models.EnvelopeMovement{
+		ID:          uuid.New(),
+		UserID:      userID,
+		CategoriaID: in.CategoriaID,
+		Valor:       in.Valor,
+		Tipo:        models.EnvelopeAssign,
+		Observacoes: in.Observacoes,
+		CreatedAt:   time.Now(),
+	}
+	if err := insertEnvelopeMovement(tx, &m, month); err != nil {
+		http.Error(w, "Erro ao atribuir envelope: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	out, err := buildEnvelopeMonth(tx, userIDStr, inicio, month)
+	if err != nil {
+		http.Error(w, "Erro ao calcular envelopes: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		http.Error(w, "Erro ao atribuir envelope: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(out)
+}
+
+// MoveEnvelope transfere dinheiro de um envelope para outro no mesmo mês
+//
+// @Summary Transferir entre envelopes
+// @Tags Envelopes
+// @Security BearerAuth
+// @Param userId path string true "ID do usuário"
+// @Param movement body object{de_categoria_id=string,para_categoria_id=string,mes=string,valor=string,observacoes=string} true "Envelopes de origem e destino, mês (YYYY-MM) e valor"
+// @Success 201 {object} models.EnvelopeMonth
+// @Failure 400,401,404,409,500 {string} string
+// @Router /users/{userId}/envelopes/move [post]
+func MoveEnvelope(w http.ResponseWriter, r *http.Request) {
+	userIDStr := mux.Vars(r)["userId"]
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		http.Error(w, "ID de usuário inválido", http.StatusBadRequest)
+		return
+	}
+
+	var in struct {
+		De          uuid.UUID    `json:"de_categoria_id"`
+		Para        uuid.UUID    `json:"para_categoria_id"`
+		Mes         string       `json:"mes"`
+		Valor       models.Money `json:"valor"`
+		Observacoes *string      `json:"observacoes"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		http.Error(w, "JSON inválido: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if in.Valor <= 0 {
+		http.Error(w, "Valor deve ser maior que zero", http.StatusBadRequest)
+		return
+	}
+	if in.De == in.Para {
+		http.Error(w, "Envelopes de origem e destino devem ser diferentes", http.StatusBadRequest)
+		return
+	}
+
+	tx, inicio, month, ok := envelopeTx(w, userIDStr, in.Mes)
+	if !ok {
+		return
+	}
+	defer tx.Rollback()
+
+	for _, id := range []uuid.UUID{in.De, in.Para} {
+		if _, _, err := resolveCategory(tx, userIDStr, models.CategoryExpense, &id, ""); err != nil {
+			writeCategoryError(w, err)
+			return
+		}
+	}
+
+	before, err := buildEnvelopeMonth(tx, userIDStr, inicio, month)
+	if err != nil {
+		http.Error(w, "Erro ao calcular envelopes: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if envelopeBalance(before, in.De) < in.Valor {
+		http.Error(w, "O envelope de origem não tem saldo suficiente", http.StatusConflict)
+		return
+	}
+
+	transferID := uuid.New()
+	now := time.Now()
+	for _, leg := range []struct {
+		id    uuid.UUID
+		valor models.Money
+	}{{in.De, -in.Valor}, {in.Para, in.Valor}} {
+		m := models.EnvelopeMovement{
+			ID:              uuid.New(),
+			UserID:          userID,
+			CategoriaID:     leg.id,
+			Valor:           leg.valor,
+			Tipo:            models.EnvelopeTransfer,
+			TransferenciaID: &transferID,
+			Observacoes:     in.Observacoes,
+			CreatedAt:       now,
+		}
+		if err := insertEnvelopeMovement(tx, &m, month); err != nil {
+			http.Error(w, "Erro ao transferir entre envelopes: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	out, err := buildEnvelopeMonth(tx, userIDStr, inicio, month)
+	if err != nil {
+		http.Error(w, "Erro ao calcular envelopes: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		http.Error(w, "Erro ao transferir entre envelopes: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(out)
+}
+
+// ListEnvelopeMovements lista as atribuições e transferências de um mês
+//
+// @Summary Movimentações dos envelopes
+// @Tags Envelopes
+// @Security BearerAuth
+// @Param userId path string true "ID do usuário"
+// @Param mes query string false "Mês (YYYY-MM); padrão: mês corrente"
+// @Success 200 {array} models.EnvelopeMovement
+// @Failure 400,401,500 {string} string
+// @Router /users/{userId}/envelopes/movements [get]
+func ListEnvelopeMovements(w http.ResponseWriter, r *http.Request) {
+	month, ok := envelopeMonthParam(r)
+	if !ok {
+		http.Error(w, "Mês inválido: use o formato YYYY-MM", http.StatusBadRequest)
+		return
+	}
+
+	rows, err := db.DB.Query(`
+		SELECT `+envelopeMovementColumns+`
+		FROM envelope_movements m
+		JOIN categories c ON c.id = m.category_id
+		WHERE m.user_id = $1 AND m.mes = $2
+		ORDER BY m.created_at, m.valor
+	`, mux.Vars(r)["userId"], month)
+	if err != nil {
+		http.Error(w, "Erro ao buscar movimentações: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	movements := []models.EnvelopeMovement{}
+	for rows.Next() {
+		var m models.EnvelopeMovement
+		if err := scanEnvelopeMovement(rows, &m); err != nil {
+			http.Error(w, "Erro ao ler movimentação: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		movements = append(movements, m)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(movements)
+}
+
+// DeleteEnvelopeMovement desfaz uma atribuição ou uma transferência inteira
+//
+// @Summary Desfazer movimentação de envelope
+// @Tags Envelopes
+// @Security BearerAuth
+// @Param userId path string true "ID do usuário"
+// @Param id path string true "ID da movimentação (qualquer perna, numa transferência)"
+// @Success 200 {object} Message
+// @Failure 400,401,404,500 {string} string
+// @Router /users/{userId}/envelopes/movements/{id} [delete]
+func DeleteEnvelopeMovement(w http.ResponseWriter, r *http.Request) {
+	p := mux.Vars(r)
+	if _, err := uuid.Parse(p["id"]); err != nil {
+		http.Error(w, "ID de movimentação inválido", http.StatusBadRequest)
+		return
+	}
+
+	result, err := db.DB.Exec(`
+		DELETE FROM envelope_movements
+		WHERE user_id = $1 AND (id = $2 OR transferencia_id = (
+			SELECT transferencia_id FROM envelope_movements WHERE user_id = $1 AND id = $2
+		))
+	`, p["userId"], p["id"])
+	if err != nil {
+		http.Error(w, "Erro ao excluir movimentação: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if rowsAffected, _ := result.RowsAffected(); rowsAffected == 0 {
+		http.Error(w, "Movimentação não encontrada", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(Message{Message: "Movimentação desfeita com sucesso"})
+}
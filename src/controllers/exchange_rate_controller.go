@@ -0,0 +1,364 @@
+package controllers
+
+import (
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"finance/src/db"
+	"finance/src/models"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+var errInvalidCurrency = errors.New("Moeda inválida: use o código ISO 4217 (ex: BRL, USD)")
+
+// resolveCurrency valida a moeda informada ou, se vazia, devolve a moeda base
+// do usuário (a padrão, se o usuário não existir)
+func resolveCurrency(q dbExecutor, userID, moeda string) (string, error) {
+	if moeda != "" {
+		moeda = strings.ToUpper(moeda)
+		if !models.ValidCurrency(moeda) {
+			return "", errInvalidCurrency
+		}
+		return moeda, nil
+	}
+
+	err := q.QueryRow(`SELECT moeda_base FROM users WHERE id = $1`, userID).Scan(&moeda)
+	if err == sql.ErrNoRows {
+		return models.DefaultCurrency, nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return moeda, nil
+}
+
+// writeCurrencyError responde 400 para moeda inválida e 500 para falhas do banco
+func writeCurrencyError(w http.ResponseWriter, err error) {
+	if err == errInvalidCurrency {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	http.Error(w, "Erro ao buscar moeda base: "+err.Error(), http.StatusInternalServerError)
+}
+
+// convertedSQL devolve uma subconsulta sobre table com as colunas extras:
+//
+//	moeda_base  moeda base do dono da transação
+//	fator       multiplicador para a moeda base (1 na própria moeda, NULL sem taxa)
+//	taxa_data   data da taxa aplicada
+//	valor_base  valor convertido (NULL quando não há taxa)
+//
+// A taxa usada é a mais recente com data <= dateColumn, cadastrada na direção
+// moeda -> base ou, na falta dela, a inversa da direção base -> moeda.
+func convertedSQL(table, dateColumn string) string {
+	return convertedValueSQL(table, dateColumn, "t.valor")
+}
+
+// convertedValueSQL é convertedSQL com valor_base calculado a partir de uma
+// expressão sobre a linha t em vez da coluna valor
+func convertedValueSQL(table, dateColumn, value string) string {
+	return `(
+		SELECT t.*, u.moeda_base, r.data AS taxa_data,
+			CASE WHEN t.moeda = u.moeda_base THEN 1 ELSE r.taxa END AS fator,
+			ROUND((` + value + `) * CASE WHEN t.moeda = u.moeda_base THEN 1 ELSE r.taxa END, 2) AS valor_base
+		FROM ` + table + ` t
+		JOIN users u ON u.id = t.user_id
+		LEFT JOIN LATERAL (
+			SELECT c.taxa, c.data
+			FROM (
+				SELECT er.taxa, er.data
+				FROM exchange_rates er
+				WHERE er.user_id = t.user_id AND er.moeda_origem = t.moeda AND er.moeda_destino = u.moeda_base
+				  AND er.data <= t.` + dateColumn + `
+				UNION ALL
+				SELECT 1 / er.taxa, er.data
+				FROM exchange_rates er
+				WHERE er.user_id = t.user_id AND er.moeda_origem = u.moeda_base AND er.moeda_destino = t.moeda
+				  AND er.data <= t.` + dateColumn + `
+			) c
+			ORDER BY c.data DESC
+			LIMIT 1
+		) r ON t.moeda <> u.moeda_base
+	)`
+}
+
+// ratesUsedSQL agrega em JSON as taxas aplicadas às linhas de uma consulta convertida
+const ratesUsedSQL = `COALESCE(jsonb_agg(DISTINCT jsonb_build_object('moeda', x.moeda, 'data', x.taxa_data, 'taxa', x.fator))
+	FILTER (WHERE x.moeda <> x.moeda_base AND x.fator IS NOT NULL), '[]')`
+
+// missingRatesSQL lista as moedas que ficaram sem taxa (e fora dos totais) numa consulta convertida
+const missingRatesSQL = `COALESCE(array_agg(DISTINCT x.moeda) FILTER (WHERE x.fator IS NULL), '{}')`
+
+const exchangeRateColumns = `id, user_id, moeda_origem, moeda_destino, data, taxa, fonte, created_at`
+
+// upsertExchangeRate grava a taxa do dia, substituindo uma existente para o mesmo par e data
+func upsertExchangeRate(q dbExecutor, rate *models.ExchangeRate) error {
+	return q.QueryRow(`
+		INSERT INTO exchange_rates (id, user_id, moeda_origem, moeda_destino, data, taxa, fonte, created_at)
+		VALUES ($1,$2,$3,$4,$5,$6,$7,$8)
+		ON CONFLICT (user_id, moeda_origem, moeda_destino, data)
+		DO UPDATE SET taxa = EXCLUDED.taxa, fonte = EXCLUDED.fonte
+		RETURNING id
+	`, rate.ID, rate.UserID, rate.MoedaOrigem, rate.MoedaDestino, rate.Data, rate.Taxa, rate.Fonte, rate.CreatedAt).Scan(&rate.ID)
+}
+
+func validateExchangeRate(rate *models.ExchangeRate) error {
+	rate.MoedaOrigem = strings.ToUpper(strings.TrimSpace(rate.MoedaOrigem))
+	rate.MoedaDestino = strings.ToUpper(strings.TrimSpace(rate.MoedaDestino))
+	if !models.ValidCurrency(rate.MoedaOrigem) || !models.ValidCurrency(rate.MoedaDestino) {
+		return errors.New("moedas devem seguir o código ISO 4217 (ex: BRL, USD)")
+	}
+	if rate.MoedaOrigem == rate.MoedaDestino {
+		return errors.New("moedas de origem e destino devem ser diferentes")
+	}
+	if rate.Taxa <= 0 {
+		return errors.New("taxa deve ser maior que zero")
+	}
+	if rate.Data.IsZero() {
+		return errors.New("data é obrigatória")
+	}
+	return nil
+}
+
+// CreateExchangeRate cadastra manualmente uma taxa de câmbio
+//
+// @Summary Cadastrar taxa de câmbio
+// @Tags Exchange Rates
+// @Security BearerAuth
+// @Param userId path string true "ID do usuário"
+// @Param rate body models.ExchangeRate true "1 moeda_origem = taxa moeda_destino"
+// @Success 201 {object} models.ExchangeRate
+// @Failure 400,401,500 {string} string
+// @Router /users/{userId}/exchange-rates [post]
+func CreateExchangeRate(w http.ResponseWriter, r *http.Request) {
+	userID, err := uuid.Parse(mux.Vars(r)["userId"])
+	if err != nil {
+		http.Error(w, "ID de usuário inválido", http.StatusBadRequest)
+		return
+	}
+
+	var rate models.ExchangeRate
+	if err := json.NewDecoder(r.Body).Decode(&rate); err != nil {
+		http.Error(w, "JSON inválido: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := validateExchangeRate(&rate); err != nil {
+		http.Error(w, "Taxa inválida: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	rate.ID = uuid.New()
+	rate.UserID = userID
+	rate.Fonte = "manual"
+	rate.CreatedAt = time.Now()
+
+	if err := upsertExchangeRate(db.DB, &rate); err != nil {
+		http.Error(w, "Erro ao salvar taxa: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(rate)
+}
+
+// ImportExchangeRates importa taxas de um CSV com cabeçalho data,moeda_origem,moeda_destino,taxa
+//
+// @Summary Importar taxas de câmbio (CSV)
+// @Description Aceita multipart/form-data (campo "file") ou o CSV no corpo (text/csv).
+// @Description Datas em YYYY-MM-DD; a taxa aceita ponto ou vírgula como separador decimal.
+// @Tags Exchange Rates
+// @Security BearerAuth
+// @Param userId path string true "ID do usuário"
+// @Success 200 {object} map[string]int
+// @Failure 400,401,500 {string} string
+// @Router /users/{userId}/exchange-rates/import [post]
+func ImportExchangeRates(w http.ResponseWriter, r *http.Request) {
+	userID, err := uuid.Parse(mux.Vars(r)["userId"])
+	if err != nil {
+		http.Error(w, "ID de usuário inválido", http.StatusBadRequest)
+		return
+	}
+
+	var body io.Reader = r.Body
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		file, _, err := r.FormFile("file")
+		if err != nil {
+			http.Error(w, "Arquivo CSV não enviado no campo \"file\"", http.StatusBadRequest)
+			return
+		}
+		defer file.Close()
+		body = file
+	}
+
+	rates, err := parseExchangeRatesCSV(body)
+	if err != nil {
+		http.Error(w, "CSV inválido: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	tx, err := db.DB.Begin()
+	if err != nil {
+		http.Error(w, "Erro ao iniciar transação: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+	for i := range rates {
+		rates[i].ID = uuid.New()
+		rates[i].UserID = userID
+		rates[i].Fonte = "csv"
+		rates[i].CreatedAt = now
+		if err := upsertExchangeRate(tx, &rates[i]); err != nil {
+			http.Error(w, "Erro ao salvar taxa: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		http.Error(w, "Erro ao salvar taxas: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]int{"importadas": len(rates)})
+}
+
+func parseExchangeRatesCSV(src io.Reader) ([]models.ExchangeRate, error) {
+	reader := csv.NewReader(src)
+	reader.TrimLeadingSpace = true
+	reader.Comment = '#'
+
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, errors.New("arquivo vazio")
+	}
+
+	// mapeia as colunas pelo cabeçalho para aceitar qualquer ordem
+	index := map[string]int{}
+	for i, name := range records[0] {
+		index[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, col := range []string{"data", "moeda_origem", "moeda_destino", "taxa"} {
+		if _, ok := index[col]; !ok {
+			return nil, fmt.Errorf("coluna %q ausente no cabeçalho", col)
+		}
+	}
+
+	var rates []models.ExchangeRate
+	for n, rec := range records[1:] {
+		line := n + 2
+		data, err := time.Parse("2006-01-02", strings.TrimSpace(rec[index["data"]]))
+		if err != nil {
+			return nil, fmt.Errorf("linha %d: data inválida", line)
+		}
+		taxa, err := strconv.ParseFloat(strings.Replace(strings.TrimSpace(rec[index["taxa"]]), ",", ".", 1), 64)
+		if err != nil {
+			return nil, fmt.Errorf("linha %d: taxa inválida", line)
+		}
+
+		rate := models.ExchangeRate{
+			MoedaOrigem:  rec[index["moeda_origem"]],
+			MoedaDestino: rec[index["moeda_destino"]],
+			Data:         data,
+			Taxa:         taxa,
+		}
+		if err := validateExchangeRate(&rate); err != nil {
+			return nil, fmt.Errorf("linha %d: %v", line, err)
+		}
+		rates = append(rates, rate)
+	}
+	return rates, nil
+}
+
+// ListExchangeRates lista as taxas de câmbio do usuário, com filtros opcionais
+//
+// @Summary Listar taxas de câmbio
+// @Tags Exchange Rates
+// @Security BearerAuth
+// @Param userId path string true "ID do usuário"
+// @Param from query string false "Moeda de origem"
+// @Param to query string false "Moeda de destino"
+// @Success 200 {array} models.ExchangeRate
+// @Failure 400,401,500 {string} string
+// @Router /users/{userId}/exchange-rates [get]
+func ListExchangeRates(w http.ResponseWriter, r *http.Request) {
+	uid := mux.Vars(r)["userId"]
+
+	query := `
+		SELECT ` + exchangeRateColumns + `
+		FROM exchange_rates
+		WHERE user_id = $1
+	`
+	args := []interface{}{uid}
+	if from := r.URL.Query().Get("from"); from != "" {
+		args = append(args, strings.ToUpper(from))
+		query += fmt.Sprintf(" AND moeda_origem = $%d", len(args))
+	}
+	if to := r.URL.Query().Get("to"); to != "" {
+		args = append(args, strings.ToUpper(to))
+		query += fmt.Sprintf(" AND moeda_destino = $%d", len(args))
+	}
+	query += " ORDER BY data DESC, moeda_origem, moeda_destino"
+
+	rows, err := db.DB.Query(query, args...)
+	if err != nil {
+		http.Error(w, "Erro ao buscar taxas: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	var result []models.ExchangeRate
+	for rows.Next() {
+		var rate models.ExchangeRate
+		if err := rows.Scan(&rate.ID, &rate.UserID, &rate.MoedaOrigem, &rate.MoedaDestino, &rate.Data, &rate.Taxa, &rate.Fonte, &rate.CreatedAt); err != nil {
+			http.Error(w, "Erro ao ler taxa: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		result = append(result, rate)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// DeleteExchangeRate exclui uma taxa de câmbio
+//
+// @Summary Excluir taxa de câmbio
+// @Tags Exchange Rates
+// @Security BearerAuth
+// @Param userId path string true "ID do usuário"
+// @Param id path string true "ID da taxa"
+// @Success 200 {object} Message
+// @Failure 400,401,404,500 {string} string
+// @Router /users/{userId}/exchange-rates/{id} [delete]
+func DeleteExchangeRate(w http.ResponseWriter, r *http.Request) {
+	p := mux.Vars(r)
+
+	res, err := db.DB.Exec(`DELETE FROM exchange_rates WHERE user_id = $1 AND id = $2`, p["userId"], p["id"])
+	if err != nil {
+		http.Error(w, "Erro ao excluir taxa: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if rows, _ := res.RowsAffected(); rows == 0 {
+		http.Error(w, "Taxa não encontrada", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(Message{Message: "Taxa excluída com sucesso"})
+}
@@ -7,13 +7,57 @@ import (
 	"strconv"
 	"time"
 
+	"finance/src/audit"
 	"finance/src/db"
 	"finance/src/models"
 
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
+	"github.com/lib/pq"
 )
 
+// expenseColumns são as colunas de expenses lidas por scanExpense, na mesma ordem
+var expenseColumns = `id, user_id, descricao, valor, moeda, vencimento, paga, data_pagamento,
+	COALESCE((SELECT SUM(p.valor) FROM expense_payments p WHERE p.expense_id = expenses.id), 0) AS valor_pago,
+	COALESCE((SELECT SUM(p.multa + p.juros - p.desconto) FROM expense_payments p WHERE p.expense_id = expenses.id), 0) AS encargos,
+	multa_percentual, juros_percentual, juros_periodo, desconto_percentual, desconto_ate,
+	categoria, category_id, payee_id, (SELECT py.nome FROM payees py WHERE py.id = expenses.payee_id) AS payee,
+	observacoes, recorrencia_id, ocorrencia, created_at, ` + expenseRefundedSQL("expenses.id") + ` AS valor_estornado, ` + expenseTagLink.tagsColumn() + `, ` + expenseFieldLink.valuesColumn() + `, ` + expenseSplitLink.splitsColumn()
+
+// rowScanner é satisfeito por *sql.Row e *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanExpense(row rowScanner, e *models.Expense) error {
+	return row.Scan(&e.ID, &e.UserID, &e.Descricao, &e.Valor, &e.Moeda, &e.Vencimento, &e.Paga, &e.DataPagamento, &e.ValorPago, &e.Encargos,
+		&e.MultaPercentual, &e.JurosPercentual, &e.JurosPeriodo, &e.DescontoPercentual, &e.DescontoAte,
+		&e.Categoria, &e.CategoriaID, &e.PayeeID, &e.Payee, &e.Observacoes, &e.RecorrenciaID, &e.Ocorrencia, &e.CreatedAt, &e.ValorEstornado, pq.Array(&e.Tags), &e.Campos, &e.Divisao)
+}
+
+// validateExpenseCharges confere multa, juros e desconto; o período padrão dos juros é mensal
+func validateExpenseCharges(e *models.Expense) string {
+	if e.JurosPeriodo == "" {
+		e.JurosPeriodo = models.InterestMonthly
+	}
+	if !models.ValidInterestPeriod(e.JurosPeriodo) {
+		return "Período de juros inválido: use diario ou mensal"
+	}
+	if e.MultaPercentual < 0 || e.MultaPercentual > 100 {
+		return "Multa deve ficar entre 0 e 100%"
+	}
+	if e.JurosPercentual < 0 {
+		return "Juros não podem ser negativos"
+	}
+	if e.DescontoPercentual < 0 || e.DescontoPercentual >= 100 {
+		return "Desconto deve ficar entre 0 e 100%"
+	}
+	if e.DescontoAte != nil && e.DescontoAte.After(e.Vencimento) {
+		return "O prazo do desconto não pode passar do vencimento"
+	}
+	return ""
+}
+
 // Controller para criar uma nova despesa
 
 // @Summary	Criar despesa
@@ -36,24 +80,89 @@ func CreateExpense(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Valor deve ser maior que zero", http.StatusBadRequest)
 		return
 	}
+	if msg := validateExpenseCharges(&expense); msg != "" {
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
+	if msg := models.ValidateSplits(expense.Divisao, expense.Valor); msg != "" {
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
 
 	expense.ID = uuid.New()
 	expense.CreatedAt = time.Now()
-	if expense.Paga && expense.DataPagamento == nil {
-		now := time.Now()
-		expense.DataPagamento = &now
+
+	tx, err := db.DB.Begin()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
 	}
+	defer tx.Rollback()
 
-	_, err := db.DB.Exec(`
-		INSERT INTO expenses (id, user_id, descricao, valor, vencimento, paga, data_pagamento, categoria, observacoes, created_at)
-		VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10)
-	`, expense.ID, expense.UserID, expense.Descricao, expense.Valor, expense.Vencimento, expense.Paga, expense.DataPagamento, expense.Categoria, expense.Observacoes, expense.CreatedAt)
+	if expense.Moeda, err = resolveCurrency(tx, expense.UserID.String(), expense.Moeda); err != nil {
+		writeCurrencyError(w, err)
+		return
+	}
+
+	if expense.CategoriaID, expense.Categoria, err = resolveCategory(tx, expense.UserID.String(), models.CategoryExpense, expense.CategoriaID, expense.Categoria); err != nil {
+		writeCategoryError(w, err)
+		return
+	}
+
+	if expense.PayeeID, expense.Payee, err = resolvePayee(tx, expense.UserID.String(), expense.PayeeID, expense.Descricao); err != nil {
+		writePayeeError(w, err)
+		return
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO expenses (id, user_id, descricao, valor, moeda, vencimento, paga, categoria, category_id, payee_id, observacoes, created_at,
+			multa_percentual, juros_percentual, juros_periodo, desconto_percentual, desconto_ate)
+		VALUES ($1,$2,$3,$4,$5,$6,false,$7,$8,$9,$10,$11,$12,$13,$14,$15,$16)
+	`, expense.ID, expense.UserID, expense.Descricao, expense.Valor, expense.Moeda, expense.Vencimento, expense.Categoria, expense.CategoriaID, expense.PayeeID, expense.Observacoes, expense.CreatedAt,
+		expense.MultaPercentual, expense.JurosPercentual, expense.JurosPeriodo, expense.DescontoPercentual, expense.DescontoAte)
 
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
+	if expense.Tags, err = expenseTagLink.set(tx, expense.UserID.String(), expense.ID, expense.Tags); err != nil {
+		http.Error(w, "Erro ao salvar tags: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if expense.Campos, err = expenseFieldLink.set(tx, expense.UserID.String(), expense.ID, expense.Campos); err != nil {
+		writeFieldError(w, err)
+		return
+	}
+
+	if expense.Divisao, err = expenseSplitLink.set(tx, expense.UserID.String(), expense.ID, expense.Divisao); err != nil {
+		writeSplitError(w, err)
+		return
+	}
+
+	// Despesa criada como paga ganha um pagamento integral
+	if expense.Paga {
+		pay, err := recordExpensePayment(tx, expense, PaymentInput{Valor: expense.Valor, DataPagamento: expense.DataPagamento})
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		expense.DataPagamento = &pay.DataPagamento
+		expense.ValorPago = pay.Valor
+		expense.Pagamentos = []models.ExpensePayment{pay}
+	}
+
+	if err := audit.TrackNew(models.AuditExpenses, expense.ID).Record(tx, apiAudit(r, models.AuditCreate)); err != nil {
+		http.Error(w, "Erro ao registrar histórico: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
 	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode(expense)
 }
@@ -66,6 +175,8 @@ func CreateExpense(w http.ResponseWriter, r *http.Request) {
 // @Param userId path string true "ID do usuário"
 // @Param month query string false "Mês (1-12)"
 // @Param year query string false "Ano (YYYY)"
+// @Param tag query []string false "Filtra pelas tags (todas obrigatórias); repita o parâmetro para várias" collectionFormat(multi)
+// @Param campo[nome] query string false "Filtra pelo valor de um campo personalizado; repita com outros nomes para vários"
 // @Success 200 {array} models.Expense
 // @Failure 400,401,500 {string} string
 // @Router /expenses/{userId} [get]
@@ -77,9 +188,9 @@ func ListExpenses(w http.ResponseWriter, r *http.Request) {
 
 	var filters []interface{}
 	query := `
-		SELECT id, user_id, descricao, valor, vencimento, paga, data_pagamento, categoria, observacoes, created_at
+		SELECT ` + expenseColumns + `
 		FROM expenses
-		WHERE user_id = $1
+		WHERE user_id = $1 AND deleted_at IS NULL
 	`
 	filters = append(filters, userId)
 
@@ -99,6 +210,15 @@ func ListExpenses(w http.ResponseWriter, r *http.Request) {
 		filters = append(filters, startDate, endDate)
 	}
 
+	if tags := tagFilter(r); len(tags) > 0 {
+		filters = append(filters, pq.Array(tags))
+		query += expenseTagLink.filterSQL("expenses.id", len(filters))
+	}
+
+	var fieldSQL string
+	fieldSQL, filters = expenseFieldLink.filter("expenses.id", fieldFilter(r), filters)
+	query += fieldSQL
+
 	rows, err := db.DB.Query(query, filters...)
 	if err != nil {
 		http.Error(w, "Erro ao buscar despesas: "+err.Error(), http.StatusInternalServerError)
@@ -109,22 +229,11 @@ func ListExpenses(w http.ResponseWriter, r *http.Request) {
 	var expenses []models.Expense
 	for rows.Next() {
 		var e models.Expense
-		var dataPagamento sql.NullTime
-		var observacoes sql.NullString
-
-		err := rows.Scan(&e.ID, &e.UserID, &e.Descricao, &e.Valor, &e.Vencimento, &e.Paga, &dataPagamento, &e.Categoria, &observacoes, &e.CreatedAt)
-		if err != nil {
+		if err := scanExpense(rows, &e); err != nil {
 			http.Error(w, "Erro ao ler despesa: "+err.Error(), http.StatusInternalServerError)
 			return
 		}
 
-		if dataPagamento.Valid {
-			e.DataPagamento = &dataPagamento.Time
-		}
-		if observacoes.Valid {
-			e.Observacoes = &observacoes.String
-		}
-
 		expenses = append(expenses, e)
 	}
 
@@ -146,9 +255,9 @@ func ListAllExpenses(w http.ResponseWriter, r *http.Request) {
 	userId := params["userId"]
 
 	rows, err := db.DB.Query(`
-		SELECT id, user_id, descricao, valor, vencimento, paga, data_pagamento, categoria, observacoes, created_at
+		SELECT `+expenseColumns+`
 		FROM expenses
-		WHERE user_id = $1
+		WHERE user_id = $1 AND deleted_at IS NULL
 	`, userId)
 	if err != nil {
 		http.Error(w, "Erro ao buscar despesas: "+err.Error(), http.StatusInternalServerError)
@@ -160,7 +269,7 @@ func ListAllExpenses(w http.ResponseWriter, r *http.Request) {
 
 	for rows.Next() {
 		var e models.Expense
-		err := rows.Scan(&e.ID, &e.UserID, &e.Descricao, &e.Valor, &e.Vencimento, &e.Paga, &e.DataPagamento, &e.Categoria, &e.Observacoes, &e.CreatedAt)
+		err := scanExpense(rows, &e)
 
 		if err != nil {
 			http.Error(w, "Erro ao ler despesa: "+err.Error(), http.StatusInternalServerError)
@@ -172,12 +281,30 @@ func ListAllExpenses(w http.ResponseWriter, r *http.Request) {
 			UserID:        e.UserID,
 			Descricao:     e.Descricao,
 			Valor:         e.Valor,
+			Moeda:         e.Moeda,
 			Vencimento:    e.Vencimento,
 			Paga:          e.Paga,
 			DataPagamento: e.DataPagamento,
 			Categoria:     e.Categoria,
+			CategoriaID:   e.CategoriaID,
+			Tags:          e.Tags,
+			Campos:        e.Campos,
+			Divisao:       e.Divisao,
 			Observacoes:   e.Observacoes,
+			RecorrenciaID: e.RecorrenciaID,
+			Ocorrencia:    e.Ocorrencia,
 			CreatedAt:     e.CreatedAt,
+			ValorPago:     e.ValorPago,
+			Encargos:      e.Encargos,
+			Status:        e.StatusHoje(),
+
+			MultaPercentual:    e.MultaPercentual,
+			JurosPercentual:    e.JurosPercentual,
+			JurosPeriodo:       e.JurosPeriodo,
+			DescontoPercentual: e.DescontoPercentual,
+			DescontoAte:        e.DescontoAte,
+
+			ValorEstornado: e.ValorEstornado,
 		}
 
 		expenses = append(expenses, response)
@@ -193,34 +320,23 @@ func ListAllExpenses(w http.ResponseWriter, r *http.Request) {
 // @Tags Expenses
 // @Security BearerAuth
 // @Param userId path string true "ID do usuário"
-// @Param expenseId path string true "ID da despesa"
+// @Param id path string true "ID da despesa"
 // @Success 200 {object} models.Expense
 // @Failure 400,401,404,500 {string} string
 // @Router /users/{userId}/expenses/{id} [get]
 func GetExpenseByID(w http.ResponseWriter, r *http.Request) {
 	params := mux.Vars(r)
 	userId := params["userId"]
-	expenseId := params["expenseId"]
+	expenseId := params["id"]
 
 	row := db.DB.QueryRow(`
-		SELECT id, user_id, descricao, valor, vencimento, paga, data_pagamento, categoria, observacoes, created_at
+		SELECT `+expenseColumns+`
 		FROM expenses
-		WHERE user_id = $1 AND id = $2
+		WHERE user_id = $1 AND id = $2 AND deleted_at IS NULL
 	`, userId, expenseId)
 
 	var e models.Expense
-	err := row.Scan(
-		&e.ID,
-		&e.UserID,
-		&e.Descricao,
-		&e.Valor,
-		&e.Vencimento,
-		&e.Paga,
-		&e.DataPagamento,
-		&e.Categoria,
-		&e.Observacoes,
-		&e.CreatedAt,
-	)
+	err := scanExpense(row, &e)
 
 	if err != nil {
 		if err == sql.ErrNoRows {
@@ -231,18 +347,49 @@ func GetExpenseByID(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	payments, err := loadExpensePayments(db.DB, e.ID)
+	if err != nil {
+		http.Error(w, "Erro ao buscar pagamentos: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	refunds, err := loadExpenseRefunds(db.DB, e.ID)
+	if err != nil {
+		http.Error(w, "Erro ao buscar estornos: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
 	response := models.Expense{
 		ID:            e.ID,
 		UserID:        e.UserID,
 		Descricao:     e.Descricao,
 		Valor:         e.Valor,
+		Moeda:         e.Moeda,
 		Vencimento:    e.Vencimento,
 		Paga:          e.Paga,
 		DataPagamento: e.DataPagamento,
 		Categoria:     e.Categoria,
+		CategoriaID:   e.CategoriaID,
+		Tags:          e.Tags,
+		Campos:        e.Campos,
+		Divisao:       e.Divisao,
 		Observacoes:   e.Observacoes,
+		RecorrenciaID: e.RecorrenciaID,
+		Ocorrencia:    e.Ocorrencia,
 		CreatedAt:     e.CreatedAt,
 		Status:        e.StatusHoje(),
+		ValorPago:     e.ValorPago,
+		Encargos:      e.Encargos,
+		Pagamentos:    payments,
+
+		MultaPercentual:    e.MultaPercentual,
+		JurosPercentual:    e.JurosPercentual,
+		JurosPeriodo:       e.JurosPeriodo,
+		DescontoPercentual: e.DescontoPercentual,
+		DescontoAte:        e.DescontoAte,
+
+		ValorEstornado: e.ValorEstornado,
+		Estornos:       refunds,
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -252,6 +399,7 @@ func GetExpenseByID(w http.ResponseWriter, r *http.Request) {
 // UpdateExpense atualiza uma despesa existente
 //
 // @Summary Atualizar despesa
+// @Description Uma ocorrência recorrente alterada aqui fica fora das alterações da série inteira (scope=all).
 // @Tags Expenses
 // @Security BearerAuth
 // @Param userId path string true "ID do usuário"
@@ -275,34 +423,113 @@ func UpdateExpense(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Valor deve ser maior que zero", http.StatusBadRequest)
 		return
 	}
+	if update.Moeda != "" && !models.ValidCurrency(update.Moeda) {
+		http.Error(w, "Moeda inválida: use o código ISO 4217 (ex: BRL, USD)", http.StatusBadRequest)
+		return
+	}
+	if msg := validateExpenseCharges(&update); msg != "" {
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
+	if msg := models.ValidateSplits(update.Divisao, update.Valor); msg != "" {
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
 
-	// Se a despesa foi paga e sem data, define a data de pagamento como a data atual
-	if update.Paga && update.DataPagamento == nil {
-		now := time.Now()
-		update.DataPagamento = &now
+	tx, err := db.DB.Begin()
+	if err != nil {
+		http.Error(w, "Erro ao atualizar despesa: "+err.Error(), http.StatusInternalServerError)
+		return
 	}
+	defer tx.Rollback()
 
-	// Se desmarcar a despesa como paga, remove a data de pagamento
-	if !update.Paga {
-		update.DataPagamento = nil
+	if update.CategoriaID, update.Categoria, err = resolveCategory(tx, userId, models.CategoryExpense, update.CategoriaID, update.Categoria); err != nil {
+		writeCategoryError(w, err)
+		return
 	}
 
-	result, err := db.DB.Exec(`
-		UPDATE expenses
-		SET descricao = $1, valor = $2, vencimento = $3, paga = $4, data_pagamento = $5, categoria = $6, observacoes = $7
-		WHERE user_id = $8 AND id = $9
-	`, update.Descricao, update.Valor, update.Vencimento, update.Paga, update.DataPagamento, update.Categoria, update.Observacoes, userId, expenseId)
+	if update.PayeeID != nil {
+		if _, err := findPayee(tx, userId, *update.PayeeID); err != nil {
+			writePayeeError(w, err)
+			return
+		}
+	}
 
+	history, err := audit.Track(tx, models.AuditExpenses, "user_id = $1 AND id = $2 AND deleted_at IS NULL", userId, expenseId)
 	if err != nil {
-		http.Error(w, "Erro ao atualizar despesa: "+err.Error(), http.StatusInternalServerError)
+		http.Error(w, "Erro ao buscar despesa: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	rowsAffected, _ := result.RowsAffected()
-	if rowsAffected == 0 {
+	// O status de pagamento é derivado dos pagamentos; aqui só se altera os dados da despesa
+	var current models.Expense
+	err = scanExpense(tx.QueryRow(`
+		UPDATE expenses
+		SET descricao = $1, valor = $2, vencimento = $3, categoria = $4, category_id = $5, observacoes = $6, moeda = COALESCE(NULLIF($7, ''), moeda),
+			payee_id = COALESCE($10, payee_id), multa_percentual = $11, juros_percentual = $12, juros_periodo = $13,
+			desconto_percentual = $14, desconto_ate = $15, editada = recorrencia_id IS NOT NULL
+		WHERE user_id = $8 AND id = $9 AND deleted_at IS NULL
+		RETURNING `+expenseColumns+`
+	`, update.Descricao, update.Valor, update.Vencimento, update.Categoria, update.CategoriaID, update.Observacoes, update.Moeda, userId, expenseId, update.PayeeID,
+		update.MultaPercentual, update.JurosPercentual, update.JurosPeriodo, update.DescontoPercentual, update.DescontoAte), &current)
+
+	if err == sql.ErrNoRows {
 		http.Error(w, "Despesa não encontrada ou não pertence ao usuário", http.StatusNotFound)
 		return
 	}
+	if err != nil {
+		http.Error(w, "Erro ao atualizar despesa: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if update.Tags != nil {
+		if _, err := expenseTagLink.set(tx, userId, current.ID, update.Tags); err != nil {
+			http.Error(w, "Erro ao salvar tags: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if update.Campos != nil {
+		if _, err := expenseFieldLink.set(tx, userId, current.ID, update.Campos); err != nil {
+			writeFieldError(w, err)
+			return
+		}
+	}
+
+	if update.Divisao != nil {
+		if _, err := expenseSplitLink.set(tx, userId, current.ID, update.Divisao); err != nil {
+			writeSplitError(w, err)
+			return
+		}
+	} else if differs, err := expenseSplitLink.mismatch(tx, current.ID, current.Valor); err != nil {
+		http.Error(w, "Erro ao buscar divisão: "+err.Error(), http.StatusInternalServerError)
+		return
+	} else if differs {
+		http.Error(w, "A despesa é dividida: envie a divisão com o novo valor", http.StatusBadRequest)
+		return
+	}
+
+	// Marcar como paga pela edição registra um pagamento do saldo em aberto
+	if update.Paga && current.PrincipalPago() < current.Valor {
+		in := PaymentInput{Valor: current.Valor - current.PrincipalPago(), DataPagamento: update.DataPagamento}
+		if _, err := recordExpensePayment(tx, current, in); err != nil {
+			http.Error(w, "Erro ao registrar pagamento: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+	} else if err := syncExpensePaymentStatus(tx, current.ID); err != nil {
+		http.Error(w, "Erro ao atualizar despesa: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if err := history.Record(tx, apiAudit(r, models.AuditUpdate)); err != nil {
+		http.Error(w, "Erro ao registrar histórico: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		http.Error(w, "Erro ao atualizar despesa: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
 
 	// Retorna a despesa atualizada
 	w.WriteHeader(http.StatusNoContent)
@@ -313,9 +540,10 @@ type Message struct {
 	Message string `json:"message"`
 }
 
-// DeleteExpense exclui uma despesa de um usuário pelo ID
+// DeleteExpense move uma despesa de um usuário para a lixeira
 //
 // @Summary Excluir despesa
+// @Description A despesa vai para a lixeira, de onde pode ser restaurada até ser apagada pelo job de limpeza.
 // @Tags Expenses
 // @Security BearerAuth
 // @Param userId path string true "ID do usuário"
@@ -328,10 +556,24 @@ func DeleteExpense(w http.ResponseWriter, r *http.Request) {
 	userId := params["userId"]
 	expenseId := params["id"]
 
-	result, err := db.DB.Exec(`
-		DELETE FROM expenses
-		WHERE user_id = $1 AND id = $2
-	`, userId, expenseId)
+	tx, err := db.DB.Begin()
+	if err != nil {
+		http.Error(w, "Erro ao excluir despesa: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	history, err := audit.Track(tx, models.AuditExpenses, "user_id = $1 AND id = $2 AND deleted_at IS NULL", userId, expenseId)
+	if err != nil {
+		http.Error(w, "Erro ao excluir despesa: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// pagamentos, estornos e anexos ficam com a despesa na lixeira e só somem na limpeza
+	result, err := tx.Exec(`
+		UPDATE expenses SET deleted_at = $3
+		WHERE user_id = $1 AND id = $2 AND deleted_at IS NULL
+	`, userId, expenseId, time.Now())
 
 	if err != nil {
 		http.Error(w, "Erro ao excluir despesa: "+err.Error(), http.StatusInternalServerError)
@@ -344,18 +586,31 @@ func DeleteExpense(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if err := history.Record(tx, apiAudit(r, models.AuditDelete)); err != nil {
+		http.Error(w, "Erro ao registrar histórico: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		http.Error(w, "Erro ao excluir despesa: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	_ = json.NewEncoder(w).Encode(Message{Message: "Despesa excluída com sucesso"})
 }
 
-// PayExpense marca uma despesa como paga
+// PayExpense registra um pagamento da despesa. Sem corpo, paga o saldo em aberto
+// na data de hoje, com multa e juros se vencida ou desconto se antecipada; com
+// corpo, permite pagamentos parciais e informar os encargos efetivamente cobrados.
 //
-// @Summary Marcar despesa como paga
+// @Summary Registrar pagamento da despesa
 // @Tags Expenses
 // @Security BearerAuth
 // @Param userId path string true "ID do usuário"
 // @Param id path string true "ID da despesa"
+// @Param payment body PaymentInput false "Dados do pagamento"
 // @Success 200 {object} Message "Despesa marcada como paga com sucesso"
 // @Failure 400,401,404,500 {string} string
 // @Router /users/{userId}/expenses/{id}/pay [patch]
@@ -364,33 +619,83 @@ func PayExpense(w http.ResponseWriter, r *http.Request) {
 	userId := params["userId"]
 	expenseId := params["id"]
 
-	now := time.Now()
-
-	// Atualiza a despesa para marcada como paga
-	result, err := db.DB.Exec(`
-		UPDATE expenses
-		SET paga = true, data_pagamento = $1
-		WHERE user_id = $2 AND id = $3
-	`, now, userId, expenseId)
+	in, err := decodePaymentInput(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 
+	tx, err := db.DB.Begin()
 	if err != nil {
 		http.Error(w, "Erro ao marcar despesa como paga: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
+	defer tx.Rollback()
 
-	rowsAffected, _ := result.RowsAffected()
-	if rowsAffected == 0 {
+	var e models.Expense
+	err = scanExpense(tx.QueryRow(`
+		SELECT `+expenseColumns+`
+		FROM expenses
+		WHERE user_id = $1 AND id = $2 AND deleted_at IS NULL
+		FOR UPDATE
+	`, userId, expenseId), &e)
+	if err == sql.ErrNoRows {
 		http.Error(w, "Despesa não encontrada ou não pertence ao usuário", http.StatusNotFound)
 		return
 	}
+	if err != nil {
+		http.Error(w, "Erro ao marcar despesa como paga: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if msg := validatePaymentInput(&in, e); msg != "" {
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
+	if in.ContaID != nil {
+		if err := checkAccount(tx, userId, *in.ContaID); err == errAccountNotFound {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		} else if err != nil {
+			http.Error(w, "Erro ao buscar conta: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	history, err := audit.Track(tx, models.AuditExpenses, "id = $1", e.ID)
+	if err != nil {
+		http.Error(w, "Erro ao marcar despesa como paga: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if _, err := recordExpensePayment(tx, e, in); err != nil {
+		http.Error(w, "Erro ao marcar despesa como paga: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if err := history.Record(tx, apiAudit(r, models.AuditPay)); err != nil {
+		http.Error(w, "Erro ao registrar histórico: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		http.Error(w, "Erro ao marcar despesa como paga: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	msg := "Despesa marcada como paga com sucesso"
+	if !in.Quitar && e.PrincipalPago()+in.Valor-*in.Multa-*in.Juros+*in.Desconto < e.Valor {
+		msg = "Pagamento parcial registrado com sucesso"
+	}
 
-	// Retorna a despesa atualizada
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
-	_ = json.NewEncoder(w).Encode(Message{Message: "Despesa marcada como paga com sucesso"})
+	_ = json.NewEncoder(w).Encode(Message{Message: msg})
 }
 
-// UnpayExpense marca uma despesa como não paga
+// UnpayExpense desfaz a quitação manual de uma despesa. Os pagamentos ficam no
+// histórico: enquanto eles cobrirem o valor, a despesa continua paga e é
+// preciso excluí-los um a um em /payments/{paymentId}.
 //
 // @Summary Marcar despesa como não paga
 // @Tags Expenses
@@ -398,20 +703,35 @@ func PayExpense(w http.ResponseWriter, r *http.Request) {
 // @Param userId path string true "ID do usuário"
 // @Param id path string true "ID da despesa"
 // @Success 200 {object} Message "Despesa marcada como não paga com sucesso"
-// @Failure 400,401,404,500 {string} string
+// @Failure 400,401,404,409,500 {string} string
 // @Router /users/{userId}/expenses/{id}/unpay [patch]
 func UnpayExpense(w http.ResponseWriter, r *http.Request) {
 	params := mux.Vars(r)
 	userId := params["userId"]
-	expenseId := params["id"]
+	expenseId, err := uuid.Parse(params["id"])
+	if err != nil {
+		http.Error(w, "ID de despesa inválido", http.StatusBadRequest)
+		return
+	}
+
+	tx, err := db.DB.Begin()
+	if err != nil {
+		http.Error(w, "Erro ao marcar despesa como não paga: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
 
-	// Atualiza a despesa para marcada como não paga
-	result, err := db.DB.Exec(`
+	history, err := audit.Track(tx, models.AuditExpenses, "user_id = $1 AND id = $2 AND deleted_at IS NULL", userId, expenseId)
+	if err != nil {
+		http.Error(w, "Erro ao marcar despesa como não paga: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	result, err := tx.Exec(`
 		UPDATE expenses
-		SET paga = false, data_pagamento = null
-		WHERE user_id = $1 AND id = $2
+		SET quitada = false
+		WHERE user_id = $1 AND id = $2 AND deleted_at IS NULL
 	`, userId, expenseId)
-
 	if err != nil {
 		http.Error(w, "Erro ao marcar despesa como não paga: "+err.Error(), http.StatusInternalServerError)
 		return
@@ -423,6 +743,30 @@ func UnpayExpense(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if err := syncExpensePaymentStatus(tx, expenseId); err != nil {
+		http.Error(w, "Erro ao marcar despesa como não paga: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	var paga bool
+	if err := tx.QueryRow(`SELECT paga FROM expenses WHERE id = $1`, expenseId).Scan(&paga); err != nil {
+		http.Error(w, "Erro ao marcar despesa como não paga: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if paga {
+		http.Error(w, "Os pagamentos registrados quitam a despesa: exclua-os em /payments para desfazer", http.StatusConflict)
+		return
+	}
+
+	if err := history.Record(tx, apiAudit(r, models.AuditUnpay)); err != nil {
+		http.Error(w, "Erro ao registrar histórico: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		http.Error(w, "Erro ao marcar despesa como não paga: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	_ = json.NewEncoder(w).Encode(Message{Message: "Despesa marcada como não paga com sucesso"})
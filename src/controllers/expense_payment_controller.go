@@ -0,0 +1,331 @@
+package controllers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"io"
+	"net/http"
+	"time"
+
+	"finance/src/audit"
+	"finance/src/db"
+	"finance/src/models"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+// dbExecutor é satisfeito por *sql.DB e *sql.Tx
+type dbExecutor interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// PaymentInput são os dados de um pagamento; todos os campos são opcionais
+type PaymentInput struct {
+	Valor         models.Money `json:"valor"`          // padrão: saldo em aberto
+	DataPagamento *time.Time   `json:"data_pagamento"` // padrão: hoje
+	ContaID       *uuid.UUID   `json:"conta_id"`
+	Metodo        *string      `json:"metodo"`
+	Observacoes   *string      `json:"observacoes"`
+	Quitar        bool         `json:"quitar"` // encerra a despesa mesmo pagando menos (ex: desconto)
+
+	// Parte do valor que é multa, juros ou desconto; omitidos, são calculados
+	// pelas regras da despesa na data do pagamento
+	Multa    *models.Money `json:"multa"`
+	Juros    *models.Money `json:"juros"`
+	Desconto *models.Money `json:"desconto"`
+}
+
+// expenseWithoutPayments filtra despesas sem nenhum pagamento registrado
+const expenseWithoutPayments = `NOT EXISTS (SELECT 1 FROM expense_payments p WHERE p.expense_id = expenses.id)`
+
+const expensePaymentColumns = `id, expense_id, user_id, valor, multa, juros, desconto, data_pagamento, conta_id, metodo, observacoes, created_at`
+
+func scanExpensePayment(row rowScanner, p *models.ExpensePayment) error {
+	return row.Scan(&p.ID, &p.ExpenseID, &p.UserID, &p.Valor, &p.Multa, &p.Juros, &p.Desconto, &p.DataPagamento, &p.ContaID, &p.Metodo, &p.Observacoes, &p.CreatedAt)
+}
+
+// syncExpensePaymentStatus recalcula paga e data_pagamento a partir do principal pago
+func syncExpensePaymentStatus(q dbExecutor, expenseID uuid.UUID) error {
+	_, err := q.Exec(`
+		UPDATE expenses e
+		SET paga = e.quitada OR COALESCE(p.total, 0) >= e.valor,
+			data_pagamento = CASE WHEN e.quitada OR COALESCE(p.total, 0) >= e.valor THEN p.ultima END
+		FROM (
+			SELECT SUM(valor - multa - juros + desconto) AS total, MAX(data_pagamento) AS ultima
+			FROM expense_payments
+			WHERE expense_id = $1
+		) p
+		WHERE e.id = $1
+	`, expenseID)
+	return err
+}
+
+// recordExpensePayment registra um pagamento e atualiza o status da despesa
+func recordExpensePayment(q dbExecutor, e models.Expense, in PaymentInput) (models.ExpensePayment, error) {
+	pay := models.ExpensePayment{
+		ID:            uuid.New(),
+		ExpenseID:     e.ID,
+		UserID:        e.UserID,
+		Valor:         in.Valor,
+		DataPagamento: time.Now(),
+		ContaID:       in.ContaID,
+		Metodo:        in.Metodo,
+		Observacoes:   in.Observacoes,
+		CreatedAt:     time.Now(),
+	}
+	if in.DataPagamento != nil {
+		pay.DataPagamento = *in.DataPagamento
+	}
+	if in.Multa != nil {
+		pay.Multa = *in.Multa
+	}
+	if in.Juros != nil {
+		pay.Juros = *in.Juros
+	}
+	if in.Desconto != nil {
+		pay.Desconto = *in.Desconto
+	}
+
+	_, err := q.Exec(`
+		INSERT INTO expense_payments (id, expense_id, user_id, valor, multa, juros, desconto, data_pagamento, conta_id, metodo, observacoes, created_at)
+		VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12)
+	`, pay.ID, pay.ExpenseID, pay.UserID, pay.Valor, pay.Multa, pay.Juros, pay.Desconto, pay.DataPagamento, pay.ContaID, pay.Metodo, pay.Observacoes, pay.CreatedAt)
+	if err != nil {
+		return pay, err
+	}
+
+	if in.Quitar {
+		if _, err := q.Exec(`UPDATE expenses SET quitada = true WHERE id = $1`, e.ID); err != nil {
+			return pay, err
+		}
+	}
+	return pay, syncExpensePaymentStatus(q, e.ID)
+}
+
+// decodePaymentInput lê o corpo opcional de um pagamento
+func decodePaymentInput(r *http.Request) (PaymentInput, error) {
+	var in PaymentInput
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil && err != io.EOF {
+		return in, err
+	}
+	return in, nil
+}
+
+// validatePaymentInput preenche o valor padrão (saldo atualizado com multa,
+// juros ou desconto na data do pagamento) e valida os campos do pagamento
+func validatePaymentInput(in *PaymentInput, e models.Expense) string {
+	data := time.Now()
+	if in.DataPagamento != nil {
+		data = *in.DataPagamento
+	}
+	charges := e.ChargesAt(data)
+
+	if in.Valor == 0 {
+		in.Valor = charges.Total
+		if charges.Saldo <= 0 {
+			return "Despesa já está paga"
+		}
+	}
+	if in.Valor < 0 {
+		return "Valor deve ser maior que zero"
+	}
+	if in.Metodo != nil && !models.ValidPaymentMethod(*in.Metodo) {
+		return "Método de pagamento inválido"
+	}
+
+	multa, juros, desconto := charges.Split(in.Valor)
+	if in.Multa == nil {
+		in.Multa = &multa
+	}
+	if in.Juros == nil {
+		in.Juros = &juros
+	}
+	if in.Desconto == nil {
+		in.Desconto = &desconto
+	}
+	if *in.Multa < 0 || *in.Juros < 0 || *in.Desconto < 0 {
+		return "Multa, juros e desconto não podem ser negativos"
+	}
+	if *in.Multa+*in.Juros > in.Valor {
+		return "Multa e juros não podem passar do valor pago"
+	}
+	return ""
+}
+
+// loadExpensePayments busca os pagamentos de uma despesa em ordem cronológica
+func loadExpensePayments(q dbExecutor, expenseID uuid.UUID) ([]models.ExpensePayment, error) {
+	rows, err := q.Query(`
+		SELECT `+expensePaymentColumns+`
+		FROM expense_payments
+		WHERE expense_id = $1
+		ORDER BY data_pagamento, created_at
+	`, expenseID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var payments []models.ExpensePayment
+	for rows.Next() {
+		var p models.ExpensePayment
+		if err := scanExpensePayment(rows, &p); err != nil {
+			return nil, err
+		}
+		payments = append(payments, p)
+	}
+	return payments, rows.Err()
+}
+
+// ListExpensePayments lista o histórico de pagamentos de uma despesa
+//
+// @Summary Listar pagamentos da despesa
+// @Tags Expenses
+// @Security BearerAuth
+// @Param userId path string true "ID do usuário"
+// @Param id path string true "ID da despesa"
+// @Success 200 {array} models.ExpensePayment
+// @Failure 400,401,404,500 {string} string
+// @Router /users/{userId}/expenses/{id}/payments [get]
+func ListExpensePayments(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+
+	var e models.Expense
+	err := scanExpense(db.DB.QueryRow(`
+		SELECT `+expenseColumns+`
+		FROM expenses
+		WHERE user_id = $1 AND id = $2 AND deleted_at IS NULL
+	`, params["userId"], params["id"]), &e)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Despesa não encontrada", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Erro ao buscar despesa: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	payments, err := loadExpensePayments(db.DB, e.ID)
+	if err != nil {
+		http.Error(w, "Erro ao buscar pagamentos: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(payments)
+}
+
+// GetExpenseCharges calcula quanto custa quitar a despesa numa data: saldo em
+// aberto com multa e juros de mora, se vencida, ou com o desconto por
+// pagamento antecipado
+//
+// @Summary Valor atualizado da despesa
+// @Tags Expenses
+// @Security BearerAuth
+// @Param userId path string true "ID do usuário"
+// @Param id path string true "ID da despesa"
+// @Param data query string false "Data do pagamento (YYYY-MM-DD); padrão: hoje"
+// @Success 200 {object} models.ExpenseCharges
+// @Failure 400,401,404,500 {string} string
+// @Router /users/{userId}/expenses/{id}/charges [get]
+func GetExpenseCharges(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+
+	data := time.Now()
+	if s := r.URL.Query().Get("data"); s != "" {
+		var err error
+		if data, err = time.Parse("2006-01-02", s); err != nil {
+			http.Error(w, "Data inválida: use o formato YYYY-MM-DD", http.StatusBadRequest)
+			return
+		}
+	}
+
+	var e models.Expense
+	err := scanExpense(db.DB.QueryRow(`
+		SELECT `+expenseColumns+`
+		FROM expenses
+		WHERE user_id = $1 AND id = $2 AND deleted_at IS NULL
+	`, params["userId"], params["id"]), &e)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Despesa não encontrada", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Erro ao buscar despesa: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(e.ChargesAt(data))
+}
+
+// DeleteExpensePayment exclui um pagamento e recalcula o status da despesa
+//
+// @Summary Excluir pagamento
+// @Tags Expenses
+// @Security BearerAuth
+// @Param userId path string true "ID do usuário"
+// @Param id path string true "ID da despesa"
+// @Param paymentId path string true "ID do pagamento"
+// @Success 200 {object} Message
+// @Failure 400,401,404,500 {string} string
+// @Router /users/{userId}/expenses/{id}/payments/{paymentId} [delete]
+func DeleteExpensePayment(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	expenseID, err := uuid.Parse(params["id"])
+	if err != nil {
+		http.Error(w, "ID de despesa inválido", http.StatusBadRequest)
+		return
+	}
+
+	tx, err := db.DB.Begin()
+	if err != nil {
+		http.Error(w, "Erro ao iniciar transação: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	history, err := audit.Track(tx, models.AuditExpenses, "user_id = $1 AND id = $2", params["userId"], expenseID)
+	if err != nil {
+		http.Error(w, "Erro ao buscar despesa: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	result, err := tx.Exec(`
+		DELETE FROM expense_payments
+		WHERE user_id = $1 AND expense_id = $2 AND id = $3
+	`, params["userId"], expenseID, params["paymentId"])
+	if err != nil {
+		http.Error(w, "Erro ao excluir pagamento: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if rowsAffected, _ := result.RowsAffected(); rowsAffected == 0 {
+		http.Error(w, "Pagamento não encontrado", http.StatusNotFound)
+		return
+	}
+
+	// excluir um pagamento reabre uma despesa quitada com desconto
+	if _, err := tx.Exec(`UPDATE expenses SET quitada = false WHERE id = $1`, expenseID); err != nil {
+		http.Error(w, "Erro ao atualizar despesa: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if err := syncExpensePaymentStatus(tx, expenseID); err != nil {
+		http.Error(w, "Erro ao atualizar despesa: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if err := history.Record(tx, apiAudit(r, models.AuditUnpay)); err != nil {
+		http.Error(w, "Erro ao registrar histórico: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		http.Error(w, "Erro ao excluir pagamento: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(Message{Message: "Pagamento excluído com sucesso"})
+}
@@ -0,0 +1,236 @@
+package controllers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"io"
+	"net/http"
+	"time"
+
+	"finance/src/db"
+	"finance/src/models"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+// expenseRefundedSQL soma os estornos da despesa cujo id é a coluna informada
+func expenseRefundedSQL(idColumn string) string {
+	return `COALESCE((SELECT SUM(rf.valor) FROM expense_refunds rf WHERE rf.expense_id = ` + idColumn + `), 0)`
+}
+
+// netExpensesSQL é convertedSQL de expenses com valor_base líquido dos estornos.
+// É a fonte dos totais de despesas (resumo, tags e favorecidos; por categoria,
+// netExpenseLinesSQL): o estorno reduz o gasto no mês da despesa.
+var netExpensesSQL = convertedValueSQL(liveExpenses, "vencimento", "t.valor - "+expenseRefundedSQL("t.id"))
+
+const expenseRefundColumns = `id, expense_id, user_id, valor, data, conta_id, motivo, created_at`
+
+func scanExpenseRefund(row rowScanner, rf *models.ExpenseRefund) error {
+	return row.Scan(&rf.ID, &rf.ExpenseID, &rf.UserID, &rf.Valor, &rf.Data, &rf.ContaID, &rf.Motivo, &rf.CreatedAt)
+}
+
+// loadExpenseRefunds busca os estornos de uma despesa em ordem cronológica
+func loadExpenseRefunds(q dbExecutor, expenseID uuid.UUID) ([]models.ExpenseRefund, error) {
+	rows, err := q.Query(`
+		SELECT `+expenseRefundColumns+`
+		FROM expense_refunds
+		WHERE expense_id = $1
+		ORDER BY data, created_at
+	`, expenseID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var refunds []models.ExpenseRefund
+	for rows.Next() {
+		var rf models.ExpenseRefund
+		if err := scanExpenseRefund(rows, &rf); err != nil {
+			return nil, err
+		}
+		refunds = append(refunds, rf)
+	}
+	return refunds, rows.Err()
+}
+
+// CreateExpenseRefund registra um estorno, parcial ou total, de uma despesa
+//
+// @Summary Registrar estorno da despesa
+// @Description O valor, na moeda da despesa, é descontado dela nos totais de despesas do mês dela.
+// @Description Sem valor, estorna o que falta para o valor total.
+// @Tags Expenses
+// @Security BearerAuth
+// @Param userId path string true "ID do usuário"
+// @Param id path string true "ID da despesa"
+// @Param refund body models.ExpenseRefund false "Dados do estorno"
+// @Success 201 {object} models.ExpenseRefund
+// @Failure 400,401,404,500 {string} string
+// @Router /users/{userId}/expenses/{id}/refunds [post]
+func CreateExpenseRefund(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	userID := params["userId"]
+
+	var rf models.ExpenseRefund
+	if err := json.NewDecoder(r.Body).Decode(&rf); err != nil && err != io.EOF {
+		http.Error(w, "JSON inválido: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if rf.Valor < 0 {
+		http.Error(w, "Valor deve ser maior que zero", http.StatusBadRequest)
+		return
+	}
+	if rf.ContaID != nil {
+		if err := checkAccount(db.DB, userID, *rf.ContaID); err == errAccountNotFound {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		} else if err != nil {
+			http.Error(w, "Erro ao buscar conta: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	tx, err := db.DB.Begin()
+	if err != nil {
+		http.Error(w, "Erro ao iniciar transação: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	// trava a despesa para que estornos simultâneos não passem do valor dela
+	var e models.Expense
+	err = scanExpense(tx.QueryRow(`
+		SELECT `+expenseColumns+`
+		FROM expenses
+		WHERE user_id = $1 AND id = $2 AND deleted_at IS NULL
+		FOR UPDATE
+	`, userID, params["id"]), &e)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Despesa não encontrada", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Erro ao buscar despesa: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	restante := e.Valor - e.ValorEstornado
+	if restante <= 0 {
+		http.Error(w, "Despesa já foi estornada por inteiro", http.StatusBadRequest)
+		return
+	}
+	if rf.Valor == 0 {
+		rf.Valor = restante
+	}
+	if rf.Valor > restante {
+		http.Error(w, "Estorno passa do valor da despesa: restam "+restante.String()+" "+e.Moeda, http.StatusBadRequest)
+		return
+	}
+
+	rf.ID = uuid.New()
+	rf.ExpenseID = e.ID
+	rf.UserID = e.UserID
+	rf.CreatedAt = time.Now()
+	if rf.Data.IsZero() {
+		rf.Data = rf.CreatedAt
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO expense_refunds (id, expense_id, user_id, valor, data, conta_id, motivo, created_at)
+		VALUES ($1,$2,$3,$4,$5,$6,$7,$8)
+	`, rf.ID, rf.ExpenseID, rf.UserID, rf.Valor, rf.Data, rf.ContaID, rf.Motivo, rf.CreatedAt)
+	if err != nil {
+		http.Error(w, "Erro ao registrar estorno: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		http.Error(w, "Erro ao registrar estorno: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(rf)
+}
+
+// ListExpenseRefunds lista os estornos de uma despesa
+//
+// @Summary Listar estornos da despesa
+// @Tags Expenses
+// @Security BearerAuth
+// @Param userId path string true "ID do usuário"
+// @Param id path string true "ID da despesa"
+// @Success 200 {array} models.ExpenseRefund
+// @Failure 400,401,404,500 {string} string
+// @Router /users/{userId}/expenses/{id}/refunds [get]
+func ListExpenseRefunds(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	expenseID, err := uuid.Parse(params["id"])
+	if err != nil {
+		http.Error(w, "ID de despesa inválido", http.StatusBadRequest)
+		return
+	}
+
+	var exists bool
+	err = db.DB.QueryRow(`SELECT EXISTS (SELECT 1 FROM expenses WHERE user_id = $1 AND id = $2 AND deleted_at IS NULL)`, params["userId"], expenseID).Scan(&exists)
+	if err != nil {
+		http.Error(w, "Erro ao buscar despesa: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if !exists {
+		http.Error(w, "Despesa não encontrada", http.StatusNotFound)
+		return
+	}
+
+	refunds, err := loadExpenseRefunds(db.DB, expenseID)
+	if err != nil {
+		http.Error(w, "Erro ao buscar estornos: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if refunds == nil {
+		refunds = []models.ExpenseRefund{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(refunds)
+}
+
+// DeleteExpenseRefund exclui um estorno lançado por engano
+//
+// @Summary Excluir estorno
+// @Tags Expenses
+// @Security BearerAuth
+// @Param userId path string true "ID do usuário"
+// @Param id path string true "ID da despesa"
+// @Param refundId path string true "ID do estorno"
+// @Success 200 {object} Message
+// @Failure 400,401,404,500 {string} string
+// @Router /users/{userId}/expenses/{id}/refunds/{refundId} [delete]
+func DeleteExpenseRefund(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	if _, err := uuid.Parse(params["id"]); err != nil {
+		http.Error(w, "ID de despesa inválido", http.StatusBadRequest)
+		return
+	}
+	if _, err := uuid.Parse(params["refundId"]); err != nil {
+		http.Error(w, "ID de estorno inválido", http.StatusBadRequest)
+		return
+	}
+
+	result, err := db.DB.Exec(`
+		DELETE FROM expense_refunds
+		WHERE user_id = $1 AND expense_id = $2 AND id = $3
+	`, params["userId"], params["id"], params["refundId"])
+	if err != nil {
+		http.Error(w, "Erro ao excluir estorno: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if rowsAffected, _ := result.RowsAffected(); rowsAffected == 0 {
+		http.Error(w, "Estorno não encontrado", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(Message{Message: "Estorno excluído com sucesso"})
+}
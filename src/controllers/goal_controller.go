@@ -0,0 +1,507 @@
+package controllers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"finance/src/db"
+	"finance/src/models"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/lib/pq"
+)
+
+// goalSelect lê a meta com o total guardado e a data do primeiro aporte
+const goalSelect = `
+	SELECT g.id, g.user_id, g.nome, g.valor_alvo, g.data_alvo, g.conta_id, g.moeda, g.created_at,
+		COALESCE(SUM(c.valor), 0), MIN(c.data)
+	FROM goals g
+	LEFT JOIN goal_contributions c ON c.goal_id = g.id
+`
+
+func scanGoal(row rowScanner, g *models.Goal) error {
+	var guardado models.Money
+	var primeiro *time.Time
+	if err := row.Scan(&g.ID, &g.UserID, &g.Nome, &g.ValorAlvo, &g.DataAlvo, &g.ContaID, &g.Moeda, &g.CreatedAt, &guardado, &primeiro); err != nil {
+		return err
+	}
+	p := models.ComputeGoalProgress(*g, guardado, primeiro, time.Now())
+	g.Progresso = &p
+	return nil
+}
+
+func loadGoal(q dbExecutor, userID, id string) (models.Goal, error) {
+	var g models.Goal
+	err := scanGoal(q.QueryRow(goalSelect+`
+		WHERE g.user_id = $1 AND g.id = $2
+		GROUP BY g.id
+	`, userID, id), &g)
+	return g, err
+}
+
+// resolveGoalCurrency valida a conta vinculada e define a moeda da meta:
+// a informada, a da conta ou a moeda base do usuário
+func resolveGoalCurrency(q dbExecutor, userID string, g *models.Goal) (int, string) {
+	if g.ContaID != nil {
+		var moeda string
+		err := q.QueryRow(`SELECT moeda FROM accounts WHERE user_id = $1 AND id = $2`, userID, g.ContaID).Scan(&moeda)
+		if err == sql.ErrNoRows {
+			return http.StatusBadRequest, "Conta não encontrada"
+		}
+		if err != nil {
+			return http.StatusInternalServerError, "Erro ao buscar conta: " + err.Error()
+		}
+		if g.Moeda == "" {
+			g.Moeda = moeda
+		}
+	}
+
+	moeda, err := resolveCurrency(q, userID, g.Moeda)
+	if err == errInvalidCurrency {
+		return http.StatusBadRequest, err.Error()
+	}
+	if err != nil {
+		return http.StatusInternalServerError, "Erro ao buscar moeda base: " + err.Error()
+	}
+	g.Moeda = moeda
+	return 0, ""
+}
+
+// validateGoal confere os campos editáveis da meta
+func validateGoal(g *models.Goal) string {
+	g.Nome = strings.TrimSpace(g.Nome)
+	if g.Nome == "" {
+		return "Nome da meta é obrigatório"
+	}
+	if g.ValorAlvo <= 0 {
+		return "Valor alvo deve ser maior que zero"
+	}
+	return ""
+}
+
+// CreateGoal cria uma meta de economia
+//
+// @Summary Criar meta
+// @Tags Goals
+// @Security BearerAuth
+// @Param userId path string true "ID do usuário"
+// @Param goal body models.Goal true "Dados da meta"
+// @Success 201 {object} models.Goal
+// @Failure 400,401,409,500 {string} string
+// @Router /users/{userId}/goals [post]
+func CreateGoal(w http.ResponseWriter, r *http.Request) {
+	userID, err := uuid.Parse(mux.Vars(r)["userId"])
+	if err != nil {
+		http.Error(w, "ID de usuário inválido", http.StatusBadRequest)
+		return
+	}
+
+	var g models.Goal
+	if err := json.NewDecoder(r.Body).Decode(&g); err != nil {
+		http.Error(w, "Erro ao decodificar JSON", http.StatusBadRequest)
+		return
+	}
+	if msg := validateGoal(&g); msg != "" {
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
+	if status, msg := resolveGoalCurrency(db.DB, userID.String(), &g); status != 0 {
+		http.Error(w, msg, status)
+		return
+	}
+
+	g.ID = uuid.New()
+	g.UserID = userID
+	g.CreatedAt = time.Now()
+
+	_, err = db.DB.Exec(`
+		INSERT INTO goals (id, user_id, nome, valor_alvo, data_alvo, conta_id, moeda, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	`, g.ID, g.UserID, g.Nome, g.ValorAlvo, g.DataAlvo, g.ContaID, g.Moeda, g.CreatedAt)
+	if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+		http.Error(w, "Já existe uma meta com esse nome", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Erro ao criar meta: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	p := models.ComputeGoalProgress(g, 0, nil, time.Now())
+	g.Progresso = &p
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(g)
+}
+
+// ListGoals lista as metas do usuário com o progresso de cada uma
+//
+// @Summary Listar metas
+// @Tags Goals
+// @Security BearerAuth
+// @Param userId path string true "ID do usuário"
+// @Success 200 {array} models.Goal
+// @Failure 400,401,500 {string} string
+// @Router /users/{userId}/goals [get]
+func ListGoals(w http.ResponseWriter, r *http.Request) {
+	rows, err := db.DB.Query(goalSelect+`
+		WHERE g.user_id = $1
+		GROUP BY g.id
+		ORDER BY g.data_alvo NULLS LAST, g.nome
+	`, mux.Vars(r)["userId"])
+	if err != nil {
+		http.Error(w, "Erro ao buscar metas: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	goals := []models.Goal{}
+	for rows.Next() {
+		var g models.Goal
+		if err := scanGoal(rows, &g); err != nil {
+			http.Error(w, "Erro ao ler meta: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		goals = append(goals, g)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(goals)
+}
+
+// GetGoal retorna uma meta com progresso, aporte mensal necessário e data projetada
+//
+// @Summary Buscar meta
+// @Tags Goals
+// @Security BearerAuth
+// @Param userId path string true "ID do usuário"
+// @Param id path string true "ID da meta"
+// @Success 200 {object} models.Goal
+// @Failure 400,401,404,500 {string} string
+// @Router /users/{userId}/goals/{id} [get]
+func GetGoal(w http.ResponseWriter, r *http.Request) {
+	p := mux.Vars(r)
+	if _, err := uuid.Parse(p["id"]); err != nil {
+		http.Error(w, "ID de meta inválido", http.StatusBadRequest)
+		return
+	}
+
+	g, err := loadGoal(db.DB, p["userId"], p["id"])
+	if err == sql.ErrNoRows {
+		http.Error(w, "Meta não encontrada", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Erro ao buscar meta: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(g)
+}
+
+// UpdateGoal altera nome, valor alvo, data alvo e conta da meta
+//
+// @Summary Atualizar meta
+// @Tags Goals
+// @Security BearerAuth
+// @Param userId path string true "ID do usuário"
+// @Param id path string true "ID da meta"
+// @Param goal body models.Goal true "Dados da meta"
+// @Success 200 {object} models.Goal
+// @Failure 400,401,404,409,500 {string} string
+// @Router /users/{userId}/goals/{id} [put]
+func UpdateGoal(w http.ResponseWriter, r *http.Request) {
+	p := mux.Vars(r)
+	if _, err := uuid.Parse(p["id"]); err != nil {
+		http.Error(w, "ID de meta inválido", http.StatusBadRequest)
+		return
+	}
+
+	var in models.Goal
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		http.Error(w, "Erro ao decodificar JSON", http.StatusBadRequest)
+		return
+	}
+	if msg := validateGoal(&in); msg != "" {
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
+	if status, msg := resolveGoalCurrency(db.DB, p["userId"], &in); status != 0 {
+		http.Error(w, msg, status)
+		return
+	}
+
+	result, err := db.DB.Exec(`
+		UPDATE goals SET nome = $1, valor_alvo = $2, data_alvo = $3, conta_id = $4, moeda = $5
+		WHERE user_id = $6 AND id = $7
+	`, in.Nome, in.ValorAlvo, in.DataAlvo, in.ContaID, in.Moeda, p["userId"], p["id"])
+	if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+		http.Error(w, "Já existe uma meta com esse nome", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Erro ao atualizar meta: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if rowsAffected, _ := result.RowsAffected(); rowsAffected == 0 {
+		http.Error(w, "Meta não encontrada", http.StatusNotFound)
+		return
+	}
+
+	g, err := loadGoal(db.DB, p["userId"], p["id"])
+	if err != nil {
+		http.Error(w, "Erro ao buscar meta: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(g)
+}
+
+// DeleteGoal exclui a meta e todas as suas movimentações
+//
+// @Summary Excluir meta
+// @Tags Goals
+// @Security BearerAuth
+// @Param userId path string true "ID do usuário"
+// @Param id path string true "ID da meta"
+// @Success 200 {object} Message
+// @Failure 400,401,404,500 {string} string
+// @Router /users/{userId}/goals/{id} [delete]
+func DeleteGoal(w http.ResponseWriter, r *http.Request) {
+	p := mux.Vars(r)
+
+	result, err := db.DB.Exec(`
+		DELETE FROM goals
+		WHERE user_id = $1 AND id = $2
+	`, p["userId"], p["id"])
+	if err != nil {
+		http.Error(w, "Erro ao excluir meta: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if rowsAffected, _ := result.RowsAffected(); rowsAffected == 0 {
+		http.Error(w, "Meta não encontrada", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(Message{Message: "Meta excluída com sucesso"})
+}
+
+// CreateGoalContribution registra um aporte ou uma retirada na meta
+//
+// @Summary Registrar aporte ou retirada
+// @Tags Goals
+// @Security BearerAuth
+// @Param userId path string true "ID do usuário"
+// @Param id path string true "ID da meta"
+// @Param contribution body models.GoalContribution true "Tipo (aporte ou retirada), valor e data (padrão: hoje)"
+// @Success 201 {object} models.Goal
+// @Failure 400,401,404,500 {string} string
+// @Router /users/{userId}/goals/{id}/contributions [post]
+func CreateGoalContribution(w http.ResponseWriter, r *http.Request) {
+	p := mux.Vars(r)
+	userID, err := uuid.Parse(p["userId"])
+	if err != nil {
+		http.Error(w, "ID de usuário inválido", http.StatusBadRequest)
+		return
+	}
+	goalID, err := uuid.Parse(p["id"])
+	if err != nil {
+		http.Error(w, "ID de meta inválido", http.StatusBadRequest)
+		return
+	}
+
+	var c models.GoalContribution
+	if err := json.NewDecoder(r.Body).Decode(&c); err != nil {
+		http.Error(w, "Erro ao decodificar JSON", http.StatusBadRequest)
+		return
+	}
+	if c.Valor <= 0 {
+		http.Error(w, "Valor deve ser maior que zero", http.StatusBadRequest)
+		return
+	}
+	if c.Tipo == "" {
+		c.Tipo = models.GoalDeposit
+	}
+	if c.Tipo != models.GoalDeposit && c.Tipo != models.GoalWithdrawal {
+		http.Error(w, "Tipo inválido: use aporte ou retirada", http.StatusBadRequest)
+		return
+	}
+	if c.Data.IsZero() {
+		c.Data = time.Now()
+	}
+
+	tx, err := db.DB.Begin()
+	if err != nil {
+		http.Error(w, "Erro ao iniciar transação: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	// trava a meta para que retiradas simultâneas não deixem o saldo negativo
+	var guardado models.Money
+	err = tx.QueryRow(`
+		SELECT COALESCE((SELECT SUM(valor) FROM goal_contributions WHERE goal_id = g.id), 0)
+		FROM goals g
+		WHERE g.user_id = $1 AND g.id = $2
+		FOR UPDATE
+	`, userID, goalID).Scan(&guardado)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Meta não encontrada", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Erro ao buscar meta: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	valor := c.Valor
+	if c.Tipo == models.GoalWithdrawal {
+		if c.Valor > guardado {
+			http.Error(w, "Retirada maior que o valor guardado ("+guardado.String()+")", http.StatusBadRequest)
+			return
+		}
+		valor = -c.Valor
+	}
+
+	if _, err := tx.Exec(`
+		INSERT INTO goal_contributions (id, goal_id, user_id, valor, data, observacoes, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, NOW())
+	`, uuid.New(), goalID, userID, valor, c.Data, c.Observacoes); err != nil {
+		http.Error(w, "Erro ao registrar movimentação: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	g, err := loadGoal(tx, userID.String(), goalID.String())
+	if err != nil {
+		http.Error(w, "Erro ao buscar meta: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		http.Error(w, "Erro ao registrar movimentação: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(g)
+}
+
+// ListGoalContributions lista os aportes e retiradas da meta
+//
+// @Summary Listar movimentações da meta
+// @Tags Goals
+// @Security BearerAuth
+// @Param userId path string true "ID do usuário"
+// @Param id path string true "ID da meta"
+// @Success 200 {array} models.GoalContribution
+// @Failure 400,401,500 {string} string
+// @Router /users/{userId}/goals/{id}/contributions [get]
+func ListGoalContributions(w http.ResponseWriter, r *http.Request) {
+	p := mux.Vars(r)
+	if _, err := uuid.Parse(p["id"]); err != nil {
+		http.Error(w, "ID de meta inválido", http.StatusBadRequest)
+		return
+	}
+
+	rows, err := db.DB.Query(`
+		SELECT id, goal_id, user_id, valor, data, observacoes, created_at
+		FROM goal_contributions
+		WHERE user_id = $1 AND goal_id = $2
+		ORDER BY data, created_at
+	`, p["userId"], p["id"])
+	if err != nil {
+		http.Error(w, "Erro ao buscar movimentações: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	contributions := []models.GoalContribution{}
+	for rows.Next() {
+		var c models.GoalContribution
+		if err := rows.Scan(&c.ID, &c.GoalID, &c.UserID, &c.Valor, &c.Data, &c.Observacoes, &c.CreatedAt); err != nil {
+			http.Error(w, "Erro ao ler movimentação: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		c.Tipo = models.GoalDeposit
+		if c.Valor < 0 {
+			c.Tipo = models.GoalWithdrawal
+			c.Valor = -c.Valor
+		}
+		contributions = append(contributions, c)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(contributions)
+}
+
+// DeleteGoalContribution exclui um aporte ou retirada da meta
+//
+// @Summary Excluir movimentação da meta
+// @Tags Goals
+// @Security BearerAuth
+// @Param userId path string true "ID do usuário"
+// @Param id path string true "ID da meta"
+// @Param contributionId path string true "ID da movimentação"
+// @Success 200 {object} models.Goal
+// @Failure 400,401,404,409,500 {string} string
+// @Router /users/{userId}/goals/{id}/contributions/{contributionId} [delete]
+func DeleteGoalContribution(w http.ResponseWriter, r *http.Request) {
+	p := mux.Vars(r)
+	if _, err := uuid.Parse(p["id"]); err != nil {
+		http.Error(w, "ID de meta inválido", http.StatusBadRequest)
+		return
+	}
+
+	tx, err := db.DB.Begin()
+	if err != nil {
+		http.Error(w, "Erro ao iniciar transação: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`SELECT 1 FROM goals WHERE user_id = $1 AND id = $2 FOR UPDATE`, p["userId"], p["id"]); err != nil {
+		http.Error(w, "Erro ao buscar meta: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	result, err := tx.Exec(`
+		DELETE FROM goal_contributions
+		WHERE user_id = $1 AND goal_id = $2 AND id = $3
+	`, p["userId"], p["id"], p["contributionId"])
+	if err != nil {
+		http.Error(w, "Erro ao excluir movimentação: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if rowsAffected, _ := result.RowsAffected(); rowsAffected == 0 {
+		http.Error(w, "Movimentação não encontrada", http.StatusNotFound)
+		return
+	}
+
+	g, err := loadGoal(tx, p["userId"], p["id"])
+	if err != nil {
+		http.Error(w, "Erro ao buscar meta: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	// excluir um aporte já usado por uma retirada deixaria o saldo negativo
+	if g.Progresso.Guardado < 0 {
+		http.Error(w, "Não é possível excluir: o saldo da meta ficaria negativo", http.StatusConflict)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		http.Error(w, "Erro ao excluir movimentação: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(g)
+}
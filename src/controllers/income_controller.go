@@ -3,6 +3,7 @@ package controllers
 import (
 	"database/sql"
 	"encoding/json"
+	"finance/src/audit"
 	"finance/src/db"
 	"finance/src/models"
 	"net/http"
@@ -11,8 +12,23 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
+	"github.com/lib/pq"
 )
 
+// incomeColumns são as colunas de incomes lidas por scanIncome, na mesma ordem
+var incomeColumns = `id, user_id, descricao, valor, moeda, data_recebimento, categoria, category_id,
+	payee_id, (SELECT py.nome FROM payees py WHERE py.id = incomes.payee_id) AS payee, conta_id,
+	observacoes, status, valor_previsto, data_prevista, recorrencia_id, ocorrencia, created_at, ` + incomeTagLink.tagsColumn() + `, ` + incomeFieldLink.valuesColumn() + `, ` + incomeSplitLink.splitsColumn()
+
+func scanIncome(row rowScanner, inc *models.Income) error {
+	err := row.Scan(&inc.ID, &inc.UserID, &inc.Descricao, &inc.Valor, &inc.Moeda, &inc.DataRecebimento, &inc.Categoria, &inc.CategoriaID, &inc.PayeeID, &inc.Payee, &inc.ContaID, &inc.Observacoes,
+		&inc.Status, &inc.ValorPrevisto, &inc.DataPrevista, &inc.RecorrenciaID, &inc.Ocorrencia, &inc.CreatedAt, pq.Array(&inc.Tags), &inc.Campos, &inc.Divisao)
+	if err == nil {
+		inc.Situacao = inc.StatusHoje()
+	}
+	return err
+}
+
 // CreateIncome cria uma nova receita para um usuário
 //
 // @Summary Criar receita
@@ -42,19 +58,76 @@ func CreateIncome(w http.ResponseWriter, r *http.Request) {
 	income.UserID = userID
 	income.CreatedAt = time.Now()
 
+	if income.Moeda, err = resolveCurrency(db.DB, userIDStr, income.Moeda); err != nil {
+		writeCurrencyError(w, err)
+		return
+	}
+
+	if income.CategoriaID, income.Categoria, err = resolveCategory(db.DB, userIDStr, models.CategoryIncome, income.CategoriaID, income.Categoria); err != nil {
+		writeCategoryError(w, err)
+		return
+	}
+
+	if income.PayeeID, income.Payee, err = resolvePayee(db.DB, userIDStr, income.PayeeID, income.Descricao); err != nil {
+		writePayeeError(w, err)
+		return
+	}
+
+	if msg := models.ValidateSplits(income.Divisao, income.Valor); msg != "" {
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
+
+	if income.ContaID != nil {
+		if err := checkAccount(db.DB, userIDStr, *income.ContaID); err == errAccountNotFound {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		} else if err != nil {
+			http.Error(w, "Erro ao buscar conta: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	// Receitas avulsas são recebidas por padrão; "prevista" registra uma receita esperada
+	switch income.Status {
+	case "":
+		income.Status = models.IncomeReceived
+	case models.IncomeExpected:
+		income.ValorPrevisto = &income.Valor
+		income.DataPrevista = &income.DataRecebimento
+	case models.IncomeReceived:
+	default:
+		http.Error(w, "Status inválido: use prevista ou recebida", http.StatusBadRequest)
+		return
+	}
+
+	tx, err := db.DB.Begin()
+	if err != nil {
+		http.Error(w, "Erro ao iniciar transação: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
 	query := `
-		INSERT INTO incomes (id, user_id, descricao, valor, data_recebimento, categoria, observacoes, created_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		INSERT INTO incomes (id, user_id, descricao, valor, moeda, data_recebimento, categoria, category_id, observacoes, status, valor_previsto, data_prevista, created_at, payee_id, conta_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)
 	`
-	_, err = db.DB.Exec(query,
+	_, err = tx.Exec(query,
 		income.ID,
 		income.UserID,
 		income.Descricao,
 		income.Valor,
+		income.Moeda,
 		income.DataRecebimento,
 		income.Categoria,
+		income.CategoriaID,
 		income.Observacoes,
+		income.Status,
+		income.ValorPrevisto,
+		income.DataPrevista,
 		income.CreatedAt,
+		income.PayeeID,
+		income.ContaID,
 	)
 
 	if err != nil {
@@ -62,6 +135,31 @@ func CreateIncome(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if income.Tags, err = incomeTagLink.set(tx, userIDStr, income.ID, income.Tags); err != nil {
+		http.Error(w, "Erro ao salvar tags: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if income.Campos, err = incomeFieldLink.set(tx, userIDStr, income.ID, income.Campos); err != nil {
+		writeFieldError(w, err)
+		return
+	}
+
+	if income.Divisao, err = incomeSplitLink.set(tx, userIDStr, income.ID, income.Divisao); err != nil {
+		writeSplitError(w, err)
+		return
+	}
+
+	if err := audit.TrackNew(models.AuditIncomes, income.ID).Record(tx, apiAudit(r, models.AuditCreate)); err != nil {
+		http.Error(w, "Erro ao registrar histórico: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		http.Error(w, "Erro ao criar receita: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
 	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode(income)
 }
@@ -74,6 +172,8 @@ func CreateIncome(w http.ResponseWriter, r *http.Request) {
 // @Param userId path string true "ID do usuário"
 // @Param month query string true "Mês (1-12)"
 // @Param year query string true "Ano (YYYY)"
+// @Param tag query []string false "Filtra pelas tags (todas obrigatórias); repita o parâmetro para várias" collectionFormat(multi)
+// @Param campo[nome] query string false "Filtra pelo valor de um campo personalizado; repita com outros nomes para vários"
 // @Success 200 {array} models.Income
 // @Failure 400,401,500 {string} string
 // @Router /incomes/{userId} [get]
@@ -90,12 +190,21 @@ func ListIncomes(w http.ResponseWriter, r *http.Request) {
 	start := time.Date(year, time.Month(month), 1, 0, 0, 0, 0, time.UTC)
 	end := start.AddDate(0, 1, 0)
 
-	rows, err := db.DB.Query(`
-		SELECT id, user_id, descricao, valor, data_recebimento, categoria, observacoes, created_at
+	query := `
+		SELECT ` + incomeColumns + `
 		FROM incomes
-		WHERE user_id = $1 AND data_recebimento >= $2 AND data_recebimento < $3
-		ORDER BY data_recebimento
-	`, uid, start, end) // Consulta as receitas do usuário no mês e ano especificados
+		WHERE user_id = $1 AND data_recebimento >= $2 AND data_recebimento < $3 AND deleted_at IS NULL
+	`
+	args := []interface{}{uid, start, end}
+	if tags := tagFilter(r); len(tags) > 0 {
+		args = append(args, pq.Array(tags))
+		query += incomeTagLink.filterSQL("incomes.id", len(args))
+	}
+	fieldSQL, args := incomeFieldLink.filter("incomes.id", fieldFilter(r), args)
+	query += fieldSQL
+	query += " ORDER BY data_recebimento"
+
+	rows, err := db.DB.Query(query, args...) // Consulta as receitas do usuário no mês e ano especificados
 
 	if err != nil {
 		http.Error(w, "Erro ao consultar receitas: "+err.Error(), http.StatusInternalServerError)
@@ -107,7 +216,7 @@ func ListIncomes(w http.ResponseWriter, r *http.Request) {
 	var result []models.Income
 	for rows.Next() {
 		var inc models.Income
-		if err := rows.Scan(&inc.ID, &inc.UserID, &inc.Descricao, &inc.Valor, &inc.DataRecebimento, &inc.Categoria, &inc.Observacoes, &inc.CreatedAt); err != nil {
+		if err := scanIncome(rows, &inc); err != nil {
 			http.Error(w, "Erro ao ler receita: "+err.Error(), http.StatusInternalServerError)
 			return
 		}
@@ -131,13 +240,13 @@ func ListIncomes(w http.ResponseWriter, r *http.Request) {
 func GetIncomeByID(w http.ResponseWriter, r *http.Request) {
 	p := mux.Vars(r)
 	row := db.DB.QueryRow(`
-		SELECT id, user_id, descricao, valor, data_recebimento, categoria, observacoes, created_at
+		SELECT `+incomeColumns+`
 		FROM incomes
-		WHERE user_id=$1 AND id=$2
+		WHERE user_id=$1 AND id=$2 AND deleted_at IS NULL
 	`, p["userId"], p["id"])
 
 	var inc models.Income
-	if err := row.Scan(&inc.ID, &inc.UserID, &inc.Descricao, &inc.Valor, &inc.DataRecebimento, &inc.Categoria, &inc.Observacoes, &inc.CreatedAt); err != nil {
+	if err := scanIncome(row, &inc); err != nil {
 		http.Error(w, "Receita não encontrada: "+err.Error(), http.StatusNotFound)
 		return
 	}
@@ -163,11 +272,18 @@ func UpdateIncome(w http.ResponseWriter, r *http.Request) {
 	incomeID := p["id"]
 
 	var in struct {
-		Descricao       string    `json:"descricao"`
-		Valor           float64   `json:"valor"`
-		DataRecebimento time.Time `json:"data_recebimento"`
-		Categoria       string    `json:"categoria"`
-		Observacoes     *string   `json:"observacoes"`
+		Descricao       string              `json:"descricao"`
+		Valor           models.Money        `json:"valor"`
+		Moeda           string              `json:"moeda"`
+		DataRecebimento time.Time           `json:"data_recebimento"`
+		Categoria       string              `json:"categoria"`
+		CategoriaID     *uuid.UUID          `json:"categoria_id"`
+		PayeeID         *uuid.UUID          `json:"payee_id"` // omitido mantém o favorecido atual
+		ContaID         *uuid.UUID          `json:"conta_id"` // omitido mantém a conta atual
+		Observacoes     *string             `json:"observacoes"`
+		Tags            []string            `json:"tags"`    // omitido mantém as tags atuais
+		Campos          models.CustomValues `json:"campos"`  // omitido mantém os campos personalizados atuais
+		Divisao         models.Splits       `json:"divisao"` // omitida mantém a divisão atual; [] desfaz
 	}
 	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
 		http.Error(w, "JSON inválido", http.StatusBadRequest)
@@ -177,20 +293,61 @@ func UpdateIncome(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Valor deve ser positivo", http.StatusBadRequest)
 		return
 	}
+	if msg := models.ValidateSplits(in.Divisao, in.Valor); msg != "" {
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
+	if in.Moeda != "" && !models.ValidCurrency(in.Moeda) {
+		http.Error(w, "Moeda inválida: use o código ISO 4217 (ex: BRL, USD)", http.StatusBadRequest)
+		return
+	}
+
+	var err error
+	if in.CategoriaID, in.Categoria, err = resolveCategory(db.DB, userID, models.CategoryIncome, in.CategoriaID, in.Categoria); err != nil {
+		writeCategoryError(w, err)
+		return
+	}
+	if in.PayeeID != nil {
+		if _, err := findPayee(db.DB, userID, *in.PayeeID); err != nil {
+			writePayeeError(w, err)
+			return
+		}
+	}
+	if in.ContaID != nil {
+		if err := checkAccount(db.DB, userID, *in.ContaID); err == errAccountNotFound {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		} else if err != nil {
+			http.Error(w, "Erro ao buscar conta: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
 
 	query := `
 		UPDATE incomes
-		SET descricao=$1, valor=$2, data_recebimento=$3, categoria=$4, observacoes=$5
-		WHERE user_id=$6 AND id=$7
-		RETURNING id, user_id, descricao, valor, data_recebimento, categoria, observacoes, created_at;
+		SET descricao=$1, valor=$2, data_recebimento=$3, categoria=$4, category_id=$9, observacoes=$5, moeda=COALESCE(NULLIF($8, ''), moeda),
+			payee_id=COALESCE($10, payee_id), conta_id=COALESCE($11, conta_id)
+		WHERE user_id=$6 AND id=$7 AND deleted_at IS NULL
+		RETURNING ` + incomeColumns + `;
 	`
 
+	tx, err := db.DB.Begin()
+	if err != nil {
+		http.Error(w, "Erro ao iniciar transação: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	history, err := audit.Track(tx, models.AuditIncomes, "user_id = $1 AND id = $2 AND deleted_at IS NULL", userID, incomeID)
+	if err != nil {
+		http.Error(w, "Erro ao buscar receita: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
 	var out models.Income
-	err := db.DB.QueryRow(query,
+	err = scanIncome(tx.QueryRow(query,
 		in.Descricao, in.Valor, in.DataRecebimento, in.Categoria, in.Observacoes,
-		userID, incomeID).
-		Scan(&out.ID, &out.UserID, &out.Descricao, &out.Valor,
-			&out.DataRecebimento, &out.Categoria, &out.Observacoes, &out.CreatedAt)
+		userID, incomeID, in.Moeda, in.CategoriaID, in.PayeeID, in.ContaID), &out)
 
 	if err == sql.ErrNoRows {
 		http.Error(w, "Receita não encontrada", http.StatusNotFound)
@@ -201,13 +358,51 @@ func UpdateIncome(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if in.Tags != nil {
+		if out.Tags, err = incomeTagLink.set(tx, userID, out.ID, in.Tags); err != nil {
+			http.Error(w, "Erro ao salvar tags: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if in.Campos != nil {
+		if out.Campos, err = incomeFieldLink.set(tx, userID, out.ID, in.Campos); err != nil {
+			writeFieldError(w, err)
+			return
+		}
+	}
+
+	if in.Divisao != nil {
+		if out.Divisao, err = incomeSplitLink.set(tx, userID, out.ID, in.Divisao); err != nil {
+			writeSplitError(w, err)
+			return
+		}
+	} else if differs, err := incomeSplitLink.mismatch(tx, out.ID, out.Valor); err != nil {
+		http.Error(w, "Erro ao buscar divisão: "+err.Error(), http.StatusInternalServerError)
+		return
+	} else if differs {
+		http.Error(w, "A receita é dividida: envie a divisão com o novo valor", http.StatusBadRequest)
+		return
+	}
+
+	if err := history.Record(tx, apiAudit(r, models.AuditUpdate)); err != nil {
+		http.Error(w, "Erro ao registrar histórico: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		http.Error(w, "Erro ao atualizar receita: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(out)
 }
 
-// DeleteIncome exclui uma receita de um usuário pelo ID
+// DeleteIncome move uma receita de um usuário para a lixeira
 //
 // @Summary Excluir receita
+// @Description A receita vai para a lixeira, de onde pode ser restaurada até ser apagada pelo job de limpeza.
 // @Tags Incomes
 // @Security BearerAuth
 // @Param userId path string true "ID do usuário"
@@ -217,10 +412,24 @@ func UpdateIncome(w http.ResponseWriter, r *http.Request) {
 // @Router /incomes/{userId}/{id} [delete]
 func DeleteIncome(w http.ResponseWriter, r *http.Request) {
 	p := mux.Vars(r)
-	res, err := db.DB.Exec(`
-		DELETE FROM incomes
-		WHERE user_id = $1 AND id = $2
-	`, p["userId"], p["id"]) // Deleta a receita do usuário
+
+	tx, err := db.DB.Begin()
+	if err != nil {
+		http.Error(w, "Erro ao iniciar transação: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	history, err := audit.Track(tx, models.AuditIncomes, "user_id = $1 AND id = $2 AND deleted_at IS NULL", p["userId"], p["id"])
+	if err != nil {
+		http.Error(w, "Erro ao deletar receita: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	res, err := tx.Exec(`
+		UPDATE incomes SET deleted_at = $3
+		WHERE user_id = $1 AND id = $2 AND deleted_at IS NULL
+	`, p["userId"], p["id"], time.Now()) // Move a receita do usuário para a lixeira
 
 	if err != nil {
 		http.Error(w, "Erro ao deletar receita: "+err.Error(), http.StatusInternalServerError)
@@ -232,5 +441,153 @@ func DeleteIncome(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if err := history.Record(tx, apiAudit(r, models.AuditDelete)); err != nil {
+		http.Error(w, "Erro ao registrar histórico: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		http.Error(w, "Erro ao deletar receita: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
 	json.NewEncoder(w).Encode(map[string]string{"message": "Receita excluída com sucesso"})
 }
+
+// ConfirmIncome confirma o recebimento de uma receita prevista com o valor e a data reais
+//
+// @Summary Confirmar recebimento
+// @Tags Incomes
+// @Security BearerAuth
+// @Param userId path string true "ID do usuário"
+// @Param id path string true "ID da receita"
+// @Param receipt body object{valor=string,data_recebimento=string,conta_id=string} true "Valor, data efetivos e conta creditada (opcional)"
+// @Success 200 {object} models.Income
+// @Failure 400,401,404,500 {string} string
+// @Router /incomes/{userId}/{id}/confirm [patch]
+func ConfirmIncome(w http.ResponseWriter, r *http.Request) {
+	p := mux.Vars(r)
+
+	var in struct {
+		Valor           models.Money `json:"valor"`
+		DataRecebimento *time.Time   `json:"data_recebimento"`
+		ContaID         *uuid.UUID   `json:"conta_id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		http.Error(w, "JSON inválido", http.StatusBadRequest)
+		return
+	}
+	if in.Valor <= 0 {
+		http.Error(w, "Valor deve ser positivo", http.StatusBadRequest)
+		return
+	}
+	if in.ContaID != nil {
+		if err := checkAccount(db.DB, p["userId"], *in.ContaID); err == errAccountNotFound {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		} else if err != nil {
+			http.Error(w, "Erro ao buscar conta: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+	received := time.Now()
+	if in.DataRecebimento != nil {
+		received = *in.DataRecebimento
+	}
+
+	tx, err := db.DB.Begin()
+	if err != nil {
+		http.Error(w, "Erro ao iniciar transação: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	history, err := audit.Track(tx, models.AuditIncomes, "user_id = $1 AND id = $2 AND deleted_at IS NULL", p["userId"], p["id"])
+	if err != nil {
+		http.Error(w, "Erro ao confirmar receita: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	var out models.Income
+	err = scanIncome(tx.QueryRow(`
+		UPDATE incomes
+		SET status = $1, valor = $2, data_recebimento = $3, conta_id = COALESCE($7, conta_id)
+		WHERE user_id = $4 AND id = $5 AND status = $6 AND deleted_at IS NULL
+		RETURNING `+incomeColumns+`
+	`, models.IncomeReceived, in.Valor, received, p["userId"], p["id"], models.IncomeExpected, in.ContaID), &out)
+
+	if err == sql.ErrNoRows {
+		http.Error(w, "Receita prevista não encontrada", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Erro ao confirmar receita: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if err := history.Record(tx, apiAudit(r, models.AuditPay)); err != nil {
+		http.Error(w, "Erro ao registrar histórico: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		http.Error(w, "Erro ao confirmar receita: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(out)
+}
+
+// ListPendingIncomes lista as receitas previstas ainda não recebidas, com as atrasadas primeiro
+//
+// @Summary Receitas pendentes e atrasadas
+// @Tags Incomes
+// @Security BearerAuth
+// @Param userId path string true "ID do usuário"
+// @Param late query bool false "Somente receitas atrasadas"
+// @Param tag query []string false "Filtra pelas tags (todas obrigatórias); repita o parâmetro para várias" collectionFormat(multi)
+// @Param campo[nome] query string false "Filtra pelo valor de um campo personalizado; repita com outros nomes para vários"
+// @Success 200 {array} models.Income
+// @Failure 400,401,500 {string} string
+// @Router /incomes/{userId}/pending [get]
+func ListPendingIncomes(w http.ResponseWriter, r *http.Request) {
+	uid := mux.Vars(r)["userId"]
+
+	query := `
+		SELECT ` + incomeColumns + `
+		FROM incomes
+		WHERE user_id = $1 AND status = $2 AND deleted_at IS NULL
+	`
+	if late, _ := strconv.ParseBool(r.URL.Query().Get("late")); late {
+		query += " AND COALESCE(data_prevista, data_recebimento) < CURRENT_DATE"
+	}
+	args := []interface{}{uid, models.IncomeExpected}
+	if tags := tagFilter(r); len(tags) > 0 {
+		args = append(args, pq.Array(tags))
+		query += incomeTagLink.filterSQL("incomes.id", len(args))
+	}
+	fieldSQL, args := incomeFieldLink.filter("incomes.id", fieldFilter(r), args)
+	query += fieldSQL
+	query += " ORDER BY COALESCE(data_prevista, data_recebimento)"
+
+	rows, err := db.DB.Query(query, args...)
+	if err != nil {
+		http.Error(w, "Erro ao consultar receitas: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	var result []models.Income
+	for rows.Next() {
+		var inc models.Income
+		if err := scanIncome(rows, &inc); err != nil {
+			http.Error(w, "Erro ao ler receita: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		result = append(result, inc)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
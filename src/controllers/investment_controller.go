@@ -0,0 +1,442 @@
+package controllers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"strings"
+	"time"
+
+	"finance/src/audit"
+	"finance/src/db"
+	"finance/src/models"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+const investmentColumns = `id, user_id, conta_id, ticker, tipo, quantidade, preco, valor, taxas, data, income_id, observacoes, created_at`
+
+func scanInvestmentTransaction(row rowScanner, t *models.InvestmentTransaction) error {
+	return row.Scan(&t.ID, &t.UserID, &t.ContaID, &t.Ticker, &t.Tipo, &t.Quantidade, &t.Preco, &t.Valor, &t.Taxas, &t.Data, &t.IncomeID, &t.Observacoes, &t.CreatedAt)
+}
+
+// loadInvestmentTransactions lê as operações do usuário, opcionalmente de uma conta e de um ativo
+func loadInvestmentTransactions(q dbExecutor, userID, contaID, ticker string) ([]models.InvestmentTransaction, error) {
+	rows, err := q.Query(`
+		SELECT `+investmentColumns+`
+		FROM investment_transactions
+		WHERE user_id = $1
+		  AND ($2 = '' OR conta_id::text = $2)
+		  AND ($3 = '' OR ticker = $3)
+		ORDER BY data, created_at
+	`, userID, contaID, ticker)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	txs := []models.InvestmentTransaction{}
+	for rows.Next() {
+		var t models.InvestmentTransaction
+		if err := scanInvestmentTransaction(rows, &t); err != nil {
+			return nil, err
+		}
+		txs = append(txs, t)
+	}
+	return txs, rows.Err()
+}
+
+// investmentAccount confere se a conta é do usuário e do tipo investimento e devolve sua moeda
+func investmentAccount(q dbExecutor, userID string, contaID uuid.UUID) (int, string, string) {
+	var tipo, moeda string
+	err := q.QueryRow(`SELECT tipo, moeda FROM accounts WHERE user_id = $1 AND id = $2`, userID, contaID).Scan(&tipo, &moeda)
+	if err == sql.ErrNoRows {
+		return http.StatusBadRequest, "Conta não encontrada", ""
+	}
+	if err != nil {
+		return http.StatusInternalServerError, "Erro ao buscar conta: " + err.Error(), ""
+	}
+	if tipo != models.InvestmentAccountType {
+		return http.StatusBadRequest, "A conta deve ser do tipo investimento", ""
+	}
+	return 0, "", moeda
+}
+
+// validateInvestmentTransaction confere os campos conforme o tipo da operação
+func validateInvestmentTransaction(t *models.InvestmentTransaction) string {
+	t.Ticker = models.NormalizeTicker(t.Ticker)
+	if t.Ticker == "" {
+		return "Ticker é obrigatório"
+	}
+	if t.Data.IsZero() {
+		return "Data é obrigatória"
+	}
+	if t.Quantidade < 0 || t.Preco < 0 || t.Valor < 0 || t.Taxas < 0 {
+		return "Quantidade, preço, valor e taxas não podem ser negativos"
+	}
+
+	switch t.Tipo {
+	case models.InvestmentBuy, models.InvestmentSell:
+		if t.Quantidade <= 0 || t.Preco <= 0 {
+			return "Compra e venda exigem quantidade e preço maiores que zero"
+		}
+		if t.Valor == 0 {
+			t.Valor = models.TradeValue(t.Quantidade, t.Preco)
+		}
+	case models.InvestmentDividend:
+		if t.Valor == 0 && t.Quantidade > 0 && t.Preco > 0 {
+			t.Valor = models.TradeValue(t.Quantidade, t.Preco)
+		}
+		if t.Valor-t.Taxas <= 0 {
+			return "Dividendo exige valor líquido maior que zero"
+		}
+	default:
+		return "Tipo inválido: use compra, venda ou dividendo"
+	}
+	return ""
+}
+
+// checkOversold impede que uma venda deixe a posição negativa em alguma data
+func checkOversold(q dbExecutor, userID string, contaID uuid.UUID, ticker string, extra ...models.InvestmentTransaction) (int, string) {
+	txs, err := loadInvestmentTransactions(q, userID, contaID.String(), ticker)
+	if err != nil {
+		return http.StatusInternalServerError, "Erro ao buscar operações: " + err.Error()
+	}
+	if t := models.Oversold(append(txs, extra...)); t != nil {
+		return http.StatusConflict, fmt.Sprintf("Venda de %s em %s excede a quantidade em carteira", t.Ticker, t.Data.Format("2006-01-02"))
+	}
+	return 0, ""
+}
+
+// CreateInvestmentTransaction registra uma compra, venda ou dividendo numa conta de investimento.
+// O dividendo também gera uma receita recebida na categoria Dividendos, para entrar no resumo mensal.
+//
+// @Summary Registrar operação de investimento
+// @Tags Investments
+// @Security BearerAuth
+// @Param userId path string true "ID do usuário"
+// @Param transaction body models.InvestmentTransaction true "Conta, ticker, tipo, quantidade, preço e data"
+// @Success 201 {object} models.InvestmentTransaction
+// @Failure 400,401,409,500 {string} string
+// @Router /users/{userId}/investments/transactions [post]
+func CreateInvestmentTransaction(w http.ResponseWriter, r *http.Request) {
+	userIDStr := mux.Vars(r)["userId"]
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		http.Error(w, "ID de usuário inválido", http.StatusBadRequest)
+		return
+	}
+
+	var t models.InvestmentTransaction
+	if err := json.NewDecoder(r.Body).Decode(&t); err != nil {
+		http.Error(w, "JSON inválido: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if msg := validateInvestmentTransaction(&t); msg != "" {
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
+	t.ID = uuid.New()
+	t.UserID = userID
+	t.IncomeID = nil
+	t.CreatedAt = time.Now()
+
+	tx, err := db.DB.Begin()
+	if err != nil {
+		http.Error(w, "Erro ao iniciar transação: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	status, msg, moeda := investmentAccount(tx, userIDStr, t.ContaID)
+	if status != 0 {
+		http.Error(w, msg, status)
+		return
+	}
+
+	if t.Tipo == models.InvestmentSell {
+		// trava a conta para que duas vendas simultâneas não passem da posição
+		if _, err := tx.Exec(`SELECT 1 FROM accounts WHERE id = $1 FOR UPDATE`, t.ContaID); err != nil {
+			http.Error(w, "Erro ao buscar conta: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if status, msg := checkOversold(tx, userIDStr, t.ContaID, t.Ticker, t); status != 0 {
+			http.Error(w, msg, status)
+			return
+		}
+	}
+
+	if t.Tipo == models.InvestmentDividend {
+		categoryID, categoria, err := resolveCategory(tx, userIDStr, models.CategoryIncome, nil, models.DividendCategory)
+		if err != nil {
+			writeCategoryError(w, err)
+			return
+		}
+		incomeID := uuid.New()
+		_, err = tx.Exec(`
+			INSERT INTO incomes (id, user_id, descricao, valor, moeda, data_recebimento, categoria, category_id, observacoes, status, conta_id, created_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+		`, incomeID, userID, "Dividendos "+t.Ticker, t.Valor-t.Taxas, moeda, t.Data, categoria, categoryID, t.Observacoes, models.IncomeReceived, t.ContaID, t.CreatedAt)
+		if err != nil {
+			http.Error(w, "Erro ao criar receita do dividendo: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if err := audit.TrackNew(models.AuditIncomes, incomeID).Record(tx, apiAudit(r, models.AuditCreate)); err != nil {
+			http.Error(w, "Erro ao registrar histórico: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		t.IncomeID = &incomeID
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO investment_transactions (`+investmentColumns+`)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+	`, t.ID, t.UserID, t.ContaID, t.Ticker, t.Tipo, t.Quantidade, t.Preco, t.Valor, t.Taxas, t.Data, t.IncomeID, t.Observacoes, t.CreatedAt)
+	if err != nil {
+		http.Error(w, "Erro ao registrar operação: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		http.Error(w, "Erro ao registrar operação: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(t)
+}
+
+// ListInvestmentTransactions lista as operações de investimento do usuário
+//
+// @Summary Listar operações de investimento
+// @Tags Investments
+// @Security BearerAuth
+// @Param userId path string true "ID do usuário"
+// @Param conta_id query string false "Filtra pela conta"
+// @Param ticker query string false "Filtra pelo ativo"
+// @Success 200 {array} models.InvestmentTransaction
+// @Failure 400,401,500 {string} string
+// @Router /users/{userId}/investments/transactions [get]
+func ListInvestmentTransactions(w http.ResponseWriter, r *http.Request) {
+	contaID := r.URL.Query().Get("conta_id")
+	if contaID != "" {
+		if _, err := uuid.Parse(contaID); err != nil {
+			http.Error(w, "ID de conta inválido", http.StatusBadRequest)
+			return
+		}
+	}
+
+	txs, err := loadInvestmentTransactions(db.DB, mux.Vars(r)["userId"], contaID, models.NormalizeTicker(r.URL.Query().Get("ticker")))
+	if err != nil {
+		http.Error(w, "Erro ao buscar operações: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(txs)
+}
+
+// DeleteInvestmentTransaction exclui uma operação; o dividendo leva junto a receita gerada
+//
+// @Summary Excluir operação de investimento
+// @Tags Investments
+// @Security BearerAuth
+// @Param userId path string true "ID do usuário"
+// @Param id path string true "ID da operação"
+// @Success 204
+// @Failure 400,401,404,409,500 {string} string
+// @Router /users/{userId}/investments/transactions/{id} [delete]
+func DeleteInvestmentTransaction(w http.ResponseWriter, r *http.Request) {
+	p := mux.Vars(r)
+	if _, err := uuid.Parse(p["id"]); err != nil {
+		http.Error(w, "ID de operação inválido", http.StatusBadRequest)
+		return
+	}
+
+	tx, err := db.DB.Begin()
+	if err != nil {
+		http.Error(w, "Erro ao iniciar transação: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	var t models.InvestmentTransaction
+	err = scanInvestmentTransaction(tx.QueryRow(`
+		DELETE FROM investment_transactions
+		WHERE user_id = $1 AND id = $2
+		RETURNING `+investmentColumns, p["userId"], p["id"]), &t)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Operação não encontrada", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Erro ao excluir operação: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// excluir uma compra já usada por uma venda deixaria a posição negativa
+	if t.Tipo == models.InvestmentBuy {
+		if status, msg := checkOversold(tx, p["userId"], t.ContaID, t.Ticker); status != 0 {
+			if status == http.StatusConflict {
+				msg = "Não é possível excluir: " + strings.ToLower(msg[:1]) + msg[1:]
+			}
+			http.Error(w, msg, status)
+			return
+		}
+	}
+
+	if t.IncomeID != nil {
+		history, err := audit.Track(tx, models.AuditIncomes, "user_id = $1 AND id = $2", p["userId"], t.IncomeID)
+		if err != nil {
+			http.Error(w, "Erro ao buscar receita do dividendo: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if _, err := tx.Exec(`DELETE FROM incomes WHERE user_id = $1 AND id = $2`, p["userId"], t.IncomeID); err != nil {
+			http.Error(w, "Erro ao excluir receita do dividendo: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if err := history.Record(tx, apiAudit(r, models.AuditDelete)); err != nil {
+			http.Error(w, "Erro ao registrar histórico: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		http.Error(w, "Erro ao excluir operação: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetPortfolio calcula as posições abertas com a última cotação de cada ativo:
+// valor atual, ganho ou perda não realizado e alocação dentro da moeda
+//
+// @Summary Carteira de investimentos
+// @Description Ativos sem cotação são avaliados pelo custo e marcados com sem_cotacao.
+// @Description Os totais trazem também o lucro realizado e os dividendos, inclusive de posições já encerradas.
+// @Tags Investments
+// @Security BearerAuth
+// @Param userId path string true "ID do usuário"
+// @Param conta_id query string false "Restringe a uma conta"
+// @Success 200 {object} models.Portfolio
+// @Failure 400,401,500 {string} string
+// @Router /users/{userId}/investments/portfolio [get]
+func GetPortfolio(w http.ResponseWriter, r *http.Request) {
+	userID := mux.Vars(r)["userId"]
+	contaID := r.URL.Query().Get("conta_id")
+	if contaID != "" {
+		if _, err := uuid.Parse(contaID); err != nil {
+			http.Error(w, "ID de conta inválido", http.StatusBadRequest)
+			return
+		}
+	}
+
+	txs, err := loadInvestmentTransactions(db.DB, userID, contaID, "")
+	if err != nil {
+		http.Error(w, "Erro ao buscar operações: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	type account struct{ nome, moeda string }
+	accounts := map[uuid.UUID]account{}
+	rows, err := db.DB.Query(`SELECT id, nome, moeda FROM accounts WHERE user_id = $1`, userID)
+	if err != nil {
+		http.Error(w, "Erro ao buscar contas: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	for rows.Next() {
+		var id uuid.UUID
+		var a account
+		if err := rows.Scan(&id, &a.nome, &a.moeda); err != nil {
+			rows.Close()
+			http.Error(w, "Erro ao ler conta: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		accounts[id] = a
+	}
+	rows.Close()
+
+	type quote struct {
+		data  time.Time
+		preco float64
+	}
+	quotes := map[string]quote{}
+	rows, err = db.DB.Query(`
+		SELECT DISTINCT ON (ticker) ticker, data, preco
+		FROM quotes
+		WHERE user_id = $1
+		ORDER BY ticker, data DESC
+	`, userID)
+	if err != nil {
+		http.Error(w, "Erro ao buscar cotações: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	for rows.Next() {
+		var ticker string
+		var q quote
+		if err := rows.Scan(&ticker, &q.data, &q.preco); err != nil {
+			rows.Close()
+			http.Error(w, "Erro ao ler cotação: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		quotes[ticker] = q
+	}
+	rows.Close()
+
+	portfolio := models.Portfolio{Posicoes: []models.Position{}, Totais: []models.PortfolioTotal{}}
+	totals := map[string]*models.PortfolioTotal{}
+	for _, p := range models.BuildPositions(txs) {
+		a := accounts[p.ContaID]
+		p.Conta, p.Moeda = a.nome, a.moeda
+
+		total, ok := totals[p.Moeda]
+		if !ok {
+			portfolio.Totais = append(portfolio.Totais, models.PortfolioTotal{Moeda: p.Moeda})
+			total = &portfolio.Totais[len(portfolio.Totais)-1]
+			totals[p.Moeda] = total
+		}
+		total.LucroRealizado += p.LucroRealizado
+		total.Dividendos += p.Dividendos
+
+		if p.Quantidade <= 0 {
+			continue
+		}
+		if q, ok := quotes[p.Ticker]; ok {
+			preco, data := q.preco, q.data
+			p.Cotacao, p.DataCotacao = &preco, &data
+			p.ValorAtual = models.TradeValue(p.Quantidade, preco)
+		} else {
+			p.ValorAtual = p.Custo
+			p.SemCotacao = true
+		}
+		p.Ganho = p.ValorAtual - p.Custo
+		if p.Custo > 0 {
+			p.GanhoPercentual = math.Round(float64(p.Ganho)/float64(p.Custo)*1000) / 10
+		}
+		total.Custo += p.Custo
+		total.ValorAtual += p.ValorAtual
+		total.Ganho += p.Ganho
+		portfolio.Posicoes = append(portfolio.Posicoes, p)
+	}
+
+	for i := range portfolio.Posicoes {
+		p := &portfolio.Posicoes[i]
+		if total := totals[p.Moeda]; total.ValorAtual > 0 {
+			p.Alocacao = math.Round(float64(p.ValorAtual)/float64(total.ValorAtual)*1000) / 10
+		}
+	}
+	for i := range portfolio.Totais {
+		t := &portfolio.Totais[i]
+		if t.Custo > 0 {
+			t.Percentual = math.Round(float64(t.Ganho)/float64(t.Custo)*1000) / 10
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(portfolio)
+}
@@ -0,0 +1,632 @@
+package controllers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strings"
+	"time"
+
+	"finance/src/audit"
+	"finance/src/db"
+	"finance/src/models"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+const loanColumns = `id, user_id, nome, principal, taxa_mensal, prazo, sistema, data_inicio, moeda, COALESCE(categoria, ''), category_id, created_at`
+
+var errLoanOutOfOrder = errors.New("Há parcelas pagas depois de parcelas em aberto; acerte os pagamentos antes de amortizar")
+var errLoanInstallmentPaid = errors.New("Há parcelas em aberto com pagamentos parciais ou anexos; elas não podem ser recalculadas")
+
+func scanLoan(row rowScanner, l *models.Loan) error {
+	err := row.Scan(&l.ID, &l.UserID, &l.Nome, &l.Principal, &l.TaxaMensal, &l.Prazo, &l.Sistema, &l.DataInicio, &l.Moeda, &l.Categoria, &l.CategoriaID, &l.CreatedAt)
+	if err == nil {
+		l.TaxaAnual = math.Round(models.AnnualFromMonthlyRate(l.TaxaMensal)*10000) / 10000
+	}
+	return err
+}
+
+// loadInstallments lê o cronograma; a parcela está paga quando a despesa gerada está paga
+func loadInstallments(q dbExecutor, loanID uuid.UUID) ([]models.LoanInstallment, error) {
+	rows, err := q.Query(`
+		SELECT i.numero, i.vencimento, i.amortizacao, i.juros, i.parcela, i.saldo_devedor, i.expense_id, COALESCE(e.paga, false)
+		FROM loan_installments i
+		LEFT JOIN expenses e ON e.id = i.expense_id
+		WHERE i.loan_id = $1
+		ORDER BY i.numero
+	`, loanID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []models.LoanInstallment
+	for rows.Next() {
+		var p models.LoanInstallment
+		if err := rows.Scan(&p.Numero, &p.Vencimento, &p.Amortizacao, &p.Juros, &p.Parcela, &p.SaldoDevedor, &p.ExpenseID, &p.Paga); err != nil {
+			return nil, err
+		}
+		out = append(out, p)
+	}
+	return out, rows.Err()
+}
+
+// openInstallments devolve as parcelas a partir da primeira em aberto
+func openInstallments(all []models.LoanInstallment) ([]models.LoanInstallment, error) {
+	for i, p := range all {
+		if p.Paga {
+			continue
+		}
+		for _, later := range all[i+1:] {
+			if later.Paga {
+				return nil, errLoanOutOfOrder
+			}
+		}
+		return all[i:], nil
+	}
+	return nil, nil
+}
+
+// summarizeLoan preenche saldo devedor e contagem de parcelas a partir do cronograma
+func summarizeLoan(l *models.Loan, installments []models.LoanInstallment) {
+	l.SaldoDevedor, l.ParcelasPagas, l.ParcelasRestante = 0, 0, 0
+	for _, p := range installments {
+		if p.Paga {
+			l.ParcelasPagas++
+			continue
+		}
+		if l.ParcelasRestante == 0 {
+			l.SaldoDevedor = p.SaldoDevedor + p.Amortizacao
+		}
+		l.ParcelasRestante++
+	}
+}
+
+// insertInstallments grava as parcelas e cria uma despesa para cada uma
+func insertInstallments(q dbExecutor, e models.AuditEntry, l models.Loan, installments []models.LoanInstallment) error {
+	if len(installments) == 0 {
+		return nil
+	}
+	var ids []uuid.UUID
+	total := installments[len(installments)-1].Numero
+	now := time.Now()
+	for i := range installments {
+		p := &installments[i]
+		expenseID := uuid.New()
+		descricao := fmt.Sprintf("%s (%d/%d)", l.Nome, p.Numero, total)
+		observacoes := fmt.Sprintf("Amortização %s + juros %s", p.Amortizacao, p.Juros)
+		if _, err := q.Exec(`
+			INSERT INTO expenses (id, user_id, descricao, valor, moeda, vencimento, paga, categoria, category_id, observacoes, created_at)
+			VALUES ($1,$2,$3,$4,$5,$6,false,$7,$8,$9,$10)
+		`, expenseID, l.UserID, descricao, p.Parcela, l.Moeda, p.Vencimento, l.Categoria, l.CategoriaID, observacoes, now); err != nil {
+			return err
+		}
+		if _, err := q.Exec(`
+			INSERT INTO loan_installments (loan_id, numero, vencimento, amortizacao, juros, parcela, saldo_devedor, expense_id)
+			VALUES ($1,$2,$3,$4,$5,$6,$7,$8)
+		`, l.ID, p.Numero, p.Vencimento, p.Amortizacao, p.Juros, p.Parcela, p.SaldoDevedor, expenseID); err != nil {
+			return err
+		}
+		p.ExpenseID = &expenseID
+		ids = append(ids, expenseID)
+	}
+	return audit.TrackNew(models.AuditExpenses, ids...).Record(q, e)
+}
+
+// deleteOpenInstallments remove as parcelas em aberto a partir de numero e as
+// despesas geradas para elas. Falha se alguma despesa já tiver pagamento ou anexo.
+func deleteOpenInstallments(q dbExecutor, e models.AuditEntry, loanID uuid.UUID, numero int) error {
+	var touched bool
+	if err := q.QueryRow(`
+		SELECT EXISTS (
+			SELECT 1 FROM loan_installments i
+			JOIN expenses ON expenses.id = i.expense_id
+			WHERE i.loan_id = $1 AND i.numero >= $2
+			  AND NOT (`+expenseWithoutPayments+` AND `+expenseWithoutAttachments+`)
+		)
+	`, loanID, numero).Scan(&touched); err != nil {
+		return err
+	}
+	if touched {
+		return errLoanInstallmentPaid
+	}
+
+	where := "id IN (SELECT expense_id FROM loan_installments WHERE loan_id = $1 AND numero >= $2)"
+	history, err := audit.Track(q, models.AuditExpenses, where, loanID, numero)
+	if err != nil {
+		return err
+	}
+	if _, err := q.Exec(`DELETE FROM expenses WHERE `+where, loanID, numero); err != nil {
+		return err
+	}
+	if err := history.Record(q, e); err != nil {
+		return err
+	}
+	_, err = q.Exec(`DELETE FROM loan_installments WHERE loan_id = $1 AND numero >= $2`, loanID, numero)
+	return err
+}
+
+// CreateLoan cadastra um empréstimo, gera o cronograma e uma despesa por parcela
+//
+// @Summary Criar empréstimo
+// @Description Informe taxa_mensal ou taxa_anual (em %). sistema: sac ou price.
+// @Tags Loans
+// @Security BearerAuth
+// @Param userId path string true "ID do usuário"
+// @Param loan body models.Loan true "Dados do empréstimo"
+// @Success 201 {object} models.Loan
+// @Failure 400,401,500 {string} string
+// @Router /users/{userId}/loans [post]
+func CreateLoan(w http.ResponseWriter, r *http.Request) {
+	userID, err := uuid.Parse(mux.Vars(r)["userId"])
+	if err != nil {
+		http.Error(w, "ID de usuário inválido", http.StatusBadRequest)
+		return
+	}
+
+	var l models.Loan
+	if err := json.NewDecoder(r.Body).Decode(&l); err != nil {
+		http.Error(w, "Erro ao decodificar JSON", http.StatusBadRequest)
+		return
+	}
+	l.Nome = strings.TrimSpace(l.Nome)
+	l.Sistema = strings.ToLower(l.Sistema)
+	switch {
+	case l.Nome == "":
+		http.Error(w, "Nome do empréstimo é obrigatório", http.StatusBadRequest)
+		return
+	case l.Principal <= 0:
+		http.Error(w, "Valor financiado deve ser maior que zero", http.StatusBadRequest)
+		return
+	case l.Prazo < 1 || l.Prazo > 600:
+		http.Error(w, "Prazo deve ser de 1 a 600 parcelas", http.StatusBadRequest)
+		return
+	case !models.ValidLoanSystem(l.Sistema):
+		http.Error(w, "Sistema inválido: use sac ou price", http.StatusBadRequest)
+		return
+	case l.TaxaMensal < 0 || l.TaxaAnual < 0:
+		http.Error(w, "Taxa de juros não pode ser negativa", http.StatusBadRequest)
+		return
+	case l.DataInicio.IsZero():
+		http.Error(w, "Data da primeira parcela é obrigatória", http.StatusBadRequest)
+		return
+	}
+	if l.TaxaMensal == 0 && l.TaxaAnual > 0 {
+		l.TaxaMensal = math.Round(models.MonthlyFromAnnualRate(l.TaxaAnual)*1e6) / 1e6
+	}
+	l.TaxaAnual = math.Round(models.AnnualFromMonthlyRate(l.TaxaMensal)*10000) / 10000
+
+	tx, err := db.DB.Begin()
+	if err != nil {
+		http.Error(w, "Erro ao iniciar transação: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	if l.Moeda, err = resolveCurrency(tx, userID.String(), l.Moeda); err != nil {
+		writeCurrencyError(w, err)
+		return
+	}
+	if l.CategoriaID, l.Categoria, err = resolveCategory(tx, userID.String(), models.CategoryExpense, l.CategoriaID, l.Categoria); err != nil {
+		writeCategoryError(w, err)
+		return
+	}
+
+	l.ID = uuid.New()
+	l.UserID = userID
+	l.CreatedAt = time.Now()
+
+	if _, err := tx.Exec(`
+		INSERT INTO loans (id, user_id, nome, principal, taxa_mensal, prazo, sistema, data_inicio, moeda, categoria, category_id, created_at)
+		VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12)
+	`, l.ID, l.UserID, l.Nome, l.Principal, l.TaxaMensal, l.Prazo, l.Sistema, l.DataInicio, l.Moeda, l.Categoria, l.CategoriaID, l.CreatedAt); err != nil {
+		http.Error(w, "Erro ao criar empréstimo: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	l.Parcelas = models.BuildSchedule(l.Principal, l.TaxaMensal, l.Prazo, l.Sistema, l.DataInicio, 1)
+	if err := insertInstallments(tx, apiAudit(r, models.AuditCreate), l, l.Parcelas); err != nil {
+		http.Error(w, "Erro ao gerar parcelas: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		http.Error(w, "Erro ao criar empréstimo: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	summarizeLoan(&l, l.Parcelas)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(l)
+}
+
+// ListLoans lista os empréstimos do usuário com saldo devedor e parcelas restantes
+//
+// @Summary Listar empréstimos
+// @Tags Loans
+// @Security BearerAuth
+// @Param userId path string true "ID do usuário"
+// @Success 200 {array} models.Loan
+// @Failure 400,401,500 {string} string
+// @Router /users/{userId}/loans [get]
+func ListLoans(w http.ResponseWriter, r *http.Request) {
+	rows, err := db.DB.Query(`
+		SELECT `+loanColumns+`
+		FROM loans
+		WHERE user_id = $1
+		ORDER BY data_inicio, nome
+	`, mux.Vars(r)["userId"])
+	if err != nil {
+		http.Error(w, "Erro ao buscar empréstimos: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	loans := []models.Loan{}
+	for rows.Next() {
+		var l models.Loan
+		if err := scanLoan(rows, &l); err != nil {
+			rows.Close()
+			http.Error(w, "Erro ao ler empréstimo: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		loans = append(loans, l)
+	}
+	rows.Close()
+
+	for i := range loans {
+		installments, err := loadInstallments(db.DB, loans[i].ID)
+		if err != nil {
+			http.Error(w, "Erro ao buscar parcelas: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		summarizeLoan(&loans[i], installments)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(loans)
+}
+
+// loadLoan busca o empréstimo com cronograma e amortizações extraordinárias
+func loadLoan(q dbExecutor, userID, id string, lock bool) (models.Loan, error) {
+	var l models.Loan
+	query := `SELECT ` + loanColumns + ` FROM loans WHERE user_id = $1 AND id = $2`
+	if lock {
+		query += ` FOR UPDATE`
+	}
+	if err := scanLoan(q.QueryRow(query, userID, id), &l); err != nil {
+		return l, err
+	}
+
+	var err error
+	if l.Parcelas, err = loadInstallments(q, l.ID); err != nil {
+		return l, err
+	}
+	summarizeLoan(&l, l.Parcelas)
+
+	rows, err := q.Query(`
+		SELECT id, loan_id, data, valor, efeito, expense_id, created_at
+		FROM loan_prepayments
+		WHERE loan_id = $1
+		ORDER BY data, created_at
+	`, l.ID)
+	if err != nil {
+		return l, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var p models.LoanPrepayment
+		if err := rows.Scan(&p.ID, &p.LoanID, &p.Data, &p.Valor, &p.Efeito, &p.ExpenseID, &p.CreatedAt); err != nil {
+			return l, err
+		}
+		l.Amortizacoes = append(l.Amortizacoes, p)
+	}
+	return l, rows.Err()
+}
+
+// GetLoan retorna o empréstimo com o cronograma completo
+//
+// @Summary Buscar empréstimo
+// @Tags Loans
+// @Security BearerAuth
+// @Param userId path string true "ID do usuário"
+// @Param id path string true "ID do empréstimo"
+// @Success 200 {object} models.Loan
+// @Failure 400,401,404,500 {string} string
+// @Router /users/{userId}/loans/{id} [get]
+func GetLoan(w http.ResponseWriter, r *http.Request) {
+	p := mux.Vars(r)
+	if _, err := uuid.Parse(p["id"]); err != nil {
+		http.Error(w, "ID de empréstimo inválido", http.StatusBadRequest)
+		return
+	}
+
+	l, err := loadLoan(db.DB, p["userId"], p["id"], false)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Empréstimo não encontrado", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Erro ao buscar empréstimo: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(l)
+}
+
+// DeleteLoan exclui o empréstimo e as despesas das parcelas em aberto.
+// Parcelas já pagas continuam como despesas.
+//
+// @Summary Excluir empréstimo
+// @Tags Loans
+// @Security BearerAuth
+// @Param userId path string true "ID do usuário"
+// @Param id path string true "ID do empréstimo"
+// @Success 200 {object} Message
+// @Failure 400,401,404,500 {string} string
+// @Router /users/{userId}/loans/{id} [delete]
+func DeleteLoan(w http.ResponseWriter, r *http.Request) {
+	p := mux.Vars(r)
+	if _, err := uuid.Parse(p["id"]); err != nil {
+		http.Error(w, "ID de empréstimo inválido", http.StatusBadRequest)
+		return
+	}
+
+	tx, err := db.DB.Begin()
+	if err != nil {
+		http.Error(w, "Erro ao iniciar transação: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	where := `id IN (
+			SELECT i.expense_id FROM loan_installments i
+			JOIN loans l ON l.id = i.loan_id
+			WHERE l.user_id = $1 AND l.id = $2
+		) AND ` + expenseWithoutPayments + ` AND ` + expenseWithoutAttachments
+	history, err := audit.Track(tx, models.AuditExpenses, where, p["userId"], p["id"])
+	if err != nil {
+		http.Error(w, "Erro ao buscar parcelas: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	_, err = tx.Exec(`DELETE FROM expenses WHERE `+where, p["userId"], p["id"])
+	if err != nil {
+		http.Error(w, "Erro ao excluir parcelas: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if err := history.Record(tx, apiAudit(r, models.AuditDelete)); err != nil {
+		http.Error(w, "Erro ao registrar histórico: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	result, err := tx.Exec(`DELETE FROM loans WHERE user_id = $1 AND id = $2`, p["userId"], p["id"])
+	if err != nil {
+		http.Error(w, "Erro ao excluir empréstimo: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if rowsAffected, _ := result.RowsAffected(); rowsAffected == 0 {
+		http.Error(w, "Empréstimo não encontrado", http.StatusNotFound)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		http.Error(w, "Erro ao excluir empréstimo: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(Message{Message: "Empréstimo excluído com sucesso"})
+}
+
+// PrepaymentInput é uma amortização extraordinária, simulada ou efetivada
+type PrepaymentInput struct {
+	Valor  models.Money `json:"valor"`
+	Efeito string       `json:"efeito"` // prazo (padrão) ou parcela
+	Data   *time.Time   `json:"data"`   // padrão: hoje
+}
+
+func decodePrepayment(r *http.Request) (PrepaymentInput, string) {
+	var in PrepaymentInput
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		return in, "Erro ao decodificar JSON"
+	}
+	if in.Valor <= 0 {
+		return in, "Valor deve ser maior que zero"
+	}
+	if in.Efeito == "" {
+		in.Efeito = models.PrepayReduceTerm
+	}
+	if in.Efeito != models.PrepayReduceTerm && in.Efeito != models.PrepayReduceInstallment {
+		return in, "Efeito inválido: use prazo ou parcela"
+	}
+	return in, ""
+}
+
+// SimulateLoanPrepayment mostra o efeito de uma amortização extra sem gravá-la
+//
+// @Summary Simular amortização extraordinária
+// @Tags Loans
+// @Security BearerAuth
+// @Param userId path string true "ID do usuário"
+// @Param id path string true "ID do empréstimo"
+// @Param prepayment body PrepaymentInput true "Valor e efeito (prazo ou parcela)"
+// @Success 200 {object} models.LoanSimulation
+// @Failure 400,401,404,409,500 {string} string
+// @Router /users/{userId}/loans/{id}/simulate [post]
+func SimulateLoanPrepayment(w http.ResponseWriter, r *http.Request) {
+	p := mux.Vars(r)
+	if _, err := uuid.Parse(p["id"]); err != nil {
+		http.Error(w, "ID de empréstimo inválido", http.StatusBadRequest)
+		return
+	}
+	in, msg := decodePrepayment(r)
+	if msg != "" {
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
+
+	l, err := loadLoan(db.DB, p["userId"], p["id"], false)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Empréstimo não encontrado", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Erro ao buscar empréstimo: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	open, err := openInstallments(l.Parcelas)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	if len(open) == 0 {
+		http.Error(w, "Empréstimo já quitado", http.StatusConflict)
+		return
+	}
+	if in.Valor > l.SaldoDevedor {
+		http.Error(w, "Valor maior que o saldo devedor ("+l.SaldoDevedor.String()+")", http.StatusBadRequest)
+		return
+	}
+
+	sim := models.SimulatePrepayment(open, l.TaxaMensal, l.Sistema, l.DataInicio, in.Efeito, in.Valor)
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(sim)
+}
+
+// CreateLoanPrepayment registra uma amortização extraordinária: lança a
+// despesa paga do valor extra e recalcula as parcelas em aberto
+//
+// @Summary Amortizar empréstimo
+// @Tags Loans
+// @Security BearerAuth
+// @Param userId path string true "ID do usuário"
+// @Param id path string true "ID do empréstimo"
+// @Param prepayment body PrepaymentInput true "Valor, efeito (prazo ou parcela) e data"
+// @Success 201 {object} models.Loan
+// @Failure 400,401,404,409,500 {string} string
+// @Router /users/{userId}/loans/{id}/prepayments [post]
+func CreateLoanPrepayment(w http.ResponseWriter, r *http.Request) {
+	p := mux.Vars(r)
+	if _, err := uuid.Parse(p["id"]); err != nil {
+		http.Error(w, "ID de empréstimo inválido", http.StatusBadRequest)
+		return
+	}
+	in, msg := decodePrepayment(r)
+	if msg != "" {
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
+	data := time.Now()
+	if in.Data != nil {
+		data = *in.Data
+	}
+
+	tx, err := db.DB.Begin()
+	if err != nil {
+		http.Error(w, "Erro ao iniciar transação: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	l, err := loadLoan(tx, p["userId"], p["id"], true)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Empréstimo não encontrado", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Erro ao buscar empréstimo: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	open, err := openInstallments(l.Parcelas)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	if len(open) == 0 {
+		http.Error(w, "Empréstimo já quitado", http.StatusConflict)
+		return
+	}
+	if in.Valor > l.SaldoDevedor {
+		http.Error(w, "Valor maior que o saldo devedor ("+l.SaldoDevedor.String()+")", http.StatusBadRequest)
+		return
+	}
+
+	schedule := models.Reschedule(open, l.TaxaMensal, l.Sistema, l.DataInicio, in.Efeito, in.Valor)
+	if schedule == nil && in.Valor < l.SaldoDevedor {
+		http.Error(w, "Não foi possível recalcular as parcelas", http.StatusBadRequest)
+		return
+	}
+
+	if err := deleteOpenInstallments(tx, apiAudit(r, models.AuditDelete), l.ID, open[0].Numero); err != nil {
+		if errors.Is(err, errLoanInstallmentPaid) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		http.Error(w, "Erro ao recalcular parcelas: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if err := insertInstallments(tx, apiAudit(r, models.AuditCreate), l, schedule); err != nil {
+		http.Error(w, "Erro ao recalcular parcelas: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// o valor extra sai do bolso como uma despesa já paga
+	extra := models.Expense{
+		ID:         uuid.New(),
+		UserID:     l.UserID,
+		Descricao:  l.Nome + " (amortização extraordinária)",
+		Valor:      in.Valor,
+		Moeda:      l.Moeda,
+		Vencimento: data,
+		Categoria:  l.Categoria,
+	}
+	if _, err := tx.Exec(`
+		INSERT INTO expenses (id, user_id, descricao, valor, moeda, vencimento, paga, categoria, category_id, created_at)
+		VALUES ($1,$2,$3,$4,$5,$6,false,$7,$8,NOW())
+	`, extra.ID, extra.UserID, extra.Descricao, extra.Valor, extra.Moeda, extra.Vencimento, l.Categoria, l.CategoriaID); err != nil {
+		http.Error(w, "Erro ao lançar despesa: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if _, err := recordExpensePayment(tx, extra, PaymentInput{Valor: in.Valor, DataPagamento: &data}); err != nil {
+		http.Error(w, "Erro ao registrar pagamento: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if err := audit.TrackNew(models.AuditExpenses, extra.ID).Record(tx, apiAudit(r, models.AuditCreate)); err != nil {
+		http.Error(w, "Erro ao registrar histórico: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if _, err := tx.Exec(`
+		INSERT INTO loan_prepayments (id, loan_id, user_id, data, valor, efeito, expense_id, created_at)
+		VALUES ($1,$2,$3,$4,$5,$6,$7,NOW())
+	`, uuid.New(), l.ID, l.UserID, data, in.Valor, in.Efeito, extra.ID); err != nil {
+		http.Error(w, "Erro ao registrar amortização: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	l, err = loadLoan(tx, p["userId"], p["id"], false)
+	if err != nil {
+		http.Error(w, "Erro ao buscar empréstimo: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		http.Error(w, "Erro ao registrar amortização: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(l)
+}
@@ -0,0 +1,165 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"slices"
+	"time"
+
+	"finance/src/db"
+	"finance/src/jobs"
+	"finance/src/models"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+// netWorthRange lê from e to (YYYY-MM); o padrão são os últimos 12 meses
+func netWorthRange(r *http.Request) (time.Time, time.Time, string) {
+	now := time.Now()
+	to := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	from := to.AddDate(0, -11, 0)
+
+	var err error
+	if s := r.URL.Query().Get("to"); s != "" {
+		if to, err = models.ParseBudgetMonth(s); err != nil {
+			return from, to, "Mês final inválido: use o formato YYYY-MM"
+		}
+		if r.URL.Query().Get("from") == "" {
+			from = to.AddDate(0, -11, 0)
+		}
+	}
+	if s := r.URL.Query().Get("from"); s != "" {
+		if from, err = models.ParseBudgetMonth(s); err != nil {
+			return from, to, "Mês inicial inválido: use o formato YYYY-MM"
+		}
+	}
+	if to.Before(from) {
+		return from, to, "Mês final deve ser igual ou posterior ao inicial"
+	}
+	return from, models.MonthEnd(to), ""
+}
+
+// GetNetWorthHistory devolve a série mensal do patrimônio líquido a partir
+// das fotografias registradas, com ativos e passivos detalhados por conta e
+// empréstimo e convertidos para a moeda base pela taxa do fim de cada mês
+//
+// @Summary Histórico do patrimônio líquido
+// @Description As fotografias são gravadas pelo job mensal ou pelo comando networth-backfill.
+// @Description O mês corrente vem marcado como parcial.
+// @Tags Net Worth
+// @Security BearerAuth
+// @Param userId path string true "ID do usuário"
+// @Param from query string false "Mês inicial (YYYY-MM); padrão: 11 meses antes do final"
+// @Param to query string false "Mês final (YYYY-MM); padrão: mês corrente"
+// @Success 200 {object} models.NetWorthHistory
+// @Failure 400,401,500 {string} string
+// @Router /users/{userId}/net-worth [get]
+func GetNetWorthHistory(w http.ResponseWriter, r *http.Request) {
+	userID := mux.Vars(r)["userId"]
+	from, to, msg := netWorthRange(r)
+	if msg != "" {
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
+
+	moedaBase, err := resolveCurrency(db.DB, userID, "")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	rows, err := db.DB.Query(`
+		SELECT x.data, x.apurado_em, x.origem, x.origem_id, x.nome, x.tipo, x.classe, x.moeda, x.valor, x.valor_base
+		FROM `+convertedSQL("net_worth_snapshots", "data")+` x
+		WHERE x.user_id = $1 AND x.data BETWEEN $2 AND $3
+		ORDER BY x.data, x.classe, x.nome
+	`, userID, from, to)
+	if err != nil {
+		http.Error(w, "Erro ao buscar patrimônio: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	history := models.NetWorthHistory{MoedaBase: moedaBase, Pontos: []models.NetWorthPoint{}}
+	for rows.Next() {
+		var data, apuradoEm time.Time
+		var it models.NetWorthItem
+		if err := rows.Scan(&data, &apuradoEm, &it.Origem, &it.OrigemID, &it.Nome, &it.Tipo, &it.Classe, &it.Moeda, &it.Valor, &it.ValorBase); err != nil {
+			http.Error(w, "Erro ao ler patrimônio: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		n := len(history.Pontos)
+		if n == 0 || !history.Pontos[n-1].Data.Equal(data) {
+			history.Pontos = append(history.Pontos, models.NetWorthPoint{
+				Data:      data,
+				Parcial:   apuradoEm.Before(data.AddDate(0, 0, 1)),
+				ApuradoEm: apuradoEm,
+				Itens:     []models.NetWorthItem{},
+			})
+			n++
+		}
+		p := &history.Pontos[n-1]
+		p.Itens = append(p.Itens, it)
+
+		if it.ValorBase == nil {
+			if !slices.Contains(p.MoedasSemTaxa, it.Moeda) {
+				p.MoedasSemTaxa = append(p.MoedasSemTaxa, it.Moeda)
+			}
+			continue
+		}
+		if it.Classe == models.NetWorthAsset {
+			p.Ativos += *it.ValorBase
+		} else {
+			p.Passivos -= *it.ValorBase
+		}
+		p.Patrimonio = p.Ativos - p.Passivos
+	}
+	if err := rows.Err(); err != nil {
+		http.Error(w, "Erro ao ler patrimônio: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(history)
+}
+
+// RefreshNetWorthSnapshot recalcula na hora a fotografia de um mês, sem
+// esperar o job (ex: depois de lançar movimentações antigas)
+//
+// @Summary Recalcular fotografia do patrimônio
+// @Tags Net Worth
+// @Security BearerAuth
+// @Param userId path string true "ID do usuário"
+// @Param mes query string false "Mês (YYYY-MM); padrão: mês corrente"
+// @Success 200 {object} Message
+// @Failure 400,401,500 {string} string
+// @Router /users/{userId}/net-worth/snapshots [post]
+func RefreshNetWorthSnapshot(w http.ResponseWriter, r *http.Request) {
+	userID, err := uuid.Parse(mux.Vars(r)["userId"])
+	if err != nil {
+		http.Error(w, "ID de usuário inválido", http.StatusBadRequest)
+		return
+	}
+
+	month := time.Now()
+	if s := r.URL.Query().Get("mes"); s != "" {
+		if month, err = models.ParseBudgetMonth(s); err != nil {
+			http.Error(w, "Mês inválido: use o formato YYYY-MM", http.StatusBadRequest)
+			return
+		}
+		if month.After(time.Now()) {
+			http.Error(w, "Não é possível registrar o patrimônio de um mês futuro", http.StatusBadRequest)
+			return
+		}
+	}
+
+	if err := jobs.SnapshotNetWorth(userID, month); err != nil {
+		http.Error(w, "Erro ao registrar patrimônio: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(Message{Message: "Patrimônio de " + month.Format(models.BudgetMonthLayout) + " registrado com sucesso"})
+}
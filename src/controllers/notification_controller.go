@@ -0,0 +1,261 @@
+package controllers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"finance/src/db"
+	"finance/src/models"
+	"finance/src/notify"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/lib/pq"
+)
+
+const reminderPreferencesColumns = `user_id, ativo, dias_antes, no_dia, vencidas, canais, email, webhook_url, webhook_secret, updated_at`
+
+func scanReminderPreferences(row rowScanner, p *models.ReminderPreferences) error {
+	var dias pq.Int64Array
+	err := row.Scan(&p.UserID, &p.Ativo, &dias, &p.NoDia, &p.Vencidas, pq.Array(&p.Canais), &p.Email, &p.WebhookURL, &p.WebhookSecret, &p.UpdatedAt)
+	p.DiasAntes = make([]int, len(dias))
+	for i, d := range dias {
+		p.DiasAntes[i] = int(d)
+	}
+	return err
+}
+
+// validateReminderPreferences confere antecedências, canais e destinos
+func validateReminderPreferences(p *models.ReminderPreferences) string {
+	for _, d := range p.DiasAntes {
+		if d < 1 || d > models.ReminderMaxDays {
+			return "Dias de antecedência devem ficar entre 1 e 30"
+		}
+	}
+	slices.Sort(p.DiasAntes)
+	p.DiasAntes = slices.Compact(p.DiasAntes)
+
+	for i, c := range p.Canais {
+		p.Canais[i] = strings.ToLower(strings.TrimSpace(c))
+		if !notify.ValidChannel(p.Canais[i]) {
+			return "Canal inválido: use email, webhook ou inbox"
+		}
+	}
+	slices.Sort(p.Canais)
+	p.Canais = slices.Compact(p.Canais)
+	if p.Ativo && len(p.Canais) == 0 {
+		return "Informe ao menos um canal"
+	}
+
+	if p.Email != nil {
+		if *p.Email = strings.TrimSpace(*p.Email); *p.Email == "" {
+			p.Email = nil
+		} else if !strings.Contains(*p.Email, "@") {
+			return "E-mail inválido"
+		}
+	}
+	if p.WebhookURL != nil {
+		if *p.WebhookURL = strings.TrimSpace(*p.WebhookURL); *p.WebhookURL == "" {
+			p.WebhookURL = nil
+		} else if u, err := url.Parse(*p.WebhookURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return "URL do webhook inválida: use http(s)://..."
+		}
+	}
+	if p.WebhookURL == nil && slices.Contains(p.Canais, notify.ChannelWebhook) {
+		return "O canal webhook exige webhook_url"
+	}
+	return ""
+}
+
+// GetReminderPreferences devolve as preferências de lembretes de vencimento
+//
+// @Summary Preferências de lembretes
+// @Tags Notifications
+// @Security BearerAuth
+// @Param userId path string true "ID do usuário"
+// @Success 200 {object} models.ReminderPreferences
+// @Failure 400,401,500 {string} string
+// @Router /users/{userId}/reminder-preferences [get]
+func GetReminderPreferences(w http.ResponseWriter, r *http.Request) {
+	userID, err := uuid.Parse(mux.Vars(r)["userId"])
+	if err != nil {
+		http.Error(w, "ID de usuário inválido", http.StatusBadRequest)
+		return
+	}
+
+	p := models.DefaultReminderPreferences(userID)
+	err = scanReminderPreferences(db.DB.QueryRow(`
+		SELECT `+reminderPreferencesColumns+`
+		FROM reminder_preferences
+		WHERE user_id = $1
+	`, userID), &p)
+	if err == sql.ErrNoRows {
+		p = models.DefaultReminderPreferences(userID)
+	} else if err != nil {
+		http.Error(w, "Erro ao buscar preferências: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(p)
+}
+
+// UpdateReminderPreferences salva quando e por quais canais avisar as despesas não pagas
+//
+// @Summary Alterar preferências de lembretes
+// @Description dias_antes: avisa uma vez quando faltam até N dias para o vencimento, para cada N (1 a 30).
+// @Description Canais: email (caixa de saída em arquivo), webhook (POST JSON) e inbox (caixa de entrada do app).
+// @Description O webhook só é entregue a endereços públicos: destinos locais ou da rede interna falham no envio.
+// @Description Ao informar webhook_url o servidor gera webhook_secret, com que as entregas são assinadas
+// @Description (X-SaldoZen-Signature: sha256=HMAC do corpo); novo_segredo=true gera outro.
+// @Tags Notifications
+// @Security BearerAuth
+// @Param userId path string true "ID do usuário"
+// @Param preferences body models.ReminderPreferences true "Preferências"
+// @Param novo_segredo query bool false "Gera um novo segredo do webhook"
+// @Success 200 {object} models.ReminderPreferences
+// @Failure 400,401,500 {string} string
+// @Router /users/{userId}/reminder-preferences [put]
+func UpdateReminderPreferences(w http.ResponseWriter, r *http.Request) {
+	userID, err := uuid.Parse(mux.Vars(r)["userId"])
+	if err != nil {
+		http.Error(w, "ID de usuário inválido", http.StatusBadRequest)
+		return
+	}
+
+	p := models.DefaultReminderPreferences(userID)
+	if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
+		http.Error(w, "JSON inválido: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	p.UserID = userID
+	if msg := validateReminderPreferences(&p); msg != "" {
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
+
+	// o segredo nunca vem do cliente: é gerado com a URL e mantido até ela sair
+	p.WebhookSecret = nil
+	if p.WebhookURL != nil {
+		secret, err := notify.NewWebhookSecret()
+		if err != nil {
+			http.Error(w, "Erro ao gerar segredo do webhook: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		p.WebhookSecret = &secret
+	}
+	rotate := r.URL.Query().Get("novo_segredo") == "true"
+
+	err = scanReminderPreferences(db.DB.QueryRow(`
+		INSERT INTO reminder_preferences (user_id, ativo, dias_antes, no_dia, vencidas, canais, email, webhook_url, webhook_secret, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		ON CONFLICT (user_id) DO UPDATE
+		SET ativo = EXCLUDED.ativo, dias_antes = EXCLUDED.dias_antes, no_dia = EXCLUDED.no_dia, vencidas = EXCLUDED.vencidas,
+			canais = EXCLUDED.canais, email = EXCLUDED.email, webhook_url = EXCLUDED.webhook_url, updated_at = EXCLUDED.updated_at,
+			webhook_secret = CASE WHEN EXCLUDED.webhook_url IS NULL OR $11 THEN EXCLUDED.webhook_secret
+				ELSE COALESCE(reminder_preferences.webhook_secret, EXCLUDED.webhook_secret) END
+		RETURNING `+reminderPreferencesColumns,
+		userID, p.Ativo, pq.Array(p.DiasAntes), p.NoDia, p.Vencidas, pq.Array(p.Canais), p.Email, p.WebhookURL, p.WebhookSecret, time.Now(), rotate), &p)
+	if err != nil {
+		http.Error(w, "Erro ao salvar preferências: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(p)
+}
+
+// ListNotifications lista a caixa de entrada do usuário, mais recentes primeiro
+//
+// @Summary Caixa de entrada
+// @Tags Notifications
+// @Security BearerAuth
+// @Param userId path string true "ID do usuário"
+// @Param nao_lidas query bool false "Somente as não lidas"
+// @Success 200 {array} models.Notification
+// @Failure 400,401,500 {string} string
+// @Router /users/{userId}/notifications [get]
+func ListNotifications(w http.ResponseWriter, r *http.Request) {
+	unread := r.URL.Query().Get("nao_lidas") == "true"
+
+	rows, err := db.DB.Query(`
+		SELECT id, user_id, tipo, titulo, mensagem, referencia_id, lida, created_at
+		FROM notifications
+		WHERE user_id = $1 AND (NOT $2 OR lida = false)
+		ORDER BY created_at DESC
+		LIMIT 200
+	`, mux.Vars(r)["userId"], unread)
+	if err != nil {
+		http.Error(w, "Erro ao buscar notificações: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	notifications := []models.Notification{}
+	for rows.Next() {
+		var n models.Notification
+		if err := rows.Scan(&n.ID, &n.UserID, &n.Tipo, &n.Titulo, &n.Mensagem, &n.ReferenciaID, &n.Lida, &n.CreatedAt); err != nil {
+			http.Error(w, "Erro ao ler notificação: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		notifications = append(notifications, n)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(notifications)
+}
+
+// MarkNotificationRead marca uma notificação como lida
+//
+// @Summary Marcar notificação como lida
+// @Tags Notifications
+// @Security BearerAuth
+// @Param userId path string true "ID do usuário"
+// @Param id path string true "ID da notificação"
+// @Success 200 {object} Message
+// @Failure 400,401,404,500 {string} string
+// @Router /users/{userId}/notifications/{id}/read [patch]
+func MarkNotificationRead(w http.ResponseWriter, r *http.Request) {
+	p := mux.Vars(r)
+	if _, err := uuid.Parse(p["id"]); err != nil {
+		http.Error(w, "ID de notificação inválido", http.StatusBadRequest)
+		return
+	}
+
+	result, err := db.DB.Exec(`UPDATE notifications SET lida = true WHERE user_id = $1 AND id = $2`, p["userId"], p["id"])
+	if err != nil {
+		http.Error(w, "Erro ao atualizar notificação: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if rowsAffected, _ := result.RowsAffected(); rowsAffected == 0 {
+		http.Error(w, "Notificação não encontrada", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(Message{Message: "Notificação marcada como lida"})
+}
+
+// MarkAllNotificationsRead marca toda a caixa de entrada como lida
+//
+// @Summary Marcar todas as notificações como lidas
+// @Tags Notifications
+// @Security BearerAuth
+// @Param userId path string true "ID do usuário"
+// @Success 200 {object} Message
+// @Failure 401,500 {string} string
+// @Router /users/{userId}/notifications/read-all [post]
+func MarkAllNotificationsRead(w http.ResponseWriter, r *http.Request) {
+	if _, err := db.DB.Exec(`UPDATE notifications SET lida = true WHERE user_id = $1 AND lida = false`, mux.Vars(r)["userId"]); err != nil {
+		http.Error(w, "Erro ao atualizar notificações: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(Message{Message: "Notificações marcadas como lidas"})
+}
@@ -0,0 +1,662 @@
+package controllers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"finance/src/db"
+	"finance/src/models"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/lib/pq"
+)
+
+var errPayeeNotFound = errors.New("Favorecido não encontrado")
+
+// writePayeeError traduz os erros de resolvePayee em respostas HTTP
+func writePayeeError(w http.ResponseWriter, err error) {
+	if errors.Is(err, errPayeeNotFound) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	http.Error(w, "Erro ao buscar favorecido: "+err.Error(), http.StatusInternalServerError)
+}
+
+// findPayee devolve o nome do favorecido, garantindo que pertence ao usuário
+func findPayee(q dbExecutor, userID string, id uuid.UUID) (string, error) {
+	var nome string
+	err := q.QueryRow(`SELECT nome FROM payees WHERE user_id = $1 AND id = $2`, userID, id).Scan(&nome)
+	if err == sql.ErrNoRows {
+		return "", errPayeeNotFound
+	}
+	return nome, err
+}
+
+// payeeMatchers carrega os padrões que reconhecem os favorecidos do usuário.
+// O próprio nome do favorecido conta como um apelido do tipo "contem".
+func payeeMatchers(q dbExecutor, userID string) ([]models.PayeeAlias, error) {
+	rows, err := q.Query(`
+		SELECT NULL::uuid, p.id, p.nome, 'nome' FROM payees p WHERE p.user_id = $1
+		UNION ALL
+		SELECT a.id, a.payee_id, a.padrao, a.tipo
+		FROM payee_aliases a
+		JOIN payees p ON p.id = a.payee_id
+		WHERE p.user_id = $1
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var aliases []models.PayeeAlias
+	for rows.Next() {
+		var a models.PayeeAlias
+		var id uuid.NullUUID
+		if err := rows.Scan(&id, &a.PayeeID, &a.Padrao, &a.Tipo); err != nil {
+			return nil, err
+		}
+		if a.Tipo == "nome" {
+			a.Tipo = models.PayeeMatchContains
+			a.Padrao = models.NormalizePayeeText(a.Padrao)
+		}
+		a.ID = id.UUID
+		aliases = append(aliases, a)
+	}
+	return aliases, rows.Err()
+}
+
+// resolvePayee valida o favorecido informado ou, sem ele, sugere um pela descrição.
+// Devolve nil quando nenhum favorecido reconhece a descrição.
+func resolvePayee(q dbExecutor, userID string, id *uuid.UUID, descricao string) (*uuid.UUID, *string, error) {
+	if id != nil {
+		nome, err := findPayee(q, userID, *id)
+		if err != nil {
+			return nil, nil, err
+		}
+		return id, &nome, nil
+	}
+
+	aliases, err := payeeMatchers(q, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+	match, ok := models.MatchPayee(descricao, aliases)
+	if !ok {
+		return nil, nil, nil
+	}
+	nome, err := findPayee(q, userID, match)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &match, &nome, nil
+}
+
+// insertPayeeAlias grava um apelido já normalizado; apelidos repetidos são ignorados
+func insertPayeeAlias(q dbExecutor, a *models.PayeeAlias) error {
+	err := q.QueryRow(`
+		INSERT INTO payee_aliases (id, payee_id, padrao, tipo, created_at)
+		VALUES ($1, $2, $3, $4, NOW())
+		ON CONFLICT (payee_id, tipo, padrao) DO UPDATE SET padrao = EXCLUDED.padrao
+		RETURNING id
+	`, uuid.New(), a.PayeeID, a.Padrao, a.Tipo).Scan(&a.ID)
+	return err
+}
+
+// loadPayees busca os favorecidos do usuário com apelidos e quantidade de usos
+func loadPayees(q dbExecutor, userID string, id *uuid.UUID) ([]models.Payee, error) {
+	rows, err := q.Query(`
+		SELECT p.id, p.user_id, p.nome, p.created_at,
+			(SELECT COUNT(*) FROM expenses e WHERE e.payee_id = p.id AND e.deleted_at IS NULL) +
+			(SELECT COUNT(*) FROM incomes i WHERE i.payee_id = p.id AND i.deleted_at IS NULL) AS usos
+		FROM payees p
+		WHERE p.user_id = $1 AND ($2::uuid IS NULL OR p.id = $2)
+		ORDER BY p.nome
+	`, userID, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	payees := []models.Payee{}
+	index := map[uuid.UUID]int{}
+	for rows.Next() {
+		var p models.Payee
+		if err := rows.Scan(&p.ID, &p.UserID, &p.Nome, &p.CreatedAt, &p.Usos); err != nil {
+			return nil, err
+		}
+		p.Aliases = []models.PayeeAlias{}
+		index[p.ID] = len(payees)
+		payees = append(payees, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	aliasRows, err := q.Query(`
+		SELECT a.id, a.payee_id, a.padrao, a.tipo
+		FROM payee_aliases a
+		JOIN payees p ON p.id = a.payee_id
+		WHERE p.user_id = $1 AND ($2::uuid IS NULL OR p.id = $2)
+		ORDER BY a.created_at
+	`, userID, id)
+	if err != nil {
+		return nil, err
+	}
+	defer aliasRows.Close()
+
+	for aliasRows.Next() {
+		var a models.PayeeAlias
+		if err := aliasRows.Scan(&a.ID, &a.PayeeID, &a.Padrao, &a.Tipo); err != nil {
+			return nil, err
+		}
+		if i, ok := index[a.PayeeID]; ok {
+			payees[i].Aliases = append(payees[i].Aliases, a)
+		}
+	}
+	return payees, aliasRows.Err()
+}
+
+// CreatePayee cria um favorecido, opcionalmente já com apelidos
+//
+// @Summary Criar favorecido
+// @Tags Payees
+// @Security BearerAuth
+// @Param userId path string true "ID do usuário"
+// @Param payee body models.Payee true "Nome e apelidos"
+// @Success 201 {object} models.Payee
+// @Failure 400,401,409,500 {string} string
+// @Router /users/{userId}/payees [post]
+func CreatePayee(w http.ResponseWriter, r *http.Request) {
+	userID, err := uuid.Parse(mux.Vars(r)["userId"])
+	if err != nil {
+		http.Error(w, "ID de usuário inválido", http.StatusBadRequest)
+		return
+	}
+
+	var payee models.Payee
+	if err := json.NewDecoder(r.Body).Decode(&payee); err != nil {
+		http.Error(w, "Erro ao decodificar JSON", http.StatusBadRequest)
+		return
+	}
+	payee.Nome = strings.TrimSpace(payee.Nome)
+	if payee.Nome == "" {
+		http.Error(w, "Nome do favorecido é obrigatório", http.StatusBadRequest)
+		return
+	}
+	for i := range payee.Aliases {
+		if err := models.NormalizePayeeAlias(&payee.Aliases[i]); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	payee.ID = uuid.New()
+	payee.UserID = userID
+	payee.Usos = 0
+	payee.CreatedAt = time.Now()
+
+	tx, err := db.DB.Begin()
+	if err != nil {
+		http.Error(w, "Erro ao iniciar transação: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`
+		INSERT INTO payees (id, user_id, nome, created_at)
+		VALUES ($1, $2, $3, $4)
+	`, payee.ID, payee.UserID, payee.Nome, payee.CreatedAt)
+	if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+		http.Error(w, "Já existe um favorecido com esse nome", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Erro ao criar favorecido: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if payee.Aliases == nil {
+		payee.Aliases = []models.PayeeAlias{}
+	}
+	for i := range payee.Aliases {
+		payee.Aliases[i].PayeeID = payee.ID
+		if err := insertPayeeAlias(tx, &payee.Aliases[i]); err != nil {
+			http.Error(w, "Erro ao salvar apelido: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		http.Error(w, "Erro ao criar favorecido: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(payee)
+}
+
+// ListPayees lista os favorecidos do usuário com apelidos e quantidade de lançamentos
+//
+// @Summary Listar favorecidos
+// @Tags Payees
+// @Security BearerAuth
+// @Param userId path string true "ID do usuário"
+// @Success 200 {array} models.Payee
+// @Failure 400,401,500 {string} string
+// @Router /users/{userId}/payees [get]
+func ListPayees(w http.ResponseWriter, r *http.Request) {
+	payees, err := loadPayees(db.DB, mux.Vars(r)["userId"], nil)
+	if err != nil {
+		http.Error(w, "Erro ao buscar favorecidos: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(payees)
+}
+
+// UpdatePayee renomeia um favorecido
+//
+// @Summary Renomear favorecido
+// @Tags Payees
+// @Security BearerAuth
+// @Param userId path string true "ID do usuário"
+// @Param id path string true "ID do favorecido"
+// @Param payee body models.Payee true "Novo nome"
+// @Success 200 {object} models.Payee
+// @Failure 400,401,404,409,500 {string} string
+// @Router /users/{userId}/payees/{id} [put]
+func UpdatePayee(w http.ResponseWriter, r *http.Request) {
+	p := mux.Vars(r)
+	id, err := uuid.Parse(p["id"])
+	if err != nil {
+		http.Error(w, "ID de favorecido inválido", http.StatusBadRequest)
+		return
+	}
+
+	var in models.Payee
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		http.Error(w, "Erro ao decodificar JSON", http.StatusBadRequest)
+		return
+	}
+	in.Nome = strings.TrimSpace(in.Nome)
+	if in.Nome == "" {
+		http.Error(w, "Nome do favorecido é obrigatório", http.StatusBadRequest)
+		return
+	}
+
+	result, err := db.DB.Exec(`
+		UPDATE payees SET nome = $1
+		WHERE user_id = $2 AND id = $3
+	`, in.Nome, p["userId"], id)
+	if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+		http.Error(w, "Já existe um favorecido com esse nome", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Erro ao atualizar favorecido: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if rowsAffected, _ := result.RowsAffected(); rowsAffected == 0 {
+		http.Error(w, "Favorecido não encontrado", http.StatusNotFound)
+		return
+	}
+
+	payees, err := loadPayees(db.DB, p["userId"], &id)
+	if err != nil || len(payees) == 0 {
+		http.Error(w, "Erro ao buscar favorecido", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(payees[0])
+}
+
+// DeletePayee exclui um favorecido; os lançamentos ficam sem favorecido
+//
+// @Summary Excluir favorecido
+// @Tags Payees
+// @Security BearerAuth
+// @Param userId path string true "ID do usuário"
+// @Param id path string true "ID do favorecido"
+// @Success 200 {object} Message
+// @Failure 400,401,404,500 {string} string
+// @Router /users/{userId}/payees/{id} [delete]
+func DeletePayee(w http.ResponseWriter, r *http.Request) {
+	p := mux.Vars(r)
+
+	result, err := db.DB.Exec(`
+		DELETE FROM payees
+		WHERE user_id = $1 AND id = $2
+	`, p["userId"], p["id"])
+	if err != nil {
+		http.Error(w, "Erro ao excluir favorecido: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if rowsAffected, _ := result.RowsAffected(); rowsAffected == 0 {
+		http.Error(w, "Favorecido não encontrado", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(Message{Message: "Favorecido excluído com sucesso"})
+}
+
+// AddPayeeAlias adiciona um apelido (padrão de reconhecimento) ao favorecido
+//
+// @Summary Adicionar apelido ao favorecido
+// @Tags Payees
+// @Security BearerAuth
+// @Param userId path string true "ID do usuário"
+// @Param id path string true "ID do favorecido"
+// @Param alias body models.PayeeAlias true "Padrão e tipo (exato, contem ou regex)"
+// @Success 201 {object} models.PayeeAlias
+// @Failure 400,401,404,500 {string} string
+// @Router /users/{userId}/payees/{id}/aliases [post]
+func AddPayeeAlias(w http.ResponseWriter, r *http.Request) {
+	p := mux.Vars(r)
+	payeeID, err := uuid.Parse(p["id"])
+	if err != nil {
+		http.Error(w, "ID de favorecido inválido", http.StatusBadRequest)
+		return
+	}
+
+	var alias models.PayeeAlias
+	if err := json.NewDecoder(r.Body).Decode(&alias); err != nil {
+		http.Error(w, "Erro ao decodificar JSON", http.StatusBadRequest)
+		return
+	}
+	if err := models.NormalizePayeeAlias(&alias); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if _, err := findPayee(db.DB, p["userId"], payeeID); err != nil {
+		if errors.Is(err, errPayeeNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Erro ao buscar favorecido: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	alias.PayeeID = payeeID
+	if err := insertPayeeAlias(db.DB, &alias); err != nil {
+		http.Error(w, "Erro ao salvar apelido: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(alias)
+}
+
+// DeletePayeeAlias remove um apelido do favorecido
+//
+// @Summary Remover apelido do favorecido
+// @Tags Payees
+// @Security BearerAuth
+// @Param userId path string true "ID do usuário"
+// @Param id path string true "ID do favorecido"
+// @Param aliasId path string true "ID do apelido"
+// @Success 200 {object} Message
+// @Failure 400,401,404,500 {string} string
+// @Router /users/{userId}/payees/{id}/aliases/{aliasId} [delete]
+func DeletePayeeAlias(w http.ResponseWriter, r *http.Request) {
+	p := mux.Vars(r)
+
+	result, err := db.DB.Exec(`
+		DELETE FROM payee_aliases a
+		USING payees p
+		WHERE p.id = a.payee_id AND p.user_id = $1 AND a.payee_id = $2 AND a.id = $3
+	`, p["userId"], p["id"], p["aliasId"])
+	if err != nil {
+		http.Error(w, "Erro ao excluir apelido: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if rowsAffected, _ := result.RowsAffected(); rowsAffected == 0 {
+		http.Error(w, "Apelido não encontrado", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(Message{Message: "Apelido excluído com sucesso"})
+}
+
+// PayeeSuggestion é o favorecido reconhecido numa descrição
+type PayeeSuggestion struct {
+	Descricao   string     `json:"descricao"`
+	Normalizada string     `json:"normalizada"`
+	PayeeID     *uuid.UUID `json:"payee_id"`
+	Payee       *string    `json:"payee"`
+}
+
+// SuggestPayee mostra qual favorecido seria sugerido para uma descrição
+//
+// @Summary Sugerir favorecido
+// @Tags Payees
+// @Security BearerAuth
+// @Param userId path string true "ID do usuário"
+// @Param descricao query string true "Descrição do lançamento"
+// @Success 200 {object} PayeeSuggestion
+// @Failure 400,401,500 {string} string
+// @Router /users/{userId}/payees/suggest [get]
+func SuggestPayee(w http.ResponseWriter, r *http.Request) {
+	descricao := r.URL.Query().Get("descricao")
+	if strings.TrimSpace(descricao) == "" {
+		http.Error(w, "Informe a descrição", http.StatusBadRequest)
+		return
+	}
+
+	id, nome, err := resolvePayee(db.DB, mux.Vars(r)["userId"], nil, descricao)
+	if err != nil {
+		writePayeeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(PayeeSuggestion{
+		Descricao:   descricao,
+		Normalizada: models.NormalizePayeeText(descricao),
+		PayeeID:     id,
+		Payee:       nome,
+	})
+}
+
+// applyPayees preenche o favorecido dos lançamentos de table pela descrição.
+// Sem overwrite, só os lançamentos ainda sem favorecido são alterados.
+func applyPayees(q dbExecutor, table, userID string, aliases []models.PayeeAlias, overwrite bool) (int, error) {
+	rows, err := q.Query(`
+		SELECT id, descricao, payee_id FROM `+table+`
+		WHERE user_id = $1 AND ($2 OR payee_id IS NULL)
+	`, userID, overwrite)
+	if err != nil {
+		return 0, err
+	}
+
+	changes := map[uuid.UUID][]string{}
+	for rows.Next() {
+		var id uuid.UUID
+		var descricao string
+		var current uuid.NullUUID
+		if err := rows.Scan(&id, &descricao, &current); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		if match, ok := models.MatchPayee(descricao, aliases); ok && (!current.Valid || current.UUID != match) {
+			changes[match] = append(changes[match], id.String())
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	total := 0
+	for payeeID, ids := range changes {
+		result, err := q.Exec(`
+			UPDATE `+table+` SET payee_id = $1 WHERE user_id = $2 AND id = ANY($3::uuid[])
+		`, payeeID, userID, pq.Array(ids))
+		if err != nil {
+			return 0, err
+		}
+		n, _ := result.RowsAffected()
+		total += int(n)
+	}
+	return total, nil
+}
+
+// ApplyPayeesResult informa quantos lançamentos receberam favorecido
+type ApplyPayeesResult struct {
+	Despesas int `json:"despesas"`
+	Receitas int `json:"receitas"`
+}
+
+// ApplyPayees reconhece o favorecido dos lançamentos existentes pela descrição
+//
+// @Summary Aplicar favorecidos aos lançamentos
+// @Description Preenche o favorecido de despesas e receitas a partir dos nomes e apelidos.
+// @Description Com sobrescrever=true também recalcula lançamentos que já têm favorecido.
+// @Tags Payees
+// @Security BearerAuth
+// @Param userId path string true "ID do usuário"
+// @Param sobrescrever query bool false "Recalcula também lançamentos que já têm favorecido"
+// @Success 200 {object} ApplyPayeesResult
+// @Failure 400,401,500 {string} string
+// @Router /users/{userId}/payees/apply [post]
+func ApplyPayees(w http.ResponseWriter, r *http.Request) {
+	userID := mux.Vars(r)["userId"]
+	overwrite := r.URL.Query().Get("sobrescrever") == "true"
+
+	tx, err := db.DB.Begin()
+	if err != nil {
+		http.Error(w, "Erro ao iniciar transação: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	aliases, err := payeeMatchers(tx, userID)
+	if err != nil {
+		http.Error(w, "Erro ao buscar favorecidos: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	var result ApplyPayeesResult
+	if result.Despesas, err = applyPayees(tx, "expenses", userID, aliases, overwrite); err != nil {
+		http.Error(w, "Erro ao atualizar despesas: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if result.Receitas, err = applyPayees(tx, "incomes", userID, aliases, overwrite); err != nil {
+		http.Error(w, "Erro ao atualizar receitas: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		http.Error(w, "Erro ao aplicar favorecidos: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(result)
+}
+
+// PayeeChart é o total de um favorecido no período, na moeda base do usuário
+type PayeeChart struct {
+	PayeeID         uuid.UUID        `json:"payee_id"`
+	Payee           string           `json:"payee"`
+	Quantidade      int              `json:"quantidade"`
+	Total           models.Money     `json:"total"`
+	TaxasUtilizadas models.RatesUsed `json:"taxas_utilizadas,omitempty"`
+	MoedasSemTaxa   []string         `json:"moedas_sem_taxa,omitempty"`
+}
+
+// GetTopPayees retorna os favorecidos com maior total no mês ou no ano
+//
+// @Summary Principais favorecidos
+// @Tags Charts
+// @Security BearerAuth
+// @Param userId path string true "ID do usuário"
+// @Param year query string true "Ano (YYYY)"
+// @Param month query string false "Mês (1-12); omitido, considera o ano inteiro"
+// @Param tipo query string false "despesas (padrão) ou receitas"
+// @Param limit query int false "Quantidade de favorecidos (padrão 10)"
+// @Success 200 {array} PayeeChart
+// @Failure 400,401,500 {string} string
+// @Router /charts/top-payees/{userId} [get]
+func GetTopPayees(w http.ResponseWriter, r *http.Request) {
+	userID := mux.Vars(r)["userId"]
+	q := r.URL.Query()
+
+	year, err := strconv.Atoi(q.Get("year"))
+	if err != nil {
+		http.Error(w, "Parâmetros de mês/ano inválidos", http.StatusBadRequest)
+		return
+	}
+	start := time.Date(year, time.January, 1, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(1, 0, 0)
+	if m := q.Get("month"); m != "" {
+		month, err := strconv.Atoi(m)
+		if err != nil || month < 1 || month > 12 {
+			http.Error(w, "Parâmetros de mês/ano inválidos", http.StatusBadRequest)
+			return
+		}
+		start = time.Date(year, time.Month(month), 1, 0, 0, 0, 0, time.UTC)
+		end = start.AddDate(0, 1, 0)
+	}
+
+	limit := 10
+	if l := q.Get("limit"); l != "" {
+		if limit, err = strconv.Atoi(l); err != nil || limit < 1 || limit > 100 {
+			http.Error(w, "Limite inválido: use de 1 a 100", http.StatusBadRequest)
+			return
+		}
+	}
+
+	var source, where string
+	switch q.Get("tipo") {
+	case "", "despesas":
+		source = netExpensesSQL
+		where = `x.user_id = $1 AND x.vencimento >= $2 AND x.vencimento < $3`
+	case "receitas":
+		source = convertedSQL(liveIncomes, "data_recebimento")
+		where = `x.user_id = $1 AND x.status = 'recebida' AND x.data_recebimento >= $2 AND x.data_recebimento < $3`
+	default:
+		http.Error(w, "Tipo inválido: use despesas ou receitas", http.StatusBadRequest)
+		return
+	}
+
+	rows, err := db.DB.Query(`
+		SELECT py.id, py.nome, COUNT(*), COALESCE(SUM(x.valor_base), 0) AS total, `+ratesUsedSQL+`, `+missingRatesSQL+`
+		FROM `+source+` x
+		JOIN payees py ON py.id = x.payee_id
+		WHERE `+where+`
+		GROUP BY py.id, py.nome
+		ORDER BY total DESC
+		LIMIT $4
+	`, userID, start, end, limit)
+	if err != nil {
+		http.Error(w, "Erro ao buscar dados", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	var result []PayeeChart
+	for rows.Next() {
+		var row PayeeChart
+		if err := rows.Scan(&row.PayeeID, &row.Payee, &row.Quantidade, &row.Total, &row.TaxasUtilizadas, pq.Array(&row.MoedasSemTaxa)); err != nil {
+			http.Error(w, "Erro ao processar dados", http.StatusInternalServerError)
+			return
+		}
+		result = append(result, row)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
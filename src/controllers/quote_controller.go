@@ -0,0 +1,234 @@
+package controllers
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"finance/src/db"
+	"finance/src/models"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+const quoteColumns = `id, user_id, ticker, data, preco, fonte, created_at`
+
+// upsertQuote grava a cotação do dia, substituindo uma existente para o mesmo ativo e data
+func upsertQuote(q dbExecutor, quote *models.Quote) error {
+	return q.QueryRow(`
+		INSERT INTO quotes (id, user_id, ticker, data, preco, fonte, created_at)
+		VALUES ($1,$2,$3,$4,$5,$6,$7)
+		ON CONFLICT (user_id, ticker, data) DO UPDATE
+		SET preco = EXCLUDED.preco, fonte = EXCLUDED.fonte, created_at = EXCLUDED.created_at
+		RETURNING id
+	`, quote.ID, quote.UserID, quote.Ticker, quote.Data, quote.Preco, quote.Fonte, quote.CreatedAt).Scan(&quote.ID)
+}
+
+func validateQuote(quote *models.Quote) error {
+	quote.Ticker = models.NormalizeTicker(quote.Ticker)
+	if quote.Ticker == "" {
+		return errors.New("ticker é obrigatório")
+	}
+	if quote.Preco <= 0 {
+		return errors.New("preço deve ser maior que zero")
+	}
+	if quote.Data.IsZero() {
+		return errors.New("data é obrigatória")
+	}
+	return nil
+}
+
+// CreateQuote cadastra manualmente a cotação de um ativo
+//
+// @Summary Cadastrar cotação
+// @Tags Investments
+// @Security BearerAuth
+// @Param userId path string true "ID do usuário"
+// @Param quote body models.Quote true "Ticker, data e preço"
+// @Success 201 {object} models.Quote
+// @Failure 400,401,500 {string} string
+// @Router /users/{userId}/quotes [post]
+func CreateQuote(w http.ResponseWriter, r *http.Request) {
+	userID, err := uuid.Parse(mux.Vars(r)["userId"])
+	if err != nil {
+		http.Error(w, "ID de usuário inválido", http.StatusBadRequest)
+		return
+	}
+
+	var quote models.Quote
+	if err := json.NewDecoder(r.Body).Decode(&quote); err != nil {
+		http.Error(w, "JSON inválido", http.StatusBadRequest)
+		return
+	}
+	if err := validateQuote(&quote); err != nil {
+		http.Error(w, "Cotação inválida: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	quote.ID = uuid.New()
+	quote.UserID = userID
+	quote.Fonte = "manual"
+	quote.CreatedAt = time.Now()
+	if err := upsertQuote(db.DB, &quote); err != nil {
+		http.Error(w, "Erro ao salvar cotação: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(quote)
+}
+
+// ImportQuotes importa cotações de um CSV com cabeçalho ticker,data,preco
+//
+// @Summary Importar cotações (CSV)
+// @Description Aceita multipart/form-data (campo "file") ou o CSV no corpo (text/csv).
+// @Description Datas em YYYY-MM-DD; o preço aceita ponto ou vírgula como separador decimal.
+// @Tags Investments
+// @Security BearerAuth
+// @Param userId path string true "ID do usuário"
+// @Success 200 {object} map[string]int
+// @Failure 400,401,500 {string} string
+// @Router /users/{userId}/quotes/import [post]
+func ImportQuotes(w http.ResponseWriter, r *http.Request) {
+	userID, err := uuid.Parse(mux.Vars(r)["userId"])
+	if err != nil {
+		http.Error(w, "ID de usuário inválido", http.StatusBadRequest)
+		return
+	}
+
+	var body io.Reader = r.Body
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		file, _, err := r.FormFile("file")
+		if err != nil {
+			http.Error(w, "Arquivo CSV não enviado no campo \"file\"", http.StatusBadRequest)
+			return
+		}
+		defer file.Close()
+		body = file
+	}
+
+	quotes, err := parseQuotesCSV(body)
+	if err != nil {
+		http.Error(w, "CSV inválido: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	tx, err := db.DB.Begin()
+	if err != nil {
+		http.Error(w, "Erro ao iniciar transação: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+	for i := range quotes {
+		quotes[i].ID = uuid.New()
+		quotes[i].UserID = userID
+		quotes[i].Fonte = "csv"
+		quotes[i].CreatedAt = now
+		if err := upsertQuote(tx, &quotes[i]); err != nil {
+			http.Error(w, "Erro ao salvar cotação: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		http.Error(w, "Erro ao salvar cotações: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]int{"importadas": len(quotes)})
+}
+
+func parseQuotesCSV(src io.Reader) ([]models.Quote, error) {
+	reader := csv.NewReader(src)
+	reader.TrimLeadingSpace = true
+	reader.Comment = '#'
+
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, errors.New("arquivo vazio")
+	}
+
+	// mapeia as colunas pelo cabeçalho para aceitar qualquer ordem
+	index := map[string]int{}
+	for i, name := range records[0] {
+		index[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, col := range []string{"ticker", "data", "preco"} {
+		if _, ok := index[col]; !ok {
+			return nil, fmt.Errorf("coluna %q ausente no cabeçalho", col)
+		}
+	}
+
+	var quotes []models.Quote
+	for n, rec := range records[1:] {
+		line := n + 2
+		data, err := time.Parse("2006-01-02", strings.TrimSpace(rec[index["data"]]))
+		if err != nil {
+			return nil, fmt.Errorf("linha %d: data inválida", line)
+		}
+		preco, err := strconv.ParseFloat(strings.Replace(strings.TrimSpace(rec[index["preco"]]), ",", ".", 1), 64)
+		if err != nil {
+			return nil, fmt.Errorf("linha %d: preço inválido", line)
+		}
+
+		quote := models.Quote{Ticker: rec[index["ticker"]], Data: data, Preco: preco}
+		if err := validateQuote(&quote); err != nil {
+			return nil, fmt.Errorf("linha %d: %v", line, err)
+		}
+		quotes = append(quotes, quote)
+	}
+	return quotes, nil
+}
+
+// ListQuotes lista as cotações do usuário, mais recentes primeiro
+//
+// @Summary Listar cotações
+// @Tags Investments
+// @Security BearerAuth
+// @Param userId path string true "ID do usuário"
+// @Param ticker query string false "Filtra pelo ativo"
+// @Success 200 {array} models.Quote
+// @Failure 400,401,500 {string} string
+// @Router /users/{userId}/quotes [get]
+func ListQuotes(w http.ResponseWriter, r *http.Request) {
+	ticker := models.NormalizeTicker(r.URL.Query().Get("ticker"))
+
+	rows, err := db.DB.Query(`
+		SELECT `+quoteColumns+`
+		FROM quotes
+		WHERE user_id = $1 AND ($2 = '' OR ticker = $2)
+		ORDER BY data DESC, ticker
+	`, mux.Vars(r)["userId"], ticker)
+	if err != nil {
+		http.Error(w, "Erro ao buscar cotações: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	quotes := []models.Quote{}
+	for rows.Next() {
+		var q models.Quote
+		if err := rows.Scan(&q.ID, &q.UserID, &q.Ticker, &q.Data, &q.Preco, &q.Fonte, &q.CreatedAt); err != nil {
+			http.Error(w, "Erro ao ler cotação: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		quotes = append(quotes, q)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(quotes)
+}
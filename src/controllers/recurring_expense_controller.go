@@ -0,0 +1,611 @@
+package controllers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"finance/src/audit"
+	"finance/src/db"
+	"finance/src/jobs"
+	"finance/src/models"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+// Escopos de edição de uma ocorrência de despesa recorrente
+const (
+	ScopeThis      = "this"
+	ScopeFollowing = "following"
+	ScopeAll       = "all"
+)
+
+// RecurringEditInput são os dados aceitos ao editar uma série recorrente
+type RecurringEditInput struct {
+	Descricao   string       `json:"descricao"`
+	Valor       models.Money `json:"valor"`
+	Vencimento  *time.Time   `json:"vencimento,omitempty"` // usado apenas com scope=this
+	Categoria   string       `json:"categoria"`
+	CategoriaID *uuid.UUID   `json:"categoria_id,omitempty"`
+	Observacoes *string      `json:"observacoes,omitempty"`
+	Regra       string       `json:"regra,omitempty"` // usado com scope=following ou scope=all
+}
+
+const recurringExpenseColumns = `id, user_id, descricao, valor, moeda, categoria, category_id, observacoes, regra, data_inicio, gerada_ate, ativa, created_at`
+
+func scanRecurringExpense(row rowScanner, rec *models.RecurringExpense) error {
+	return row.Scan(&rec.ID, &rec.UserID, &rec.Descricao, &rec.Valor, &rec.Moeda, &rec.Categoria, &rec.CategoriaID, &rec.Observacoes,
+		&rec.Regra, &rec.DataInicio, &rec.GeradaAte, &rec.Ativa, &rec.CreatedAt)
+}
+
+// normalizeRule valida a regra e devolve sua forma canônica a partir do DTSTART
+// resolveEditCategory resolve a categoria informada na edição de uma série
+func resolveEditCategory(w http.ResponseWriter, userID, kind string, in *RecurringEditInput) bool {
+	var err error
+	if in.CategoriaID, in.Categoria, err = resolveCategory(db.DB, userID, kind, in.CategoriaID, in.Categoria); err != nil {
+		writeCategoryError(w, err)
+		return false
+	}
+	return true
+}
+
+func normalizeRule(regra string, dtstart time.Time) (models.RecurrenceRule, error) {
+	rule, err := models.ParseRRule(regra)
+	if err != nil {
+		return rule, err
+	}
+	return rule.Pin(dtstart), nil
+}
+
+// CreateRecurringExpense cria um modelo de despesa recorrente e materializa as próximas ocorrências
+//
+// @Summary Criar despesa recorrente
+// @Description A regra segue o formato RRULE (RFC 5545). Exemplos: "FREQ=MONTHLY;BYMONTHDAY=10",
+// @Description "FREQ=WEEKLY;INTERVAL=2", "FREQ=YEARLY;COUNT=3", "FREQ=MONTHLY;BYDAY=MO,TU,WE,TH,FR;BYSETPOS=-1" (último dia útil).
+// @Description Sem BYMONTHDAY, o dia vem de data_inicio e cai no último dia dos meses mais curtos (ex: 31 vira 30 em abril). Um BYMONTHDAY explícito segue o RFC 5545: meses sem o dia não têm ocorrência; use BYMONTHDAY=-1 para o último dia.
+// @Tags Recurring Expenses
+// @Security BearerAuth
+// @Param userId path string true "ID do usuário"
+// @Param recurring body models.RecurringExpense true "Modelo da despesa recorrente"
+// @Success 201 {object} models.RecurringExpense
+// @Failure 400,401,500 {string} string
+// @Router /users/{userId}/recurring-expenses [post]
+func CreateRecurringExpense(w http.ResponseWriter, r *http.Request) {
+	userID, err := uuid.Parse(mux.Vars(r)["userId"])
+	if err != nil {
+		http.Error(w, "ID de usuário inválido", http.StatusBadRequest)
+		return
+	}
+
+	var rec models.RecurringExpense
+	if err := json.NewDecoder(r.Body).Decode(&rec); err != nil {
+		http.Error(w, "JSON inválido: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if rec.Valor <= 0 {
+		http.Error(w, "Valor deve ser maior que zero", http.StatusBadRequest)
+		return
+	}
+	if rec.Descricao == "" || rec.DataInicio.IsZero() {
+		http.Error(w, "Descrição e data de início são obrigatórias", http.StatusBadRequest)
+		return
+	}
+
+	rule, err := normalizeRule(rec.Regra, rec.DataInicio)
+	if err != nil {
+		http.Error(w, "Regra de recorrência inválida: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if rec.Moeda, err = resolveCurrency(db.DB, userID.String(), rec.Moeda); err != nil {
+		writeCurrencyError(w, err)
+		return
+	}
+	if rec.CategoriaID, rec.Categoria, err = resolveCategory(db.DB, userID.String(), models.CategoryExpense, rec.CategoriaID, rec.Categoria); err != nil {
+		writeCategoryError(w, err)
+		return
+	}
+
+	rec.ID = uuid.New()
+	rec.UserID = userID
+	rec.Regra = rule.String()
+	rec.Ativa = true
+	rec.CreatedAt = time.Now()
+
+	_, err = db.DB.Exec(`
+		INSERT INTO recurring_expenses (id, user_id, descricao, valor, moeda, categoria, category_id, observacoes, regra, data_inicio, ativa, created_at)
+		VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12)
+	`, rec.ID, rec.UserID, rec.Descricao, rec.Valor, rec.Moeda, rec.Categoria, rec.CategoriaID, rec.Observacoes, rec.Regra, rec.DataInicio, rec.Ativa, rec.CreatedAt)
+	if err != nil {
+		http.Error(w, "Erro ao criar despesa recorrente: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if err := jobs.MaterializeRecurringExpense(rec.ID); err != nil {
+		http.Error(w, "Erro ao gerar ocorrências: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(rec)
+}
+
+// ListRecurringExpenses lista os modelos de despesas recorrentes de um usuário
+//
+// @Summary Listar despesas recorrentes
+// @Tags Recurring Expenses
+// @Security BearerAuth
+// @Param userId path string true "ID do usuário"
+// @Success 200 {array} models.RecurringExpense
+// @Failure 400,401,500 {string} string
+// @Router /users/{userId}/recurring-expenses [get]
+func ListRecurringExpenses(w http.ResponseWriter, r *http.Request) {
+	userID := mux.Vars(r)["userId"]
+
+	rows, err := db.DB.Query(`
+		SELECT `+recurringExpenseColumns+`
+		FROM recurring_expenses
+		WHERE user_id = $1
+		ORDER BY descricao
+	`, userID)
+	if err != nil {
+		http.Error(w, "Erro ao buscar despesas recorrentes: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	var result []models.RecurringExpense
+	for rows.Next() {
+		var rec models.RecurringExpense
+		if err := scanRecurringExpense(rows, &rec); err != nil {
+			http.Error(w, "Erro ao ler despesa recorrente: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		result = append(result, rec)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// GetRecurringExpense busca um modelo de despesa recorrente pelo ID
+//
+// @Summary Buscar despesa recorrente
+// @Tags Recurring Expenses
+// @Security BearerAuth
+// @Param userId path string true "ID do usuário"
+// @Param id path string true "ID do modelo"
+// @Success 200 {object} models.RecurringExpense
+// @Failure 400,401,404,500 {string} string
+// @Router /users/{userId}/recurring-expenses/{id} [get]
+func GetRecurringExpense(w http.ResponseWriter, r *http.Request) {
+	p := mux.Vars(r)
+
+	var rec models.RecurringExpense
+	err := scanRecurringExpense(db.DB.QueryRow(`
+		SELECT `+recurringExpenseColumns+`
+		FROM recurring_expenses
+		WHERE user_id = $1 AND id = $2
+	`, p["userId"], p["id"]), &rec)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Despesa recorrente não encontrada", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Erro ao buscar despesa recorrente: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(rec)
+}
+
+// UpdateRecurringExpense altera o modelo e todas as ocorrências em aberto (equivale a scope=all)
+//
+// @Summary Atualizar despesa recorrente
+// @Tags Recurring Expenses
+// @Security BearerAuth
+// @Param userId path string true "ID do usuário"
+// @Param id path string true "ID do modelo"
+// @Param recurring body RecurringEditInput true "Novos dados da série"
+// @Success 200 {object} models.RecurringExpense
+// @Failure 400,401,404,500 {string} string
+// @Router /users/{userId}/recurring-expenses/{id} [put]
+func UpdateRecurringExpense(w http.ResponseWriter, r *http.Request) {
+	p := mux.Vars(r)
+
+	var in RecurringEditInput
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		http.Error(w, "JSON inválido: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if in.Valor <= 0 {
+		http.Error(w, "Valor deve ser maior que zero", http.StatusBadRequest)
+		return
+	}
+	if !resolveEditCategory(w, p["userId"], models.CategoryExpense, &in) {
+		return
+	}
+
+	rec, status, err := updateRecurringSeries(apiAudit(r, models.AuditUpdate), p["userId"], p["id"], in)
+	if err != nil {
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(rec)
+}
+
+// DeleteRecurringExpense encerra a série e remove as ocorrências futuras sem pagamentos nem anexos
+//
+// @Summary Excluir despesa recorrente
+// @Tags Recurring Expenses
+// @Security BearerAuth
+// @Param userId path string true "ID do usuário"
+// @Param id path string true "ID do modelo"
+// @Success 200 {object} Message
+// @Failure 400,401,404,500 {string} string
+// @Router /users/{userId}/recurring-expenses/{id} [delete]
+func DeleteRecurringExpense(w http.ResponseWriter, r *http.Request) {
+	p := mux.Vars(r)
+
+	tx, err := db.DB.Begin()
+	if err != nil {
+		http.Error(w, "Erro ao iniciar transação: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	history, err := audit.Track(tx, models.AuditExpenses, "user_id = $1 AND recorrencia_id = $2 AND vencimento >= CURRENT_DATE", p["userId"], p["id"])
+	if err != nil {
+		http.Error(w, "Erro ao buscar ocorrências: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	_, err = tx.Exec(`
+		DELETE FROM expenses
+		WHERE user_id = $1 AND recorrencia_id = $2 AND `+expenseWithoutPayments+` AND `+expenseWithoutAttachments+` AND vencimento >= CURRENT_DATE
+	`, p["userId"], p["id"])
+	if err != nil {
+		http.Error(w, "Erro ao excluir ocorrências: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if err := history.Record(tx, apiAudit(r, models.AuditDelete)); err != nil {
+		http.Error(w, "Erro ao registrar histórico: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	result, err := tx.Exec(`
+		DELETE FROM recurring_expenses
+		WHERE user_id = $1 AND id = $2
+	`, p["userId"], p["id"])
+	if err != nil {
+		http.Error(w, "Erro ao excluir despesa recorrente: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if rowsAffected, _ := result.RowsAffected(); rowsAffected == 0 {
+		http.Error(w, "Despesa recorrente não encontrada", http.StatusNotFound)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		http.Error(w, "Erro ao excluir despesa recorrente: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(Message{Message: "Despesa recorrente excluída com sucesso"})
+}
+
+// UpdateRecurringOccurrence edita uma despesa gerada por recorrência
+//
+// @Summary Editar ocorrência recorrente
+// @Description scope=this altera só esta despesa; scope=following divide a série a partir desta
+// @Description ocorrência; scope=all altera o modelo e todas as ocorrências em aberto, exceto as
+// @Description já editadas individualmente (scope=this ou PUT /expenses/{id}).
+// @Tags Recurring Expenses
+// @Security BearerAuth
+// @Param userId path string true "ID do usuário"
+// @Param id path string true "ID da despesa"
+// @Param scope query string true "this, following ou all"
+// @Param recurring body RecurringEditInput true "Novos dados"
+// @Success 200 {object} Message
+// @Failure 400,401,404,500 {string} string
+// @Router /users/{userId}/expenses/{id}/recurrence [put]
+func UpdateRecurringOccurrence(w http.ResponseWriter, r *http.Request) {
+	p := mux.Vars(r)
+	userID := p["userId"]
+	scope := r.URL.Query().Get("scope")
+
+	var in RecurringEditInput
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		http.Error(w, "JSON inválido: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if in.Valor <= 0 {
+		http.Error(w, "Valor deve ser maior que zero", http.StatusBadRequest)
+		return
+	}
+	if !resolveEditCategory(w, userID, models.CategoryExpense, &in) {
+		return
+	}
+
+	var e models.Expense
+	err := scanExpense(db.DB.QueryRow(`
+		SELECT `+expenseColumns+`
+		FROM expenses
+		WHERE user_id = $1 AND id = $2 AND deleted_at IS NULL
+	`, userID, p["id"]), &e)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Despesa não encontrada", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Erro ao buscar despesa: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if e.RecorrenciaID == nil || e.Ocorrencia == nil {
+		http.Error(w, "Despesa não pertence a uma série recorrente", http.StatusBadRequest)
+		return
+	}
+
+	var status int
+	switch scope {
+	case ScopeThis:
+		err = updateRecurringOccurrence(apiAudit(r, models.AuditUpdate), e, in)
+		status = http.StatusInternalServerError
+	case ScopeFollowing:
+		status, err = splitRecurringSeries(apiAudit(r, models.AuditUpdate), userID, *e.RecorrenciaID, *e.Ocorrencia, in)
+	case ScopeAll:
+		_, status, err = updateRecurringSeries(apiAudit(r, models.AuditUpdate), userID, e.RecorrenciaID.String(), in)
+	default:
+		http.Error(w, "Escopo inválido: use this, following ou all", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(Message{Message: "Despesa recorrente atualizada com sucesso"})
+}
+
+// httpError é um erro cuja mensagem pode ser devolvida ao cliente
+type httpError struct {
+	msg string
+}
+
+func (e httpError) Error() string { return e.msg }
+
+// updateRecurringOccurrence altera só esta ocorrência da série (scope=this), inclusive o vencimento.
+// A ocorrência fica marcada como editada e as alterações da série (scope=all) não a sobrescrevem.
+func updateRecurringOccurrence(entry models.AuditEntry, e models.Expense, in RecurringEditInput) error {
+	vencimento := e.Vencimento
+	if in.Vencimento != nil {
+		vencimento = *in.Vencimento
+	}
+
+	tx, err := db.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	history, err := audit.Track(tx, models.AuditExpenses, "id = $1", e.ID)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`
+		UPDATE expenses
+		SET descricao = $1, valor = $2, vencimento = $3, categoria = $4, category_id = $5, observacoes = $6, editada = true
+		WHERE user_id = $7 AND id = $8
+	`, in.Descricao, in.Valor, vencimento, in.Categoria, in.CategoriaID, in.Observacoes, e.UserID, e.ID); err != nil {
+		return err
+	}
+	if err := history.Record(tx, entry); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// updateRecurringSeries aplica a alteração ao modelo e às ocorrências sem pagamentos
+// que não foram editadas uma a uma. Se a regra mudar, as ocorrências futuras em
+// aberto e não editadas são recriadas.
+func updateRecurringSeries(entry models.AuditEntry, userID, id string, in RecurringEditInput) (models.RecurringExpense, int, error) {
+	var rec models.RecurringExpense
+
+	tx, err := db.DB.Begin()
+	if err != nil {
+		return rec, http.StatusInternalServerError, err
+	}
+	defer tx.Rollback()
+
+	err = scanRecurringExpense(tx.QueryRow(`
+		SELECT `+recurringExpenseColumns+`
+		FROM recurring_expenses
+		WHERE user_id = $1 AND id = $2
+		FOR UPDATE
+	`, userID, id), &rec)
+	if err == sql.ErrNoRows {
+		return rec, http.StatusNotFound, httpError{"Despesa recorrente não encontrada"}
+	}
+	if err != nil {
+		return rec, http.StatusInternalServerError, err
+	}
+
+	ruleChanged := false
+	if in.Regra != "" {
+		rule, err := normalizeRule(in.Regra, rec.DataInicio)
+		if err != nil {
+			return rec, http.StatusBadRequest, httpError{"Regra de recorrência inválida: " + err.Error()}
+		}
+		ruleChanged = rule.String() != rec.Regra
+		rec.Regra = rule.String()
+	}
+
+	rec.Descricao = in.Descricao
+	rec.Valor = in.Valor
+	rec.Categoria = in.Categoria
+	rec.CategoriaID = in.CategoriaID
+	rec.Observacoes = in.Observacoes
+
+	_, err = tx.Exec(`
+		UPDATE recurring_expenses
+		SET descricao = $1, valor = $2, categoria = $3, category_id = $4, observacoes = $5, regra = $6
+		WHERE id = $7
+	`, rec.Descricao, rec.Valor, rec.Categoria, rec.CategoriaID, rec.Observacoes, rec.Regra, rec.ID)
+	if err != nil {
+		return rec, http.StatusInternalServerError, err
+	}
+
+	history, err := audit.Track(tx, models.AuditExpenses, "recorrencia_id = $1 AND NOT editada AND "+expenseWithoutPayments, rec.ID)
+	if err != nil {
+		return rec, http.StatusInternalServerError, err
+	}
+
+	_, err = tx.Exec(`
+		UPDATE expenses
+		SET descricao = $1, valor = $2, categoria = $3, category_id = $4, observacoes = $5
+		WHERE recorrencia_id = $6 AND NOT editada AND `+expenseWithoutPayments+`
+	`, rec.Descricao, rec.Valor, rec.Categoria, rec.CategoriaID, rec.Observacoes, rec.ID)
+	if err != nil {
+		return rec, http.StatusInternalServerError, err
+	}
+
+	if ruleChanged {
+		// recria as ocorrências a partir de hoje com a nova regra
+		if _, err := tx.Exec(`
+			DELETE FROM expenses
+			WHERE recorrencia_id = $1 AND NOT editada AND `+expenseWithoutPayments+` AND `+expenseWithoutAttachments+` AND ocorrencia >= CURRENT_DATE
+		`, rec.ID); err != nil {
+			return rec, http.StatusInternalServerError, err
+		}
+		if _, err := tx.Exec(`
+			UPDATE recurring_expenses SET gerada_ate = CURRENT_DATE - 1 WHERE id = $1 AND gerada_ate >= CURRENT_DATE
+		`, rec.ID); err != nil {
+			return rec, http.StatusInternalServerError, err
+		}
+	}
+
+	if err := history.Record(tx, entry); err != nil {
+		return rec, http.StatusInternalServerError, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return rec, http.StatusInternalServerError, err
+	}
+	if err := jobs.MaterializeRecurringExpense(rec.ID); err != nil {
+		return rec, http.StatusInternalServerError, err
+	}
+	return rec, http.StatusOK, nil
+}
+
+// splitRecurringSeries encerra a série antes de "from" e cria uma nova série a
+// partir dessa ocorrência com os novos dados ("esta e as próximas")
+func splitRecurringSeries(entry models.AuditEntry, userID string, id uuid.UUID, from time.Time, in RecurringEditInput) (int, error) {
+	tx, err := db.DB.Begin()
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+	defer tx.Rollback()
+
+	var old models.RecurringExpense
+	err = scanRecurringExpense(tx.QueryRow(`
+		SELECT `+recurringExpenseColumns+`
+		FROM recurring_expenses
+		WHERE user_id = $1 AND id = $2
+		FOR UPDATE
+	`, userID, id), &old)
+	if err == sql.ErrNoRows {
+		return http.StatusNotFound, httpError{"Despesa recorrente não encontrada"}
+	}
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+
+	oldRule, err := models.ParseRRule(old.Regra)
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+
+	newRule := oldRule
+	if in.Regra != "" {
+		if newRule, err = normalizeRule(in.Regra, from); err != nil {
+			return http.StatusBadRequest, httpError{"Regra de recorrência inválida: " + err.Error()}
+		}
+	}
+
+	// a série antiga passa a terminar no dia anterior à ocorrência editada
+	before := len(oldRule.Between(old.DataInicio, old.DataInicio, from.AddDate(0, 0, -1)))
+	if oldRule.Count > 0 && in.Regra == "" {
+		newRule.Count = oldRule.Count - before
+	}
+	until := from.AddDate(0, 0, -1)
+	oldRule.Count = 0
+	oldRule.Until = &until
+
+	next := models.RecurringExpense{
+		ID:          uuid.New(),
+		UserID:      old.UserID,
+		Descricao:   in.Descricao,
+		Valor:       in.Valor,
+		Moeda:       old.Moeda,
+		Categoria:   in.Categoria,
+		CategoriaID: in.CategoriaID,
+		Observacoes: in.Observacoes,
+		Regra:       newRule.String(),
+		DataInicio:  from,
+		Ativa:       true,
+		CreatedAt:   time.Now(),
+	}
+
+	if _, err := tx.Exec(`UPDATE recurring_expenses SET regra = $1 WHERE id = $2`, oldRule.String(), old.ID); err != nil {
+		return http.StatusInternalServerError, err
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO recurring_expenses (id, user_id, descricao, valor, moeda, categoria, category_id, observacoes, regra, data_inicio, ativa, created_at)
+		VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12)
+	`, next.ID, next.UserID, next.Descricao, next.Valor, next.Moeda, next.Categoria, next.CategoriaID, next.Observacoes, next.Regra, next.DataInicio, next.Ativa, next.CreatedAt)
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+
+	history, err := audit.Track(tx, models.AuditExpenses, "recorrencia_id = $1 AND ocorrencia >= $2", old.ID, from)
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+
+	// ocorrências sem pagamento, anexo ou edição própria são recriadas pela nova série; as demais passam a pertencer a ela
+	if _, err := tx.Exec(`
+		DELETE FROM expenses
+		WHERE recorrencia_id = $1 AND NOT editada AND `+expenseWithoutPayments+` AND `+expenseWithoutAttachments+` AND ocorrencia >= $2
+	`, old.ID, from); err != nil {
+		return http.StatusInternalServerError, err
+	}
+	if _, err := tx.Exec(`
+		UPDATE expenses SET recorrencia_id = $1
+		WHERE recorrencia_id = $2 AND ocorrencia >= $3
+	`, next.ID, old.ID, from); err != nil {
+		return http.StatusInternalServerError, err
+	}
+
+	if err := history.Record(tx, entry); err != nil {
+		return http.StatusInternalServerError, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return http.StatusInternalServerError, err
+	}
+	if err := jobs.MaterializeRecurringExpense(next.ID); err != nil {
+		return http.StatusInternalServerError, err
+	}
+	return http.StatusOK, nil
+}
@@ -0,0 +1,322 @@
+package controllers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"finance/src/audit"
+	"finance/src/db"
+	"finance/src/jobs"
+	"finance/src/models"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+const recurringIncomeColumns = `id, user_id, descricao, valor, moeda, categoria, category_id, observacoes, regra, data_inicio, gerada_ate, ativa, created_at`
+
+func scanRecurringIncome(row rowScanner, rec *models.RecurringIncome) error {
+	return row.Scan(&rec.ID, &rec.UserID, &rec.Descricao, &rec.Valor, &rec.Moeda, &rec.Categoria, &rec.CategoriaID, &rec.Observacoes,
+		&rec.Regra, &rec.DataInicio, &rec.GeradaAte, &rec.Ativa, &rec.CreatedAt)
+}
+
+// CreateRecurringIncome cria uma receita recorrente (ex: salário) e gera as receitas previstas
+//
+// @Summary Criar receita recorrente
+// @Description A regra segue o formato RRULE (RFC 5545), ex: "FREQ=MONTHLY;BYDAY=MO,TU,WE,TH,FR;BYSETPOS=5" (5º dia útil).
+// @Description Sem BYMONTHDAY, o dia vem de data_inicio e cai no último dia dos meses mais curtos (ex: 31 vira 30 em abril). Um BYMONTHDAY explícito segue o RFC 5545: meses sem o dia não têm ocorrência; use BYMONTHDAY=-1 para o último dia.
+// @Tags Recurring Incomes
+// @Security BearerAuth
+// @Param userId path string true "ID do usuário"
+// @Param recurring body models.RecurringIncome true "Modelo da receita recorrente"
+// @Success 201 {object} models.RecurringIncome
+// @Failure 400,401,500 {string} string
+// @Router /users/{userId}/recurring-incomes [post]
+func CreateRecurringIncome(w http.ResponseWriter, r *http.Request) {
+	userID, err := uuid.Parse(mux.Vars(r)["userId"])
+	if err != nil {
+		http.Error(w, "ID de usuário inválido", http.StatusBadRequest)
+		return
+	}
+
+	var rec models.RecurringIncome
+	if err := json.NewDecoder(r.Body).Decode(&rec); err != nil {
+		http.Error(w, "JSON inválido: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if rec.Valor <= 0 {
+		http.Error(w, "Valor deve ser positivo", http.StatusBadRequest)
+		return
+	}
+	if rec.Descricao == "" || rec.DataInicio.IsZero() {
+		http.Error(w, "Descrição e data de início são obrigatórias", http.StatusBadRequest)
+		return
+	}
+
+	rule, err := normalizeRule(rec.Regra, rec.DataInicio)
+	if err != nil {
+		http.Error(w, "Regra de recorrência inválida: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if rec.Moeda, err = resolveCurrency(db.DB, userID.String(), rec.Moeda); err != nil {
+		writeCurrencyError(w, err)
+		return
+	}
+	if rec.CategoriaID, rec.Categoria, err = resolveCategory(db.DB, userID.String(), models.CategoryIncome, rec.CategoriaID, rec.Categoria); err != nil {
+		writeCategoryError(w, err)
+		return
+	}
+
+	rec.ID = uuid.New()
+	rec.UserID = userID
+	rec.Regra = rule.String()
+	rec.Ativa = true
+	rec.CreatedAt = time.Now()
+
+	_, err = db.DB.Exec(`
+		INSERT INTO recurring_incomes (id, user_id, descricao, valor, moeda, categoria, category_id, observacoes, regra, data_inicio, ativa, created_at)
+		VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12)
+	`, rec.ID, rec.UserID, rec.Descricao, rec.Valor, rec.Moeda, rec.Categoria, rec.CategoriaID, rec.Observacoes, rec.Regra, rec.DataInicio, rec.Ativa, rec.CreatedAt)
+	if err != nil {
+		http.Error(w, "Erro ao criar receita recorrente: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if err := jobs.MaterializeRecurringIncome(rec.ID); err != nil {
+		http.Error(w, "Erro ao gerar receitas previstas: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(rec)
+}
+
+// ListRecurringIncomes lista as receitas recorrentes de um usuário
+//
+// @Summary Listar receitas recorrentes
+// @Tags Recurring Incomes
+// @Security BearerAuth
+// @Param userId path string true "ID do usuário"
+// @Success 200 {array} models.RecurringIncome
+// @Failure 400,401,500 {string} string
+// @Router /users/{userId}/recurring-incomes [get]
+func ListRecurringIncomes(w http.ResponseWriter, r *http.Request) {
+	userID := mux.Vars(r)["userId"]
+
+	rows, err := db.DB.Query(`
+		SELECT `+recurringIncomeColumns+`
+		FROM recurring_incomes
+		WHERE user_id = $1
+		ORDER BY descricao
+	`, userID)
+	if err != nil {
+		http.Error(w, "Erro ao buscar receitas recorrentes: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	var result []models.RecurringIncome
+	for rows.Next() {
+		var rec models.RecurringIncome
+		if err := scanRecurringIncome(rows, &rec); err != nil {
+			http.Error(w, "Erro ao ler receita recorrente: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		result = append(result, rec)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// UpdateRecurringIncome altera a receita recorrente e as receitas previstas ainda não recebidas
+//
+// @Summary Atualizar receita recorrente
+// @Tags Recurring Incomes
+// @Security BearerAuth
+// @Param userId path string true "ID do usuário"
+// @Param id path string true "ID do modelo"
+// @Param recurring body RecurringEditInput true "Novos dados da série"
+// @Success 200 {object} models.RecurringIncome
+// @Failure 400,401,404,500 {string} string
+// @Router /users/{userId}/recurring-incomes/{id} [put]
+func UpdateRecurringIncome(w http.ResponseWriter, r *http.Request) {
+	p := mux.Vars(r)
+
+	var in RecurringEditInput
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		http.Error(w, "JSON inválido: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if in.Valor <= 0 {
+		http.Error(w, "Valor deve ser positivo", http.StatusBadRequest)
+		return
+	}
+	if !resolveEditCategory(w, p["userId"], models.CategoryIncome, &in) {
+		return
+	}
+
+	tx, err := db.DB.Begin()
+	if err != nil {
+		http.Error(w, "Erro ao iniciar transação: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	var rec models.RecurringIncome
+	err = scanRecurringIncome(tx.QueryRow(`
+		SELECT `+recurringIncomeColumns+`
+		FROM recurring_incomes
+		WHERE user_id = $1 AND id = $2
+		FOR UPDATE
+	`, p["userId"], p["id"]), &rec)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Receita recorrente não encontrada", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Erro ao buscar receita recorrente: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	ruleChanged := false
+	if in.Regra != "" {
+		rule, err := normalizeRule(in.Regra, rec.DataInicio)
+		if err != nil {
+			http.Error(w, "Regra de recorrência inválida: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		ruleChanged = rule.String() != rec.Regra
+		rec.Regra = rule.String()
+	}
+
+	rec.Descricao = in.Descricao
+	rec.Valor = in.Valor
+	rec.Categoria = in.Categoria
+	rec.CategoriaID = in.CategoriaID
+	rec.Observacoes = in.Observacoes
+
+	_, err = tx.Exec(`
+		UPDATE recurring_incomes
+		SET descricao = $1, valor = $2, categoria = $3, category_id = $4, observacoes = $5, regra = $6
+		WHERE id = $7
+	`, rec.Descricao, rec.Valor, rec.Categoria, rec.CategoriaID, rec.Observacoes, rec.Regra, rec.ID)
+	if err != nil {
+		http.Error(w, "Erro ao atualizar receita recorrente: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	history, err := audit.Track(tx, models.AuditIncomes, "recorrencia_id = $1 AND status = $2", rec.ID, models.IncomeExpected)
+	if err != nil {
+		http.Error(w, "Erro ao buscar receitas previstas: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	_, err = tx.Exec(`
+		UPDATE incomes
+		SET descricao = $1, valor = $2, valor_previsto = $2, categoria = $3, category_id = $4, observacoes = $5
+		WHERE recorrencia_id = $6 AND status = $7
+	`, rec.Descricao, rec.Valor, rec.Categoria, rec.CategoriaID, rec.Observacoes, rec.ID, models.IncomeExpected)
+	if err != nil {
+		http.Error(w, "Erro ao atualizar receitas previstas: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if ruleChanged {
+		// recria as receitas previstas a partir de hoje com a nova regra
+		if _, err := tx.Exec(`
+			DELETE FROM incomes
+			WHERE recorrencia_id = $1 AND status = $2 AND ocorrencia >= CURRENT_DATE
+		`, rec.ID, models.IncomeExpected); err != nil {
+			http.Error(w, "Erro ao atualizar receitas previstas: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if _, err := tx.Exec(`
+			UPDATE recurring_incomes SET gerada_ate = CURRENT_DATE - 1 WHERE id = $1 AND gerada_ate >= CURRENT_DATE
+		`, rec.ID); err != nil {
+			http.Error(w, "Erro ao atualizar receita recorrente: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if err := history.Record(tx, apiAudit(r, models.AuditUpdate)); err != nil {
+		http.Error(w, "Erro ao registrar histórico: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		http.Error(w, "Erro ao atualizar receita recorrente: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if err := jobs.MaterializeRecurringIncome(rec.ID); err != nil {
+		http.Error(w, "Erro ao gerar receitas previstas: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(rec)
+}
+
+// DeleteRecurringIncome encerra a série e remove as receitas previstas futuras
+//
+// @Summary Excluir receita recorrente
+// @Tags Recurring Incomes
+// @Security BearerAuth
+// @Param userId path string true "ID do usuário"
+// @Param id path string true "ID do modelo"
+// @Success 200 {object} map[string]string
+// @Failure 400,401,404,500 {string} string
+// @Router /users/{userId}/recurring-incomes/{id} [delete]
+func DeleteRecurringIncome(w http.ResponseWriter, r *http.Request) {
+	p := mux.Vars(r)
+
+	tx, err := db.DB.Begin()
+	if err != nil {
+		http.Error(w, "Erro ao iniciar transação: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	where := "user_id = $1 AND recorrencia_id = $2 AND status = $3 AND data_recebimento >= CURRENT_DATE"
+	history, err := audit.Track(tx, models.AuditIncomes, where, p["userId"], p["id"], models.IncomeExpected)
+	if err != nil {
+		http.Error(w, "Erro ao buscar receitas previstas: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	_, err = tx.Exec(`DELETE FROM incomes WHERE `+where, p["userId"], p["id"], models.IncomeExpected)
+	if err != nil {
+		http.Error(w, "Erro ao excluir receitas previstas: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if err := history.Record(tx, apiAudit(r, models.AuditDelete)); err != nil {
+		http.Error(w, "Erro ao registrar histórico: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	res, err := tx.Exec(`
+		DELETE FROM recurring_incomes
+		WHERE user_id = $1 AND id = $2
+	`, p["userId"], p["id"])
+	if err != nil {
+		http.Error(w, "Erro ao excluir receita recorrente: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if rows, _ := res.RowsAffected(); rows == 0 {
+		http.Error(w, "Receita recorrente não encontrada", http.StatusNotFound)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		http.Error(w, "Erro ao excluir receita recorrente: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{"message": "Receita recorrente excluída com sucesso"})
+}
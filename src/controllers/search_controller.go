@@ -0,0 +1,200 @@
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"finance/src/db"
+	"finance/src/models"
+
+	"github.com/gorilla/mux"
+)
+
+// defaultSearchLimit e maxSearchLimit limitam quantos lançamentos a busca devolve
+const (
+	defaultSearchLimit = 50
+	maxSearchLimit     = 200
+)
+
+// searchSource descreve um tipo de lançamento pesquisável
+type searchSource struct {
+	tipo    string // expenses ou incomes, também o nome da tabela
+	data    string // coluna com a data do lançamento
+	quitado string // condição de despesa paga ou receita recebida
+}
+
+var searchSources = []searchSource{
+	{tipo: "expenses", data: "vencimento", quitado: "t.paga"},
+	{tipo: "incomes", data: "data_recebimento", quitado: "t.status = '" + models.IncomeReceived + "'"},
+}
+
+// sql seleciona os lançamentos do usuário ($1) fora da lixeira, com a situação já calculada
+func (s searchSource) sql() string {
+	return `
+		SELECT '` + s.tipo + `' AS tipo, t.id, t.descricao, COALESCE(t.categoria, '') AS categoria, t.observacoes, t.valor, t.moeda,
+			t.` + s.data + ` AS data,
+			CASE WHEN ` + s.quitado + ` THEN '` + models.SearchSettled + `'
+				WHEN t.` + s.data + ` < CURRENT_DATE THEN '` + models.SearchLate + `'
+				ELSE '` + models.SearchPending + `' END AS status,
+			busca_documento(t.descricao, t.categoria, t.observacoes) AS documento,
+			busca_texto(t.descricao, t.categoria, t.observacoes) AS texto
+		FROM ` + s.tipo + ` t
+		WHERE t.user_id = $1 AND t.deleted_at IS NULL`
+}
+
+// searchAmount interpreta o termo como valor ("150", "150.90" ou "150,90"), para encontrar lançamentos pelo valor
+func searchAmount(q string) *models.Money {
+	q = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(q), "R$"))
+	if !strings.Contains(q, ".") {
+		q = strings.Replace(q, ",", ".", 1)
+	}
+	v, err := models.ParseMoney(q)
+	if err != nil || v <= 0 {
+		return nil
+	}
+	return &v
+}
+
+// SearchTransactions busca despesas e receitas por descrição, observações, categoria e valor
+//
+// @Summary Buscar lançamentos
+// @Description Busca textual em português que ignora acentos ("medico" encontra "Médico") e tolera erros de digitação
+// @Description ("dentsta" encontra "Dentista"). Um termo numérico também encontra lançamentos com esse valor.
+// @Description Os resultados vêm do mais ao menos relevante, com os termos destacados em trecho.
+// @Tags Search
+// @Security BearerAuth
+// @Param userId path string true "ID do usuário"
+// @Param q query string true "Termos da busca; aceita frase entre aspas, OR e -termo para excluir"
+// @Param tipo query string false "expenses ou incomes (padrão: ambos)"
+// @Param de query string false "A partir da data (YYYY-MM-DD)"
+// @Param ate query string false "Até a data, inclusive (YYYY-MM-DD)"
+// @Param valor_min query string false "Valor mínimo"
+// @Param valor_max query string false "Valor máximo"
+// @Param status query string false "quitado, pendente ou atrasado"
+// @Param limit query int false "Quantidade máxima de resultados (padrão 50, máximo 200)"
+// @Success 200 {array} models.SearchResult
+// @Failure 400,401,500 {string} string
+// @Router /users/{userId}/search [get]
+func SearchTransactions(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+
+	termo := strings.TrimSpace(q.Get("q"))
+	if termo == "" {
+		http.Error(w, "Informe o termo da busca em q", http.StatusBadRequest)
+		return
+	}
+
+	tipo := q.Get("tipo")
+	var sources []string
+	for _, s := range searchSources {
+		if tipo == "" || tipo == s.tipo {
+			sources = append(sources, s.sql())
+		}
+	}
+	if len(sources) == 0 {
+		http.Error(w, "Tipo inválido: use expenses ou incomes", http.StatusBadRequest)
+		return
+	}
+
+	args := []interface{}{mux.Vars(r)["userId"], termo, searchAmount(termo)}
+	var where string
+
+	if raw := q.Get("de"); raw != "" {
+		de, err := time.Parse("2006-01-02", raw)
+		if err != nil {
+			http.Error(w, "Data inicial inválida: use o formato YYYY-MM-DD", http.StatusBadRequest)
+			return
+		}
+		args = append(args, de)
+		where += fmt.Sprintf(" AND l.data >= $%d", len(args))
+	}
+	if raw := q.Get("ate"); raw != "" {
+		ate, err := time.Parse("2006-01-02", raw)
+		if err != nil {
+			http.Error(w, "Data final inválida: use o formato YYYY-MM-DD", http.StatusBadRequest)
+			return
+		}
+		args = append(args, ate.AddDate(0, 0, 1))
+		where += fmt.Sprintf(" AND l.data < $%d", len(args))
+	}
+	if raw := q.Get("valor_min"); raw != "" {
+		v, err := models.ParseMoney(raw)
+		if err != nil {
+			http.Error(w, "valor_min inválido", http.StatusBadRequest)
+			return
+		}
+		args = append(args, v)
+		where += fmt.Sprintf(" AND l.valor >= $%d", len(args))
+	}
+	if raw := q.Get("valor_max"); raw != "" {
+		v, err := models.ParseMoney(raw)
+		if err != nil {
+			http.Error(w, "valor_max inválido", http.StatusBadRequest)
+			return
+		}
+		args = append(args, v)
+		where += fmt.Sprintf(" AND l.valor <= $%d", len(args))
+	}
+	if status := q.Get("status"); status != "" {
+		if !models.ValidSearchStatus(status) {
+			http.Error(w, "Status inválido: use quitado, pendente ou atrasado", http.StatusBadRequest)
+			return
+		}
+		args = append(args, status)
+		where += fmt.Sprintf(" AND l.status = $%d", len(args))
+	}
+
+	limit := defaultSearchLimit
+	if raw := q.Get("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 1 || n > maxSearchLimit {
+			http.Error(w, "limit inválido: use um número entre 1 e 200", http.StatusBadRequest)
+			return
+		}
+		limit = n
+	}
+	args = append(args, limit)
+
+	// encontra pelo texto (com radicais, sem acentos), por semelhança de
+	// trigramas (erros de digitação) ou pelo valor exato
+	rows, err := db.DB.Query(`
+		WITH consulta AS (
+			SELECT websearch_to_tsquery('portuguese_unaccent', $2) AS tsq, f_unaccent(lower($2)) AS termo
+		)
+		SELECT l.tipo, l.id, l.descricao, l.categoria, l.observacoes, l.valor, l.moeda, l.data, l.status,
+			ts_rank(l.documento, c.tsq) + word_similarity(c.termo, l.texto) + CASE WHEN l.valor = $3 THEN 1 ELSE 0 END AS relevancia,
+			ts_headline('portuguese_unaccent', l.descricao || COALESCE(' — ' || l.observacoes, ''), c.tsq,
+				'StartSel=<mark>, StopSel=</mark>, MaxFragments=2, FragmentDelimiter=" … "') AS trecho
+		FROM (`+strings.Join(sources, "\n\t\tUNION ALL")+`
+		) l, consulta c
+		WHERE (l.documento @@ c.tsq OR c.termo <% l.texto OR l.valor = $3)`+where+`
+		ORDER BY relevancia DESC, l.data DESC
+		LIMIT $`+strconv.Itoa(len(args)), args...)
+	if err != nil {
+		http.Error(w, "Erro ao buscar lançamentos: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	results := []models.SearchResult{}
+	for rows.Next() {
+		var s models.SearchResult
+		if err := rows.Scan(&s.Tipo, &s.ID, &s.Descricao, &s.Categoria, &s.Observacoes, &s.Valor, &s.Moeda, &s.Data, &s.Status,
+			&s.Relevancia, &s.Trecho); err != nil {
+			http.Error(w, "Erro ao ler resultado: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		results = append(results, s)
+	}
+	if err := rows.Err(); err != nil {
+		http.Error(w, "Erro ao ler resultado: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(results)
+}
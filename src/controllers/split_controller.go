@@ -0,0 +1,102 @@
+package controllers
+
+import (
+	"net/http"
+
+	"finance/src/models"
+
+	"github.com/google/uuid"
+)
+
+// splitLink descreve a tabela com as linhas da divisão de um tipo de lançamento
+type splitLink struct {
+	table  string // tabela das linhas
+	column string // coluna com o ID do lançamento
+	owner  string // tabela do lançamento
+	kind   string // expense ou income, para conferir as categorias das linhas
+}
+
+var (
+	expenseSplitLink = splitLink{table: "expense_splits", column: "expense_id", owner: "expenses", kind: models.CategoryExpense}
+	incomeSplitLink  = splitLink{table: "income_splits", column: "income_id", owner: "incomes", kind: models.CategoryIncome}
+	splitLinks       = []splitLink{expenseSplitLink, incomeSplitLink}
+)
+
+// splitsColumn devolve as linhas da divisão do lançamento corrente como array JSON, para uso nas listas de colunas
+func (l splitLink) splitsColumn() string {
+	return `COALESCE((SELECT jsonb_agg(jsonb_build_object('id', s.id, 'categoria_id', s.category_id, 'categoria', s.categoria,
+			'valor', s.valor, 'observacao', s.observacao) ORDER BY s.posicao)
+		FROM ` + l.table + ` s WHERE s.` + l.column + ` = ` + l.owner + `.id), '[]') AS divisao`
+}
+
+// set substitui as linhas da divisão de um lançamento; sem linhas o lançamento
+// deixa de ser dividido. As categorias são resolvidas como as do lançamento.
+func (l splitLink) set(q dbExecutor, userID string, ownerID uuid.UUID, splits models.Splits) (models.Splits, error) {
+	if _, err := q.Exec(`DELETE FROM `+l.table+` WHERE `+l.column+` = $1`, ownerID); err != nil {
+		return nil, err
+	}
+
+	out := make(models.Splits, 0, len(splits))
+	for i, s := range splits {
+		var err error
+		if s.CategoriaID, s.Categoria, err = resolveCategory(q, userID, l.kind, s.CategoriaID, s.Categoria); err != nil {
+			return nil, err
+		}
+		s.ID = uuid.New()
+		if _, err := q.Exec(`
+			INSERT INTO `+l.table+` (id, `+l.column+`, category_id, categoria, valor, observacao, posicao)
+			VALUES ($1, $2, $3, $4, $5, $6, $7)
+		`, s.ID, ownerID, s.CategoriaID, s.Categoria, s.Valor, s.Observacao, i); err != nil {
+			return nil, err
+		}
+		out = append(out, s)
+	}
+	return out, nil
+}
+
+// mismatch indica se o lançamento é dividido em linhas que não somam valor
+// (ex: o valor mudou numa edição que não reenviou a divisão)
+func (l splitLink) mismatch(q dbExecutor, ownerID uuid.UUID, valor models.Money) (bool, error) {
+	var differs bool
+	err := q.QueryRow(`
+		SELECT COALESCE(SUM(valor) <> $2, false) FROM `+l.table+` WHERE `+l.column+` = $1
+	`, ownerID, valor).Scan(&differs)
+	return differs, err
+}
+
+// writeSplitError responde 400 para categorias inválidas nas linhas e 500 para o resto
+func writeSplitError(w http.ResponseWriter, err error) {
+	if err == errCategoryNotFound || err == errCategoryKind {
+		http.Error(w, "Divisão: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	http.Error(w, "Erro ao salvar divisão: "+err.Error(), http.StatusInternalServerError)
+}
+
+// linesSQL devolve os lançamentos de l fora da lixeira como linhas: uma por
+// linha da divisão, com a categoria dela e proporcao = sua parte no valor, ou
+// a própria linha com proporcao 1 quando não é dividido. As colunas são as
+// usadas pelos totais por categoria. A soma das linhas é calculada por
+// lançamento (e não com uma função de janela) para que os filtros de usuário
+// e data das consultas de fora cheguem à tabela do lançamento.
+func (l splitLink) linesSQL(columns string) string {
+	return `(
+		SELECT ` + columns + `, t.valor,
+			COALESCE(s.category_id, t.category_id) AS category_id, COALESCE(s.categoria, t.categoria) AS categoria,
+			CASE WHEN s.id IS NULL THEN 1
+				ELSE s.valor / (SELECT SUM(st.valor) FROM ` + l.table + ` st WHERE st.` + l.column + ` = t.id) END AS proporcao
+		FROM ` + l.owner + ` t
+		LEFT JOIN ` + l.table + ` s ON s.` + l.column + ` = t.id
+		WHERE t.deleted_at IS NULL
+	)`
+}
+
+// netExpenseLinesSQL é netExpensesSQL por linha da divisão: o valor líquido
+// dos estornos é repartido entre as linhas na proporção de cada uma. É a
+// fonte dos totais de despesas por categoria (gráficos, orçamentos e envelopes).
+var netExpenseLinesSQL = convertedValueSQL(expenseSplitLink.linesSQL(`t.id, t.user_id, t.moeda, t.vencimento, t.paga`),
+	"vencimento", "(t.valor - "+expenseRefundedSQL("t.id")+") * t.proporcao")
+
+// incomeLinesSQL é convertedSQL(liveIncomes, ...) por linha da divisão, para os totais de receitas por categoria
+var incomeLinesSQL = convertedValueSQL(incomeSplitLink.linesSQL(`t.id, t.user_id, t.moeda, t.data_recebimento, t.status`),
+	"data_recebimento", "t.valor * t.proporcao")
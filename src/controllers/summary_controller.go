@@ -11,6 +11,7 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
+	"github.com/lib/pq"
 )
 
 // GetMonthlySummary retorna o resumo mensal de despesas e receitas de um usuário
@@ -55,26 +56,61 @@ func GetMonthlySummary(w http.ResponseWriter, r *http.Request) {
 	summary.Mes = month
 	summary.Ano = year
 
-	// Consulta para obter o resumo mensal
+	// Consulta para obter o resumo mensal, com valores convertidos para a moeda base
 	query := `
-		SELECT
-			COALESCE(SUM(valor),0) AS total_despesas,
-			COALESCE(SUM(valor),0) FILTER (WHERE paga=true)                          AS total_pagas,
-			COALESCE(SUM(valor),0) FILTER (WHERE paga=false AND vencimento>NOW())    AS pendentes,
-			COALESCE(SUM(valor),0) FILTER (WHERE paga=false AND vencimento<NOW())    AS total_vencidas,
-			(SELECT COALESCE(SUM(valor),0)
-			 FROM incomes
-			 WHERE user_id=$1 AND data_recebimento >= $2 AND data_recebimento < $3) AS receitas
-		FROM expenses
-		WHERE user_id=$1 AND vencimento >= $2 AND vencimento < $3;
+		WITH ex AS (
+			SELECT x.*, COALESCE(p.pago,0) AS pago, COALESCE(p.principal,0) AS principal
+			FROM ` + netExpensesSQL + ` x
+			LEFT JOIN LATERAL (
+				SELECT SUM(valor) AS pago, SUM(valor - multa - juros + desconto) AS principal
+				FROM expense_payments
+				WHERE expense_id = x.id
+			) p ON true
+			WHERE x.user_id=$1 AND x.vencimento >= $2 AND x.vencimento < $3
+		), inc AS (
+			SELECT x.*
+			FROM ` + convertedSQL(liveIncomes, "data_recebimento") + ` x
+			WHERE x.user_id=$1 AND x.data_recebimento >= $2 AND x.data_recebimento < $3
+		), e AS (
+			SELECT
+				COALESCE(SUM(valor_base),0)                                                                     AS total_despesas,
+				COALESCE(SUM(pago * fator),0)                                                                   AS total_pagas,
+				COALESCE(SUM((valor - principal) * fator) FILTER (WHERE NOT paga AND vencimento>=CURRENT_DATE),0) AS pendentes,
+				COALESCE(SUM((valor - principal) * fator) FILTER (WHERE NOT paga AND vencimento<CURRENT_DATE),0)  AS total_vencidas
+			FROM ex
+		), i AS (
+			SELECT
+				COALESCE(SUM(valor_base) FILTER (WHERE status='recebida'),0)                                     AS receitas,
+				COALESCE(SUM(valor_base) FILTER (WHERE status='prevista'),0)                                     AS receitas_previstas,
+				COALESCE(SUM(valor_base) FILTER (WHERE status='prevista' AND data_recebimento<CURRENT_DATE),0) AS receitas_atrasadas
+			FROM inc
+		), x AS (
+			SELECT moeda, moeda_base, taxa_data, fator FROM ex
+			UNION ALL
+			SELECT moeda, moeda_base, taxa_data, fator FROM inc
+		), c AS (
+			SELECT
+				` + ratesUsedSQL + ` AS taxas,
+				` + missingRatesSQL + ` AS sem_taxa
+			FROM x
+		)
+		SELECT e.total_despesas, e.total_pagas, e.pendentes, e.total_vencidas,
+			i.receitas, i.receitas_previstas, i.receitas_atrasadas,
+			(SELECT moeda_base FROM users WHERE id=$1), c.taxas, c.sem_taxa
+		FROM e, i, c;
 	`
 
 	err = db.DB.QueryRow(query, userID, startDate, endDate).Scan(
+		&summary.TotalDespesas,
 		&summary.TotalPagas,
 		&summary.Pendentes,
 		&summary.TotalVencidas,
-		&summary.TotalDespesas,
 		&summary.Receitas,
+		&summary.ReceitasPrevistas,
+		&summary.ReceitasAtrasadas,
+		&summary.MoedaBase,
+		&summary.TaxasUtilizadas,
+		pq.Array(&summary.MoedasSemTaxa),
 	)
 	if err != nil && err != sql.ErrNoRows {
 		http.Error(w, "Erro ao buscar resumo: "+err.Error(), http.StatusInternalServerError)
@@ -82,6 +118,7 @@ func GetMonthlySummary(w http.ResponseWriter, r *http.Request) {
 	}
 
 	summary.Saldo = summary.Receitas - summary.TotalDespesas // Calcular o saldo
+	summary.SaldoProjetado = summary.Saldo + summary.ReceitasPrevistas
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
@@ -0,0 +1,339 @@
+package controllers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"finance/src/db"
+	"finance/src/models"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/lib/pq"
+)
+
+// tagLink descreve a tabela que liga um tipo de lançamento às tags
+type tagLink struct {
+	table  string // tabela de vínculo
+	column string // coluna com o ID do lançamento
+	owner  string // tabela do lançamento
+}
+
+var (
+	expenseTagLink = tagLink{table: "expense_tags", column: "expense_id", owner: "expenses"}
+	incomeTagLink  = tagLink{table: "income_tags", column: "income_id", owner: "incomes"}
+)
+
+// tagsColumn devolve as tags do lançamento corrente como array ordenado, para uso nas listas de colunas
+func (l tagLink) tagsColumn() string {
+	return `ARRAY(SELECT t.nome FROM ` + l.table + ` lt JOIN tags t ON t.id = lt.tag_id
+		WHERE lt.` + l.column + ` = ` + l.owner + `.id ORDER BY t.nome) AS tags`
+}
+
+// filterSQL exige que o lançamento tenha todas as tags do parâmetro $arg
+func (l tagLink) filterSQL(ownerRef string, arg int) string {
+	return fmt.Sprintf(` AND (
+		SELECT COUNT(*) FROM %s lt JOIN tags t ON t.id = lt.tag_id
+		WHERE lt.%s = %s AND t.nome = ANY($%d)
+	) = cardinality($%d::text[])`, l.table, l.column, ownerRef, arg, arg)
+}
+
+// set substitui as tags de um lançamento, criando as que ainda não existem
+func (l tagLink) set(q dbExecutor, userID string, ownerID uuid.UUID, nomes []string) ([]string, error) {
+	nomes = models.NormalizeTags(nomes)
+
+	if _, err := q.Exec(`DELETE FROM `+l.table+` WHERE `+l.column+` = $1`, ownerID); err != nil {
+		return nil, err
+	}
+	for _, nome := range nomes {
+		var tagID uuid.UUID
+		err := q.QueryRow(`
+			INSERT INTO tags (id, user_id, nome, created_at)
+			VALUES ($1, $2, $3, $4)
+			ON CONFLICT (user_id, nome) DO UPDATE SET nome = EXCLUDED.nome
+			RETURNING id
+		`, uuid.New(), userID, nome, time.Now()).Scan(&tagID)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := q.Exec(`INSERT INTO `+l.table+` (`+l.column+`, tag_id) VALUES ($1, $2)`, ownerID, tagID); err != nil {
+			return nil, err
+		}
+	}
+	return nomes, nil
+}
+
+// tagFilter lê os parâmetros ?tag= repetidos; o lançamento precisa ter todas as tags
+func tagFilter(r *http.Request) []string {
+	return models.NormalizeTags(r.URL.Query()["tag"])
+}
+
+// CreateTag cria uma tag
+//
+// @Summary Criar tag
+// @Tags Tags
+// @Security BearerAuth
+// @Param userId path string true "ID do usuário"
+// @Param tag body models.Tag true "Dados da tag"
+// @Success 201 {object} models.Tag
+// @Failure 400,401,409,500 {string} string
+// @Router /users/{userId}/tags [post]
+func CreateTag(w http.ResponseWriter, r *http.Request) {
+	userID, err := uuid.Parse(mux.Vars(r)["userId"])
+	if err != nil {
+		http.Error(w, "ID de usuário inválido", http.StatusBadRequest)
+		return
+	}
+
+	var tag models.Tag
+	if err := json.NewDecoder(r.Body).Decode(&tag); err != nil {
+		http.Error(w, "Erro ao decodificar JSON", http.StatusBadRequest)
+		return
+	}
+	tag.Nome = models.NormalizeTag(tag.Nome)
+	if tag.Nome == "" {
+		http.Error(w, "Nome da tag é obrigatório", http.StatusBadRequest)
+		return
+	}
+	if tag.Cor != nil && !models.ValidCategoryColor(*tag.Cor) {
+		http.Error(w, "Cor inválida: use o formato #RRGGBB", http.StatusBadRequest)
+		return
+	}
+
+	tag.ID = uuid.New()
+	tag.UserID = userID
+	tag.Usos = 0
+	tag.CreatedAt = time.Now()
+
+	_, err = db.DB.Exec(`
+		INSERT INTO tags (id, user_id, nome, cor, created_at)
+		VALUES ($1, $2, $3, $4, $5)
+	`, tag.ID, tag.UserID, tag.Nome, tag.Cor, tag.CreatedAt)
+	if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+		http.Error(w, "Já existe uma tag com esse nome", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Erro ao criar tag: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(tag)
+}
+
+// ListTags lista as tags do usuário com a quantidade de lançamentos de cada uma
+//
+// @Summary Listar tags
+// @Tags Tags
+// @Security BearerAuth
+// @Param userId path string true "ID do usuário"
+// @Success 200 {array} models.Tag
+// @Failure 400,401,500 {string} string
+// @Router /users/{userId}/tags [get]
+func ListTags(w http.ResponseWriter, r *http.Request) {
+	userID := mux.Vars(r)["userId"]
+
+	rows, err := db.DB.Query(`
+		SELECT t.id, t.user_id, t.nome, t.cor, t.created_at,
+			(SELECT COUNT(*) FROM expense_tags et JOIN expenses e ON e.id = et.expense_id WHERE et.tag_id = t.id AND e.deleted_at IS NULL) +
+			(SELECT COUNT(*) FROM income_tags it JOIN incomes i ON i.id = it.income_id WHERE it.tag_id = t.id AND i.deleted_at IS NULL) AS usos
+		FROM tags t
+		WHERE t.user_id = $1
+		ORDER BY t.nome
+	`, userID)
+	if err != nil {
+		http.Error(w, "Erro ao buscar tags: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	var tags []models.Tag
+	for rows.Next() {
+		var tag models.Tag
+		if err := rows.Scan(&tag.ID, &tag.UserID, &tag.Nome, &tag.Cor, &tag.CreatedAt, &tag.Usos); err != nil {
+			http.Error(w, "Erro ao ler tag: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		tags = append(tags, tag)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(tags)
+}
+
+// UpdateTag renomeia ou muda a cor de uma tag
+//
+// @Summary Atualizar tag
+// @Tags Tags
+// @Security BearerAuth
+// @Param userId path string true "ID do usuário"
+// @Param id path string true "ID da tag"
+// @Param tag body models.Tag true "Novo nome e cor"
+// @Success 200 {object} models.Tag
+// @Failure 400,401,404,409,500 {string} string
+// @Router /users/{userId}/tags/{id} [put]
+func UpdateTag(w http.ResponseWriter, r *http.Request) {
+	p := mux.Vars(r)
+
+	var in models.Tag
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		http.Error(w, "Erro ao decodificar JSON", http.StatusBadRequest)
+		return
+	}
+	in.Nome = models.NormalizeTag(in.Nome)
+	if in.Nome == "" {
+		http.Error(w, "Nome da tag é obrigatório", http.StatusBadRequest)
+		return
+	}
+	if in.Cor != nil && !models.ValidCategoryColor(*in.Cor) {
+		http.Error(w, "Cor inválida: use o formato #RRGGBB", http.StatusBadRequest)
+		return
+	}
+
+	var tag models.Tag
+	err := db.DB.QueryRow(`
+		UPDATE tags SET nome = $1, cor = $2
+		WHERE user_id = $3 AND id = $4
+		RETURNING id, user_id, nome, cor, created_at,
+			(SELECT COUNT(*) FROM expense_tags et JOIN expenses e ON e.id = et.expense_id WHERE et.tag_id = tags.id AND e.deleted_at IS NULL) +
+			(SELECT COUNT(*) FROM income_tags it JOIN incomes i ON i.id = it.income_id WHERE it.tag_id = tags.id AND i.deleted_at IS NULL)
+	`, in.Nome, in.Cor, p["userId"], p["id"]).Scan(&tag.ID, &tag.UserID, &tag.Nome, &tag.Cor, &tag.CreatedAt, &tag.Usos)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Tag não encontrada", http.StatusNotFound)
+		return
+	}
+	if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+		http.Error(w, "Já existe uma tag com esse nome", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Erro ao atualizar tag: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(tag)
+}
+
+// DeleteTag exclui uma tag e a remove de todos os lançamentos
+//
+// @Summary Excluir tag
+// @Tags Tags
+// @Security BearerAuth
+// @Param userId path string true "ID do usuário"
+// @Param id path string true "ID da tag"
+// @Success 200 {object} Message
+// @Failure 400,401,404,500 {string} string
+// @Router /users/{userId}/tags/{id} [delete]
+func DeleteTag(w http.ResponseWriter, r *http.Request) {
+	p := mux.Vars(r)
+
+	result, err := db.DB.Exec(`
+		DELETE FROM tags
+		WHERE user_id = $1 AND id = $2
+	`, p["userId"], p["id"])
+	if err != nil {
+		http.Error(w, "Erro ao excluir tag: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if rowsAffected, _ := result.RowsAffected(); rowsAffected == 0 {
+		http.Error(w, "Tag não encontrada", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(Message{Message: "Tag excluída com sucesso"})
+}
+
+// TagChart é o total de uma tag no período, na moeda base do usuário.
+// Um lançamento com várias tags entra no total de cada uma delas.
+type TagChart struct {
+	TagID           uuid.UUID        `json:"tag_id"`
+	Tag             string           `json:"tag"`
+	Cor             *string          `json:"cor,omitempty"`
+	Total           models.Money     `json:"total"`
+	TaxasUtilizadas models.RatesUsed `json:"taxas_utilizadas,omitempty"`
+	MoedasSemTaxa   []string         `json:"moedas_sem_taxa,omitempty"`
+}
+
+// tagChart soma os lançamentos de source por tag
+func tagChart(w http.ResponseWriter, r *http.Request, link tagLink, source, where string) {
+	userID := mux.Vars(r)["userId"]
+
+	month, err1 := strconv.Atoi(r.URL.Query().Get("month"))
+	year, err2 := strconv.Atoi(r.URL.Query().Get("year"))
+	if err1 != nil || err2 != nil || month < 1 || month > 12 {
+		http.Error(w, "Parâmetros de mês/ano inválidos", http.StatusBadRequest)
+		return
+	}
+
+	start := time.Date(year, time.Month(month), 1, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(0, 1, 0)
+
+	rows, err := db.DB.Query(`
+		SELECT t.id, t.nome, t.cor, COALESCE(SUM(x.valor_base), 0) AS total, `+ratesUsedSQL+`, `+missingRatesSQL+`
+		FROM `+source+` x
+		JOIN `+link.table+` lt ON lt.`+link.column+` = x.id
+		JOIN tags t ON t.id = lt.tag_id
+		WHERE `+where+`
+		GROUP BY t.id, t.nome, t.cor
+		ORDER BY total DESC
+	`, userID, start, end)
+	if err != nil {
+		http.Error(w, "Erro ao buscar dados", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	var result []TagChart
+	for rows.Next() {
+		var row TagChart
+		if err := rows.Scan(&row.TagID, &row.Tag, &row.Cor, &row.Total, &row.TaxasUtilizadas, pq.Array(&row.MoedasSemTaxa)); err != nil {
+			http.Error(w, "Erro ao processar dados", http.StatusInternalServerError)
+			return
+		}
+		result = append(result, row)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// GetExpensesByTag retorna soma das despesas agrupadas por tag
+//
+// @Summary Despesas por tag
+// @Tags Charts
+// @Security BearerAuth
+// @Param userId path string true "ID do usuário"
+// @Param month query string true "Mês (1-12)"
+// @Param year query string true "Ano (YYYY)"
+// @Success 200 {array} TagChart
+// @Failure 400,401,500 {string} string
+// @Router /charts/expenses-by-tag/{userId} [get]
+func GetExpensesByTag(w http.ResponseWriter, r *http.Request) {
+	tagChart(w, r, expenseTagLink, netExpensesSQL,
+		`x.user_id = $1 AND x.vencimento >= $2 AND x.vencimento < $3`)
+}
+
+// GetIncomesByTag retorna soma das receitas recebidas agrupadas por tag
+//
+// @Summary Receitas por tag
+// @Tags Charts
+// @Security BearerAuth
+// @Param userId path string true "ID do usuário"
+// @Param month query string true "Mês (1-12)"
+// @Param year query string true "Ano (YYYY)"
+// @Success 200 {array} TagChart
+// @Failure 400,401,500 {string} string
+// @Router /charts/incomes-by-tag/{userId} [get]
+func GetIncomesByTag(w http.ResponseWriter, r *http.Request) {
+	tagChart(w, r, incomeTagLink, convertedSQL(liveIncomes, "data_recebimento"),
+		`x.user_id = $1 AND x.status = 'recebida' AND x.data_recebimento >= $2 AND x.data_recebimento < $3`)
+}
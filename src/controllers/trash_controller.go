@@ -0,0 +1,209 @@
+package controllers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+
+	"finance/src/audit"
+	"finance/src/db"
+	"finance/src/jobs"
+	"finance/src/models"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/lib/pq"
+)
+
+// liveExpenses e liveIncomes são as despesas e receitas fora da lixeira, para
+// usar no lugar das tabelas nos totais e gráficos (ex: convertedSQL(liveIncomes, ...))
+const (
+	liveExpenses = `(SELECT * FROM expenses WHERE deleted_at IS NULL)`
+	liveIncomes  = `(SELECT * FROM incomes WHERE deleted_at IS NULL)`
+)
+
+// ListTrash lista os itens na lixeira do usuário, dos excluídos mais recentemente aos mais antigos
+//
+// @Summary Listar lixeira
+// @Description Despesas, receitas e categorias excluídas ficam na lixeira por TRASH_RETENTION_DAYS dias (padrão 30)
+// @Description e depois são apagadas de vez; expira_em indica quando.
+// @Tags Trash
+// @Security BearerAuth
+// @Param userId path string true "ID do usuário"
+// @Param tipo query string false "expenses, incomes ou categories"
+// @Success 200 {array} models.TrashItem
+// @Failure 400,401,500 {string} string
+// @Router /users/{userId}/trash [get]
+func ListTrash(w http.ResponseWriter, r *http.Request) {
+	userID := mux.Vars(r)["userId"]
+
+	tipo := r.URL.Query().Get("tipo")
+	if tipo != "" && !models.ValidTrashType(tipo) {
+		http.Error(w, "Tipo inválido: use expenses, incomes ou categories", http.StatusBadRequest)
+		return
+	}
+
+	rows, err := db.DB.Query(`
+		SELECT tipo, id, descricao, valor, moeda, data, deleted_at
+		FROM (
+			SELECT 'expenses' AS tipo, id, descricao, valor, moeda, vencimento AS data, deleted_at
+			FROM expenses
+			WHERE user_id = $1 AND deleted_at IS NOT NULL
+			UNION ALL
+			SELECT 'incomes', id, descricao, valor, moeda, data_recebimento, deleted_at
+			FROM incomes
+			WHERE user_id = $1 AND deleted_at IS NOT NULL
+			UNION ALL
+			SELECT 'categories', id, name, NULL, NULL, NULL, deleted_at
+			FROM categories
+			WHERE user_id = $1 AND deleted_at IS NOT NULL
+		) lixeira
+		WHERE $2 = '' OR tipo = $2
+		ORDER BY deleted_at DESC, descricao
+	`, userID, tipo)
+	if err != nil {
+		http.Error(w, "Erro ao buscar lixeira: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	retention := jobs.TrashRetention()
+	items := []models.TrashItem{}
+	for rows.Next() {
+		var it models.TrashItem
+		if err := rows.Scan(&it.Tipo, &it.ID, &it.Descricao, &it.Valor, &it.Moeda, &it.Data, &it.ExcluidoEm); err != nil {
+			http.Error(w, "Erro ao ler lixeira: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		it.ExpiraEm = it.ExcluidoEm.Add(retention)
+		items = append(items, it)
+	}
+	if err := rows.Err(); err != nil {
+		http.Error(w, "Erro ao ler lixeira: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(items)
+}
+
+// RestoreTrashItem tira uma despesa, receita ou categoria da lixeira
+//
+// @Summary Restaurar item da lixeira
+// @Description Uma categoria volta junto com as subcategorias excluídas com ela; se o pai ainda estiver na lixeira,
+// @Description ela volta para a raiz. Falha com 409 se outra categoria já usa o nome.
+// @Tags Trash
+// @Security BearerAuth
+// @Param userId path string true "ID do usuário"
+// @Param tipo path string true "expenses, incomes ou categories"
+// @Param id path string true "ID do item"
+// @Success 200 {object} Message
+// @Failure 400,401,404,409,500 {string} string
+// @Router /users/{userId}/trash/{tipo}/{id}/restore [post]
+func RestoreTrashItem(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	userID := params["userId"]
+	tipo := params["tipo"]
+
+	if !models.ValidTrashType(tipo) {
+		http.Error(w, "Tipo inválido: use expenses, incomes ou categories", http.StatusBadRequest)
+		return
+	}
+	id, err := uuid.Parse(params["id"])
+	if err != nil {
+		http.Error(w, "ID inválido", http.StatusBadRequest)
+		return
+	}
+
+	tx, err := db.DB.Begin()
+	if err != nil {
+		http.Error(w, "Erro ao iniciar transação: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	// para categorias, acompanha toda a lixeira do usuário: só as restauradas junto entram no histórico
+	where := "user_id = $1 AND id = $2 AND deleted_at IS NOT NULL"
+	args := []interface{}{userID, id}
+	if tipo == models.TrashCategories {
+		where, args = "user_id = $1 AND deleted_at IS NOT NULL", args[:1]
+	}
+	history, err := audit.Track(tx, tipo, where, args...)
+	if err != nil {
+		http.Error(w, "Erro ao restaurar item: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	var restored int64
+	if tipo == models.TrashCategories {
+		restored, err = restoreCategory(tx, userID, id)
+	} else {
+		var result sql.Result
+		result, err = tx.Exec(`
+			UPDATE `+tipo+` SET deleted_at = NULL
+			WHERE user_id = $1 AND id = $2 AND deleted_at IS NOT NULL
+		`, userID, id)
+		if err == nil {
+			restored, _ = result.RowsAffected()
+		}
+	}
+	if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+		http.Error(w, "Já existe uma categoria com esse nome: renomeie-a antes de restaurar", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Erro ao restaurar item: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if restored == 0 {
+		http.Error(w, "Item não encontrado na lixeira", http.StatusNotFound)
+		return
+	}
+
+	if err := history.Record(tx, apiAudit(r, models.AuditRestore)); err != nil {
+		http.Error(w, "Erro ao registrar histórico: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		http.Error(w, "Erro ao restaurar item: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(Message{Message: "Item restaurado com sucesso"})
+}
+
+// restoreCategory restaura a categoria e as subcategorias que foram para a
+// lixeira junto com ela (mesmo deleted_at). Devolve quantas foram restauradas.
+func restoreCategory(q dbExecutor, userID string, id uuid.UUID) (int64, error) {
+	// se o pai continua na lixeira a categoria volta para a raiz
+	result, err := q.Exec(`
+		UPDATE categories c
+		SET parent_id = CASE WHEN EXISTS (
+				SELECT 1 FROM categories p WHERE p.id = c.parent_id AND p.deleted_at IS NOT NULL
+			) THEN NULL ELSE c.parent_id END
+		WHERE c.user_id = $1 AND c.id = $2 AND c.deleted_at IS NOT NULL
+	`, userID, id)
+	if err != nil {
+		return 0, err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return 0, nil
+	}
+
+	result, err = q.Exec(`
+		WITH RECURSIVE sub AS (
+			SELECT id, deleted_at FROM categories WHERE id = $1
+			UNION ALL
+			SELECT c.id, c.deleted_at FROM categories c JOIN sub s ON c.parent_id = s.id
+			WHERE c.deleted_at = s.deleted_at
+		)
+		UPDATE categories SET deleted_at = NULL
+		WHERE id IN (SELECT id FROM sub)
+	`, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
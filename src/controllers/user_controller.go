@@ -8,6 +8,7 @@ import (
 	"net/http"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 
 	"database/sql"
@@ -19,6 +20,13 @@ import (
 	"github.com/gorilla/mux"
 )
 
+// userColumns são as colunas de users lidas por scanUser, na mesma ordem
+const userColumns = `id, name, email, moeda_base, locale, created_at, modo_orcamento, to_char(envelope_inicio, 'YYYY-MM')`
+
+func scanUser(row rowScanner, u *models.User) error {
+	return row.Scan(&u.ID, &u.Name, &u.Email, &u.MoedaBase, &u.Locale, &u.CreatedAt, &u.ModoOrcamento, &u.EnvelopeInicio)
+}
+
 func CreateUser(w http.ResponseWriter, r *http.Request) {
 	var user models.User
 	if err := json.NewDecoder(r.Body).Decode(&user); err != nil {
@@ -31,6 +39,21 @@ func CreateUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if user.MoedaBase == "" {
+		user.MoedaBase = models.DefaultCurrency
+	}
+	user.MoedaBase = strings.ToUpper(user.MoedaBase)
+	if !models.ValidCurrency(user.MoedaBase) {
+		http.Error(w, "Moeda base inválida: use o código ISO 4217 (ex: BRL, USD)", http.StatusBadRequest)
+		return
+	}
+
+	// Sem locale no corpo, usa o idioma do navegador (Accept-Language)
+	if user.Locale == "" {
+		user.Locale = r.Header.Get("Accept-Language")
+	}
+	user.Locale = models.NormalizeLocale(user.Locale)
+
 	// Gerar o hash da senha
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(user.Password), bcrypt.DefaultCost)
 	if err != nil {
@@ -41,17 +64,36 @@ func CreateUser(w http.ResponseWriter, r *http.Request) {
 	user.ID = uuid.New()
 	user.PasswordHash = string(hashedPassword)
 	user.CreatedAt = time.Now()
+	user.ModoOrcamento = models.BudgetModeCategories
+	user.EnvelopeInicio = nil
+
+	tx, err := db.DB.Begin()
+	if err != nil {
+		http.Error(w, "Erro ao iniciar transação: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
 
-	_, err = db.DB.Exec(`
-		INSERT INTO users (id, name, email, password_hash, created_at)
-		VALUES ($1, $2, $3, $4, $5)
-	`, user.ID, user.Name, user.Email, user.PasswordHash, user.CreatedAt)
+	_, err = tx.Exec(`
+		INSERT INTO users (id, name, email, password_hash, moeda_base, locale, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`, user.ID, user.Name, user.Email, user.PasswordHash, user.MoedaBase, user.Locale, user.CreatedAt)
 
 	if err != nil {
 		http.Error(w, "Erro ao salvar usuário: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
 
+	if err := seedDefaultCategories(tx, user.ID, user.Locale); err != nil {
+		http.Error(w, "Erro ao criar categorias padrão: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		http.Error(w, "Erro ao salvar usuário: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
 	// limpar a senha do retorno
 	user.Password = ""
 
@@ -148,11 +190,11 @@ func GetUserById(w http.ResponseWriter, r *http.Request) {
 	id := mux.Vars(r)["id"]
 
 	var user models.User
-	err := db.DB.QueryRow(`
-		SELECT id, name, email, created_at
+	err := scanUser(db.DB.QueryRow(`
+		SELECT `+userColumns+`
 		FROM users
 		WHERE id = $1
-	`, id).Scan(&user.ID, &user.Name, &user.Email, &user.CreatedAt)
+	`, id), &user)
 
 	if err == sql.ErrNoRows {
 		http.Error(w, "Usuário não encontrado", http.StatusNotFound)
@@ -167,6 +209,113 @@ func GetUserById(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(user)
 }
 
+// UpdateUserCurrency altera a moeda base usada em resumos e gráficos
+//
+// @Summary	Alterar moeda base
+// @Tags	Users
+// @Security BearerAuth
+// @Param	id	path	string	true	"ID do usuário"
+// @Param	body	body	object{moeda_base=string}	true	"Código ISO 4217"
+// @Success	200	{object}	models.User
+// @Failure	400,404,500	{string}	string
+// @Router	/users/{id}/currency [patch]
+func UpdateUserCurrency(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	var body struct {
+		MoedaBase string `json:"moeda_base"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, "Erro ao decodificar JSON", http.StatusBadRequest)
+		return
+	}
+	moeda := strings.ToUpper(body.MoedaBase)
+	if !models.ValidCurrency(moeda) {
+		http.Error(w, "Moeda base inválida: use o código ISO 4217 (ex: BRL, USD)", http.StatusBadRequest)
+		return
+	}
+
+	var user models.User
+	err := scanUser(db.DB.QueryRow(`
+		UPDATE users SET moeda_base = $1
+		WHERE id = $2
+		RETURNING `+userColumns, moeda, id), &user)
+
+	if err == sql.ErrNoRows {
+		http.Error(w, "Usuário não encontrado", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Erro ao atualizar usuário: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(user)
+}
+
+// UpdateUserBudgetMode liga ou desliga o orçamento base zero com envelopes
+//
+// @Summary	Alterar modo de orçamento
+// @Description	Ao ativar envelopes, inicio (YYYY-MM) define o primeiro mês considerado; o padrão é o mês corrente,
+// @Description	ou o início já registrado se o modo for reativado.
+// @Tags	Users
+// @Security BearerAuth
+// @Param	id	path	string	true	"ID do usuário"
+// @Param	body	body	object{modo_orcamento=string,inicio=string}	true	"categorias ou envelopes"
+// @Success	200	{object}	models.User
+// @Failure	400,404,500	{string}	string
+// @Router	/users/{id}/budget-mode [patch]
+func UpdateUserBudgetMode(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	var body struct {
+		ModoOrcamento string `json:"modo_orcamento"`
+		Inicio        string `json:"inicio"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, "Erro ao decodificar JSON", http.StatusBadRequest)
+		return
+	}
+	if body.ModoOrcamento != models.BudgetModeCategories && body.ModoOrcamento != models.BudgetModeEnvelopes {
+		http.Error(w, "Modo de orçamento inválido: use categorias ou envelopes", http.StatusBadRequest)
+		return
+	}
+
+	var inicio *time.Time
+	if body.Inicio != "" {
+		mes, err := models.ParseBudgetMonth(body.Inicio)
+		if err != nil {
+			http.Error(w, "Mês inicial inválido: use o formato YYYY-MM", http.StatusBadRequest)
+			return
+		}
+		inicio = &mes
+	} else if body.ModoOrcamento == models.BudgetModeEnvelopes {
+		now := time.Now()
+		mes := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+		inicio = &mes
+	}
+
+	// sem inicio explícito, preserva o início de uma ativação anterior
+	var user models.User
+	err := scanUser(db.DB.QueryRow(`
+		UPDATE users
+		SET modo_orcamento = $1,
+			envelope_inicio = CASE WHEN $3 THEN $2::date ELSE COALESCE(envelope_inicio, $2::date) END
+		WHERE id = $4
+		RETURNING `+userColumns, body.ModoOrcamento, inicio, body.Inicio != "", id), &user)
+
+	if err == sql.ErrNoRows {
+		http.Error(w, "Usuário não encontrado", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Erro ao atualizar usuário: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(user)
+}
+
 // RefreshToken renova o token de acesso usando o refresh token
 //
 // @Summary	Revalidar token
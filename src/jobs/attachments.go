@@ -0,0 +1,53 @@
+package jobs
+
+import (
+	"context"
+	"database/sql"
+	"log"
+
+	"finance/src/db"
+	"finance/src/storage"
+)
+
+// LockBlob trava a chave do blob até o fim da transação. Quem grava um anexo e
+// quem apaga o blob sem uso seguram a mesma trava: o blob não some entre o Put
+// de um envio e o INSERT que passa a usá-lo.
+func LockBlob(tx *sql.Tx, key string) error {
+	_, err := tx.Exec(`SELECT pg_advisory_xact_lock(hashtext($1))`, key)
+	return err
+}
+
+// RemoveUnreferencedBlobs apaga do armazenamento os blobs que nenhum anexo
+// usa mais. Falhas só são registradas: o registro no banco já foi removido.
+func RemoveUnreferencedBlobs(ctx context.Context, keys []string) {
+	for _, key := range keys {
+		if err := removeUnreferencedBlob(ctx, key); err != nil {
+			log.Println("Erro ao remover anexo", key+":", err)
+		}
+	}
+}
+
+func removeUnreferencedBlob(ctx context.Context, key string) error {
+	tx, err := db.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := LockBlob(tx, key); err != nil {
+		return err
+	}
+	var inUse bool
+	if err := tx.QueryRow(`
+		SELECT EXISTS (SELECT 1 FROM expense_attachments WHERE chave = $1)
+	`, key).Scan(&inUse); err != nil {
+		return err
+	}
+	if inUse {
+		return nil
+	}
+	if err := storage.Blobs.Delete(ctx, key); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
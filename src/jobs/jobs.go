@@ -0,0 +1,31 @@
+package jobs
+
+import (
+	"log"
+	"time"
+)
+
+// Start inicia os jobs em segundo plano
+func Start() {
+	go every(time.Hour, "materializar despesas recorrentes", MaterializeRecurringExpenses)
+	go every(time.Hour, "materializar receitas recorrentes", MaterializeRecurringIncomes)
+	go every(6*time.Hour, "registrar patrimônio", SnapshotAllNetWorth)
+	go every(time.Hour, "enviar lembretes de vencimento", SendBillReminders)
+	go every(24*time.Hour, "esvaziar lixeira", PurgeTrash)
+}
+
+// every executa fn imediatamente e depois a cada intervalo, registrando erros no log
+func every(interval time.Duration, name string, fn func() error) {
+	run := func() {
+		if err := fn(); err != nil {
+			log.Printf("Erro no job %q: %v", name, err)
+		}
+	}
+
+	run()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		run()
+	}
+}
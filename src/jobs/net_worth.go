@@ -0,0 +1,272 @@
+package jobs
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"finance/src/db"
+	"finance/src/models"
+
+	"github.com/google/uuid"
+)
+
+// NetWorthAt reconstrói, a partir dos lançamentos, o saldo de cada conta e
+// empréstimo do usuário ao fim do dia cutoff:
+//
+//	contas        saldo inicial + receitas recebidas - pagamentos feitos pela conta + estornos recebidos nela
+//	investimento  o saldo acima + posições pela última cotação até cutoff (ou pelo custo)
+//	empréstimos   saldo devedor após a última parcela vencida, menos amortizações posteriores
+//
+// Os lançamentos são somados na moeda da conta. Itens zerados são omitidos.
+func NetWorthAt(userID uuid.UUID, cutoff time.Time) ([]models.NetWorthItem, error) {
+	positions, err := positionsAt(userID, cutoff)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := db.DB.Query(`
+		SELECT a.id, a.nome, a.tipo, a.moeda,
+			a.saldo_inicial
+			+ COALESCE((SELECT SUM(i.valor) FROM incomes i
+				WHERE i.conta_id = a.id AND i.user_id = a.user_id AND i.status = $3 AND i.data_recebimento <= $2 AND i.deleted_at IS NULL), 0)
+			- COALESCE((SELECT SUM(p.valor) FROM expense_payments p JOIN expenses e ON e.id = p.expense_id
+				WHERE p.conta_id = a.id AND p.user_id = a.user_id AND p.data_pagamento <= $2 AND e.deleted_at IS NULL), 0)
+			+ COALESCE((SELECT SUM(rf.valor) FROM expense_refunds rf JOIN expenses e ON e.id = rf.expense_id
+				WHERE rf.conta_id = a.id AND rf.user_id = a.user_id AND rf.data <= $2 AND e.deleted_at IS NULL), 0)
+		FROM accounts a
+		WHERE a.user_id = $1
+		ORDER BY a.nome
+	`, userID, cutoff, models.IncomeReceived)
+	if err != nil {
+		return nil, err
+	}
+
+	var items []models.NetWorthItem
+	for rows.Next() {
+		it := models.NetWorthItem{Origem: models.NetWorthAccount}
+		if err := rows.Scan(&it.OrigemID, &it.Nome, &it.Tipo, &it.Moeda, &it.Valor); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		it.Valor += positions[it.OrigemID]
+		if it.Valor == 0 {
+			continue
+		}
+		it.Classe = models.NetWorthClass(it.Tipo, it.Valor)
+		items = append(items, it)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// empréstimos contam a partir de um mês antes do primeiro vencimento
+	rows, err = db.DB.Query(`
+		SELECT l.id, l.nome, l.moeda,
+			COALESCE(last.saldo_devedor, l.principal)
+			- COALESCE((SELECT SUM(pp.valor) FROM loan_prepayments pp
+				WHERE pp.loan_id = l.id AND pp.data <= $2
+				  AND (last.vencimento IS NULL OR pp.data > last.vencimento)), 0)
+		FROM loans l
+		LEFT JOIN LATERAL (
+			SELECT i.saldo_devedor, i.vencimento
+			FROM loan_installments i
+			WHERE i.loan_id = l.id AND i.vencimento <= $2
+			ORDER BY i.numero DESC
+			LIMIT 1
+		) last ON true
+		WHERE l.user_id = $1 AND l.data_inicio - INTERVAL '1 month' <= $2
+		ORDER BY l.nome
+	`, userID, cutoff)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		it := models.NetWorthItem{Origem: models.NetWorthLoan, Tipo: models.NetWorthLoan, Classe: models.NetWorthLiability}
+		var saldo models.Money
+		if err := rows.Scan(&it.OrigemID, &it.Nome, &it.Moeda, &saldo); err != nil {
+			return nil, err
+		}
+		if saldo <= 0 {
+			continue
+		}
+		it.Valor = -saldo
+		items = append(items, it)
+	}
+	return items, rows.Err()
+}
+
+// positionsAt soma, por conta, o valor de mercado das posições em cutoff
+func positionsAt(userID uuid.UUID, cutoff time.Time) (map[uuid.UUID]models.Money, error) {
+	rows, err := db.DB.Query(`
+		SELECT conta_id, ticker, tipo, quantidade, valor, taxas, data, created_at
+		FROM investment_transactions
+		WHERE user_id = $1 AND data <= $2
+	`, userID, cutoff)
+	if err != nil {
+		return nil, err
+	}
+	var txs []models.InvestmentTransaction
+	for rows.Next() {
+		var t models.InvestmentTransaction
+		if err := rows.Scan(&t.ContaID, &t.Ticker, &t.Tipo, &t.Quantidade, &t.Valor, &t.Taxas, &t.Data, &t.CreatedAt); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		txs = append(txs, t)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	quotes := map[string]float64{}
+	rows, err = db.DB.Query(`
+		SELECT DISTINCT ON (ticker) ticker, preco
+		FROM quotes
+		WHERE user_id = $1 AND data <= $2
+		ORDER BY ticker, data DESC
+	`, userID, cutoff)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var ticker string
+		var preco float64
+		if err := rows.Scan(&ticker, &preco); err != nil {
+			return nil, err
+		}
+		quotes[ticker] = preco
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	values := map[uuid.UUID]models.Money{}
+	for _, p := range models.BuildPositions(txs) {
+		if p.Quantidade <= 0 {
+			continue
+		}
+		if preco, ok := quotes[p.Ticker]; ok {
+			values[p.ContaID] += models.TradeValue(p.Quantidade, preco)
+		} else {
+			values[p.ContaID] += p.Custo
+		}
+	}
+	return values, nil
+}
+
+// SnapshotNetWorth grava a fotografia do patrimônio no fim do mês de month,
+// substituindo a anterior. No mês corrente o saldo é apurado até hoje.
+func SnapshotNetWorth(userID uuid.UUID, month time.Time) error {
+	data := models.MonthEnd(month)
+	now := time.Now()
+	cutoff := data
+	if today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC); today.Before(cutoff) {
+		cutoff = today
+	}
+
+	items, err := NetWorthAt(userID, cutoff)
+	if err != nil {
+		return err
+	}
+
+	tx, err := db.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM net_worth_snapshots WHERE user_id = $1 AND data = $2`, userID, data); err != nil {
+		return err
+	}
+	for _, it := range items {
+		_, err := tx.Exec(`
+			INSERT INTO net_worth_snapshots (id, user_id, data, origem, origem_id, nome, tipo, classe, moeda, valor, apurado_em)
+			VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11)
+		`, uuid.New(), userID, data, it.Origem, it.OrigemID, it.Nome, it.Tipo, it.Classe, it.Moeda, it.Valor, now)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// SnapshotAllNetWorth atualiza, para todos os usuários, a fotografia do mês
+// corrente e a do mês anterior (que pode ter recebido lançamentos atrasados)
+func SnapshotAllNetWorth() error {
+	ids, err := UserIDs()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	current := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	for _, id := range ids {
+		for _, month := range []time.Time{current.AddDate(0, -1, 0), current} {
+			if err := SnapshotNetWorth(id, month); err != nil {
+				return fmt.Errorf("usuário %s: %w", id, err)
+			}
+		}
+	}
+	return nil
+}
+
+// BackfillNetWorth reconstrói as fotografias mensais do usuário desde from até
+// o mês corrente. Com from zero, começa no mês do lançamento mais antigo.
+// Devolve quantos meses foram gravados.
+func BackfillNetWorth(userID uuid.UUID, from time.Time) (int, error) {
+	if from.IsZero() {
+		var first sql.NullTime
+		err := db.DB.QueryRow(`
+			SELECT LEAST(
+				(SELECT MIN(data_recebimento) FROM incomes WHERE user_id = $1 AND conta_id IS NOT NULL AND deleted_at IS NULL),
+				(SELECT MIN(data_pagamento) FROM expense_payments WHERE user_id = $1 AND conta_id IS NOT NULL),
+				(SELECT MIN(data) FROM investment_transactions WHERE user_id = $1),
+				(SELECT MIN(data_inicio - INTERVAL '1 month') FROM loans WHERE user_id = $1),
+				(SELECT MIN(created_at) FROM accounts WHERE user_id = $1)
+			)
+		`, userID).Scan(&first)
+		if err != nil {
+			return 0, err
+		}
+		if !first.Valid {
+			return 0, nil
+		}
+		from = first.Time
+	}
+
+	now := time.Now()
+	current := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	n := 0
+	for month := time.Date(from.Year(), from.Month(), 1, 0, 0, 0, 0, time.UTC); !month.After(current); month = month.AddDate(0, 1, 0) {
+		if err := SnapshotNetWorth(userID, month); err != nil {
+			return n, fmt.Errorf("%s: %w", month.Format("2006-01"), err)
+		}
+		n++
+	}
+	return n, nil
+}
+
+// UserIDs lista os IDs de todos os usuários
+func UserIDs() ([]uuid.UUID, error) {
+	rows, err := db.DB.Query(`SELECT id FROM users ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
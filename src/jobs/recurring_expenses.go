@@ -0,0 +1,124 @@
+package jobs
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"finance/src/audit"
+	"finance/src/db"
+	"finance/src/models"
+
+	"github.com/google/uuid"
+)
+
+// RecurrenceHorizon devolve a data limite até onde as despesas recorrentes são
+// criadas antecipadamente (RECURRENCE_HORIZON_DAYS, padrão 60 dias)
+func RecurrenceHorizon() time.Time {
+	days, _ := strconv.Atoi(os.Getenv("RECURRENCE_HORIZON_DAYS"))
+	if days <= 0 {
+		days = 60
+	}
+	now := time.Now().UTC()
+	return time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC).AddDate(0, 0, days)
+}
+
+// MaterializeRecurringExpenses cria as despesas de todos os modelos ativos até o horizonte
+func MaterializeRecurringExpenses() error {
+	horizon := RecurrenceHorizon()
+
+	rows, err := db.DB.Query(`
+		SELECT id
+		FROM recurring_expenses
+		WHERE ativa = true AND (gerada_ate IS NULL OR gerada_ate < $1)
+	`, horizon)
+	if err != nil {
+		return err
+	}
+
+	var ids []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return err
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+
+	// um modelo com problema não impede os outros de serem gerados
+	var errs []error
+	for _, id := range ids {
+		if err := MaterializeRecurringExpense(id); err != nil {
+			log.Printf("Erro ao gerar despesas do modelo %s: %v", id, err)
+			errs = append(errs, fmt.Errorf("modelo %s: %w", id, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// MaterializeRecurringExpense cria as despesas pendentes de um modelo até o horizonte
+func MaterializeRecurringExpense(id uuid.UUID) error {
+	horizon := RecurrenceHorizon()
+
+	tx, err := db.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var rec models.RecurringExpense
+	err = tx.QueryRow(`
+		SELECT id, user_id, descricao, valor, moeda, categoria, category_id, observacoes, regra, data_inicio, gerada_ate, ativa
+		FROM recurring_expenses
+		WHERE id = $1
+		FOR UPDATE
+	`, id).Scan(&rec.ID, &rec.UserID, &rec.Descricao, &rec.Valor, &rec.Moeda, &rec.Categoria, &rec.CategoriaID, &rec.Observacoes,
+		&rec.Regra, &rec.DataInicio, &rec.GeradaAte, &rec.Ativa)
+	if err == sql.ErrNoRows || (err == nil && !rec.Ativa) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	rule, err := models.ParseRRule(rec.Regra)
+	if err != nil {
+		return err
+	}
+
+	from := rec.DataInicio
+	if rec.GeradaAte != nil {
+		from = rec.GeradaAte.AddDate(0, 0, 1)
+	}
+
+	// ocorrências que já existiam (ON CONFLICT) não entram no histórico
+	var ids []uuid.UUID
+	now := time.Now()
+	for _, occ := range rule.Between(rec.DataInicio, from, horizon) {
+		id := uuid.New()
+		ids = append(ids, id)
+		_, err := tx.Exec(`
+			INSERT INTO expenses (id, user_id, descricao, valor, moeda, vencimento, paga, categoria, category_id, observacoes, recorrencia_id, ocorrencia, created_at)
+			VALUES ($1,$2,$3,$4,$5,$6,false,$7,$8,$9,$10,$11,$12)
+			ON CONFLICT (recorrencia_id, ocorrencia) DO NOTHING
+		`, id, rec.UserID, rec.Descricao, rec.Valor, rec.Moeda, occ, rec.Categoria, rec.CategoriaID, rec.Observacoes, rec.ID, occ, now)
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := audit.TrackNew(models.AuditExpenses, ids...).Record(tx, models.AuditEntry{Acao: models.AuditCreate, Origem: models.AuditSourceRecurring}); err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(`UPDATE recurring_expenses SET gerada_ate = $1 WHERE id = $2`, horizon, rec.ID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,109 @@
+package jobs
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"finance/src/audit"
+	"finance/src/db"
+	"finance/src/models"
+
+	"github.com/google/uuid"
+)
+
+// MaterializeRecurringIncomes cria as receitas previstas de todos os modelos ativos até o horizonte
+func MaterializeRecurringIncomes() error {
+	rows, err := db.DB.Query(`
+		SELECT id
+		FROM recurring_incomes
+		WHERE ativa = true AND (gerada_ate IS NULL OR gerada_ate < $1)
+	`, RecurrenceHorizon())
+	if err != nil {
+		return err
+	}
+
+	var ids []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return err
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+
+	// um modelo com problema não impede os outros de serem gerados
+	var errs []error
+	for _, id := range ids {
+		if err := MaterializeRecurringIncome(id); err != nil {
+			log.Printf("Erro ao gerar receitas do modelo %s: %v", id, err)
+			errs = append(errs, fmt.Errorf("modelo %s: %w", id, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// MaterializeRecurringIncome cria as receitas previstas de um modelo até o horizonte
+func MaterializeRecurringIncome(id uuid.UUID) error {
+	horizon := RecurrenceHorizon()
+
+	tx, err := db.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var rec models.RecurringIncome
+	err = tx.QueryRow(`
+		SELECT id, user_id, descricao, valor, moeda, categoria, category_id, observacoes, regra, data_inicio, gerada_ate, ativa
+		FROM recurring_incomes
+		WHERE id = $1
+		FOR UPDATE
+	`, id).Scan(&rec.ID, &rec.UserID, &rec.Descricao, &rec.Valor, &rec.Moeda, &rec.Categoria, &rec.CategoriaID, &rec.Observacoes,
+		&rec.Regra, &rec.DataInicio, &rec.GeradaAte, &rec.Ativa)
+	if err == sql.ErrNoRows || (err == nil && !rec.Ativa) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	rule, err := models.ParseRRule(rec.Regra)
+	if err != nil {
+		return err
+	}
+
+	from := rec.DataInicio
+	if rec.GeradaAte != nil {
+		from = rec.GeradaAte.AddDate(0, 0, 1)
+	}
+
+	// ocorrências que já existiam (ON CONFLICT) não entram no histórico
+	var ids []uuid.UUID
+	now := time.Now()
+	for _, occ := range rule.Between(rec.DataInicio, from, horizon) {
+		id := uuid.New()
+		ids = append(ids, id)
+		_, err := tx.Exec(`
+			INSERT INTO incomes (id, user_id, descricao, valor, moeda, data_recebimento, categoria, category_id, observacoes, status, valor_previsto, data_prevista, recorrencia_id, ocorrencia, created_at)
+			VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$4,$6,$11,$6,$12)
+			ON CONFLICT (recorrencia_id, ocorrencia) DO NOTHING
+		`, id, rec.UserID, rec.Descricao, rec.Valor, rec.Moeda, occ, rec.Categoria, rec.CategoriaID, rec.Observacoes, models.IncomeExpected, rec.ID, now)
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := audit.TrackNew(models.AuditIncomes, ids...).Record(tx, models.AuditEntry{Acao: models.AuditCreate, Origem: models.AuditSourceRecurring}); err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(`UPDATE recurring_incomes SET gerada_ate = $1 WHERE id = $2`, horizon, rec.ID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,202 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"finance/src/db"
+	"finance/src/models"
+	"finance/src/notify"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// reminderOverdueDays limita os avisos de vencidas às despesas vencidas há
+// poucos dias, para não disparar o histórico inteiro ao ativar os lembretes
+const reminderOverdueDays = 30
+
+// reminderTarget são as preferências do usuário com os destinos já resolvidos
+type reminderTarget struct {
+	prefs   models.ReminderPreferences
+	email   string
+	webhook string
+	secret  string // assina o webhook
+}
+
+// SendBillReminders avisa, pelos canais de cada usuário, as despesas não pagas
+// que vencem em breve, vencem hoje ou já venceram. Cada lembrete é registrado
+// em reminder_deliveries antes do envio e só sai uma vez por canal; se a
+// entrega falhar o registro é desfeito e o lembrete volta na próxima execução.
+func SendBillReminders() error {
+	now := time.Now()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+
+	targets, err := reminderTargets()
+	if err != nil {
+		return err
+	}
+
+	rows, err := db.DB.Query(`
+		SELECT e.id, e.user_id, e.descricao, e.valor - COALESCE(SUM(p.valor - p.multa - p.juros + p.desconto), 0), e.moeda, e.vencimento
+		FROM expenses e
+		LEFT JOIN expense_payments p ON p.expense_id = e.id
+		WHERE e.paga = false AND e.deleted_at IS NULL AND e.vencimento >= $1 AND e.vencimento <= $2
+		  AND e.valor > COALESCE((SELECT SUM(rf.valor) FROM expense_refunds rf WHERE rf.expense_id = e.id), 0)
+		GROUP BY e.id
+		ORDER BY e.vencimento
+	`, today.AddDate(0, 0, -reminderOverdueDays), today.AddDate(0, 0, models.ReminderMaxDays))
+	if err != nil {
+		return err
+	}
+
+	var expenses []models.Expense
+	for rows.Next() {
+		var e models.Expense
+		if err := rows.Scan(&e.ID, &e.UserID, &e.Descricao, &e.Valor, &e.Moeda, &e.Vencimento); err != nil {
+			rows.Close()
+			return err
+		}
+		expenses = append(expenses, e)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, e := range expenses {
+		t, ok := targets[e.UserID]
+		if !ok || !t.prefs.Ativo {
+			continue
+		}
+		tipo, dias, ok := models.DueReminder(t.prefs, e.Vencimento, today)
+		if !ok {
+			continue
+		}
+
+		n := billReminder(e, tipo, dias)
+		n.Email, n.WebhookURL, n.WebhookSecret = t.email, t.webhook, t.secret
+		for _, canal := range t.prefs.Canais {
+			if err := deliverReminder(e, tipo, dias, canal, n); err != nil {
+				errs = append(errs, fmt.Errorf("despesa %s, canal %s: %w", e.ID, canal, err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// reminderTargets carrega as preferências de todos os usuários, com os padrões
+// para quem nunca as salvou
+func reminderTargets() (map[uuid.UUID]reminderTarget, error) {
+	rows, err := db.DB.Query(`
+		SELECT u.id, u.email, p.ativo, p.dias_antes, p.no_dia, p.vencidas, p.canais, p.email, p.webhook_url, p.webhook_secret
+		FROM users u
+		LEFT JOIN reminder_preferences p ON p.user_id = u.id
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	targets := map[uuid.UUID]reminderTarget{}
+	for rows.Next() {
+		var id uuid.UUID
+		var userEmail string
+		var ativo, noDia, vencidas *bool
+		var dias pq.Int64Array
+		var canais pq.StringArray
+		var email, webhook, secret *string
+		if err := rows.Scan(&id, &userEmail, &ativo, &dias, &noDia, &vencidas, &canais, &email, &webhook, &secret); err != nil {
+			return nil, err
+		}
+
+		t := reminderTarget{prefs: models.DefaultReminderPreferences(id), email: userEmail}
+		if ativo != nil {
+			t.prefs.Ativo, t.prefs.NoDia, t.prefs.Vencidas = *ativo, *noDia, *vencidas
+			t.prefs.DiasAntes = make([]int, len(dias))
+			for i, d := range dias {
+				t.prefs.DiasAntes[i] = int(d)
+			}
+			t.prefs.Canais = canais
+		}
+		if email != nil && *email != "" {
+			t.email = *email
+		}
+		if webhook != nil {
+			t.webhook = *webhook
+		}
+		if secret != nil {
+			t.secret = *secret
+		}
+		targets[id] = t
+	}
+	return targets, rows.Err()
+}
+
+// billReminder monta o texto do lembrete; Valor é o saldo ainda não pago
+func billReminder(e models.Expense, tipo string, dias int) notify.Notification {
+	valor := e.Moeda + " " + e.Valor.String()
+	data := e.Vencimento.Format("02/01/2006")
+
+	n := notify.Notification{
+		UserID:       e.UserID,
+		Tipo:         models.NotificationBillReminder,
+		ReferenciaID: &e.ID,
+		CriadaEm:     time.Now(),
+		Dados: map[string]interface{}{
+			"expense_id": e.ID,
+			"descricao":  e.Descricao,
+			"valor":      e.Valor,
+			"moeda":      e.Moeda,
+			"vencimento": e.Vencimento.Format("2006-01-02"),
+			"lembrete":   tipo,
+			"dias":       dias,
+		},
+	}
+	switch tipo {
+	case models.ReminderOverdue:
+		n.Titulo = "Conta vencida: " + e.Descricao
+		n.Mensagem = fmt.Sprintf("%s (%s) venceu em %s e ainda não foi paga.", e.Descricao, valor, data)
+	case models.ReminderDueToday:
+		n.Titulo = "Conta vence hoje: " + e.Descricao
+		n.Mensagem = fmt.Sprintf("%s (%s) vence hoje, %s.", e.Descricao, valor, data)
+	default:
+		n.Titulo = "Conta a vencer: " + e.Descricao
+		n.Mensagem = fmt.Sprintf("%s (%s) vence em %s.", e.Descricao, valor, data)
+	}
+	return n
+}
+
+// deliverReminder reserva o lembrete e o envia; sem reserva (já enviado), não faz nada
+func deliverReminder(e models.Expense, tipo string, dias int, canal string, n notify.Notification) error {
+	notifier, ok := notify.Channels[canal]
+	if !ok {
+		return fmt.Errorf("canal desconhecido")
+	}
+
+	id := uuid.New()
+	result, err := db.DB.Exec(`
+		INSERT INTO reminder_deliveries (id, user_id, expense_id, tipo, dias, canal, vencimento, enviado_em)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		ON CONFLICT (expense_id, tipo, dias, canal, vencimento) DO NOTHING
+	`, id, e.UserID, e.ID, tipo, dias, canal, e.Vencimento, n.CriadaEm)
+	if err != nil {
+		return err
+	}
+	if claimed, _ := result.RowsAffected(); claimed == 0 {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err := notifier.Send(ctx, n); err != nil {
+		if _, undo := db.DB.Exec(`DELETE FROM reminder_deliveries WHERE id = $1`, id); undo != nil {
+			return errors.Join(err, undo)
+		}
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,99 @@
+package jobs
+
+import (
+	"context"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"finance/src/db"
+	"finance/src/models"
+
+	"github.com/lib/pq"
+)
+
+// defaultTrashRetentionDays é por quanto tempo os itens ficam na lixeira
+// quando TRASH_RETENTION_DAYS não está definido
+const defaultTrashRetentionDays = 30
+
+// TrashRetention é por quanto tempo despesas, receitas e categorias excluídas
+// ficam na lixeira antes de serem apagadas de vez
+func TrashRetention() time.Duration {
+	days := defaultTrashRetentionDays
+	if v, err := strconv.Atoi(os.Getenv("TRASH_RETENTION_DAYS")); err == nil && v > 0 {
+		days = v
+	}
+	return time.Duration(days) * 24 * time.Hour
+}
+
+// PurgeTrash apaga de vez os itens que estão na lixeira há mais tempo que a
+// retenção. Pagamentos, estornos, tags e anexos das despesas somem em cascata;
+// os arquivos dos anexos são removidos do armazenamento se nenhum outro anexo
+// os usa.
+func PurgeTrash() error {
+	limite := time.Now().Add(-TrashRetention())
+
+	tx, err := db.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var keys []string
+	if err := tx.QueryRow(`
+		SELECT array_agg(DISTINCT a.chave)
+		FROM expense_attachments a
+		JOIN expenses e ON e.id = a.expense_id
+		WHERE e.deleted_at < $1
+	`, limite).Scan(pq.Array(&keys)); err != nil {
+		return err
+	}
+
+	expenses, err := tx.Exec(`DELETE FROM expenses WHERE deleted_at < $1`, limite)
+	if err != nil {
+		return err
+	}
+	incomes, err := tx.Exec(`DELETE FROM incomes WHERE deleted_at < $1`, limite)
+	if err != nil {
+		return err
+	}
+
+	// lançamentos e subcategorias que ainda apontam para as categorias
+	// apagadas (ex: despesas restauradas depois delas) ficam sem categoria
+	var ids []string
+	if err := tx.QueryRow(`
+		SELECT array_agg(id::text) FROM categories WHERE deleted_at < $1
+	`, limite).Scan(pq.Array(&ids)); err != nil {
+		return err
+	}
+	if len(ids) > 0 {
+		for _, table := range models.CategorizedTables {
+			if _, err := tx.Exec(`UPDATE `+table+` SET category_id = NULL WHERE category_id = ANY($1::uuid[])`, pq.Array(ids)); err != nil {
+				return err
+			}
+		}
+		if _, err := tx.Exec(`
+			UPDATE categories SET parent_id = NULL
+			WHERE parent_id = ANY($1::uuid[]) AND NOT (id = ANY($1::uuid[]))
+		`, pq.Array(ids)); err != nil {
+			return err
+		}
+		if _, err := tx.Exec(`DELETE FROM categories WHERE id = ANY($1::uuid[])`, pq.Array(ids)); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	RemoveUnreferencedBlobs(context.Background(), keys)
+
+	e, _ := expenses.RowsAffected()
+	i, _ := incomes.RowsAffected()
+	if e+i+int64(len(ids)) > 0 {
+		log.Printf("Lixeira: %d despesas, %d receitas e %d categorias apagadas de vez", e, i, len(ids))
+	}
+	return nil
+}
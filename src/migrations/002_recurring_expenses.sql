@@ -0,0 +1,23 @@
+-- recurring expenses (modelos de despesas recorrentes)
+CREATE TABLE IF NOT EXISTS recurring_expenses (
+  id UUID PRIMARY KEY,
+  user_id UUID REFERENCES users(id) ON DELETE CASCADE,
+  descricao TEXT NOT NULL,
+  valor NUMERIC(10,2) CHECK (valor > 0),
+  categoria TEXT NOT NULL,
+  observacoes TEXT,
+  regra TEXT NOT NULL,            -- regra no formato RRULE (RFC 5545), ex: FREQ=MONTHLY;BYMONTHDAY=10
+  data_inicio DATE NOT NULL,      -- DTSTART da regra
+  gerada_ate DATE,                -- até onde as despesas já foram materializadas
+  ativa BOOLEAN DEFAULT true,
+  created_at TIMESTAMP DEFAULT NOW()
+);
+
+-- vínculo das despesas com o modelo que as gerou
+ALTER TABLE expenses
+  ADD COLUMN IF NOT EXISTS recorrencia_id UUID REFERENCES recurring_expenses(id) ON DELETE SET NULL,
+  ADD COLUMN IF NOT EXISTS ocorrencia DATE;
+
+-- impede que a mesma ocorrência seja materializada duas vezes
+CREATE UNIQUE INDEX IF NOT EXISTS expenses_recorrencia_ocorrencia_idx
+  ON expenses (recorrencia_id, ocorrencia);
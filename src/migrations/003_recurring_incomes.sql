@@ -0,0 +1,25 @@
+-- recurring incomes (modelos de receitas recorrentes, ex: salário)
+CREATE TABLE IF NOT EXISTS recurring_incomes (
+  id UUID PRIMARY KEY,
+  user_id UUID REFERENCES users(id) ON DELETE CASCADE,
+  descricao TEXT NOT NULL,
+  valor NUMERIC(10,2) CHECK (valor > 0),
+  categoria TEXT NOT NULL,
+  observacoes TEXT,
+  regra TEXT NOT NULL,            -- regra no formato RRULE (RFC 5545)
+  data_inicio DATE NOT NULL,
+  gerada_ate DATE,
+  ativa BOOLEAN DEFAULT true,
+  created_at TIMESTAMP DEFAULT NOW()
+);
+
+-- receitas previstas x recebidas
+ALTER TABLE incomes
+  ADD COLUMN IF NOT EXISTS status TEXT NOT NULL DEFAULT 'recebida' CHECK (status IN ('prevista', 'recebida')),
+  ADD COLUMN IF NOT EXISTS valor_previsto NUMERIC(10,2),
+  ADD COLUMN IF NOT EXISTS data_prevista DATE,
+  ADD COLUMN IF NOT EXISTS recorrencia_id UUID REFERENCES recurring_incomes(id) ON DELETE SET NULL,
+  ADD COLUMN IF NOT EXISTS ocorrencia DATE;
+
+CREATE UNIQUE INDEX IF NOT EXISTS incomes_recorrencia_ocorrencia_idx
+  ON incomes (recorrencia_id, ocorrencia);
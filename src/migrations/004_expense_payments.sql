@@ -0,0 +1,35 @@
+-- accounts (contas de onde sai ou entra o dinheiro)
+CREATE TABLE IF NOT EXISTS accounts (
+  id UUID PRIMARY KEY,
+  user_id UUID REFERENCES users(id) ON DELETE CASCADE,
+  nome TEXT NOT NULL,
+  tipo TEXT NOT NULL DEFAULT 'corrente',
+  created_at TIMESTAMP DEFAULT NOW(),
+  UNIQUE (user_id, nome)
+);
+
+-- expense payments (pagamentos, inclusive parciais, de uma despesa)
+CREATE TABLE IF NOT EXISTS expense_payments (
+  id UUID PRIMARY KEY,
+  expense_id UUID REFERENCES expenses(id) ON DELETE CASCADE,
+  user_id UUID REFERENCES users(id) ON DELETE CASCADE,
+  valor NUMERIC(10,2) CHECK (valor > 0),
+  data_pagamento DATE NOT NULL,
+  conta_id UUID REFERENCES accounts(id) ON DELETE SET NULL,
+  metodo TEXT,
+  observacoes TEXT,
+  created_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS expense_payments_expense_idx ON expense_payments (expense_id);
+
+-- quitada: despesa encerrada com pagamento menor que o valor (ex: desconto)
+-- paga e data_pagamento passam a ser derivadas dos pagamentos
+ALTER TABLE expenses ADD COLUMN IF NOT EXISTS quitada BOOLEAN NOT NULL DEFAULT false;
+
+-- despesas já marcadas como pagas ganham um pagamento integral
+INSERT INTO expense_payments (id, expense_id, user_id, valor, data_pagamento, created_at)
+SELECT gen_random_uuid(), e.id, e.user_id, e.valor, COALESCE(e.data_pagamento, e.vencimento), NOW()
+FROM expenses e
+WHERE e.paga = true
+  AND NOT EXISTS (SELECT 1 FROM expense_payments p WHERE p.expense_id = e.id);
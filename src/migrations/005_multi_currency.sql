@@ -0,0 +1,22 @@
+-- moeda base do usuário (ISO 4217)
+ALTER TABLE users ADD COLUMN IF NOT EXISTS moeda_base CHAR(3) NOT NULL DEFAULT 'BRL';
+
+-- moeda de cada transação e conta
+ALTER TABLE expenses ADD COLUMN IF NOT EXISTS moeda CHAR(3) NOT NULL DEFAULT 'BRL';
+ALTER TABLE incomes ADD COLUMN IF NOT EXISTS moeda CHAR(3) NOT NULL DEFAULT 'BRL';
+ALTER TABLE accounts ADD COLUMN IF NOT EXISTS moeda CHAR(3) NOT NULL DEFAULT 'BRL';
+ALTER TABLE recurring_expenses ADD COLUMN IF NOT EXISTS moeda CHAR(3) NOT NULL DEFAULT 'BRL';
+ALTER TABLE recurring_incomes ADD COLUMN IF NOT EXISTS moeda CHAR(3) NOT NULL DEFAULT 'BRL';
+
+-- exchange rates: 1 unidade de moeda_origem = taxa unidades de moeda_destino
+CREATE TABLE IF NOT EXISTS exchange_rates (
+  id UUID PRIMARY KEY,
+  user_id UUID REFERENCES users(id) ON DELETE CASCADE,
+  moeda_origem CHAR(3) NOT NULL,
+  moeda_destino CHAR(3) NOT NULL,
+  data DATE NOT NULL,
+  taxa NUMERIC(18,8) NOT NULL CHECK (taxa > 0),
+  fonte TEXT NOT NULL DEFAULT 'manual', -- manual ou csv
+  created_at TIMESTAMP DEFAULT NOW(),
+  UNIQUE (user_id, moeda_origem, moeda_destino, data)
+);
@@ -0,0 +1,7 @@
+-- amplia o limite dos valores monetários de 99.999.999,99 para 9.999.999.999.999,99
+ALTER TABLE expenses ALTER COLUMN valor TYPE NUMERIC(15,2);
+ALTER TABLE incomes ALTER COLUMN valor TYPE NUMERIC(15,2);
+ALTER TABLE incomes ALTER COLUMN valor_previsto TYPE NUMERIC(15,2);
+ALTER TABLE recurring_expenses ALTER COLUMN valor TYPE NUMERIC(15,2);
+ALTER TABLE recurring_incomes ALTER COLUMN valor TYPE NUMERIC(15,2);
+ALTER TABLE expense_payments ALTER COLUMN valor TYPE NUMERIC(15,2);
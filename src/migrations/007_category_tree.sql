@@ -0,0 +1,5 @@
+-- hierarquia de categorias (ex: Moradia > Aluguel)
+ALTER TABLE categories ADD COLUMN IF NOT EXISTS parent_id UUID REFERENCES categories(id);
+ALTER TABLE categories ADD COLUMN IF NOT EXISTS posicao INT NOT NULL DEFAULT 0; -- ordem entre as irmãs
+
+CREATE INDEX IF NOT EXISTS idx_categories_parent ON categories(parent_id);
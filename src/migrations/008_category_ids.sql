@@ -0,0 +1,26 @@
+-- vincula os lançamentos à tabela categories; a coluna categoria passa a ser
+-- apenas uma cópia do nome atual
+ALTER TABLE expenses ADD COLUMN IF NOT EXISTS category_id UUID REFERENCES categories(id);
+ALTER TABLE incomes ADD COLUMN IF NOT EXISTS category_id UUID REFERENCES categories(id);
+ALTER TABLE recurring_expenses ADD COLUMN IF NOT EXISTS category_id UUID REFERENCES categories(id);
+ALTER TABLE recurring_incomes ADD COLUMN IF NOT EXISTS category_id UUID REFERENCES categories(id);
+
+-- cria as categorias que só existiam como texto nos lançamentos
+INSERT INTO categories (id, user_id, name, created_at)
+SELECT gen_random_uuid(), user_id, categoria, NOW()
+FROM (
+  SELECT user_id, categoria FROM expenses
+  UNION SELECT user_id, categoria FROM incomes
+  UNION SELECT user_id, categoria FROM recurring_expenses
+  UNION SELECT user_id, categoria FROM recurring_incomes
+) t
+WHERE TRIM(categoria) <> ''
+ON CONFLICT (user_id, name) DO NOTHING;
+
+UPDATE expenses x SET category_id = c.id FROM categories c WHERE c.user_id = x.user_id AND c.name = x.categoria AND x.category_id IS NULL;
+UPDATE incomes x SET category_id = c.id FROM categories c WHERE c.user_id = x.user_id AND c.name = x.categoria AND x.category_id IS NULL;
+UPDATE recurring_expenses x SET category_id = c.id FROM categories c WHERE c.user_id = x.user_id AND c.name = x.categoria AND x.category_id IS NULL;
+UPDATE recurring_incomes x SET category_id = c.id FROM categories c WHERE c.user_id = x.user_id AND c.name = x.categoria AND x.category_id IS NULL;
+
+CREATE INDEX IF NOT EXISTS idx_expenses_category ON expenses(category_id);
+CREATE INDEX IF NOT EXISTS idx_incomes_category ON incomes(category_id);
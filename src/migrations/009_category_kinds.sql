@@ -0,0 +1,20 @@
+-- tipo, aparência e arquivamento das categorias
+ALTER TABLE categories ADD COLUMN IF NOT EXISTS kind TEXT NOT NULL DEFAULT 'both'
+  CHECK (kind IN ('expense', 'income', 'both'));
+ALTER TABLE categories ADD COLUMN IF NOT EXISTS color TEXT;
+ALTER TABLE categories ADD COLUMN IF NOT EXISTS icon TEXT;
+ALTER TABLE categories ADD COLUMN IF NOT EXISTS archived BOOLEAN NOT NULL DEFAULT false;
+
+-- categorias existentes usadas só em despesas ou só em receitas ganham o tipo correspondente
+UPDATE categories c SET kind = 'expense'
+WHERE (EXISTS (SELECT 1 FROM expenses x WHERE x.category_id = c.id) OR EXISTS (SELECT 1 FROM recurring_expenses x WHERE x.category_id = c.id))
+  AND NOT EXISTS (SELECT 1 FROM incomes x WHERE x.category_id = c.id)
+  AND NOT EXISTS (SELECT 1 FROM recurring_incomes x WHERE x.category_id = c.id);
+
+UPDATE categories c SET kind = 'income'
+WHERE (EXISTS (SELECT 1 FROM incomes x WHERE x.category_id = c.id) OR EXISTS (SELECT 1 FROM recurring_incomes x WHERE x.category_id = c.id))
+  AND NOT EXISTS (SELECT 1 FROM expenses x WHERE x.category_id = c.id)
+  AND NOT EXISTS (SELECT 1 FROM recurring_expenses x WHERE x.category_id = c.id);
+
+-- idioma do usuário, usado nas categorias padrão
+ALTER TABLE users ADD COLUMN IF NOT EXISTS locale TEXT NOT NULL DEFAULT 'pt-BR';
@@ -0,0 +1,24 @@
+-- tags: rótulos livres aplicados a despesas e receitas
+CREATE TABLE IF NOT EXISTS tags (
+  id UUID PRIMARY KEY,
+  user_id UUID REFERENCES users(id) ON DELETE CASCADE,
+  nome TEXT NOT NULL, -- sempre em minúsculas
+  cor TEXT,
+  created_at TIMESTAMP DEFAULT NOW(),
+  UNIQUE (user_id, nome)
+);
+
+CREATE TABLE IF NOT EXISTS expense_tags (
+  expense_id UUID REFERENCES expenses(id) ON DELETE CASCADE,
+  tag_id UUID REFERENCES tags(id) ON DELETE CASCADE,
+  PRIMARY KEY (expense_id, tag_id)
+);
+
+CREATE TABLE IF NOT EXISTS income_tags (
+  income_id UUID REFERENCES incomes(id) ON DELETE CASCADE,
+  tag_id UUID REFERENCES tags(id) ON DELETE CASCADE,
+  PRIMARY KEY (income_id, tag_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_expense_tags_tag ON expense_tags(tag_id);
+CREATE INDEX IF NOT EXISTS idx_income_tags_tag ON income_tags(tag_id);
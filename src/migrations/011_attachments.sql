@@ -0,0 +1,17 @@
+-- expense attachments (comprovantes e notas fiscais anexados às despesas)
+-- o arquivo fica no armazenamento de blobs (disco local ou S3) sob "chave";
+-- a chave deriva do sha256, então o mesmo arquivo é guardado uma só vez por usuário
+CREATE TABLE IF NOT EXISTS expense_attachments (
+  id UUID PRIMARY KEY,
+  expense_id UUID REFERENCES expenses(id) ON DELETE CASCADE,
+  user_id UUID REFERENCES users(id) ON DELETE CASCADE,
+  nome TEXT NOT NULL,
+  content_type TEXT NOT NULL,
+  tamanho BIGINT NOT NULL CHECK (tamanho > 0),
+  sha256 TEXT NOT NULL,
+  chave TEXT NOT NULL,
+  created_at TIMESTAMP DEFAULT NOW(),
+  UNIQUE (expense_id, sha256)
+);
+
+CREATE INDEX IF NOT EXISTS expense_attachments_chave_idx ON expense_attachments (chave);
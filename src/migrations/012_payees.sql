@@ -0,0 +1,25 @@
+-- payees (favorecidos: lojas, empresas e pessoas de quem se compra ou recebe)
+CREATE TABLE IF NOT EXISTS payees (
+  id UUID PRIMARY KEY,
+  user_id UUID REFERENCES users(id) ON DELETE CASCADE,
+  nome TEXT NOT NULL,
+  created_at TIMESTAMP DEFAULT NOW(),
+  UNIQUE (user_id, nome)
+);
+
+-- apelidos reconhecem as várias formas como o favorecido aparece na descrição
+-- tipo: exato (descrição normalizada igual), contem (palavras contidas) ou regex
+CREATE TABLE IF NOT EXISTS payee_aliases (
+  id UUID PRIMARY KEY,
+  payee_id UUID REFERENCES payees(id) ON DELETE CASCADE,
+  padrao TEXT NOT NULL,
+  tipo TEXT NOT NULL DEFAULT 'contem' CHECK (tipo IN ('exato', 'contem', 'regex')),
+  created_at TIMESTAMP DEFAULT NOW(),
+  UNIQUE (payee_id, tipo, padrao)
+);
+
+ALTER TABLE expenses ADD COLUMN IF NOT EXISTS payee_id UUID REFERENCES payees(id) ON DELETE SET NULL;
+ALTER TABLE incomes ADD COLUMN IF NOT EXISTS payee_id UUID REFERENCES payees(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS expenses_payee_idx ON expenses (payee_id);
+CREATE INDEX IF NOT EXISTS incomes_payee_idx ON incomes (payee_id);
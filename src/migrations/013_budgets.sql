@@ -0,0 +1,18 @@
+-- budgets (orçamentos mensais por categoria, na moeda base do usuário)
+-- recorrente = false: vale só para o mês "mes"
+-- recorrente = true: vale de "mes" até "fim" (ou sem fim); um orçamento do mês tem prioridade
+-- rollover: o saldo não gasto no mês é somado ao orçamento do mês seguinte
+CREATE TABLE IF NOT EXISTS budgets (
+  id UUID PRIMARY KEY,
+  user_id UUID REFERENCES users(id) ON DELETE CASCADE,
+  category_id UUID NOT NULL REFERENCES categories(id) ON DELETE CASCADE,
+  valor NUMERIC(15,2) NOT NULL CHECK (valor >= 0),
+  recorrente BOOLEAN NOT NULL DEFAULT false,
+  mes DATE NOT NULL CHECK (EXTRACT(DAY FROM mes) = 1),
+  fim DATE CHECK (fim IS NULL OR (recorrente AND fim >= mes AND EXTRACT(DAY FROM fim) = 1)),
+  rollover BOOLEAN NOT NULL DEFAULT false,
+  created_at TIMESTAMP DEFAULT NOW(),
+  UNIQUE (category_id, recorrente, mes)
+);
+
+CREATE INDEX IF NOT EXISTS budgets_user_idx ON budgets (user_id);
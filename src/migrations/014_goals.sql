@@ -0,0 +1,25 @@
+-- goals (metas de economia: viagem, reserva de emergência...)
+CREATE TABLE IF NOT EXISTS goals (
+  id UUID PRIMARY KEY,
+  user_id UUID REFERENCES users(id) ON DELETE CASCADE,
+  nome TEXT NOT NULL,
+  valor_alvo NUMERIC(15,2) NOT NULL CHECK (valor_alvo > 0),
+  data_alvo DATE,
+  conta_id UUID REFERENCES accounts(id) ON DELETE SET NULL,
+  moeda CHAR(3) NOT NULL DEFAULT 'BRL',
+  created_at TIMESTAMP DEFAULT NOW(),
+  UNIQUE (user_id, nome)
+);
+
+-- goal contributions (aportes positivos e retiradas negativas)
+CREATE TABLE IF NOT EXISTS goal_contributions (
+  id UUID PRIMARY KEY,
+  goal_id UUID REFERENCES goals(id) ON DELETE CASCADE,
+  user_id UUID REFERENCES users(id) ON DELETE CASCADE,
+  valor NUMERIC(15,2) NOT NULL CHECK (valor <> 0),
+  data DATE NOT NULL,
+  observacoes TEXT,
+  created_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS goal_contributions_goal_idx ON goal_contributions (goal_id, data);
@@ -0,0 +1,42 @@
+-- loans (empréstimos e financiamentos)
+CREATE TABLE IF NOT EXISTS loans (
+  id UUID PRIMARY KEY,
+  user_id UUID REFERENCES users(id) ON DELETE CASCADE,
+  nome TEXT NOT NULL,
+  principal NUMERIC(15,2) NOT NULL CHECK (principal > 0),
+  taxa_mensal NUMERIC(10,6) NOT NULL CHECK (taxa_mensal >= 0), -- % ao mês
+  prazo INT NOT NULL CHECK (prazo > 0),
+  sistema TEXT NOT NULL CHECK (sistema IN ('sac', 'price')),
+  data_inicio DATE NOT NULL, -- vencimento da primeira parcela
+  moeda CHAR(3) NOT NULL DEFAULT 'BRL',
+  categoria TEXT,
+  category_id UUID REFERENCES categories(id) ON DELETE SET NULL,
+  created_at TIMESTAMP DEFAULT NOW()
+);
+
+-- cronograma de amortização; cada parcela gera uma despesa
+CREATE TABLE IF NOT EXISTS loan_installments (
+  loan_id UUID REFERENCES loans(id) ON DELETE CASCADE,
+  numero INT NOT NULL,
+  vencimento DATE NOT NULL,
+  amortizacao NUMERIC(15,2) NOT NULL,
+  juros NUMERIC(15,2) NOT NULL,
+  parcela NUMERIC(15,2) NOT NULL,
+  saldo_devedor NUMERIC(15,2) NOT NULL, -- saldo após pagar a parcela
+  expense_id UUID REFERENCES expenses(id) ON DELETE SET NULL,
+  PRIMARY KEY (loan_id, numero)
+);
+
+-- amortizações extraordinárias
+CREATE TABLE IF NOT EXISTS loan_prepayments (
+  id UUID PRIMARY KEY,
+  loan_id UUID REFERENCES loans(id) ON DELETE CASCADE,
+  user_id UUID REFERENCES users(id) ON DELETE CASCADE,
+  data DATE NOT NULL,
+  valor NUMERIC(15,2) NOT NULL CHECK (valor > 0),
+  efeito TEXT NOT NULL CHECK (efeito IN ('prazo', 'parcela')),
+  expense_id UUID REFERENCES expenses(id) ON DELETE SET NULL,
+  created_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS loan_installments_expense_idx ON loan_installments (expense_id);
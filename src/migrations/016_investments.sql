@@ -0,0 +1,30 @@
+-- investimentos: as posições são derivadas das operações em contas do tipo "investimento"
+CREATE TABLE IF NOT EXISTS investment_transactions (
+  id UUID PRIMARY KEY,
+  user_id UUID REFERENCES users(id) ON DELETE CASCADE,
+  conta_id UUID NOT NULL REFERENCES accounts(id) ON DELETE CASCADE,
+  ticker TEXT NOT NULL,
+  tipo TEXT NOT NULL CHECK (tipo IN ('compra', 'venda', 'dividendo')),
+  quantidade NUMERIC(20,8) NOT NULL DEFAULT 0 CHECK (quantidade >= 0),
+  preco NUMERIC(18,6) NOT NULL DEFAULT 0,
+  valor NUMERIC(15,2) NOT NULL CHECK (valor >= 0), -- quantidade x preço, ou o valor do dividendo
+  taxas NUMERIC(15,2) NOT NULL DEFAULT 0 CHECK (taxas >= 0),
+  data DATE NOT NULL,
+  income_id UUID REFERENCES incomes(id) ON DELETE SET NULL, -- receita gerada pelo dividendo
+  observacoes TEXT,
+  created_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS investment_transactions_user_idx ON investment_transactions (user_id, conta_id, ticker, data);
+
+-- cotações informadas manualmente ou importadas de CSV
+CREATE TABLE IF NOT EXISTS quotes (
+  id UUID PRIMARY KEY,
+  user_id UUID REFERENCES users(id) ON DELETE CASCADE,
+  ticker TEXT NOT NULL,
+  data DATE NOT NULL,
+  preco NUMERIC(18,6) NOT NULL CHECK (preco > 0),
+  fonte TEXT NOT NULL DEFAULT 'manual', -- manual ou csv
+  created_at TIMESTAMP DEFAULT NOW(),
+  UNIQUE (user_id, ticker, data)
+);
@@ -0,0 +1,25 @@
+-- saldo da conta antes do primeiro lançamento registrado
+ALTER TABLE accounts ADD COLUMN IF NOT EXISTS saldo_inicial NUMERIC(15,2) NOT NULL DEFAULT 0;
+
+-- conta onde a receita foi creditada (as despesas usam conta_id dos pagamentos)
+ALTER TABLE incomes ADD COLUMN IF NOT EXISTS conta_id UUID REFERENCES accounts(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS incomes_conta_idx ON incomes (conta_id) WHERE conta_id IS NOT NULL;
+CREATE INDEX IF NOT EXISTS expense_payments_conta_idx ON expense_payments (conta_id) WHERE conta_id IS NOT NULL;
+
+-- fotografias mensais do patrimônio: um registro por conta ou empréstimo no fim de cada mês.
+-- origem_id não tem chave estrangeira para preservar o histórico de contas excluídas
+CREATE TABLE IF NOT EXISTS net_worth_snapshots (
+  id UUID PRIMARY KEY,
+  user_id UUID REFERENCES users(id) ON DELETE CASCADE,
+  data DATE NOT NULL, -- último dia do mês
+  origem TEXT NOT NULL CHECK (origem IN ('conta', 'emprestimo')),
+  origem_id UUID NOT NULL,
+  nome TEXT NOT NULL,
+  tipo TEXT NOT NULL, -- tipo da conta ou "emprestimo"
+  classe TEXT NOT NULL CHECK (classe IN ('ativo', 'passivo')),
+  moeda CHAR(3) NOT NULL,
+  valor NUMERIC(15,2) NOT NULL, -- saldo na moeda de origem; dívidas ficam negativas
+  apurado_em TIMESTAMP NOT NULL DEFAULT NOW(),
+  UNIQUE (user_id, data, origem, origem_id)
+);
@@ -0,0 +1,22 @@
+-- modo de orçamento: por categoria (budgets) ou base zero com envelopes
+ALTER TABLE users ADD COLUMN IF NOT EXISTS modo_orcamento TEXT NOT NULL DEFAULT 'categorias'
+  CHECK (modo_orcamento IN ('categorias', 'envelopes'));
+-- primeiro mês do modo envelope: receitas e despesas anteriores ficam fora dos envelopes
+ALTER TABLE users ADD COLUMN IF NOT EXISTS envelope_inicio DATE;
+
+-- envelope_movements: dinheiro atribuído aos envelopes (categorias de despesa), na moeda base.
+-- Uma transferência gera duas linhas (saída negativa e entrada positiva) com o mesmo transferencia_id
+CREATE TABLE IF NOT EXISTS envelope_movements (
+  id UUID PRIMARY KEY,
+  user_id UUID REFERENCES users(id) ON DELETE CASCADE,
+  category_id UUID NOT NULL REFERENCES categories(id) ON DELETE CASCADE,
+  mes DATE NOT NULL, -- primeiro dia do mês
+  valor NUMERIC(15,2) NOT NULL CHECK (valor <> 0),
+  tipo TEXT NOT NULL CHECK (tipo IN ('atribuicao', 'transferencia')),
+  transferencia_id UUID,
+  observacoes TEXT,
+  created_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS envelope_movements_user_idx ON envelope_movements (user_id, mes);
+CREATE INDEX IF NOT EXISTS envelope_movements_transfer_idx ON envelope_movements (transferencia_id) WHERE transferencia_id IS NOT NULL;
@@ -0,0 +1,40 @@
+-- preferências de lembretes de vencimento; sem linha, valem os padrões
+CREATE TABLE IF NOT EXISTS reminder_preferences (
+  user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+  ativo BOOLEAN NOT NULL DEFAULT true,
+  dias_antes INT[] NOT NULL DEFAULT '{3}', -- avisa quando faltam até N dias
+  no_dia BOOLEAN NOT NULL DEFAULT true,
+  vencidas BOOLEAN NOT NULL DEFAULT true,
+  canais TEXT[] NOT NULL DEFAULT '{inbox}', -- email, webhook e/ou inbox
+  email TEXT, -- padrão: e-mail do usuário
+  webhook_url TEXT,
+  updated_at TIMESTAMP DEFAULT NOW()
+);
+
+-- lembretes já entregues: cada lembrete sai uma única vez por canal.
+-- O vencimento faz parte da chave para que uma data alterada gere novos lembretes
+CREATE TABLE IF NOT EXISTS reminder_deliveries (
+  id UUID PRIMARY KEY,
+  user_id UUID REFERENCES users(id) ON DELETE CASCADE,
+  expense_id UUID NOT NULL REFERENCES expenses(id) ON DELETE CASCADE,
+  tipo TEXT NOT NULL CHECK (tipo IN ('antecedencia', 'no_dia', 'vencida')),
+  dias INT NOT NULL DEFAULT 0,
+  canal TEXT NOT NULL,
+  vencimento DATE NOT NULL,
+  enviado_em TIMESTAMP NOT NULL DEFAULT NOW(),
+  UNIQUE (expense_id, tipo, dias, canal, vencimento)
+);
+
+-- caixa de entrada do aplicativo
+CREATE TABLE IF NOT EXISTS notifications (
+  id UUID PRIMARY KEY,
+  user_id UUID REFERENCES users(id) ON DELETE CASCADE,
+  tipo TEXT NOT NULL,
+  titulo TEXT NOT NULL,
+  mensagem TEXT NOT NULL,
+  referencia_id UUID,
+  lida BOOLEAN NOT NULL DEFAULT false,
+  created_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS notifications_user_idx ON notifications (user_id, lida, created_at DESC);
@@ -0,0 +1,18 @@
+-- encargos por atraso e desconto por pagamento antecipado de cada despesa
+ALTER TABLE expenses ADD COLUMN IF NOT EXISTS multa_percentual NUMERIC(7,4) NOT NULL DEFAULT 0
+  CHECK (multa_percentual >= 0 AND multa_percentual <= 100);
+ALTER TABLE expenses ADD COLUMN IF NOT EXISTS juros_percentual NUMERIC(7,4) NOT NULL DEFAULT 0
+  CHECK (juros_percentual >= 0);
+-- juros simples: % ao dia, ou % ao mês cobrado pro rata dia (mês de 30 dias)
+ALTER TABLE expenses ADD COLUMN IF NOT EXISTS juros_periodo TEXT NOT NULL DEFAULT 'mensal'
+  CHECK (juros_periodo IN ('diario', 'mensal'));
+ALTER TABLE expenses ADD COLUMN IF NOT EXISTS desconto_percentual NUMERIC(7,4) NOT NULL DEFAULT 0
+  CHECK (desconto_percentual >= 0 AND desconto_percentual < 100);
+-- último dia com desconto; sem data, vale até o vencimento
+ALTER TABLE expenses ADD COLUMN IF NOT EXISTS desconto_ate DATE;
+
+-- parte de cada pagamento que foi multa, juros ou desconto; o principal
+-- abatido da despesa é valor - multa - juros + desconto
+ALTER TABLE expense_payments ADD COLUMN IF NOT EXISTS multa NUMERIC(15,2) NOT NULL DEFAULT 0 CHECK (multa >= 0);
+ALTER TABLE expense_payments ADD COLUMN IF NOT EXISTS juros NUMERIC(15,2) NOT NULL DEFAULT 0 CHECK (juros >= 0);
+ALTER TABLE expense_payments ADD COLUMN IF NOT EXISTS desconto NUMERIC(15,2) NOT NULL DEFAULT 0 CHECK (desconto >= 0);
@@ -0,0 +1,16 @@
+-- expense refunds (estornos, parciais ou totais, de uma despesa)
+-- o valor estornado é descontado da despesa nos totais por mês, categoria,
+-- tag, favorecido e orçamento; o histórico de pagamentos não muda
+CREATE TABLE IF NOT EXISTS expense_refunds (
+  id UUID PRIMARY KEY,
+  expense_id UUID NOT NULL REFERENCES expenses(id) ON DELETE CASCADE,
+  user_id UUID REFERENCES users(id) ON DELETE CASCADE,
+  valor NUMERIC(15,2) NOT NULL CHECK (valor > 0), -- na moeda da despesa
+  data DATE NOT NULL,
+  conta_id UUID REFERENCES accounts(id) ON DELETE SET NULL, -- conta que recebeu o estorno
+  motivo TEXT,
+  created_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS expense_refunds_expense_idx ON expense_refunds (expense_id);
+CREATE INDEX IF NOT EXISTS expense_refunds_conta_idx ON expense_refunds (conta_id);
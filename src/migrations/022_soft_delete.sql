@@ -0,0 +1,13 @@
+-- lixeira: despesas, receitas e categorias excluídas ficam com deleted_at
+-- preenchido até o job de limpeza apagá-las de vez (TRASH_RETENTION_DAYS)
+ALTER TABLE expenses ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP;
+ALTER TABLE incomes ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP;
+ALTER TABLE categories ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP;
+
+CREATE INDEX IF NOT EXISTS expenses_deleted_idx ON expenses (user_id, deleted_at) WHERE deleted_at IS NOT NULL;
+CREATE INDEX IF NOT EXISTS incomes_deleted_idx ON incomes (user_id, deleted_at) WHERE deleted_at IS NOT NULL;
+CREATE INDEX IF NOT EXISTS categories_deleted_idx ON categories (user_id, deleted_at) WHERE deleted_at IS NOT NULL;
+
+-- o nome de uma categoria na lixeira pode ser reutilizado
+ALTER TABLE categories DROP CONSTRAINT IF EXISTS categories_user_id_name_key;
+CREATE UNIQUE INDEX IF NOT EXISTS categories_user_name_live_idx ON categories (user_id, name) WHERE deleted_at IS NULL;
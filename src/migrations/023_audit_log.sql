@@ -0,0 +1,17 @@
+-- histórico de alterações de despesas, receitas e categorias
+-- só recebe inserções: nenhum registro é alterado ou excluído pela aplicação,
+-- e ele sobrevive à exclusão definitiva do lançamento (sem FK para a entidade)
+CREATE TABLE IF NOT EXISTS audit_log (
+  id UUID PRIMARY KEY,
+  user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE, -- dono do lançamento
+  ator_id UUID REFERENCES users(id) ON DELETE SET NULL,         -- quem alterou; NULL nos jobs
+  entidade TEXT NOT NULL,        -- expenses, incomes ou categories
+  entidade_id UUID NOT NULL,
+  acao TEXT NOT NULL,            -- criacao, alteracao, exclusao, restauracao, pagamento, estorno_pagamento
+  origem TEXT NOT NULL,          -- api, importacao ou recorrencia
+  alteracoes JSONB NOT NULL,     -- {"campo": {"de": ..., "para": ...}}
+  created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS audit_log_entidade_idx ON audit_log (entidade, entidade_id, created_at);
+CREATE INDEX IF NOT EXISTS audit_log_user_idx ON audit_log (user_id, created_at);
@@ -0,0 +1,29 @@
+-- campos personalizados: definidos pelo usuário e preenchidos em despesas e receitas
+CREATE TABLE IF NOT EXISTS custom_fields (
+  id UUID PRIMARY KEY,
+  user_id UUID REFERENCES users(id) ON DELETE CASCADE,
+  nome TEXT NOT NULL,
+  tipo TEXT NOT NULL CHECK (tipo IN ('texto', 'numero', 'data', 'selecao', 'booleano')),
+  kind TEXT NOT NULL DEFAULT 'both' CHECK (kind IN ('expense', 'income', 'both')),
+  opcoes TEXT[], -- valores aceitos pelos campos do tipo selecao
+  created_at TIMESTAMP DEFAULT NOW(),
+  UNIQUE (user_id, nome)
+);
+
+-- valor em JSON: texto, número, data (YYYY-MM-DD) ou booleano, já validado pelo tipo do campo
+CREATE TABLE IF NOT EXISTS expense_field_values (
+  expense_id UUID REFERENCES expenses(id) ON DELETE CASCADE,
+  field_id UUID REFERENCES custom_fields(id) ON DELETE CASCADE,
+  valor JSONB NOT NULL,
+  PRIMARY KEY (expense_id, field_id)
+);
+
+CREATE TABLE IF NOT EXISTS income_field_values (
+  income_id UUID REFERENCES incomes(id) ON DELETE CASCADE,
+  field_id UUID REFERENCES custom_fields(id) ON DELETE CASCADE,
+  valor JSONB NOT NULL,
+  PRIMARY KEY (income_id, field_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_expense_field_values_field ON expense_field_values(field_id, (valor #>> '{}'));
+CREATE INDEX IF NOT EXISTS idx_income_field_values_field ON income_field_values(field_id, (valor #>> '{}'));
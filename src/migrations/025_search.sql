@@ -0,0 +1,36 @@
+-- busca textual em português, sem acentos e com tolerância a erros de digitação
+CREATE EXTENSION IF NOT EXISTS unaccent;
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+-- unaccent não é IMMUTABLE, o que impede seu uso em índices; fixando o dicionário ele pode ser
+CREATE OR REPLACE FUNCTION f_unaccent(text) RETURNS text AS $$
+  SELECT public.unaccent('public.unaccent'::regdictionary, $1)
+$$ LANGUAGE sql IMMUTABLE PARALLEL SAFE STRICT;
+
+-- configuração portuguese que ignora acentos: "médico" e "medico" viram o mesmo lexema
+DO $$
+BEGIN
+  IF NOT EXISTS (SELECT 1 FROM pg_ts_config WHERE cfgname = 'portuguese_unaccent') THEN
+    CREATE TEXT SEARCH CONFIGURATION portuguese_unaccent (COPY = portuguese);
+    ALTER TEXT SEARCH CONFIGURATION portuguese_unaccent
+      ALTER MAPPING FOR hword, hword_part, word WITH unaccent, portuguese_stem;
+  END IF;
+END
+$$;
+
+-- documento da busca textual: descrição (peso A), categoria (B) e observações (C)
+CREATE OR REPLACE FUNCTION busca_documento(descricao text, categoria text, observacoes text) RETURNS tsvector AS $$
+  SELECT setweight(to_tsvector('portuguese_unaccent'::regconfig, COALESCE(descricao, '')), 'A') ||
+         setweight(to_tsvector('portuguese_unaccent'::regconfig, COALESCE(categoria, '')), 'B') ||
+         setweight(to_tsvector('portuguese_unaccent'::regconfig, COALESCE(observacoes, '')), 'C')
+$$ LANGUAGE sql IMMUTABLE PARALLEL SAFE;
+
+-- texto da busca aproximada (trigramas): os mesmos campos, em minúsculas e sem acentos
+CREATE OR REPLACE FUNCTION busca_texto(descricao text, categoria text, observacoes text) RETURNS text AS $$
+  SELECT f_unaccent(lower(concat_ws(' ', descricao, categoria, observacoes)))
+$$ LANGUAGE sql IMMUTABLE PARALLEL SAFE;
+
+CREATE INDEX IF NOT EXISTS idx_expenses_busca ON expenses USING GIN (busca_documento(descricao, categoria, observacoes));
+CREATE INDEX IF NOT EXISTS idx_expenses_busca_trgm ON expenses USING GIN (busca_texto(descricao, categoria, observacoes) gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS idx_incomes_busca ON incomes USING GIN (busca_documento(descricao, categoria, observacoes));
+CREATE INDEX IF NOT EXISTS idx_incomes_busca_trgm ON incomes USING GIN (busca_texto(descricao, categoria, observacoes) gin_trgm_ops);
@@ -0,0 +1,26 @@
+-- divisão de um lançamento em linhas com categoria, valor e observação próprias;
+-- as linhas somam o valor do lançamento e substituem a categoria dele nos totais por categoria
+CREATE TABLE IF NOT EXISTS expense_splits (
+  id UUID PRIMARY KEY,
+  expense_id UUID REFERENCES expenses(id) ON DELETE CASCADE,
+  category_id UUID REFERENCES categories(id) ON DELETE SET NULL,
+  categoria TEXT NOT NULL DEFAULT '',
+  valor NUMERIC(15,2) NOT NULL CHECK (valor > 0),
+  observacao TEXT,
+  posicao INT NOT NULL DEFAULT 0
+);
+
+CREATE TABLE IF NOT EXISTS income_splits (
+  id UUID PRIMARY KEY,
+  income_id UUID REFERENCES incomes(id) ON DELETE CASCADE,
+  category_id UUID REFERENCES categories(id) ON DELETE SET NULL,
+  categoria TEXT NOT NULL DEFAULT '',
+  valor NUMERIC(15,2) NOT NULL CHECK (valor > 0),
+  observacao TEXT,
+  posicao INT NOT NULL DEFAULT 0
+);
+
+CREATE INDEX IF NOT EXISTS idx_expense_splits_expense ON expense_splits(expense_id);
+CREATE INDEX IF NOT EXISTS idx_expense_splits_category ON expense_splits(category_id);
+CREATE INDEX IF NOT EXISTS idx_income_splits_income ON income_splits(income_id);
+CREATE INDEX IF NOT EXISTS idx_income_splits_category ON income_splits(category_id);
@@ -0,0 +1,5 @@
+-- excluir uma conta de investimento não pode apagar as operações dela:
+-- posições, lucro realizado e o histórico do patrimônio dependem delas
+ALTER TABLE investment_transactions DROP CONSTRAINT IF EXISTS investment_transactions_conta_id_fkey;
+ALTER TABLE investment_transactions ADD CONSTRAINT investment_transactions_conta_id_fkey
+  FOREIGN KEY (conta_id) REFERENCES accounts(id) ON DELETE RESTRICT;
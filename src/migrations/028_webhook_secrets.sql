@@ -0,0 +1,7 @@
+-- segredo de assinatura do webhook, um por usuário: só ele valida as próprias
+-- entregas e não consegue forjar as dos outros
+ALTER TABLE reminder_preferences ADD COLUMN IF NOT EXISTS webhook_secret TEXT;
+
+UPDATE reminder_preferences
+SET webhook_secret = replace(gen_random_uuid()::text || gen_random_uuid()::text, '-', '')
+WHERE webhook_url IS NOT NULL AND webhook_secret IS NULL;
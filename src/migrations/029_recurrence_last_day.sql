@@ -0,0 +1,19 @@
+-- regras guardadas com o dia herdado do início (29 a 31) passam a cair no
+-- último dia dos meses mais curtos em vez de pular esses meses:
+-- BYMONTHDAY=31 vira BYMONTHDAY=28,29,30,31;BYSETPOS=-1
+UPDATE recurring_expenses SET regra = regexp_replace(regra, 'BYMONTHDAY=(29|30|31)(;|$)',
+    'BYMONTHDAY=' || CASE substring(regra FROM 'BYMONTHDAY=(29|30|31)(?:;|$)')
+      WHEN '29' THEN '28,29' WHEN '30' THEN '28,29,30' ELSE '28,29,30,31' END || ';BYSETPOS=-1\2')
+WHERE regra ~ '^FREQ=MONTHLY;(INTERVAL=\d+;)?BYMONTHDAY=(29|30|31)(;|$)' AND regra !~ 'BYSETPOS';
+
+UPDATE recurring_incomes SET regra = regexp_replace(regra, 'BYMONTHDAY=(29|30|31)(;|$)',
+    'BYMONTHDAY=' || CASE substring(regra FROM 'BYMONTHDAY=(29|30|31)(?:;|$)')
+      WHEN '29' THEN '28,29' WHEN '30' THEN '28,29,30' ELSE '28,29,30,31' END || ';BYSETPOS=-1\2')
+WHERE regra ~ '^FREQ=MONTHLY;(INTERVAL=\d+;)?BYMONTHDAY=(29|30|31)(;|$)' AND regra !~ 'BYSETPOS';
+
+-- 29 de fevereiro cai no dia 28 fora dos anos bissextos
+UPDATE recurring_expenses SET regra = regexp_replace(regra, 'BYMONTH=2;BYMONTHDAY=29(;|$)', 'BYMONTH=2;BYMONTHDAY=28,29;BYSETPOS=-1\1')
+WHERE regra ~ '^FREQ=YEARLY;' AND regra !~ 'BYSETPOS';
+
+UPDATE recurring_incomes SET regra = regexp_replace(regra, 'BYMONTH=2;BYMONTHDAY=29(;|$)', 'BYMONTH=2;BYMONTHDAY=28,29;BYSETPOS=-1\1')
+WHERE regra ~ '^FREQ=YEARLY;' AND regra !~ 'BYSETPOS';
@@ -0,0 +1,3 @@
+-- ocorrências recorrentes editadas uma a uma (scope=this): alterações na
+-- série inteira (scope=all) não as sobrescrevem nem as recriam
+ALTER TABLE expenses ADD COLUMN IF NOT EXISTS editada BOOLEAN NOT NULL DEFAULT false;
@@ -0,0 +1,17 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type Account struct {
+	ID           uuid.UUID `json:"id"`
+	UserID       uuid.UUID `json:"user_id"`
+	Nome         string    `json:"nome"`
+	Tipo         string    `json:"tipo"` // corrente, poupanca, cartao_credito, carteira, investimento
+	Moeda        string    `json:"moeda"`
+	SaldoInicial Money     `json:"saldo_inicial"` // saldo antes do primeiro lançamento registrado
+	CreatedAt    time.Time `json:"created_at"`
+}
@@ -0,0 +1,31 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Tipos de arquivo aceitos como anexo
+var AttachmentContentTypes = []string{"application/pdf", "image/jpeg", "image/png"}
+
+type Attachment struct {
+	ID          uuid.UUID `json:"id"`
+	ExpenseID   uuid.UUID `json:"expense_id"`
+	UserID      uuid.UUID `json:"user_id"`
+	Nome        string    `json:"nome"`
+	ContentType string    `json:"content_type"`
+	Tamanho     int64     `json:"tamanho"`
+	SHA256      string    `json:"sha256"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// ValidAttachmentType indica se o tipo de arquivo é aceito
+func ValidAttachmentType(ct string) bool {
+	for _, t := range AttachmentContentTypes {
+		if t == ct {
+			return true
+		}
+	}
+	return false
+}
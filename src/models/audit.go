@@ -0,0 +1,64 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Entidades com histórico de alterações (o nome da tabela)
+const (
+	AuditExpenses   = "expenses"
+	AuditIncomes    = "incomes"
+	AuditCategories = "categories"
+)
+
+// ValidAuditEntity indica se a entidade tem histórico de alterações
+func ValidAuditEntity(e string) bool {
+	return e == AuditExpenses || e == AuditIncomes || e == AuditCategories
+}
+
+// Ações registradas no histórico
+const (
+	AuditCreate  = "criacao"
+	AuditUpdate  = "alteracao"
+	AuditDelete  = "exclusao"
+	AuditRestore = "restauracao"       // saída da lixeira
+	AuditPay     = "pagamento"         // pagamento da despesa ou confirmação da receita
+	AuditUnpay   = "estorno_pagamento" // pagamento desfeito ou excluído
+)
+
+// ValidAuditAction indica se a ação é uma das registradas no histórico
+func ValidAuditAction(a string) bool {
+	switch a {
+	case AuditCreate, AuditUpdate, AuditDelete, AuditRestore, AuditPay, AuditUnpay:
+		return true
+	}
+	return false
+}
+
+// Origens de uma alteração
+const (
+	AuditSourceAPI       = "api"
+	AuditSourceImport    = "importacao"
+	AuditSourceRecurring = "recorrencia" // job que materializa lançamentos recorrentes
+)
+
+// AuditChange é o valor de um campo antes e depois da alteração (null quando não existia)
+type AuditChange struct {
+	De   interface{} `json:"de"`
+	Para interface{} `json:"para"`
+}
+
+// AuditEntry é um registro do histórico de alterações, que nunca é alterado nem excluído
+type AuditEntry struct {
+	ID         uuid.UUID              `json:"id"`
+	UserID     uuid.UUID              `json:"user_id"`           // dono do lançamento
+	AtorID     *uuid.UUID             `json:"ator_id,omitempty"` // quem fez a alteração; vazio nos jobs
+	Entidade   string                 `json:"entidade"`
+	EntidadeID uuid.UUID              `json:"entidade_id"`
+	Acao       string                 `json:"acao"`
+	Origem     string                 `json:"origem"`
+	Alteracoes map[string]AuditChange `json:"alteracoes"` // campo a campo
+	CreatedAt  time.Time              `json:"created_at"`
+}
@@ -0,0 +1,135 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Situação de uma categoria em relação ao orçamento do mês
+const (
+	BudgetWithin = "dentro"    // gasto dentro do orçamento
+	BudgetAtRisk = "em_risco"  // no ritmo atual, o gasto vai passar do orçamento até o fim do mês
+	BudgetOver   = "estourado" // gasto acima do orçamento
+)
+
+// BudgetMonthLayout é o formato dos meses de orçamento no JSON
+const BudgetMonthLayout = "2006-01"
+
+// Budget é o valor planejado para uma categoria (e suas subcategorias) num mês
+type Budget struct {
+	ID          uuid.UUID `json:"id"`
+	UserID      uuid.UUID `json:"user_id"`
+	CategoriaID uuid.UUID `json:"categoria_id"`
+	Categoria   string    `json:"categoria"`
+	Valor       Money     `json:"valor"` // na moeda base do usuário
+	Recorrente  bool      `json:"recorrente"`
+	Mes         string    `json:"mes"`           // YYYY-MM: o mês do orçamento ou, se recorrente, o primeiro mês
+	Fim         *string   `json:"fim,omitempty"` // YYYY-MM: último mês de um orçamento recorrente
+	Rollover    bool      `json:"rollover"`      // transporta o saldo não gasto para o mês seguinte
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// BudgetProgress compara o orçado com o gasto de uma categoria no mês
+type BudgetProgress struct {
+	BudgetID        uuid.UUID `json:"budget_id"`
+	CategoriaID     uuid.UUID `json:"categoria_id"`
+	Categoria       string    `json:"categoria"`
+	Orcado          Money     `json:"orcado"`
+	Transportado    Money     `json:"transportado"` // saldo trazido dos meses anteriores (rollover)
+	Disponivel      Money     `json:"disponivel"`   // orcado + transportado
+	Gasto           Money     `json:"gasto"`
+	Restante        Money     `json:"restante"` // negativo quando estourado
+	Percentual      float64   `json:"percentual"`
+	Projetado       *Money    `json:"projetado,omitempty"` // gasto previsto até o fim do mês, só no mês corrente
+	Status          string    `json:"status"`
+	TaxasUtilizadas RatesUsed `json:"taxas_utilizadas,omitempty"`
+	MoedasSemTaxa   []string  `json:"moedas_sem_taxa,omitempty"`
+}
+
+// ParseBudgetMonth lê um mês no formato YYYY-MM
+func ParseBudgetMonth(s string) (time.Time, error) {
+	return time.Parse(BudgetMonthLayout, s)
+}
+
+// BudgetStatus classifica o gasto frente ao disponível; projetado (opcional)
+// é o gasto estimado até o fim do mês no ritmo atual
+func BudgetStatus(disponivel, gasto Money, projetado *Money) string {
+	switch {
+	case gasto > disponivel:
+		return BudgetOver
+	case projetado != nil && *projetado > disponivel:
+		return BudgetAtRisk
+	}
+	return BudgetWithin
+}
+
+// CategoryBudgets são os orçamentos de uma categoria, para achar o vigente em cada mês
+type CategoryBudgets []Budget
+
+// Effective devolve o orçamento em vigor no mês: o específico do mês ou o
+// recorrente mais recente que cobre o mês
+func (cb CategoryBudgets) Effective(month time.Time) *Budget {
+	key := month.Format(BudgetMonthLayout)
+	var best *Budget
+	for i := range cb {
+		b := &cb[i]
+		if !b.Recorrente {
+			if b.Mes == key {
+				return b
+			}
+			continue
+		}
+		if b.Mes > key || (b.Fim != nil && *b.Fim < key) {
+			continue
+		}
+		if best == nil || b.Mes > best.Mes {
+			best = b
+		}
+	}
+	return best
+}
+
+// FirstMonth é o mês mais antigo entre os orçamentos da categoria
+func (cb CategoryBudgets) FirstMonth() time.Time {
+	first, _ := ParseBudgetMonth(cb[0].Mes)
+	for _, b := range cb[1:] {
+		if m, _ := ParseBudgetMonth(b.Mes); m.Before(first) {
+			first = m
+		}
+	}
+	return first
+}
+
+// HasRollover indica se algum orçamento da categoria transporta o saldo
+func (cb CategoryBudgets) HasRollover() bool {
+	for _, b := range cb {
+		if b.Rollover {
+			return true
+		}
+	}
+	return false
+}
+
+// Carry é o saldo transportado para month: o que sobrou de cada mês com
+// rollover passa adiante; um mês sem rollover (ou sem orçamento) zera o saldo
+// e um mês estourado não deixa saldo negativo. spent é o gasto por mês (YYYY-MM).
+func (cb CategoryBudgets) Carry(month time.Time, spent map[string]Money) Money {
+	var carry Money
+	if !cb.HasRollover() {
+		return carry
+	}
+	for m := cb.FirstMonth(); m.Before(month); m = m.AddDate(0, 1, 0) {
+		b := cb.Effective(m)
+		if b == nil || !b.Rollover {
+			carry = 0
+			continue
+		}
+		left := b.Valor + carry - spent[m.Format(BudgetMonthLayout)]
+		if left < 0 {
+			left = 0
+		}
+		carry = left
+	}
+	return carry
+}
@@ -0,0 +1,83 @@
+package models
+
+import "testing"
+
+func TestCategoryBudgetsEffective(t *testing.T) {
+	fim := "2026-06"
+	cb := CategoryBudgets{
+		{Valor: 10000, Recorrente: true, Mes: "2026-01", Fim: &fim},
+		{Valor: 12000, Recorrente: true, Mes: "2026-04"},
+		{Valor: 50000, Mes: "2026-05"},
+	}
+	cases := []struct {
+		mes  string
+		want Money
+	}{
+		{"2025-12", 0},
+		{"2026-01", 10000},
+		{"2026-03", 10000},
+		{"2026-04", 12000}, // o recorrente mais recente vence
+		{"2026-05", 50000}, // o orçamento do mês vence o recorrente
+		{"2026-07", 12000},
+	}
+	for _, c := range cases {
+		m, _ := ParseBudgetMonth(c.mes)
+		got := cb.Effective(m)
+		if (got == nil && c.want != 0) || (got != nil && got.Valor != c.want) {
+			t.Errorf("Effective(%s) = %+v, esperava %d", c.mes, got, c.want)
+		}
+	}
+	if first := cb.FirstMonth().Format(BudgetMonthLayout); first != "2026-01" {
+		t.Errorf("FirstMonth = %s", first)
+	}
+}
+
+func TestCategoryBudgetsCarry(t *testing.T) {
+	cb := CategoryBudgets{
+		{Valor: 10000, Recorrente: true, Mes: "2026-01", Rollover: true},
+		{Valor: 15000, Mes: "2026-03"}, // sem rollover: zera o saldo
+	}
+	spent := map[string]Money{"2026-01": 6000, "2026-02": 12000, "2026-03": 1000, "2026-04": 11000}
+	cases := []struct {
+		mes  string
+		want Money
+	}{
+		{"2026-01", 0},
+		{"2026-02", 4000},  // sobraram 4000 de janeiro
+		{"2026-03", 2000},  // 10000 + 4000 - 12000
+		{"2026-04", 0},     // março não transporta
+		{"2026-05", 0},     // abril estourou: o saldo não fica negativo
+		{"2026-06", 10000}, // maio sem gasto
+	}
+	for _, c := range cases {
+		m, _ := ParseBudgetMonth(c.mes)
+		if got := cb.Carry(m, spent); got != c.want {
+			t.Errorf("Carry(%s) = %d, esperava %d", c.mes, got, c.want)
+		}
+	}
+
+	sem := CategoryBudgets{{Valor: 10000, Recorrente: true, Mes: "2026-01"}}
+	if m, _ := ParseBudgetMonth("2026-03"); sem.HasRollover() || sem.Carry(m, nil) != 0 {
+		t.Error("orçamento sem rollover não transporta saldo")
+	}
+}
+
+func TestBudgetStatus(t *testing.T) {
+	projetado := Money(12000)
+	cases := []struct {
+		gasto     Money
+		projetado *Money
+		want      string
+	}{
+		{5000, nil, BudgetWithin},
+		{10000, nil, BudgetWithin},
+		{10001, nil, BudgetOver},
+		{5000, &projetado, BudgetAtRisk},
+		{11000, &projetado, BudgetOver},
+	}
+	for _, c := range cases {
+		if got := BudgetStatus(10000, c.gasto, c.projetado); got != c.want {
+			t.Errorf("BudgetStatus(10000, %d) = %s, esperava %s", c.gasto, got, c.want)
+		}
+	}
+}
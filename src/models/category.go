@@ -1,14 +1,81 @@
 package models
 
 import (
+	"regexp"
 	"time"
 
 	"github.com/google/uuid"
 )
 
 type Category struct {
-	ID        uuid.UUID `json:"id"`
-	UserID    uuid.UUID `json:"user_id"`
-	Name      string    `json:"name"`
-	CreatedAt time.Time `json:"created_at"`
+	ID        uuid.UUID  `json:"id"`
+	UserID    uuid.UUID  `json:"user_id"`
+	ParentID  *uuid.UUID `json:"parent_id,omitempty"` // nil para categorias raiz
+	Name      string     `json:"name"`
+	Posicao   int        `json:"posicao"`         // ordem entre as categorias irmãs
+	Kind      string     `json:"kind"`            // expense, income ou both
+	Color     *string    `json:"color,omitempty"` // hexadecimal, ex: "#4CAF50"
+	Icon      *string    `json:"icon,omitempty"`
+	Archived  bool       `json:"archived"` // arquivadas saem dos seletores, mas mantêm o histórico
+	CreatedAt time.Time  `json:"created_at"`
+
+	// Preenchidos na listagem
+	Caminho string     `json:"caminho,omitempty"` // ex: "Moradia > Aluguel"
+	Nivel   int        `json:"nivel"`             // 0 para categorias raiz
+	Filhos  []Category `json:"filhos,omitempty"`  // apenas com ?tree=true
+}
+
+// Políticas para as subcategorias ao excluir uma categoria
+const (
+	ChildrenRestrict = "restrict" // recusa a exclusão se houver subcategorias
+	ChildrenPromote  = "promote"  // sobe as subcategorias para o pai da excluída
+	ChildrenCascade  = "cascade"  // exclui toda a subárvore
+)
+
+// Tipos de categoria
+const (
+	CategoryExpense = "expense"
+	CategoryIncome  = "income"
+	CategoryBoth    = "both"
+)
+
+// CategorizedTables são as tabelas que apontam para categories por category_id.
+// A coluna categoria (texto) é mantida como cópia do nome atual. Os empréstimos
+// entram para que as parcelas geradas depois (amortizações) herdem a categoria.
+var CategorizedTables = []string{"expenses", "incomes", "recurring_expenses", "recurring_incomes", "expense_splits", "income_splits", "loans"}
+
+// IsInSubtree indica se candidate é a própria categoria root ou uma de suas
+// descendentes, subindo de candidate pelos pais (parents: id -> parent_id).
+// Um ciclo já existente nos pais não trava a busca.
+func IsInSubtree(parents map[uuid.UUID]uuid.UUID, root, candidate uuid.UUID) bool {
+	seen := make(map[uuid.UUID]bool)
+	for id := candidate; !seen[id]; {
+		if id == root {
+			return true
+		}
+		seen[id] = true
+		parent, ok := parents[id]
+		if !ok {
+			return false
+		}
+		id = parent
+	}
+	return false
+}
+
+// ValidCategoryKind indica se o tipo de categoria é suportado
+func ValidCategoryKind(kind string) bool {
+	return kind == CategoryExpense || kind == CategoryIncome || kind == CategoryBoth
+}
+
+// CategoryAppliesTo indica se uma categoria do tipo kind pode ser usada em lançamentos do tipo use
+func CategoryAppliesTo(kind, use string) bool {
+	return kind == CategoryBoth || kind == use
+}
+
+var hexColor = regexp.MustCompile(`^#[0-9A-Fa-f]{6}$`)
+
+// ValidCategoryColor aceita cores no formato #RRGGBB
+func ValidCategoryColor(color string) bool {
+	return hexColor.MatchString(color)
 }
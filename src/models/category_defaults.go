@@ -0,0 +1,73 @@
+package models
+
+import "strings"
+
+// DefaultCategory é uma categoria criada automaticamente para novos usuários
+type DefaultCategory struct {
+	Name     string
+	Kind     string
+	Color    string
+	Icon     string
+	Children []string // subcategorias, com o mesmo tipo, cor e ícone da categoria pai
+}
+
+// Idiomas com categorias padrão
+const (
+	LocalePtBR = "pt-BR"
+	LocaleEn   = "en"
+
+	DefaultLocale = LocalePtBR
+)
+
+var defaultCategories = map[string][]DefaultCategory{
+	LocalePtBR: {
+		{Name: "Moradia", Kind: CategoryExpense, Color: "#8D6E63", Icon: "home", Children: []string{"Aluguel", "Condomínio", "Contas da casa"}},
+		{Name: "Alimentação", Kind: CategoryExpense, Color: "#FF7043", Icon: "utensils", Children: []string{"Mercado", "Restaurantes"}},
+		{Name: "Transporte", Kind: CategoryExpense, Color: "#42A5F5", Icon: "car", Children: []string{"Combustível", "Transporte público"}},
+		{Name: "Saúde", Kind: CategoryExpense, Color: "#EF5350", Icon: "heart-pulse"},
+		{Name: "Educação", Kind: CategoryExpense, Color: "#5C6BC0", Icon: "graduation-cap"},
+		{Name: "Lazer", Kind: CategoryExpense, Color: "#AB47BC", Icon: "gamepad"},
+		{Name: "Compras", Kind: CategoryExpense, Color: "#EC407A", Icon: "shopping-bag"},
+		{Name: "Assinaturas", Kind: CategoryExpense, Color: "#26A69A", Icon: "repeat"},
+		{Name: "Impostos e taxas", Kind: CategoryExpense, Color: "#78909C", Icon: "landmark"},
+		{Name: "Salário", Kind: CategoryIncome, Color: "#66BB6A", Icon: "briefcase"},
+		{Name: "Freelance", Kind: CategoryIncome, Color: "#9CCC65", Icon: "laptop"},
+		{Name: "Rendimentos", Kind: CategoryIncome, Color: "#FFCA28", Icon: "chart-line"},
+		{Name: "Reembolsos", Kind: CategoryIncome, Color: "#29B6F6", Icon: "rotate-left"},
+		{Name: "Outros", Kind: CategoryBoth, Color: "#BDBDBD", Icon: "ellipsis"},
+	},
+	LocaleEn: {
+		{Name: "Housing", Kind: CategoryExpense, Color: "#8D6E63", Icon: "home", Children: []string{"Rent", "HOA fees", "Utilities"}},
+		{Name: "Food", Kind: CategoryExpense, Color: "#FF7043", Icon: "utensils", Children: []string{"Groceries", "Restaurants"}},
+		{Name: "Transportation", Kind: CategoryExpense, Color: "#42A5F5", Icon: "car", Children: []string{"Fuel", "Public transit"}},
+		{Name: "Health", Kind: CategoryExpense, Color: "#EF5350", Icon: "heart-pulse"},
+		{Name: "Education", Kind: CategoryExpense, Color: "#5C6BC0", Icon: "graduation-cap"},
+		{Name: "Entertainment", Kind: CategoryExpense, Color: "#AB47BC", Icon: "gamepad"},
+		{Name: "Shopping", Kind: CategoryExpense, Color: "#EC407A", Icon: "shopping-bag"},
+		{Name: "Subscriptions", Kind: CategoryExpense, Color: "#26A69A", Icon: "repeat"},
+		{Name: "Taxes & fees", Kind: CategoryExpense, Color: "#78909C", Icon: "landmark"},
+		{Name: "Salary", Kind: CategoryIncome, Color: "#66BB6A", Icon: "briefcase"},
+		{Name: "Freelance", Kind: CategoryIncome, Color: "#9CCC65", Icon: "laptop"},
+		{Name: "Investment income", Kind: CategoryIncome, Color: "#FFCA28", Icon: "chart-line"},
+		{Name: "Refunds", Kind: CategoryIncome, Color: "#29B6F6", Icon: "rotate-left"},
+		{Name: "Other", Kind: CategoryBoth, Color: "#BDBDBD", Icon: "ellipsis"},
+	},
+}
+
+// NormalizeLocale reduz um idioma (ex: "pt", "pt-PT", "en-US,en;q=0.9") a um
+// dos idiomas com categorias padrão
+func NormalizeLocale(locale string) string {
+	locale = strings.ToLower(strings.TrimSpace(locale))
+	switch {
+	case strings.HasPrefix(locale, "pt"):
+		return LocalePtBR
+	case strings.HasPrefix(locale, "en"):
+		return LocaleEn
+	}
+	return DefaultLocale
+}
+
+// DefaultCategories devolve as categorias padrão do idioma
+func DefaultCategories(locale string) []DefaultCategory {
+	return defaultCategories[NormalizeLocale(locale)]
+}
@@ -0,0 +1,52 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestIsInSubtree(t *testing.T) {
+	// raiz -> filho -> neto; outra é uma raiz à parte
+	raiz, filho, neto, outra := uuid.New(), uuid.New(), uuid.New(), uuid.New()
+	parents := map[uuid.UUID]uuid.UUID{filho: raiz, neto: filho}
+
+	cases := []struct {
+		name            string
+		root, candidate uuid.UUID
+		want            bool
+	}{
+		{"a própria categoria", filho, filho, true},
+		{"filha direta", raiz, filho, true},
+		{"neta", raiz, neto, true},
+		{"ancestral não é descendente", neto, raiz, false},
+		{"irmã de árvore", raiz, outra, false},
+		{"raiz para dentro de outra", outra, raiz, false},
+	}
+	for _, c := range cases {
+		if got := IsInSubtree(parents, c.root, c.candidate); got != c.want {
+			t.Errorf("%s: IsInSubtree = %v, esperava %v", c.name, got, c.want)
+		}
+	}
+
+	// pais com ciclo (dados inconsistentes) não travam a busca
+	a, b := uuid.New(), uuid.New()
+	if IsInSubtree(map[uuid.UUID]uuid.UUID{a: b, b: a}, raiz, a) {
+		t.Error("ciclo a <-> b não contém raiz")
+	}
+}
+
+func TestCategoryKinds(t *testing.T) {
+	for _, kind := range []string{CategoryExpense, CategoryIncome, CategoryBoth} {
+		if !ValidCategoryKind(kind) {
+			t.Errorf("ValidCategoryKind(%q) = false", kind)
+		}
+	}
+	if ValidCategoryKind("transfer") {
+		t.Error(`ValidCategoryKind("transfer") = true`)
+	}
+	if !CategoryAppliesTo(CategoryBoth, CategoryIncome) || !CategoryAppliesTo(CategoryExpense, CategoryExpense) ||
+		CategoryAppliesTo(CategoryIncome, CategoryExpense) {
+		t.Error("CategoryAppliesTo não respeita o tipo da categoria")
+	}
+}
@@ -0,0 +1,135 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Tipos de campo personalizado
+const (
+	FieldText    = "texto"
+	FieldNumber  = "numero"
+	FieldDate    = "data" // YYYY-MM-DD
+	FieldSelect  = "selecao"
+	FieldBoolean = "booleano"
+)
+
+// ValidFieldType indica se o tipo de campo personalizado é suportado
+func ValidFieldType(t string) bool {
+	switch t {
+	case FieldText, FieldNumber, FieldDate, FieldSelect, FieldBoolean:
+		return true
+	}
+	return false
+}
+
+// CustomField é um campo definido pelo usuário para despesas e receitas
+// (ex: número da nota fiscal, placa do carro, CNPJ do fornecedor)
+type CustomField struct {
+	ID        uuid.UUID `json:"id"`
+	UserID    uuid.UUID `json:"user_id"`
+	Nome      string    `json:"nome"`
+	Tipo      string    `json:"tipo"`             // texto, numero, data, selecao ou booleano
+	Kind      string    `json:"kind"`             // expense, income ou both, como nas categorias
+	Opcoes    []string  `json:"opcoes,omitempty"` // valores aceitos por campos do tipo selecao
+	Usos      int       `json:"usos"`             // quantidade de lançamentos com valor no campo
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Validate confere nome, tipo e opções do campo; devolve a mensagem de erro ou ""
+func (f *CustomField) Validate() string {
+	f.Nome = strings.TrimSpace(f.Nome)
+	if f.Nome == "" {
+		return "Nome do campo é obrigatório"
+	}
+	if !ValidFieldType(f.Tipo) {
+		return "Tipo inválido: use texto, numero, data, selecao ou booleano"
+	}
+	if f.Kind == "" {
+		f.Kind = CategoryBoth
+	}
+	if !ValidCategoryKind(f.Kind) {
+		return "Kind inválido: use expense, income ou both"
+	}
+
+	if f.Tipo != FieldSelect {
+		f.Opcoes = nil
+		return ""
+	}
+	seen := make(map[string]bool, len(f.Opcoes))
+	opcoes := make([]string, 0, len(f.Opcoes))
+	for _, o := range f.Opcoes {
+		o = strings.TrimSpace(o)
+		if o == "" || seen[o] {
+			continue
+		}
+		seen[o] = true
+		opcoes = append(opcoes, o)
+	}
+	if len(opcoes) == 0 {
+		return "Campos do tipo selecao precisam de ao menos uma opção"
+	}
+	f.Opcoes = opcoes
+	return ""
+}
+
+// Normalize confere se v é um valor válido para o campo e o devolve na forma
+// gravada: texto sem espaços nas pontas, número, data YYYY-MM-DD, uma das
+// opções ou booleano
+func (f CustomField) Normalize(v interface{}) (interface{}, error) {
+	switch f.Tipo {
+	case FieldText:
+		if s, ok := v.(string); ok && strings.TrimSpace(s) != "" {
+			return strings.TrimSpace(s), nil
+		}
+		return nil, fmt.Errorf("campo %q deve ser um texto", f.Nome)
+	case FieldNumber:
+		if n, ok := v.(float64); ok {
+			return n, nil
+		}
+		return nil, fmt.Errorf("campo %q deve ser um número", f.Nome)
+	case FieldDate:
+		if s, ok := v.(string); ok {
+			if _, err := time.Parse("2006-01-02", s); err == nil {
+				return s, nil
+			}
+		}
+		return nil, fmt.Errorf("campo %q deve ser uma data no formato YYYY-MM-DD", f.Nome)
+	case FieldSelect:
+		if s, ok := v.(string); ok {
+			for _, o := range f.Opcoes {
+				if s == o {
+					return s, nil
+				}
+			}
+		}
+		return nil, fmt.Errorf("campo %q deve ser uma das opções: %s", f.Nome, strings.Join(f.Opcoes, ", "))
+	case FieldBoolean:
+		if b, ok := v.(bool); ok {
+			return b, nil
+		}
+		return nil, fmt.Errorf("campo %q deve ser true ou false", f.Nome)
+	}
+	return nil, fmt.Errorf("campo %q tem tipo desconhecido", f.Nome)
+}
+
+// CustomValues são os valores dos campos personalizados de um lançamento,
+// pelo nome do campo. Lê o JSON agregado pelo banco (jsonb_object_agg).
+type CustomValues map[string]interface{}
+
+func (c *CustomValues) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		*c = CustomValues{}
+		return nil
+	case []byte:
+		return json.Unmarshal(v, c)
+	case string:
+		return json.Unmarshal([]byte(v), c)
+	}
+	return fmt.Errorf("tipo não suportado para CustomValues: %T", src)
+}
@@ -0,0 +1,72 @@
+package models
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCustomFieldValidate(t *testing.T) {
+	f := CustomField{Nome: "  Loja ", Tipo: FieldSelect, Opcoes: []string{" A ", "B", "A", ""}}
+	if msg := f.Validate(); msg != "" {
+		t.Fatalf("Validate: %s", msg)
+	}
+	if f.Nome != "Loja" || f.Kind != CategoryBoth || !reflect.DeepEqual(f.Opcoes, []string{"A", "B"}) {
+		t.Errorf("Validate = %+v", f)
+	}
+
+	texto := CustomField{Nome: "Nota", Tipo: FieldText, Opcoes: []string{"x"}}
+	if msg := texto.Validate(); msg != "" || texto.Opcoes != nil {
+		t.Errorf("opções de campo texto deveriam ser descartadas: %q, %+v", msg, texto.Opcoes)
+	}
+
+	for _, f := range []CustomField{
+		{Nome: " ", Tipo: FieldText},
+		{Nome: "Placa", Tipo: "lista"},
+		{Nome: "Placa", Tipo: FieldText, Kind: "transfer"},
+		{Nome: "Loja", Tipo: FieldSelect, Opcoes: []string{" ", ""}},
+	} {
+		if msg := f.Validate(); msg == "" {
+			t.Errorf("Validate(%+v): esperava erro", f)
+		}
+	}
+}
+
+func TestCustomFieldNormalize(t *testing.T) {
+	cases := []struct {
+		tipo string
+		in   interface{}
+		want interface{}
+	}{
+		{FieldText, "  NF 123 ", "NF 123"},
+		{FieldNumber, 42.5, 42.5},
+		{FieldDate, "2026-03-10", "2026-03-10"},
+		{FieldSelect, "B", "B"},
+		{FieldBoolean, false, false},
+	}
+	for _, c := range cases {
+		f := CustomField{Nome: "campo", Tipo: c.tipo, Opcoes: []string{"A", "B"}}
+		got, err := f.Normalize(c.in)
+		if err != nil || got != c.want {
+			t.Errorf("%s: Normalize(%#v) = %#v, %v; esperava %#v", c.tipo, c.in, got, err, c.want)
+		}
+	}
+
+	invalid := []struct {
+		tipo string
+		in   interface{}
+	}{
+		{FieldText, "   "},
+		{FieldText, 10.0},
+		{FieldNumber, "10"},
+		{FieldDate, "10/03/2026"},
+		{FieldSelect, "C"},
+		{FieldBoolean, "true"},
+		{"lista", "A"},
+	}
+	for _, c := range invalid {
+		f := CustomField{Nome: "campo", Tipo: c.tipo, Opcoes: []string{"A", "B"}}
+		if got, err := f.Normalize(c.in); err == nil {
+			t.Errorf("%s: Normalize(%#v) = %#v: esperava erro", c.tipo, c.in, got)
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Modos de orçamento do usuário
+const (
+	BudgetModeCategories = "categorias" // orçamentos por categoria (padrão)
+	BudgetModeEnvelopes  = "envelopes"  // orçamento base zero: toda receita vai para um envelope
+)
+
+// Tipos de movimentação de envelope
+const (
+	EnvelopeAssign   = "atribuicao"
+	EnvelopeTransfer = "transferencia"
+)
+
+// EnvelopeMovement é uma atribuição (ou devolução, se negativa) de dinheiro a
+// um envelope, ou uma perna de transferência entre envelopes
+type EnvelopeMovement struct {
+	ID              uuid.UUID  `json:"id"`
+	UserID          uuid.UUID  `json:"user_id"`
+	CategoriaID     uuid.UUID  `json:"categoria_id"`
+	Categoria       string     `json:"categoria"`
+	Mes             string     `json:"mes"` // YYYY-MM
+	Valor           Money      `json:"valor"`
+	Tipo            string     `json:"tipo"`
+	TransferenciaID *uuid.UUID `json:"transferencia_id,omitempty"` // liga as duas pernas de uma transferência
+	Observacoes     *string    `json:"observacoes,omitempty"`
+	CreatedAt       time.Time  `json:"created_at"`
+}
+
+// Envelope é a situação de um envelope no mês
+type Envelope struct {
+	CategoriaID  uuid.UUID `json:"categoria_id"`
+	Categoria    string    `json:"categoria"`
+	Transportado Money     `json:"transportado"` // saldo positivo trazido do mês anterior
+	Atribuido    Money     `json:"atribuido"`
+	Gasto        Money     `json:"gasto"`
+	Disponivel   Money     `json:"disponivel"` // negativo quando o gasto passou do envelope
+}
+
+// EnvelopeMonth é o orçamento base zero de um mês, na moeda base do usuário
+type EnvelopeMonth struct {
+	Mes              string     `json:"mes"`
+	MoedaBase        string     `json:"moeda_base"`
+	Receitas         Money      `json:"receitas"` // recebidas no mês
+	AAtribuir        Money      `json:"a_atribuir"`
+	Atribuido        Money      `json:"atribuido"`
+	Gasto            Money      `json:"gasto"`
+	GastoSemEnvelope Money      `json:"gasto_sem_envelope"` // despesas sem envelope, descontadas do a atribuir
+	Envelopes        []Envelope `json:"envelopes"`
+	MoedasSemTaxa    []string   `json:"moedas_sem_taxa,omitempty"`
+}
+
+// EnvelopeLedger acumula, por mês (YYYY-MM), as receitas, as atribuições e os
+// gastos de cada envelope desde o início do modo envelope
+type EnvelopeLedger struct {
+	Inicio      time.Time
+	Receitas    map[string]Money
+	SemEnvelope map[string]Money
+	Atribuido   map[uuid.UUID]map[string]Money
+	Gasto       map[uuid.UUID]map[string]Money
+}
+
+// NewEnvelopeLedger cria um razão vazio começando no mês de inicio
+func NewEnvelopeLedger(inicio time.Time) *EnvelopeLedger {
+	return &EnvelopeLedger{
+		Inicio:      time.Date(inicio.Year(), inicio.Month(), 1, 0, 0, 0, 0, time.UTC),
+		Receitas:    map[string]Money{},
+		SemEnvelope: map[string]Money{},
+		Atribuido:   map[uuid.UUID]map[string]Money{},
+		Gasto:       map[uuid.UUID]map[string]Money{},
+	}
+}
+
+// addEnvelopeValue soma valor em m[id][mes], criando o mapa do envelope se preciso
+func addEnvelopeValue(m map[uuid.UUID]map[string]Money, id uuid.UUID, mes string, valor Money) {
+	if m[id] == nil {
+		m[id] = map[string]Money{}
+	}
+	m[id][mes] += valor
+}
+
+// Assign registra valor atribuído ao envelope no mês
+func (l *EnvelopeLedger) Assign(id uuid.UUID, mes string, valor Money) {
+	addEnvelopeValue(l.Atribuido, id, mes, valor)
+}
+
+// Spend registra gasto do envelope no mês
+func (l *EnvelopeLedger) Spend(id uuid.UUID, mes string, valor Money) {
+	addEnvelopeValue(l.Gasto, id, mes, valor)
+}
+
+// Month calcula o a atribuir e o saldo de cada envelope no mês.
+//
+// O saldo positivo de um envelope passa para o mês seguinte; o negativo
+// (gasto acima do envelope) zera o envelope e sai do a atribuir do mês seguinte.
+func (l *EnvelopeLedger) Month(month time.Time) (Money, map[uuid.UUID]Envelope) {
+	ids := map[uuid.UUID]bool{}
+	for id := range l.Atribuido {
+		ids[id] = true
+	}
+	for id := range l.Gasto {
+		ids[id] = true
+	}
+
+	month = time.Date(month.Year(), month.Month(), 1, 0, 0, 0, 0, time.UTC)
+	var aAtribuir Money
+	envelopes := map[uuid.UUID]Envelope{}
+	for m := l.Inicio; !m.After(month); m = m.AddDate(0, 1, 0) {
+		key := m.Format(BudgetMonthLayout)
+		aAtribuir += l.Receitas[key] - l.SemEnvelope[key]
+
+		for id := range ids {
+			e := envelopes[id]
+			e.CategoriaID = id
+			if e.Disponivel > 0 {
+				e.Transportado = e.Disponivel
+			} else {
+				aAtribuir += e.Disponivel
+				e.Transportado = 0
+			}
+			e.Atribuido = l.Atribuido[id][key]
+			e.Gasto = l.Gasto[id][key]
+			e.Disponivel = e.Transportado + e.Atribuido - e.Gasto
+			aAtribuir -= e.Atribuido
+			envelopes[id] = e
+		}
+	}
+	return aAtribuir, envelopes
+}
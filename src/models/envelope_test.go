@@ -0,0 +1,54 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestEnvelopeLedgerMonth(t *testing.T) {
+	a, b := uuid.New(), uuid.New()
+	l := NewEnvelopeLedger(date("2026-01-15"))
+	l.Receitas["2026-01"] = 100000
+	l.SemEnvelope["2026-01"] = 5000
+	l.Assign(a, "2026-01", 50000)
+	l.Assign(b, "2026-01", 30000)
+	l.Spend(a, "2026-01", 20000)
+	l.Spend(b, "2026-01", 25000)
+	l.Spend(b, "2026-01", 15000)
+	l.Spend(a, "2026-02", 5000)
+	l.Assign(b, "2026-02", 10000)
+
+	aAtribuir, envelopes := l.Month(date("2026-01-31"))
+	// 100000 recebidos - 5000 gastos sem envelope - 80000 atribuídos
+	if aAtribuir != 15000 {
+		t.Errorf("a atribuir em janeiro = %d, esperava 15000", aAtribuir)
+	}
+	if e := envelopes[a]; e.Atribuido != 50000 || e.Gasto != 20000 || e.Disponivel != 30000 {
+		t.Errorf("envelope a em janeiro = %+v", e)
+	}
+	if e := envelopes[b]; e.Gasto != 40000 || e.Disponivel != -10000 {
+		t.Errorf("envelope b em janeiro = %+v", e)
+	}
+
+	aAtribuir, envelopes = l.Month(date("2026-02-01"))
+	// o estouro de b em janeiro (10000) e a nova atribuição (10000) saem do a atribuir
+	if aAtribuir != -5000 {
+		t.Errorf("a atribuir em fevereiro = %d, esperava -5000", aAtribuir)
+	}
+	if e := envelopes[a]; e.Transportado != 30000 || e.Gasto != 5000 || e.Disponivel != 25000 {
+		t.Errorf("envelope a em fevereiro = %+v", e)
+	}
+	if e := envelopes[b]; e.Transportado != 0 || e.Atribuido != 10000 || e.Disponivel != 10000 {
+		t.Errorf("envelope b em fevereiro = %+v", e)
+	}
+}
+
+func TestEnvelopeLedgerBeforeStart(t *testing.T) {
+	l := NewEnvelopeLedger(date("2026-03-01"))
+	l.Receitas["2026-03"] = 1000
+	l.Assign(uuid.New(), "2026-03", 500)
+	if aAtribuir, envelopes := l.Month(date("2026-02-01")); aAtribuir != 0 || len(envelopes) != 0 {
+		t.Errorf("Month antes do início = %d, %+v", aAtribuir, envelopes)
+	}
+}
@@ -0,0 +1,75 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// DefaultCurrency é a moeda usada quando nenhuma é informada
+const DefaultCurrency = "BRL"
+
+var currencyCode = regexp.MustCompile(`^[A-Z]{3}$`)
+
+// ValidCurrency indica se o código segue o formato ISO 4217 (ex: BRL, USD)
+func ValidCurrency(code string) bool {
+	return currencyCode.MatchString(code)
+}
+
+// ExchangeRate indica que 1 unidade de MoedaOrigem vale Taxa unidades de MoedaDestino na Data
+type ExchangeRate struct {
+	ID           uuid.UUID `json:"id"`
+	UserID       uuid.UUID `json:"user_id"`
+	MoedaOrigem  string    `json:"moeda_origem"`
+	MoedaDestino string    `json:"moeda_destino"`
+	Data         time.Time `json:"data"`
+	Taxa         float64   `json:"taxa"`
+	Fonte        string    `json:"fonte"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// RateUsed descreve uma taxa aplicada na conversão para a moeda base
+type RateUsed struct {
+	Moeda string    `json:"moeda"`
+	Data  time.Time `json:"data"`
+	Taxa  float64   `json:"taxa"`
+}
+
+// RatesUsed lê o JSON agregado pelo banco (json_agg) com as taxas usadas
+type RatesUsed []RateUsed
+
+func (r *RatesUsed) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		*r = nil
+		return nil
+	case []byte:
+		return r.unmarshal(v)
+	case string:
+		return r.unmarshal([]byte(v))
+	}
+	return fmt.Errorf("tipo não suportado para RatesUsed: %T", src)
+}
+
+func (r *RatesUsed) unmarshal(data []byte) error {
+	var raw []struct {
+		Moeda string  `json:"moeda"`
+		Data  string  `json:"data"`
+		Taxa  float64 `json:"taxa"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	*r = make(RatesUsed, 0, len(raw))
+	for _, item := range raw {
+		d, err := time.Parse("2006-01-02", item.Data)
+		if err != nil {
+			return err
+		}
+		*r = append(*r, RateUsed{Moeda: item.Moeda, Data: d, Taxa: item.Taxa})
+	}
+	return nil
+}
@@ -7,25 +7,56 @@ import (
 )
 
 type Expense struct {
-	ID            uuid.UUID  `json:"id"`
-	UserID        uuid.UUID  `json:"user_id"`
-	Descricao     string     `json:"descricao"`
-	Valor         float64    `json:"valor"`
-	Vencimento    time.Time  `json:"vencimento"`
-	Paga          bool       `json:"paga"`
-	DataPagamento *time.Time `json:"data_pagamento,omitempty"`
-	Categoria     string     `json:"categoria"`
-	Observacoes   *string    `json:"observacoes,omitempty"`
-	CreatedAt     time.Time  `json:"created_at"`
-	Status        string     `json:"status"`
+	ID            uuid.UUID        `json:"id"`
+	UserID        uuid.UUID        `json:"user_id"`
+	Descricao     string           `json:"descricao"`
+	Valor         Money            `json:"valor"`
+	Moeda         string           `json:"moeda"`
+	Vencimento    time.Time        `json:"vencimento"`
+	Paga          bool             `json:"paga"`
+	DataPagamento *time.Time       `json:"data_pagamento,omitempty"`
+	ValorPago     Money            `json:"valor_pago"`
+	Encargos      Money            `json:"encargos"` // multa e juros pagos, menos descontos (somente leitura)
+	Categoria     string           `json:"categoria"`
+	CategoriaID   *uuid.UUID       `json:"categoria_id,omitempty"`
+	PayeeID       *uuid.UUID       `json:"payee_id,omitempty"` // ao criar, omitir sugere pela descrição; ao atualizar, mantém o atual
+	Payee         *string          `json:"payee,omitempty"`    // nome do favorecido (somente leitura)
+	Tags          []string         `json:"tags"`               // ao atualizar, omitir mantém as tags atuais
+	Campos        CustomValues     `json:"campos"`             // campos personalizados pelo nome; ao atualizar, omitir mantém os atuais
+	Divisao       Splits           `json:"divisao"`            // linhas por categoria somando o valor; ao atualizar, omitir mantém a atual e [] desfaz
+	Observacoes   *string          `json:"observacoes,omitempty"`
+	RecorrenciaID *uuid.UUID       `json:"recorrencia_id,omitempty"`
+	Ocorrencia    *time.Time       `json:"ocorrencia,omitempty"`
+	CreatedAt     time.Time        `json:"created_at"`
+	Status        string           `json:"status"`
+	Pagamentos    []ExpensePayment `json:"pagamentos,omitempty"`
+
+	MultaPercentual    float64    `json:"multa_percentual"`       // % cobrado uma vez após o vencimento
+	JurosPercentual    float64    `json:"juros_percentual"`       // juros de mora simples, % por juros_periodo
+	JurosPeriodo       string     `json:"juros_periodo"`          // diario ou mensal (padrão)
+	DescontoPercentual float64    `json:"desconto_percentual"`    // % de desconto pagando até desconto_ate
+	DescontoAte        *time.Time `json:"desconto_ate,omitempty"` // padrão: vencimento
+
+	ValorEstornado Money           `json:"valor_estornado"` // soma dos estornos (somente leitura)
+	Estornos       []ExpenseRefund `json:"estornos,omitempty"`
 }
 
+// StatusHoje deriva o status a partir dos pagamentos registrados. Paga é
+// mantido pelo banco: principal pago >= valor, ou despesa quitada com desconto.
+// Multa e juros pagos não contam como pagamento a maior; a despesa estornada
+// por inteiro fica como Estornada.
 func (e *Expense) StatusHoje() string {
 	hoje := time.Now()
-	if e.Paga {
+	switch {
+	case e.ValorEstornado >= e.Valor:
+		return "Estornada"
+	case e.Paga && e.PrincipalPago() > e.Valor:
+		return "Paga a maior"
+	case e.Paga:
 		return "Paga"
-	}
-	if hoje.After(e.Vencimento) {
+	case e.ValorPago > 0:
+		return "Parcialmente paga"
+	case hoje.After(e.Vencimento):
 		return "Vencida"
 	}
 	return "A Vencer"
@@ -0,0 +1,100 @@
+package models
+
+import (
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Períodos dos juros de mora
+const (
+	InterestDaily   = "diario" // % ao dia
+	InterestMonthly = "mensal" // % ao mês, cobrado pro rata dia
+)
+
+// ValidInterestPeriod indica se o período de juros é suportado
+func ValidInterestPeriod(p string) bool {
+	return p == InterestDaily || p == InterestMonthly
+}
+
+// ExpenseCharges é quanto custa quitar o saldo em aberto de uma despesa numa data
+type ExpenseCharges struct {
+	ExpenseID     uuid.UUID `json:"expense_id"`
+	Moeda         string    `json:"moeda"`
+	Vencimento    time.Time `json:"vencimento"`
+	DataPagamento time.Time `json:"data_pagamento"`
+	DiasAtraso    int       `json:"dias_atraso"`
+	Saldo         Money     `json:"saldo"` // principal ainda não pago
+	Multa         Money     `json:"multa"`
+	Juros         Money     `json:"juros"`
+	Desconto      Money     `json:"desconto"`
+	Total         Money     `json:"total"` // saldo + multa + juros - desconto
+}
+
+// PrincipalPago é a parte dos pagamentos que abateu o valor da despesa
+func (e *Expense) PrincipalPago() Money {
+	return e.ValorPago - e.Encargos
+}
+
+// ChargesAt calcula o valor atualizado do saldo em aberto para pagamento na data.
+//
+// Depois do vencimento incidem a multa, uma única vez, e juros simples por dia
+// de atraso (a taxa mensal é dividida por 30). Até desconto_ate (ou até o
+// vencimento, sem data) vale o desconto por pagamento antecipado.
+func (e *Expense) ChargesAt(data time.Time) ExpenseCharges {
+	data = time.Date(data.Year(), data.Month(), data.Day(), 0, 0, 0, 0, time.UTC)
+	vencimento := time.Date(e.Vencimento.Year(), e.Vencimento.Month(), e.Vencimento.Day(), 0, 0, 0, 0, time.UTC)
+
+	c := ExpenseCharges{
+		ExpenseID:     e.ID,
+		Moeda:         e.Moeda,
+		Vencimento:    vencimento,
+		DataPagamento: data,
+		Saldo:         max(e.Valor-e.PrincipalPago(), 0),
+	}
+	if c.Saldo == 0 || e.Paga {
+		c.Saldo = 0
+		return c
+	}
+
+	if data.After(vencimento) {
+		c.DiasAtraso = int(math.Round(data.Sub(vencimento).Hours() / 24))
+		c.Multa = interest(c.Saldo, e.MultaPercentual)
+
+		taxaDia := e.JurosPercentual
+		if e.JurosPeriodo != InterestDaily {
+			taxaDia /= 30
+		}
+		c.Juros = interest(c.Saldo, taxaDia*float64(c.DiasAtraso))
+	} else if e.DescontoPercentual > 0 {
+		limite := vencimento
+		if e.DescontoAte != nil {
+			limite = *e.DescontoAte
+		}
+		if !data.After(limite) {
+			c.Desconto = interest(c.Saldo, e.DescontoPercentual)
+		}
+	}
+
+	c.Total = c.Saldo + c.Multa + c.Juros - c.Desconto
+	return c
+}
+
+// Split divide um pagamento entre principal e encargos. Quem paga o total (ou
+// mais) paga multa e juros inteiros e fica com o desconto que não pagou; um
+// pagamento parcial em atraso abate o principal na proporção saldo/total e
+// paga os encargos dessa parte. Desconto só vale para quem quita o saldo.
+func (c ExpenseCharges) Split(valor Money) (multa, juros, desconto Money) {
+	if valor >= c.Total {
+		return c.Multa, c.Juros, max(c.Desconto-(valor-c.Total), 0)
+	}
+	if c.Multa+c.Juros == 0 {
+		return 0, 0, 0
+	}
+
+	principal := Money(math.Round(float64(valor) * float64(c.Saldo) / float64(c.Total)))
+	encargos := valor - principal
+	multa = Money(math.Round(float64(encargos) * float64(c.Multa) / float64(c.Multa+c.Juros)))
+	return multa, encargos - multa, 0
+}
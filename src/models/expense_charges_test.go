@@ -0,0 +1,82 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestExpenseChargesAt(t *testing.T) {
+	ate := date("2026-03-05")
+	cases := []struct {
+		name    string
+		expense Expense
+		data    string
+		want    ExpenseCharges
+	}{
+		{"em dia, sem encargos",
+			Expense{Valor: 10000, MultaPercentual: 2, JurosPercentual: 1},
+			"2026-03-10", ExpenseCharges{Saldo: 10000, Total: 10000}},
+		{"atraso com juros mensais pro rata",
+			Expense{Valor: 10000, MultaPercentual: 2, JurosPercentual: 1},
+			"2026-03-25", ExpenseCharges{DiasAtraso: 15, Saldo: 10000, Multa: 200, Juros: 50, Total: 10250}},
+		{"atraso com juros diários",
+			Expense{Valor: 10000, MultaPercentual: 2, JurosPercentual: 0.1, JurosPeriodo: InterestDaily},
+			"2026-03-25", ExpenseCharges{DiasAtraso: 15, Saldo: 10000, Multa: 200, Juros: 150, Total: 10350}},
+		{"encargos só sobre o saldo em aberto",
+			Expense{Valor: 10000, ValorPago: 4000, MultaPercentual: 2, JurosPercentual: 1},
+			"2026-04-09", ExpenseCharges{DiasAtraso: 30, Saldo: 6000, Multa: 120, Juros: 60, Total: 6180}},
+		{"desconto até o vencimento",
+			Expense{Valor: 10000, DescontoPercentual: 5},
+			"2026-03-10", ExpenseCharges{Saldo: 10000, Desconto: 500, Total: 9500}},
+		{"desconto até desconto_ate",
+			Expense{Valor: 10000, DescontoPercentual: 5, DescontoAte: &ate},
+			"2026-03-05", ExpenseCharges{Saldo: 10000, Desconto: 500, Total: 9500}},
+		{"sem desconto depois de desconto_ate",
+			Expense{Valor: 10000, DescontoPercentual: 5, DescontoAte: &ate},
+			"2026-03-06", ExpenseCharges{Saldo: 10000, Total: 10000}},
+		{"despesa paga",
+			Expense{Valor: 10000, Paga: true, MultaPercentual: 2},
+			"2026-04-10", ExpenseCharges{}},
+		{"pagamentos com encargos não abatem o principal inteiro",
+			Expense{Valor: 10000, ValorPago: 10100, Encargos: 200},
+			"2026-03-10", ExpenseCharges{Saldo: 100, Total: 100}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			c.expense.Vencimento = date("2026-03-10")
+			got := c.expense.ChargesAt(date(c.data).Add(15 * time.Hour))
+			if got.DiasAtraso != c.want.DiasAtraso || got.Saldo != c.want.Saldo || got.Multa != c.want.Multa ||
+				got.Juros != c.want.Juros || got.Desconto != c.want.Desconto || got.Total != c.want.Total {
+				t.Errorf("ChargesAt(%s) = %+v, esperava %+v", c.data, got, c.want)
+			}
+		})
+	}
+}
+
+func TestExpenseChargesSplit(t *testing.T) {
+	atraso := ExpenseCharges{Saldo: 10000, Multa: 200, Juros: 50, Total: 10250}
+	antecipado := ExpenseCharges{Saldo: 10000, Desconto: 500, Total: 9500}
+	cases := []struct {
+		name                   string
+		charges                ExpenseCharges
+		valor                  Money
+		multa, juros, desconto Money
+	}{
+		{"total em atraso", atraso, 10250, 200, 50, 0},
+		{"acima do total", atraso, 11000, 200, 50, 0},
+		{"parcial em atraso, proporcional", atraso, 5125, 100, 25, 0},
+		{"parcial com arredondamento", atraso, 1000, 19, 5, 0},
+		{"total com desconto", antecipado, 9500, 0, 0, 500},
+		{"acima do total perde parte do desconto", antecipado, 9700, 0, 0, 300},
+		{"parcial não tem desconto", antecipado, 5000, 0, 0, 0},
+	}
+	for _, c := range cases {
+		multa, juros, desc := c.charges.Split(c.valor)
+		if multa != c.multa || juros != c.juros || desc != c.desconto {
+			t.Errorf("%s: Split(%d) = %d, %d, %d; esperava %d, %d, %d", c.name, c.valor, multa, juros, desc, c.multa, c.juros, c.desconto)
+		}
+		if principal := c.valor - multa - juros + desc; c.valor < c.charges.Total && principal > c.charges.Saldo {
+			t.Errorf("%s: principal %d acima do saldo %d", c.name, principal, c.charges.Saldo)
+		}
+	}
+}
@@ -0,0 +1,35 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Métodos de pagamento aceitos
+var PaymentMethods = []string{"pix", "boleto", "cartao_credito", "cartao_debito", "dinheiro", "transferencia", "debito_automatico", "outro"}
+
+type ExpensePayment struct {
+	ID            uuid.UUID  `json:"id"`
+	ExpenseID     uuid.UUID  `json:"expense_id"`
+	UserID        uuid.UUID  `json:"user_id"`
+	Valor         Money      `json:"valor"` // total pago, com multa e juros e sem o desconto
+	Multa         Money      `json:"multa"`
+	Juros         Money      `json:"juros"`
+	Desconto      Money      `json:"desconto"`
+	DataPagamento time.Time  `json:"data_pagamento"`
+	ContaID       *uuid.UUID `json:"conta_id,omitempty"`
+	Metodo        *string    `json:"metodo,omitempty"`
+	Observacoes   *string    `json:"observacoes,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+}
+
+// ValidPaymentMethod indica se o método informado é aceito
+func ValidPaymentMethod(m string) bool {
+	for _, pm := range PaymentMethods {
+		if pm == m {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,19 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ExpenseRefund é um estorno, parcial ou total, de uma despesa, na moeda dela
+type ExpenseRefund struct {
+	ID        uuid.UUID  `json:"id"`
+	ExpenseID uuid.UUID  `json:"expense_id"`
+	UserID    uuid.UUID  `json:"user_id"`
+	Valor     Money      `json:"valor"`
+	Data      time.Time  `json:"data"`               // padrão: hoje
+	ContaID   *uuid.UUID `json:"conta_id,omitempty"` // conta que recebeu o dinheiro de volta
+	Motivo    *string    `json:"motivo,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
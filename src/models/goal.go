@@ -0,0 +1,100 @@
+package models
+
+import (
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Tipos de movimentação de uma meta
+const (
+	GoalDeposit    = "aporte"
+	GoalWithdrawal = "retirada"
+)
+
+// Goal é uma meta de economia com valor e, opcionalmente, data alvo
+type Goal struct {
+	ID        uuid.UUID     `json:"id"`
+	UserID    uuid.UUID     `json:"user_id"`
+	Nome      string        `json:"nome"`
+	ValorAlvo Money         `json:"valor_alvo"`
+	DataAlvo  *time.Time    `json:"data_alvo,omitempty"`
+	ContaID   *uuid.UUID    `json:"conta_id,omitempty"` // conta onde o dinheiro fica guardado
+	Moeda     string        `json:"moeda"`              // padrão: moeda da conta ou moeda base
+	CreatedAt time.Time     `json:"created_at"`
+	Progresso *GoalProgress `json:"progresso,omitempty"`
+}
+
+// GoalContribution é um aporte ou uma retirada; Valor é sempre positivo
+type GoalContribution struct {
+	ID          uuid.UUID `json:"id"`
+	GoalID      uuid.UUID `json:"goal_id"`
+	UserID      uuid.UUID `json:"user_id"`
+	Tipo        string    `json:"tipo"` // aporte ou retirada
+	Valor       Money     `json:"valor"`
+	Data        time.Time `json:"data"`
+	Observacoes *string   `json:"observacoes,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// GoalProgress resume quanto falta e quando a meta deve ser atingida
+type GoalProgress struct {
+	Guardado   Money   `json:"guardado"`
+	Restante   Money   `json:"restante"`
+	Percentual float64 `json:"percentual"`
+	Concluida  bool    `json:"concluida"`
+	// RitmoMensal é a média guardada por mês desde o primeiro aporte
+	RitmoMensal Money `json:"ritmo_mensal"`
+	// DataProjetada estima quando a meta será atingida mantendo o ritmo
+	DataProjetada *time.Time `json:"data_projetada,omitempty"`
+	// Com data alvo: meses cheios até ela e quanto guardar por mês para chegar lá
+	MesesRestantes         *int   `json:"meses_restantes,omitempty"`
+	AporteMensalNecessario *Money `json:"aporte_mensal_necessario,omitempty"`
+	NoPrazo                *bool  `json:"no_prazo,omitempty"` // a data projetada não passa da data alvo
+}
+
+// monthsBetween conta as viradas de mês de a até b (negativo se b < a)
+func monthsBetween(a, b time.Time) int {
+	return (b.Year()-a.Year())*12 + int(b.Month()) - int(a.Month())
+}
+
+// ComputeGoalProgress calcula o progresso a partir do total guardado e da
+// data do primeiro aporte (nil se não houver movimentações)
+func ComputeGoalProgress(g Goal, guardado Money, primeiro *time.Time, now time.Time) GoalProgress {
+	p := GoalProgress{Guardado: guardado, Restante: g.ValorAlvo - guardado}
+	if p.Restante < 0 {
+		p.Restante = 0
+	}
+	p.Concluida = p.Restante == 0
+	p.Percentual = math.Round(float64(guardado)/float64(g.ValorAlvo)*1000) / 10
+
+	if primeiro != nil && guardado > 0 {
+		months := monthsBetween(*primeiro, now) + 1
+		if months < 1 {
+			months = 1
+		}
+		p.RitmoMensal = guardado / Money(months)
+	}
+
+	if !p.Concluida && p.RitmoMensal > 0 {
+		needed := int(math.Ceil(float64(p.Restante) / float64(p.RitmoMensal)))
+		d := now.AddDate(0, needed, 0)
+		d = time.Date(d.Year(), d.Month(), d.Day(), 0, 0, 0, 0, time.UTC)
+		p.DataProjetada = &d
+	}
+
+	if g.DataAlvo != nil {
+		months := monthsBetween(now, *g.DataAlvo)
+		if months < 1 {
+			months = 1 // prazo neste mês ou vencido: falta guardar tudo agora
+		}
+		p.MesesRestantes = &months
+		perMonth := Money(math.Ceil(float64(p.Restante) / float64(months)))
+		p.AporteMensalNecessario = &perMonth
+
+		onTrack := p.Concluida || (p.DataProjetada != nil && !p.DataProjetada.After(*g.DataAlvo))
+		p.NoPrazo = &onTrack
+	}
+	return p
+}
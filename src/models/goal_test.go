@@ -0,0 +1,48 @@
+package models
+
+import "testing"
+
+func TestComputeGoalProgress(t *testing.T) {
+	now := date("2026-03-20")
+	primeiro := date("2026-01-05")
+	alvo := date("2026-09-01")
+
+	p := ComputeGoalProgress(Goal{ValorAlvo: 120000, DataAlvo: &alvo}, 30000, &primeiro, now)
+	if p.Restante != 90000 || p.Percentual != 25 || p.Concluida {
+		t.Errorf("progresso = %+v", p)
+	}
+	// 30000 em três meses (janeiro a março)
+	if p.RitmoMensal != 10000 {
+		t.Errorf("ritmo mensal = %d, esperava 10000", p.RitmoMensal)
+	}
+	if p.DataProjetada == nil || p.DataProjetada.Format("2006-01-02") != "2026-12-20" {
+		t.Errorf("data projetada = %v", p.DataProjetada)
+	}
+	if p.MesesRestantes == nil || *p.MesesRestantes != 6 || *p.AporteMensalNecessario != 15000 || *p.NoPrazo {
+		t.Errorf("meses %v, aporte %v, no prazo %v", *p.MesesRestantes, *p.AporteMensalNecessario, *p.NoPrazo)
+	}
+}
+
+func TestComputeGoalProgressCompleted(t *testing.T) {
+	primeiro := date("2026-01-05")
+	vencida := date("2026-01-01")
+	p := ComputeGoalProgress(Goal{ValorAlvo: 120000, DataAlvo: &vencida}, 130000, &primeiro, date("2026-03-20"))
+	if !p.Concluida || p.Restante != 0 || p.Percentual != 108.3 || p.DataProjetada != nil {
+		t.Errorf("progresso = %+v", p)
+	}
+	if *p.MesesRestantes != 1 || *p.AporteMensalNecessario != 0 || !*p.NoPrazo {
+		t.Errorf("meses %d, aporte %d, no prazo %v", *p.MesesRestantes, *p.AporteMensalNecessario, *p.NoPrazo)
+	}
+}
+
+func TestComputeGoalProgressWithoutContributions(t *testing.T) {
+	alvo := date("2026-12-31")
+	p := ComputeGoalProgress(Goal{ValorAlvo: 100000, DataAlvo: &alvo}, 0, nil, date("2026-03-20"))
+	if p.RitmoMensal != 0 || p.DataProjetada != nil || *p.NoPrazo {
+		t.Errorf("progresso = %+v", p)
+	}
+	// nove viradas de mês até dezembro
+	if *p.AporteMensalNecessario != 11112 {
+		t.Errorf("aporte necessário = %d, esperava 11112", *p.AporteMensalNecessario)
+	}
+}
@@ -6,13 +6,48 @@ import (
 	"github.com/google/uuid"
 )
 
+// Status de uma receita no banco
+const (
+	IncomeExpected = "prevista"
+	IncomeReceived = "recebida"
+)
+
 type Income struct {
-	ID              uuid.UUID `json:"id"`
-	UserID          uuid.UUID `json:"user_id"`
-	Descricao       string    `json:"descricao"`
-	Valor           float64   `json:"valor"`
-	DataRecebimento time.Time `json:"data_recebimento"`
-	Categoria       string    `json:"categoria"`
-	Observacoes     *string   `json:"observacoes,omitempty"`
-	CreatedAt       time.Time `json:"created_at"`
+	ID              uuid.UUID    `json:"id"`
+	UserID          uuid.UUID    `json:"user_id"`
+	Descricao       string       `json:"descricao"`
+	Valor           Money        `json:"valor"`
+	Moeda           string       `json:"moeda"`
+	DataRecebimento time.Time    `json:"data_recebimento"`
+	Categoria       string       `json:"categoria"`
+	CategoriaID     *uuid.UUID   `json:"categoria_id,omitempty"`
+	PayeeID         *uuid.UUID   `json:"payee_id,omitempty"` // ao criar, omitir sugere pela descrição; ao atualizar, mantém o atual
+	Payee           *string      `json:"payee,omitempty"`    // nome do favorecido (somente leitura)
+	ContaID         *uuid.UUID   `json:"conta_id,omitempty"` // conta creditada; ao atualizar, omitir mantém a atual
+	Tags            []string     `json:"tags"`
+	Campos          CustomValues `json:"campos"`  // campos personalizados pelo nome
+	Divisao         Splits       `json:"divisao"` // linhas por categoria somando o valor
+	Observacoes     *string      `json:"observacoes,omitempty"`
+	Status          string       `json:"status"`
+	ValorPrevisto   *Money       `json:"valor_previsto,omitempty"`
+	DataPrevista    *time.Time   `json:"data_prevista,omitempty"`
+	RecorrenciaID   *uuid.UUID   `json:"recorrencia_id,omitempty"`
+	Ocorrencia      *time.Time   `json:"ocorrencia,omitempty"`
+	CreatedAt       time.Time    `json:"created_at"`
+	Situacao        string       `json:"situacao,omitempty"`
+}
+
+// StatusHoje devolve "Recebida", "Prevista" ou "Atrasada" (prevista com data já passada)
+func (i *Income) StatusHoje() string {
+	if i.Status != IncomeExpected {
+		return "Recebida"
+	}
+	prevista := i.DataRecebimento
+	if i.DataPrevista != nil {
+		prevista = *i.DataPrevista
+	}
+	if time.Now().After(prevista.AddDate(0, 0, 1)) {
+		return "Atrasada"
+	}
+	return "Prevista"
 }
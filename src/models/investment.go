@@ -0,0 +1,181 @@
+package models
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Tipos de operação de investimento
+const (
+	InvestmentBuy      = "compra"
+	InvestmentSell     = "venda"
+	InvestmentDividend = "dividendo"
+)
+
+// InvestmentAccountType é o tipo de conta que guarda posições de investimento
+const InvestmentAccountType = "investimento"
+
+// DividendCategory é a categoria das receitas geradas por dividendos
+const DividendCategory = "Dividendos"
+
+// InvestmentTransaction é uma compra, venda ou dividendo de um ativo
+type InvestmentTransaction struct {
+	ID          uuid.UUID  `json:"id"`
+	UserID      uuid.UUID  `json:"user_id"`
+	ContaID     uuid.UUID  `json:"conta_id"`
+	Ticker      string     `json:"ticker"`
+	Tipo        string     `json:"tipo"`
+	Quantidade  float64    `json:"quantidade"`
+	Preco       float64    `json:"preco"`
+	Valor       Money      `json:"valor"` // padrão: quantidade x preço; obrigatório no dividendo
+	Taxas       Money      `json:"taxas"` // corretagem e emolumentos
+	Data        time.Time  `json:"data"`
+	IncomeID    *uuid.UUID `json:"income_id,omitempty"`
+	Observacoes *string    `json:"observacoes,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+// Quote é a cotação de um ativo numa data
+type Quote struct {
+	ID        uuid.UUID `json:"id"`
+	UserID    uuid.UUID `json:"user_id"`
+	Ticker    string    `json:"ticker"`
+	Data      time.Time `json:"data"`
+	Preco     float64   `json:"preco"`
+	Fonte     string    `json:"fonte"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Position é a posição atual de um ativo numa conta
+type Position struct {
+	ContaID         uuid.UUID  `json:"conta_id"`
+	Conta           string     `json:"conta"`
+	Moeda           string     `json:"moeda"`
+	Ticker          string     `json:"ticker"`
+	Quantidade      float64    `json:"quantidade"`
+	PrecoMedio      float64    `json:"preco_medio"`
+	Custo           Money      `json:"custo"`
+	Cotacao         *float64   `json:"cotacao,omitempty"`
+	DataCotacao     *time.Time `json:"data_cotacao,omitempty"`
+	ValorAtual      Money      `json:"valor_atual"` // pelo custo quando não há cotação
+	SemCotacao      bool       `json:"sem_cotacao,omitempty"`
+	Ganho           Money      `json:"ganho"`
+	GanhoPercentual float64    `json:"ganho_percentual"`
+	Alocacao        float64    `json:"alocacao"` // % do valor atual entre as posições da mesma moeda
+	LucroRealizado  Money      `json:"lucro_realizado"`
+	Dividendos      Money      `json:"dividendos"`
+}
+
+// PortfolioTotal soma as posições de uma moeda
+type PortfolioTotal struct {
+	Moeda          string  `json:"moeda"`
+	Custo          Money   `json:"custo"`
+	ValorAtual     Money   `json:"valor_atual"`
+	Ganho          Money   `json:"ganho"`
+	Percentual     float64 `json:"ganho_percentual"`
+	LucroRealizado Money   `json:"lucro_realizado"`
+	Dividendos     Money   `json:"dividendos"`
+}
+
+// Portfolio é a carteira com posições abertas e totais por moeda
+type Portfolio struct {
+	Posicoes []Position       `json:"posicoes"`
+	Totais   []PortfolioTotal `json:"totais"`
+}
+
+// NormalizeTicker padroniza o código do ativo (ex: " petr4 " -> "PETR4")
+func NormalizeTicker(t string) string {
+	return strings.ToUpper(strings.TrimSpace(t))
+}
+
+// TradeValue é o valor financeiro de quantidade x preço, em centavos
+func TradeValue(quantidade, preco float64) Money {
+	return MoneyFromFloat(quantidade * preco)
+}
+
+// quantityEpsilon absorve o erro de ponto flutuante ao zerar uma posição
+const quantityEpsilon = 1e-9
+
+// sortTransactions ordena as operações por data e, no mesmo dia, pela criação
+func sortTransactions(txs []InvestmentTransaction) []InvestmentTransaction {
+	sorted := append([]InvestmentTransaction(nil), txs...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if !sorted[i].Data.Equal(sorted[j].Data) {
+			return sorted[i].Data.Before(sorted[j].Data)
+		}
+		return sorted[i].CreatedAt.Before(sorted[j].CreatedAt)
+	})
+	return sorted
+}
+
+// Oversold devolve a primeira venda que excede a quantidade em carteira na
+// sua data, ou nil se todas as vendas têm cobertura
+func Oversold(txs []InvestmentTransaction) *InvestmentTransaction {
+	held := map[string]float64{}
+	for _, t := range sortTransactions(txs) {
+		k := t.ContaID.String() + "|" + t.Ticker
+		switch t.Tipo {
+		case InvestmentBuy:
+			held[k] += t.Quantidade
+		case InvestmentSell:
+			if t.Quantidade > held[k]+quantityEpsilon {
+				return &t
+			}
+			held[k] -= t.Quantidade
+		}
+	}
+	return nil
+}
+
+// BuildPositions refaz as operações em ordem de data e calcula, por conta e
+// ativo, quantidade, custo pelo preço médio, lucro realizado e dividendos.
+// Taxas de compra entram no custo; taxas de venda reduzem o lucro.
+func BuildPositions(txs []InvestmentTransaction) []Position {
+	type key struct {
+		conta  uuid.UUID
+		ticker string
+	}
+	index := map[key]int{}
+	var out []Position
+	for _, t := range sortTransactions(txs) {
+		k := key{t.ContaID, t.Ticker}
+		i, ok := index[k]
+		if !ok {
+			i = len(out)
+			index[k] = i
+			out = append(out, Position{ContaID: t.ContaID, Ticker: t.Ticker})
+		}
+		p := &out[i]
+
+		switch t.Tipo {
+		case InvestmentBuy:
+			p.Quantidade += t.Quantidade
+			p.Custo += t.Valor + t.Taxas
+		case InvestmentSell:
+			if p.Quantidade <= 0 {
+				continue
+			}
+			q := math.Min(t.Quantidade, p.Quantidade)
+			cost := Money(math.Round(float64(p.Custo) * q / p.Quantidade))
+			p.LucroRealizado += t.Valor - t.Taxas - cost
+			p.Custo -= cost
+			p.Quantidade -= q
+			if p.Quantidade < quantityEpsilon {
+				p.Quantidade, p.Custo = 0, 0
+			}
+		case InvestmentDividend:
+			p.Dividendos += t.Valor - t.Taxas
+		}
+	}
+
+	for i := range out {
+		if out[i].Quantidade > 0 {
+			out[i].PrecoMedio = math.Round(float64(out[i].Custo)/out[i].Quantidade*1e4) / 1e6
+		}
+	}
+	return out
+}
@@ -0,0 +1,86 @@
+package models
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func trade(conta uuid.UUID, tipo, data string, quantidade float64, valor, taxas Money) InvestmentTransaction {
+	return InvestmentTransaction{ContaID: conta, Ticker: "PETR4", Tipo: tipo, Data: date(data), Quantidade: quantidade, Valor: valor, Taxas: taxas}
+}
+
+func TestBuildPositions(t *testing.T) {
+	a, b := uuid.New(), uuid.New()
+	positions := BuildPositions([]InvestmentTransaction{
+		// fora de ordem: BuildPositions ordena pela data
+		trade(a, InvestmentSell, "2026-02-01", 50, 150000, 300),
+		trade(a, InvestmentBuy, "2026-01-01", 100, 100000, 500),
+		trade(a, InvestmentBuy, "2026-01-10", 100, 200000, 0),
+		trade(a, InvestmentDividend, "2026-02-15", 0, 1000, 0),
+		trade(b, InvestmentBuy, "2026-01-01", 10, 10000, 0),
+		trade(b, InvestmentSell, "2026-03-01", 10, 12000, 0),
+	})
+	if len(positions) != 2 {
+		t.Fatalf("%d posições, esperava 2", len(positions))
+	}
+
+	p := positions[0]
+	if p.ContaID != a || p.Quantidade != 150 || p.Custo != 225375 {
+		t.Errorf("posição a = %+v", p)
+	}
+	// custo 300500 de 200 ações; a venda de 50 baixa 75125 do custo
+	if p.LucroRealizado != 74575 || p.Dividendos != 1000 {
+		t.Errorf("lucro %d, dividendos %d", p.LucroRealizado, p.Dividendos)
+	}
+	if math.Abs(p.PrecoMedio-15.025) > 1e-9 {
+		t.Errorf("preço médio %v, esperava 15.025", p.PrecoMedio)
+	}
+
+	zerada := positions[1]
+	if zerada.ContaID != b || zerada.Quantidade != 0 || zerada.Custo != 0 || zerada.PrecoMedio != 0 || zerada.LucroRealizado != 2000 {
+		t.Errorf("posição zerada = %+v", zerada)
+	}
+}
+
+func TestBuildPositionsSellWithoutHoldings(t *testing.T) {
+	positions := BuildPositions([]InvestmentTransaction{trade(uuid.New(), InvestmentSell, "2026-01-01", 10, 10000, 0)})
+	if len(positions) != 1 || positions[0].Quantidade != 0 || positions[0].LucroRealizado != 0 {
+		t.Errorf("BuildPositions = %+v", positions)
+	}
+}
+
+func TestOversold(t *testing.T) {
+	a, b := uuid.New(), uuid.New()
+	buy := trade(a, InvestmentBuy, "2026-01-10", 10, 10000, 0)
+
+	if got := Oversold([]InvestmentTransaction{buy, trade(a, InvestmentSell, "2026-01-20", 10, 10000, 0)}); got != nil {
+		t.Errorf("venda coberta apontada como excedente: %+v", got)
+	}
+	if got := Oversold([]InvestmentTransaction{buy, trade(a, InvestmentSell, "2026-01-05", 5, 5000, 0)}); got == nil {
+		t.Error("venda antes da compra deveria exceder a carteira")
+	}
+	if got := Oversold([]InvestmentTransaction{buy, trade(b, InvestmentSell, "2026-01-20", 5, 5000, 0)}); got == nil || got.ContaID != b {
+		t.Errorf("a compra em outra conta não cobre a venda: %+v", got)
+	}
+
+	// no mesmo dia vale a ordem de criação
+	sell := trade(a, InvestmentSell, "2026-01-10", 10, 10000, 0)
+	sell.CreatedAt = time.Now()
+	buy.CreatedAt = sell.CreatedAt.Add(time.Minute)
+	if got := Oversold([]InvestmentTransaction{buy, sell}); got == nil {
+		t.Error("venda criada antes da compra do mesmo dia deveria exceder a carteira")
+	}
+
+	// frações acumuladas não geram excedente por erro de arredondamento
+	txs := []InvestmentTransaction{}
+	for i := 0; i < 10; i++ {
+		txs = append(txs, trade(a, InvestmentBuy, "2026-01-01", 0.1, 100, 0))
+	}
+	txs = append(txs, trade(a, InvestmentSell, "2026-01-02", 1, 1000, 0))
+	if got := Oversold(txs); got != nil {
+		t.Errorf("venda de 1 depois de 10 x 0.1: %+v", got)
+	}
+}
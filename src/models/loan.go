@@ -0,0 +1,239 @@
+package models
+
+import (
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Sistemas de amortização
+const (
+	LoanSAC   = "sac"   // amortização constante: parcelas decrescentes
+	LoanPrice = "price" // tabela Price: parcelas fixas
+)
+
+// Efeito de uma amortização extraordinária
+const (
+	PrepayReduceTerm        = "prazo"   // mantém a parcela e reduz o número de parcelas
+	PrepayReduceInstallment = "parcela" // mantém o prazo e reduz o valor das parcelas
+)
+
+// Loan é um empréstimo ou financiamento pago em parcelas mensais
+type Loan struct {
+	ID          uuid.UUID  `json:"id"`
+	UserID      uuid.UUID  `json:"user_id"`
+	Nome        string     `json:"nome"`
+	Principal   Money      `json:"principal"`
+	TaxaMensal  float64    `json:"taxa_mensal"` // % ao mês
+	TaxaAnual   float64    `json:"taxa_anual"`  // % ao ano; na criação, alternativa à taxa mensal
+	Prazo       int        `json:"prazo"`       // número de parcelas
+	Sistema     string     `json:"sistema"`     // sac ou price
+	DataInicio  time.Time  `json:"data_inicio"` // vencimento da primeira parcela
+	Moeda       string     `json:"moeda"`
+	Categoria   string     `json:"categoria"`
+	CategoriaID *uuid.UUID `json:"categoria_id,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+
+	SaldoDevedor     Money `json:"saldo_devedor"`
+	ParcelasPagas    int   `json:"parcelas_pagas"`
+	ParcelasRestante int   `json:"parcelas_restantes"`
+
+	Parcelas     []LoanInstallment `json:"parcelas,omitempty"`
+	Amortizacoes []LoanPrepayment  `json:"amortizacoes,omitempty"`
+}
+
+// LoanInstallment é uma parcela do cronograma; SaldoDevedor é o saldo após pagá-la
+type LoanInstallment struct {
+	Numero       int        `json:"numero"`
+	Vencimento   time.Time  `json:"vencimento"`
+	Amortizacao  Money      `json:"amortizacao"`
+	Juros        Money      `json:"juros"`
+	Parcela      Money      `json:"parcela"`
+	SaldoDevedor Money      `json:"saldo_devedor"`
+	ExpenseID    *uuid.UUID `json:"expense_id,omitempty"`
+	Paga         bool       `json:"paga"`
+}
+
+// LoanPrepayment é uma amortização extraordinária
+type LoanPrepayment struct {
+	ID        uuid.UUID  `json:"id"`
+	LoanID    uuid.UUID  `json:"loan_id"`
+	Data      time.Time  `json:"data"`
+	Valor     Money      `json:"valor"`
+	Efeito    string     `json:"efeito"` // prazo ou parcela
+	ExpenseID *uuid.UUID `json:"expense_id,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// ValidLoanSystem indica se o sistema de amortização é suportado
+func ValidLoanSystem(s string) bool {
+	return s == LoanSAC || s == LoanPrice
+}
+
+// MonthlyFromAnnualRate converte uma taxa anual em mensal equivalente (ambas em %)
+func MonthlyFromAnnualRate(anual float64) float64 {
+	return (math.Pow(1+anual/100, 1.0/12) - 1) * 100
+}
+
+// AnnualFromMonthlyRate converte uma taxa mensal em anual equivalente (ambas em %)
+func AnnualFromMonthlyRate(mensal float64) float64 {
+	return (math.Pow(1+mensal/100, 12) - 1) * 100
+}
+
+func interest(saldo Money, taxa float64) Money {
+	return Money(math.Round(float64(saldo) * taxa / 100))
+}
+
+// PricePayment é a parcela fixa da tabela Price para o saldo, a taxa (% a.m.) e o prazo
+func PricePayment(saldo Money, taxa float64, parcelas int) Money {
+	if parcelas <= 0 {
+		return saldo
+	}
+	if taxa == 0 {
+		return Money(math.Ceil(float64(saldo) / float64(parcelas)))
+	}
+	i := taxa / 100
+	return Money(math.Round(float64(saldo) * i / (1 - math.Pow(1+i, -float64(parcelas)))))
+}
+
+// InstallmentDueDate é o vencimento da parcela numero de um empréstimo cuja
+// primeira parcela vence em inicio; dias que não existem no mês (ex: 31) caem
+// no último dia do mês.
+func InstallmentDueDate(inicio time.Time, numero int) time.Time {
+	first := time.Date(inicio.Year(), inicio.Month()+time.Month(numero-1), 1, 0, 0, 0, 0, time.UTC)
+	return clampMonthDay(first, inicio.Day())
+}
+
+// clampMonthDay devolve o dia day do mês de first, ou o último dia do mês
+// quando ele é mais curto. Ao contrário do BYMONTHDAY das recorrências, uma
+// parcela nunca pula um mês.
+func clampMonthDay(first time.Time, day int) time.Time {
+	if last := first.AddDate(0, 1, -1).Day(); day > last {
+		day = last
+	}
+	return time.Date(first.Year(), first.Month(), day, 0, 0, 0, 0, time.UTC)
+}
+
+// BuildSchedule gera as parcelas que quitam saldo em n meses, numeradas a
+// partir de numero; a última absorve os centavos do arredondamento.
+func BuildSchedule(saldo Money, taxa float64, n int, sistema string, inicio time.Time, numero int) []LoanInstallment {
+	var out []LoanInstallment
+	pmt := PricePayment(saldo, taxa, n)
+	for k := 0; k < n && saldo > 0; k++ {
+		juros := interest(saldo, taxa)
+		var amort Money
+		if sistema == LoanSAC {
+			amort = saldo / Money(n-k)
+		} else {
+			amort = pmt - juros
+		}
+		if k == n-1 || amort > saldo {
+			amort = saldo
+		}
+		saldo -= amort
+		out = append(out, LoanInstallment{
+			Numero:       numero + k,
+			Vencimento:   InstallmentDueDate(inicio, numero+k),
+			Amortizacao:  amort,
+			Juros:        juros,
+			Parcela:      amort + juros,
+			SaldoDevedor: saldo,
+		})
+	}
+	return out
+}
+
+// BuildScheduleKeeping gera as parcelas mantendo o esforço mensal e
+// encurtando o prazo: na Price mantém o valor da parcela; no SAC, a
+// amortização mensal.
+func BuildScheduleKeeping(saldo Money, taxa float64, sistema string, inicio time.Time, numero int, parcela, amortizacao Money) []LoanInstallment {
+	var out []LoanInstallment
+	for k := 0; saldo > 0; k++ {
+		juros := interest(saldo, taxa)
+		amort := amortizacao
+		if sistema == LoanPrice {
+			amort = parcela - juros
+		}
+		if amort <= 0 {
+			// a parcela não cobre os juros: o saldo nunca seria quitado
+			return nil
+		}
+		if amort > saldo {
+			amort = saldo
+		}
+		saldo -= amort
+		out = append(out, LoanInstallment{
+			Numero:       numero + k,
+			Vencimento:   InstallmentDueDate(inicio, numero+k),
+			Amortizacao:  amort,
+			Juros:        juros,
+			Parcela:      amort + juros,
+			SaldoDevedor: saldo,
+		})
+	}
+	return out
+}
+
+// Reschedule recalcula as parcelas em aberto após abater extra do saldo.
+// remaining são as parcelas ainda não pagas do cronograma atual.
+func Reschedule(remaining []LoanInstallment, taxa float64, sistema string, inicio time.Time, efeito string, extra Money) []LoanInstallment {
+	if len(remaining) == 0 {
+		return nil
+	}
+	first := remaining[0]
+	saldo := first.SaldoDevedor + first.Amortizacao - extra
+	if saldo <= 0 {
+		return nil
+	}
+	if efeito == PrepayReduceTerm {
+		return BuildScheduleKeeping(saldo, taxa, sistema, inicio, first.Numero, first.Parcela, first.Amortizacao)
+	}
+	return BuildSchedule(saldo, taxa, len(remaining), sistema, inicio, first.Numero)
+}
+
+// LoanSimulation compara o cronograma em aberto antes e depois de uma amortização extra
+type LoanSimulation struct {
+	Valor                Money             `json:"valor"`
+	Efeito               string            `json:"efeito"`
+	SaldoAntes           Money             `json:"saldo_antes"`
+	SaldoDepois          Money             `json:"saldo_depois"`
+	ParcelasAntes        int               `json:"parcelas_antes"`
+	ParcelasDepois       int               `json:"parcelas_depois"`
+	ProximaParcelaAntes  Money             `json:"proxima_parcela_antes"`
+	ProximaParcelaDepois Money             `json:"proxima_parcela_depois"`
+	JurosAntes           Money             `json:"juros_antes"`
+	JurosDepois          Money             `json:"juros_depois"`
+	EconomiaJuros        Money             `json:"economia_juros"`
+	Cronograma           []LoanInstallment `json:"cronograma"`
+}
+
+// SimulatePrepayment calcula o efeito de amortizar extra do saldo em aberto
+func SimulatePrepayment(remaining []LoanInstallment, taxa float64, sistema string, inicio time.Time, efeito string, extra Money) LoanSimulation {
+	sim := LoanSimulation{Valor: extra, Efeito: efeito, ParcelasAntes: len(remaining)}
+	if len(remaining) > 0 {
+		sim.SaldoAntes = remaining[0].SaldoDevedor + remaining[0].Amortizacao
+		sim.ProximaParcelaAntes = remaining[0].Parcela
+	}
+	for _, p := range remaining {
+		sim.JurosAntes += p.Juros
+	}
+
+	sim.Cronograma = Reschedule(remaining, taxa, sistema, inicio, efeito, extra)
+	sim.SaldoDepois = sim.SaldoAntes - extra
+	if sim.SaldoDepois < 0 {
+		sim.SaldoDepois = 0
+	}
+	sim.ParcelasDepois = len(sim.Cronograma)
+	if len(sim.Cronograma) > 0 {
+		sim.ProximaParcelaDepois = sim.Cronograma[0].Parcela
+	}
+	for _, p := range sim.Cronograma {
+		sim.JurosDepois += p.Juros
+	}
+	sim.EconomiaJuros = sim.JurosAntes - sim.JurosDepois
+	if sim.Cronograma == nil {
+		sim.Cronograma = []LoanInstallment{}
+	}
+	return sim
+}
@@ -0,0 +1,214 @@
+package models
+
+import (
+	"math"
+	"testing"
+)
+
+func TestPricePayment(t *testing.T) {
+	cases := []struct {
+		saldo    Money
+		taxa     float64
+		parcelas int
+		want     Money
+	}{
+		{100000, 1, 12, 8885},
+		{100000, 0, 3, 33334},
+		{100000, 0, 4, 25000},
+		{100000, 1, 1, 101000},
+		{100000, 1, 0, 100000},
+	}
+	for _, c := range cases {
+		if got := PricePayment(c.saldo, c.taxa, c.parcelas); got != c.want {
+			t.Errorf("PricePayment(%d, %v, %d) = %d, esperava %d", c.saldo, c.taxa, c.parcelas, got, c.want)
+		}
+	}
+}
+
+func TestLoanRates(t *testing.T) {
+	if got := AnnualFromMonthlyRate(1); math.Abs(got-12.682503) > 1e-6 {
+		t.Errorf("AnnualFromMonthlyRate(1) = %v", got)
+	}
+	if got := MonthlyFromAnnualRate(AnnualFromMonthlyRate(1.5)); math.Abs(got-1.5) > 1e-9 {
+		t.Errorf("MonthlyFromAnnualRate(AnnualFromMonthlyRate(1.5)) = %v", got)
+	}
+}
+
+func TestInstallmentDueDate(t *testing.T) {
+	cases := []struct {
+		inicio string
+		numero int
+		want   string
+	}{
+		{"2026-01-10", 1, "2026-01-10"},
+		{"2026-01-10", 13, "2027-01-10"},
+		{"2026-01-31", 2, "2026-02-28"},
+		{"2026-01-31", 3, "2026-03-31"},
+		{"2027-12-30", 3, "2028-02-29"},
+		{"2026-03-31", 2, "2026-04-30"},
+		{"2026-08-31", 7, "2027-02-28"},
+	}
+	for _, c := range cases {
+		if got := InstallmentDueDate(date(c.inicio), c.numero).Format("2006-01-02"); got != c.want {
+			t.Errorf("InstallmentDueDate(%s, %d) = %s, esperava %s", c.inicio, c.numero, got, c.want)
+		}
+	}
+}
+
+// checkSchedule confere que as parcelas quitam saldo exatamente e que cada
+// linha é coerente (parcela = amortização + juros, saldo decrescente)
+func checkSchedule(t *testing.T, parcelas []LoanInstallment, saldo Money, numero int) {
+	t.Helper()
+	var amortizado Money
+	for k, p := range parcelas {
+		if p.Numero != numero+k {
+			t.Errorf("parcela %d com numero %d", k, p.Numero)
+		}
+		if p.Parcela != p.Amortizacao+p.Juros {
+			t.Errorf("parcela %d: %d != %d + %d", p.Numero, p.Parcela, p.Amortizacao, p.Juros)
+		}
+		amortizado += p.Amortizacao
+		if p.SaldoDevedor != saldo-amortizado {
+			t.Errorf("parcela %d: saldo %d, esperava %d", p.Numero, p.SaldoDevedor, saldo-amortizado)
+		}
+	}
+	if amortizado != saldo {
+		t.Errorf("amortizado %d, esperava %d", amortizado, saldo)
+	}
+}
+
+func TestBuildSchedulePrice(t *testing.T) {
+	parcelas := BuildSchedule(100000, 1, 12, LoanPrice, date("2026-01-31"), 1)
+	if len(parcelas) != 12 {
+		t.Fatalf("%d parcelas, esperava 12", len(parcelas))
+	}
+	checkSchedule(t, parcelas, 100000, 1)
+
+	first, last := parcelas[0], parcelas[11]
+	if first.Juros != 1000 || first.Amortizacao != 7885 || first.Parcela != 8885 {
+		t.Errorf("primeira parcela = %+v", first)
+	}
+	for _, p := range parcelas[:11] {
+		if p.Parcela != 8885 {
+			t.Errorf("parcela %d = %d: a Price mantém a parcela fixa", p.Numero, p.Parcela)
+		}
+	}
+	// a última absorve os centavos do arredondamento
+	if last.Amortizacao != 8796 || last.Juros != 88 || last.Parcela != 8884 || last.SaldoDevedor != 0 {
+		t.Errorf("última parcela = %+v", last)
+	}
+	if got := parcelas[1].Vencimento.Format("2006-01-02"); got != "2026-02-28" {
+		t.Errorf("vencimento da 2ª parcela = %s", got)
+	}
+}
+
+func TestBuildScheduleSAC(t *testing.T) {
+	parcelas := BuildSchedule(100000, 1, 12, LoanSAC, date("2026-01-10"), 1)
+	if len(parcelas) != 12 {
+		t.Fatalf("%d parcelas, esperava 12", len(parcelas))
+	}
+	checkSchedule(t, parcelas, 100000, 1)
+
+	var juros Money
+	for k, p := range parcelas {
+		juros += p.Juros
+		if k > 0 && p.Parcela >= parcelas[k-1].Parcela {
+			t.Errorf("parcela %d = %d: no SAC as parcelas decrescem", p.Numero, p.Parcela)
+		}
+	}
+	if parcelas[0].Amortizacao != 8333 || parcelas[0].Parcela != 9333 || parcelas[11].Amortizacao != 8334 {
+		t.Errorf("amortizações = %d ... %d", parcelas[0].Amortizacao, parcelas[11].Amortizacao)
+	}
+	if juros != 6500 {
+		t.Errorf("juros totais = %d, esperava 6500", juros)
+	}
+}
+
+func TestBuildScheduleWithoutInterest(t *testing.T) {
+	for _, sistema := range []string{LoanPrice, LoanSAC} {
+		parcelas := BuildSchedule(100, 0, 3, sistema, date("2026-01-10"), 1)
+		checkSchedule(t, parcelas, 100, 1)
+		for _, p := range parcelas {
+			if p.Juros != 0 {
+				t.Errorf("%s: juros %d sem taxa", sistema, p.Juros)
+			}
+		}
+	}
+}
+
+func TestReschedule(t *testing.T) {
+	inicio := date("2026-01-10")
+	parcelas := BuildSchedule(100000, 1, 12, LoanPrice, inicio, 1)
+	remaining := parcelas[3:]
+	saldo := remaining[0].SaldoDevedor + remaining[0].Amortizacao - 10000
+
+	t.Run("reduz a parcela", func(t *testing.T) {
+		novas := Reschedule(remaining, 1, LoanPrice, inicio, PrepayReduceInstallment, 10000)
+		if len(novas) != len(remaining) {
+			t.Fatalf("%d parcelas, esperava %d", len(novas), len(remaining))
+		}
+		checkSchedule(t, novas, saldo, 4)
+		if novas[0].Parcela >= remaining[0].Parcela {
+			t.Errorf("parcela %d não diminuiu (antes %d)", novas[0].Parcela, remaining[0].Parcela)
+		}
+		if !novas[0].Vencimento.Equal(remaining[0].Vencimento) {
+			t.Errorf("vencimento mudou: %s", novas[0].Vencimento)
+		}
+	})
+
+	t.Run("reduz o prazo", func(t *testing.T) {
+		novas := Reschedule(remaining, 1, LoanPrice, inicio, PrepayReduceTerm, 10000)
+		if len(novas) >= len(remaining) || len(novas) == 0 {
+			t.Fatalf("%d parcelas, esperava menos que %d", len(novas), len(remaining))
+		}
+		checkSchedule(t, novas, saldo, 4)
+		for _, p := range novas {
+			if p.Parcela > remaining[0].Parcela {
+				t.Errorf("parcela %d = %d acima da original %d", p.Numero, p.Parcela, remaining[0].Parcela)
+			}
+		}
+	})
+
+	t.Run("quita o saldo", func(t *testing.T) {
+		if novas := Reschedule(remaining, 1, LoanPrice, inicio, PrepayReduceTerm, 1000000); novas != nil {
+			t.Errorf("esperava cronograma vazio, veio %d parcelas", len(novas))
+		}
+	})
+
+	t.Run("SAC mantém a amortização", func(t *testing.T) {
+		sac := BuildSchedule(100000, 1, 12, LoanSAC, inicio, 1)
+		novas := Reschedule(sac[3:], 1, LoanSAC, inicio, PrepayReduceTerm, 10000)
+		checkSchedule(t, novas, sac[3].SaldoDevedor+sac[3].Amortizacao-10000, 4)
+		if novas[0].Amortizacao != sac[3].Amortizacao {
+			t.Errorf("amortização %d, esperava %d", novas[0].Amortizacao, sac[3].Amortizacao)
+		}
+	})
+}
+
+func TestBuildScheduleKeepingUnpayable(t *testing.T) {
+	// a parcela só cobre os juros: nunca quitaria o saldo
+	if parcelas := BuildScheduleKeeping(100000, 1, LoanPrice, date("2026-01-10"), 1, 1000, 0); parcelas != nil {
+		t.Errorf("esperava nil, veio %d parcelas", len(parcelas))
+	}
+}
+
+func TestSimulatePrepayment(t *testing.T) {
+	inicio := date("2026-01-10")
+	remaining := BuildSchedule(100000, 1, 12, LoanPrice, inicio, 1)
+
+	sim := SimulatePrepayment(remaining, 1, LoanPrice, inicio, PrepayReduceTerm, 20000)
+	if sim.SaldoAntes != 100000 || sim.SaldoDepois != 80000 {
+		t.Errorf("saldo %d -> %d", sim.SaldoAntes, sim.SaldoDepois)
+	}
+	if sim.ParcelasAntes != 12 || sim.ParcelasDepois >= 12 {
+		t.Errorf("parcelas %d -> %d", sim.ParcelasAntes, sim.ParcelasDepois)
+	}
+	if sim.JurosAntes != 6619 || sim.EconomiaJuros <= 0 || sim.EconomiaJuros != sim.JurosAntes-sim.JurosDepois {
+		t.Errorf("juros %d -> %d, economia %d", sim.JurosAntes, sim.JurosDepois, sim.EconomiaJuros)
+	}
+
+	quitado := SimulatePrepayment(remaining, 1, LoanPrice, inicio, PrepayReduceInstallment, 200000)
+	if quitado.SaldoDepois != 0 || quitado.ParcelasDepois != 0 || quitado.Cronograma == nil {
+		t.Errorf("quitação: %+v", quitado)
+	}
+}
@@ -0,0 +1,152 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Money é um valor monetário em centavos. Evita os erros de arredondamento
+// de float64: somas e subtrações são exatas.
+//
+// No JSON é serializado como string decimal ("1234.56"); na entrada aceita
+// tanto string quanto número. No banco é lido e gravado como NUMERIC.
+type Money int64
+
+var errInvalidMoney = errors.New("valor monetário inválido")
+
+// ParseMoney converte um decimal ("1234.5", "-0.05", "10") em Money,
+// arredondando para o centavo mais próximo (metade para longe do zero).
+func ParseMoney(s string) (Money, error) {
+	s = strings.TrimSpace(s)
+	neg := false
+	if strings.HasPrefix(s, "-") || strings.HasPrefix(s, "+") {
+		neg = s[0] == '-'
+		s = s[1:]
+	}
+
+	intPart, frac := s, ""
+	if i := strings.IndexByte(s, '.'); i >= 0 {
+		intPart, frac = s[:i], s[i+1:]
+	}
+	if intPart == "" && frac == "" {
+		return 0, errInvalidMoney
+	}
+	if intPart == "" {
+		intPart = "0"
+	}
+	for _, part := range []string{intPart, frac} {
+		for _, c := range part {
+			if c < '0' || c > '9' {
+				return 0, errInvalidMoney
+			}
+		}
+	}
+
+	units, err := strconv.ParseInt(intPart, 10, 64)
+	if err != nil || units > math.MaxInt64/100-1 {
+		return 0, errInvalidMoney
+	}
+
+	frac += "00"
+	cents := int64(frac[0]-'0')*10 + int64(frac[1]-'0')
+	if len(frac) > 2 && frac[2] >= '5' {
+		cents++
+	}
+
+	m := Money(units*100 + cents)
+	if neg {
+		m = -m
+	}
+	return m, nil
+}
+
+// MoneyFromFloat converte um float64 (ex: resultado de uma taxa) em Money
+func MoneyFromFloat(f float64) Money {
+	return Money(math.Round(f * 100))
+}
+
+// Cents devolve o valor em centavos
+func (m Money) Cents() int64 {
+	return int64(m)
+}
+
+// String formata o valor com duas casas decimais, ex: "-1234.56"
+func (m Money) String() string {
+	sign := ""
+	v := int64(m)
+	if v < 0 {
+		sign = "-"
+		v = -v
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, v/100, v%100)
+}
+
+func (m Money) MarshalJSON() ([]byte, error) {
+	return json.Marshal(m.String())
+}
+
+func (m *Money) UnmarshalJSON(data []byte) error {
+	s := string(data)
+	if s == "null" {
+		return nil
+	}
+	if strings.HasPrefix(s, `"`) {
+		if err := json.Unmarshal(data, &s); err != nil {
+			return err
+		}
+	} else if strings.ContainsAny(s, "eE") {
+		// notação científica (ex: 1e3) só chega como número JSON
+		// e é recusada fora do alcance de Money, em vez de estourar o int64
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil || math.Abs(f) > math.MaxInt64/100-1 {
+			return errInvalidMoney
+		}
+		*m = MoneyFromFloat(f)
+		return nil
+	}
+	v, err := ParseMoney(s)
+	if err != nil {
+		return err
+	}
+	*m = v
+	return nil
+}
+
+// Scan lê colunas NUMERIC (texto), inteiras ou de ponto flutuante
+func (m *Money) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		*m = 0
+		return nil
+	case []byte:
+		return m.scanString(string(v))
+	case string:
+		return m.scanString(v)
+	case int64:
+		*m = Money(v * 100)
+		return nil
+	case float64:
+		*m = MoneyFromFloat(v)
+		return nil
+	}
+	return fmt.Errorf("tipo não suportado para Money: %T", src)
+}
+
+func (m *Money) scanString(s string) error {
+	v, err := ParseMoney(s)
+	if err != nil {
+		return fmt.Errorf("%w: %q", err, s)
+	}
+	*m = v
+	return nil
+}
+
+// Value grava o valor como texto decimal, sem passar por float
+func (m Money) Value() (driver.Value, error) {
+	return m.String(), nil
+}
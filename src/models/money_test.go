@@ -0,0 +1,152 @@
+package models
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestParseMoney(t *testing.T) {
+	cases := []struct {
+		in   string
+		want Money
+	}{
+		{"1234.56", 123456},
+		{"10", 1000},
+		{" 7.5 ", 750},
+		{".5", 50},
+		{"5.", 500},
+		{"+3.1", 310},
+		{"-0.05", -5},
+		{"0", 0},
+		{"1.234", 123},
+		{"1.235", 124},
+		{"0.005", 1},
+		{"-0.005", -1},
+		{"19.999", 2000},
+		{"92233720368547757", 9223372036854775700},
+	}
+	for _, c := range cases {
+		got, err := ParseMoney(c.in)
+		if err != nil {
+			t.Errorf("ParseMoney(%q): %v", c.in, err)
+			continue
+		}
+		if got != c.want {
+			t.Errorf("ParseMoney(%q) = %d, esperava %d", c.in, got, c.want)
+		}
+	}
+}
+
+func TestParseMoneyErrors(t *testing.T) {
+	for _, in := range []string{"", "-", ".", "abc", "1,50", "1.2.3", "1e3", "--1", "R$ 10", "92233720368547758"} {
+		if v, err := ParseMoney(in); err == nil {
+			t.Errorf("ParseMoney(%q) = %d: esperava erro", in, v)
+		}
+	}
+}
+
+func TestMoneyString(t *testing.T) {
+	cases := []struct {
+		in   Money
+		want string
+	}{
+		{0, "0.00"},
+		{5, "0.05"},
+		{-5, "-0.05"},
+		{123456, "1234.56"},
+		{-100, "-1.00"},
+	}
+	for _, c := range cases {
+		if got := c.in.String(); got != c.want {
+			t.Errorf("Money(%d).String() = %q, esperava %q", int64(c.in), got, c.want)
+		}
+	}
+}
+
+func TestMoneyJSON(t *testing.T) {
+	cases := []struct {
+		in   string
+		want Money
+	}{
+		{`"12.30"`, 1230},
+		{`12.3`, 1230},
+		{`-0.1`, -10},
+		{`1e3`, 100000},
+		{`2.5E1`, 2500},
+		{`0.125`, 13},
+	}
+	for _, c := range cases {
+		var m Money
+		if err := json.Unmarshal([]byte(c.in), &m); err != nil {
+			t.Errorf("Unmarshal(%s): %v", c.in, err)
+			continue
+		}
+		if m != c.want {
+			t.Errorf("Unmarshal(%s) = %d, esperava %d", c.in, m, c.want)
+		}
+	}
+
+	for _, in := range []string{`"abc"`, `1e300`, `-1e17`, `true`, `"1e3"`} {
+		var m Money
+		if err := json.Unmarshal([]byte(in), &m); err == nil {
+			t.Errorf("Unmarshal(%s) = %d: esperava erro", in, m)
+		}
+	}
+
+	m := Money(42)
+	if err := json.Unmarshal([]byte(`null`), &m); err != nil || m != 42 {
+		t.Errorf("Unmarshal(null) = %d, %v: deveria manter o valor", m, err)
+	}
+
+	out, err := json.Marshal(struct {
+		Valor Money `json:"valor"`
+	}{-123456})
+	if err != nil || string(out) != `{"valor":"-1234.56"}` {
+		t.Errorf("Marshal = %s, %v", out, err)
+	}
+}
+
+func TestMoneyScan(t *testing.T) {
+	cases := []struct {
+		src  interface{}
+		want Money
+	}{
+		{nil, 0},
+		{[]byte("12.34"), 1234},
+		{"-0.50", -50},
+		{int64(5), 500},
+		{float64(1.1), 110},
+	}
+	for _, c := range cases {
+		m := Money(99)
+		if err := m.Scan(c.src); err != nil {
+			t.Errorf("Scan(%#v): %v", c.src, err)
+			continue
+		}
+		if m != c.want {
+			t.Errorf("Scan(%#v) = %d, esperava %d", c.src, m, c.want)
+		}
+	}
+
+	var m Money
+	if err := m.Scan(true); err == nil {
+		t.Error("Scan(bool): esperava erro")
+	}
+}
+
+func TestMoneyFromFloat(t *testing.T) {
+	cases := []struct {
+		in   float64
+		want Money
+	}{
+		{10.004, 1000},
+		{10.006, 1001},
+		{-2.5, -250},
+		{0.1 + 0.2, 30},
+	}
+	for _, c := range cases {
+		if got := MoneyFromFloat(c.in); got != c.want {
+			t.Errorf("MoneyFromFloat(%v) = %d, esperava %d", c.in, got, c.want)
+		}
+	}
+}
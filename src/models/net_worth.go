@@ -0,0 +1,67 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Origens de um item do patrimônio
+const (
+	NetWorthAccount = "conta"
+	NetWorthLoan    = "emprestimo"
+)
+
+// Classes de um item do patrimônio
+const (
+	NetWorthAsset     = "ativo"
+	NetWorthLiability = "passivo"
+)
+
+// CreditCardAccountType é o tipo de conta tratado como dívida no patrimônio
+const CreditCardAccountType = "cartao_credito"
+
+// NetWorthItem é o saldo de uma conta ou empréstimo numa fotografia do patrimônio
+type NetWorthItem struct {
+	Origem    string    `json:"origem"`
+	OrigemID  uuid.UUID `json:"origem_id"`
+	Nome      string    `json:"nome"`
+	Tipo      string    `json:"tipo"`
+	Classe    string    `json:"classe"`
+	Moeda     string    `json:"moeda"`
+	Valor     Money     `json:"valor"`                // na moeda de origem; dívidas são negativas
+	ValorBase *Money    `json:"valor_base,omitempty"` // na moeda base; ausente sem taxa de câmbio
+}
+
+// NetWorthPoint é o patrimônio no fim de um mês, na moeda base do usuário
+type NetWorthPoint struct {
+	Data       time.Time      `json:"data"`
+	Parcial    bool           `json:"parcial,omitempty"` // apurado antes do fim do mês (ex: mês corrente)
+	ApuradoEm  time.Time      `json:"apurado_em"`
+	Ativos     Money          `json:"ativos"`
+	Passivos   Money          `json:"passivos"` // valor positivo das dívidas
+	Patrimonio Money          `json:"patrimonio_liquido"`
+	Itens      []NetWorthItem `json:"itens"`
+
+	MoedasSemTaxa []string `json:"moedas_sem_taxa,omitempty"` // itens dessas moedas ficaram fora dos totais
+}
+
+// NetWorthHistory é a série do patrimônio líquido
+type NetWorthHistory struct {
+	MoedaBase string          `json:"moeda_base"`
+	Pontos    []NetWorthPoint `json:"pontos"`
+}
+
+// MonthEnd devolve o último dia do mês de t
+func MonthEnd(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month()+1, 0, 0, 0, 0, 0, time.UTC)
+}
+
+// NetWorthClass classifica o saldo de uma conta: cartão de crédito e saldo
+// negativo contam como passivo
+func NetWorthClass(tipo string, valor Money) string {
+	if tipo == CreditCardAccountType || valor < 0 {
+		return NetWorthLiability
+	}
+	return NetWorthAsset
+}
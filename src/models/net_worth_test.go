@@ -0,0 +1,36 @@
+package models
+
+import "testing"
+
+func TestMonthEnd(t *testing.T) {
+	cases := []struct{ in, want string }{
+		{"2026-01-15", "2026-01-31"},
+		{"2026-02-01", "2026-02-28"},
+		{"2028-02-29", "2028-02-29"},
+		{"2026-12-31", "2026-12-31"},
+	}
+	for _, c := range cases {
+		if got := MonthEnd(date(c.in)).Format("2006-01-02"); got != c.want {
+			t.Errorf("MonthEnd(%s) = %s, esperava %s", c.in, got, c.want)
+		}
+	}
+}
+
+func TestNetWorthClass(t *testing.T) {
+	cases := []struct {
+		tipo  string
+		valor Money
+		want  string
+	}{
+		{"corrente", 1000, NetWorthAsset},
+		{"corrente", 0, NetWorthAsset},
+		{"corrente", -1, NetWorthLiability},
+		{CreditCardAccountType, 500, NetWorthLiability},
+		{InvestmentAccountType, 1000, NetWorthAsset},
+	}
+	for _, c := range cases {
+		if got := NetWorthClass(c.tipo, c.valor); got != c.want {
+			t.Errorf("NetWorthClass(%q, %d) = %q, esperava %q", c.tipo, c.valor, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package models
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/google/uuid"
+)
+
+// Tipos de padrão de um apelido de favorecido
+const (
+	PayeeMatchExact    = "exato"  // descrição normalizada igual ao padrão
+	PayeeMatchContains = "contem" // descrição normalizada contém as palavras do padrão
+	PayeeMatchRegex    = "regex"  // expressão regular (sem diferenciar maiúsculas) sobre a descrição
+)
+
+// Payee é um favorecido: a loja, empresa ou pessoa de uma despesa ou receita
+type Payee struct {
+	ID        uuid.UUID    `json:"id"`
+	UserID    uuid.UUID    `json:"user_id"`
+	Nome      string       `json:"nome"`
+	Aliases   []PayeeAlias `json:"aliases"`
+	Usos      int          `json:"usos"` // quantidade de lançamentos com o favorecido
+	CreatedAt time.Time    `json:"created_at"`
+}
+
+// PayeeAlias é um padrão que reconhece o favorecido na descrição.
+// Ex: "extra" (contem) casa com "MERCADO EXTRA 123" e "Extra Supermercado".
+type PayeeAlias struct {
+	ID      uuid.UUID `json:"id"`
+	PayeeID uuid.UUID `json:"payee_id"`
+	Padrao  string    `json:"padrao"`
+	Tipo    string    `json:"tipo"`
+}
+
+var accentReplacer = strings.NewReplacer(
+	"á", "a", "à", "a", "â", "a", "ã", "a", "ä", "a",
+	"é", "e", "è", "e", "ê", "e", "ë", "e",
+	"í", "i", "ì", "i", "î", "i", "ï", "i",
+	"ó", "o", "ò", "o", "ô", "o", "õ", "o", "ö", "o",
+	"ú", "u", "ù", "u", "û", "u", "ü", "u",
+	"ç", "c", "ñ", "n",
+)
+
+// NormalizePayeeText reduz uma descrição às palavras que identificam o
+// favorecido: minúsculas, sem acentos, sem pontuação e sem números soltos
+// (código de loja, parcela, data). "MERCADO EXTRA-123 *SP" vira "mercado extra sp".
+func NormalizePayeeText(s string) string {
+	s = accentReplacer.Replace(strings.ToLower(s))
+	words := strings.FieldsFunc(s, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	out := words[:0]
+	for _, w := range words {
+		if strings.IndexFunc(w, unicode.IsLetter) < 0 {
+			continue
+		}
+		out = append(out, w)
+	}
+	return strings.Join(out, " ")
+}
+
+// NormalizePayeeAlias valida o apelido e normaliza o padrão conforme o tipo
+func NormalizePayeeAlias(a *PayeeAlias) error {
+	if a.Tipo == "" {
+		a.Tipo = PayeeMatchContains
+	}
+	switch a.Tipo {
+	case PayeeMatchExact, PayeeMatchContains:
+		a.Padrao = NormalizePayeeText(a.Padrao)
+	case PayeeMatchRegex:
+		a.Padrao = strings.TrimSpace(a.Padrao)
+		if _, err := regexp.Compile("(?i)" + a.Padrao); err != nil {
+			return fmt.Errorf("expressão regular inválida: %v", err)
+		}
+	default:
+		return fmt.Errorf("tipo de apelido inválido: use exato, contem ou regex")
+	}
+	if a.Padrao == "" {
+		return fmt.Errorf("padrão do apelido é obrigatório")
+	}
+	return nil
+}
+
+// MatchPayee escolhe o favorecido cujo apelido melhor reconhece a descrição.
+// Padrões exatos vencem regex, que vencem "contem"; no empate vence o padrão
+// mais longo (mais específico).
+func MatchPayee(descricao string, aliases []PayeeAlias) (uuid.UUID, bool) {
+	text := " " + NormalizePayeeText(descricao) + " "
+	var best uuid.UUID
+	bestRank, bestLen := 0, 0
+
+	for _, a := range aliases {
+		rank := 0
+		switch a.Tipo {
+		case PayeeMatchExact:
+			if text == " "+a.Padrao+" " {
+				rank = 3
+			}
+		case PayeeMatchRegex:
+			if re, err := regexp.Compile("(?i)" + a.Padrao); err == nil && re.MatchString(descricao) {
+				rank = 2
+			}
+		case PayeeMatchContains:
+			if a.Padrao != "" && strings.Contains(text, " "+a.Padrao+" ") {
+				rank = 1
+			}
+		}
+		if rank == 0 {
+			continue
+		}
+		if rank > bestRank || rank == bestRank && len(a.Padrao) > bestLen {
+			best, bestRank, bestLen = a.PayeeID, rank, len(a.Padrao)
+		}
+	}
+	return best, bestRank > 0
+}
@@ -0,0 +1,66 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestNormalizePayeeText(t *testing.T) {
+	cases := []struct{ in, want string }{
+		{"MERCADO EXTRA-123 *SP", "mercado extra sp"},
+		{"Padaria São João 02/12", "padaria sao joao"},
+		{"  UBER* TRIP  ", "uber trip"},
+		{"ALIEXPRESS 3DPRINT", "aliexpress 3dprint"},
+		{"123 456", ""},
+	}
+	for _, c := range cases {
+		if got := NormalizePayeeText(c.in); got != c.want {
+			t.Errorf("NormalizePayeeText(%q) = %q, esperava %q", c.in, got, c.want)
+		}
+	}
+}
+
+func TestNormalizePayeeAlias(t *testing.T) {
+	a := PayeeAlias{Padrao: " Mercado EXTRA "}
+	if err := NormalizePayeeAlias(&a); err != nil || a.Tipo != PayeeMatchContains || a.Padrao != "mercado extra" {
+		t.Errorf("NormalizePayeeAlias = %+v, %v", a, err)
+	}
+	for _, a := range []PayeeAlias{
+		{Tipo: PayeeMatchRegex, Padrao: "uber("},
+		{Tipo: "prefixo", Padrao: "uber"},
+		{Tipo: PayeeMatchExact, Padrao: "123"},
+	} {
+		if err := NormalizePayeeAlias(&a); err == nil {
+			t.Errorf("NormalizePayeeAlias(%+v): esperava erro", a)
+		}
+	}
+}
+
+func TestMatchPayee(t *testing.T) {
+	mercado, extra, uber, padaria := uuid.New(), uuid.New(), uuid.New(), uuid.New()
+	aliases := []PayeeAlias{
+		{PayeeID: mercado, Tipo: PayeeMatchContains, Padrao: "mercado"},
+		{PayeeID: extra, Tipo: PayeeMatchContains, Padrao: "mercado extra"},
+		{PayeeID: uber, Tipo: PayeeMatchRegex, Padrao: `^uber\b`},
+		{PayeeID: padaria, Tipo: PayeeMatchExact, Padrao: "padaria"},
+	}
+	cases := []struct {
+		descricao string
+		want      uuid.UUID
+		ok        bool
+	}{
+		{"MERCADO EXTRA-123 *SP", extra, true}, // o padrão mais longo vence
+		{"Mercado do Zé", mercado, true},
+		{"UBER *TRIP", uber, true},
+		{"Padaria 02", padaria, true},
+		{"Padaria Mercado", mercado, true}, // exato exige a descrição inteira
+		{"Supermercados BH", uuid.Nil, false},
+	}
+	for _, c := range cases {
+		got, ok := MatchPayee(c.descricao, aliases)
+		if got != c.want || ok != c.ok {
+			t.Errorf("MatchPayee(%q) = %v, %v", c.descricao, got, ok)
+		}
+	}
+}
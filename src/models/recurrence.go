@@ -0,0 +1,383 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RecurrenceRule representa um subconjunto da RRULE do RFC 5545.
+//
+// Exemplos suportados:
+//
+//	FREQ=MONTHLY;BYMONTHDAY=10                  todo dia 10
+//	FREQ=MONTHLY;BYMONTHDAY=-1                  último dia do mês
+//	FREQ=WEEKLY;INTERVAL=2;BYDAY=FR             sexta-feira, a cada 2 semanas
+//	FREQ=YEARLY;BYMONTH=1;BYMONTHDAY=15;COUNT=5 15 de janeiro, 5 vezes
+//	FREQ=MONTHLY;BYDAY=MO,TU,WE,TH,FR;BYSETPOS=-1  último dia útil do mês
+//	FREQ=MONTHLY;BYMONTHDAY=5;UNTIL=20271231    todo dia 5 até o fim de 2027
+//	FREQ=MONTHLY;BYMONTHDAY=28,29,30;BYSETPOS=-1  dia 30, ou o último dia dos meses mais curtos
+type RecurrenceRule struct {
+	Freq       string
+	Interval   int
+	ByMonthDay []int
+	ByMonth    int
+	ByDay      []time.Weekday
+	BySetPos   int
+	Count      int
+	Until      *time.Time
+}
+
+const (
+	FreqWeekly  = "WEEKLY"
+	FreqMonthly = "MONTHLY"
+	FreqYearly  = "YEARLY"
+)
+
+var weekdayCodes = map[string]time.Weekday{
+	"SU": time.Sunday,
+	"MO": time.Monday,
+	"TU": time.Tuesday,
+	"WE": time.Wednesday,
+	"TH": time.Thursday,
+	"FR": time.Friday,
+	"SA": time.Saturday,
+}
+
+// maxRecurrencePeriods limita a iteração para regras sem fim
+const maxRecurrencePeriods = 5000
+
+// ParseRRule interpreta uma regra no formato "FREQ=MONTHLY;BYMONTHDAY=10"
+func ParseRRule(s string) (RecurrenceRule, error) {
+	rule := RecurrenceRule{Interval: 1}
+	s = strings.TrimPrefix(strings.TrimSpace(s), "RRULE:")
+	if s == "" {
+		return rule, errors.New("regra de recorrência vazia")
+	}
+
+	for _, part := range strings.Split(s, ";") {
+		kv := strings.SplitN(part, "=", 2)
+		if len(kv) != 2 {
+			return rule, fmt.Errorf("parte inválida na regra: %q", part)
+		}
+		key, value := strings.ToUpper(strings.TrimSpace(kv[0])), strings.ToUpper(strings.TrimSpace(kv[1]))
+
+		var err error
+		switch key {
+		case "FREQ":
+			if value != FreqWeekly && value != FreqMonthly && value != FreqYearly {
+				return rule, fmt.Errorf("FREQ não suportada: %s", value)
+			}
+			rule.Freq = value
+		case "INTERVAL":
+			rule.Interval, err = strconv.Atoi(value)
+			if err == nil && rule.Interval < 1 {
+				err = errors.New("INTERVAL deve ser maior que zero")
+			}
+		case "BYMONTHDAY":
+			for _, v := range strings.Split(value, ",") {
+				var day int
+				if day, err = strconv.Atoi(v); err != nil {
+					break
+				}
+				if day == 0 || day < -31 || day > 31 {
+					err = errors.New("BYMONTHDAY fora do intervalo")
+					break
+				}
+				rule.ByMonthDay = append(rule.ByMonthDay, day)
+			}
+		case "BYMONTH":
+			rule.ByMonth, err = strconv.Atoi(value)
+			if err == nil && (rule.ByMonth < 1 || rule.ByMonth > 12) {
+				err = errors.New("BYMONTH fora do intervalo")
+			}
+		case "BYDAY":
+			for _, code := range strings.Split(value, ",") {
+				wd, ok := weekdayCodes[code]
+				if !ok {
+					return rule, fmt.Errorf("BYDAY inválido: %s", code)
+				}
+				rule.ByDay = append(rule.ByDay, wd)
+			}
+		case "BYSETPOS":
+			rule.BySetPos, err = strconv.Atoi(value)
+		case "COUNT":
+			rule.Count, err = strconv.Atoi(value)
+			if err == nil && rule.Count < 1 {
+				err = errors.New("COUNT deve ser maior que zero")
+			}
+		case "UNTIL":
+			var until time.Time
+			until, err = parseRRuleDate(value)
+			rule.Until = &until
+		default:
+			return rule, fmt.Errorf("parâmetro não suportado: %s", key)
+		}
+		if err != nil {
+			return rule, fmt.Errorf("%s inválido: %v", key, err)
+		}
+	}
+
+	if rule.Freq == "" {
+		return rule, errors.New("FREQ é obrigatório")
+	}
+	if rule.Count > 0 && rule.Until != nil {
+		return rule, errors.New("COUNT e UNTIL não podem ser usados juntos")
+	}
+	if len(rule.ByMonthDay) > 0 && rule.Freq == FreqWeekly {
+		return rule, errors.New("BYMONTHDAY só é suportado com FREQ=MONTHLY ou YEARLY")
+	}
+	if rule.ByMonth != 0 && rule.Freq != FreqYearly {
+		return rule, errors.New("BYMONTH só é suportado com FREQ=YEARLY")
+	}
+	if len(rule.ByDay) > 0 && rule.Freq == FreqYearly {
+		return rule, errors.New("BYDAY não é suportado com FREQ=YEARLY")
+	}
+	if len(rule.ByDay) > 0 && len(rule.ByMonthDay) > 0 {
+		return rule, errors.New("BYDAY e BYMONTHDAY não podem ser usados juntos")
+	}
+	if rule.BySetPos != 0 && (rule.Freq == FreqWeekly || (len(rule.ByDay) == 0 && len(rule.ByMonthDay) == 0)) {
+		return rule, errors.New("BYSETPOS só é suportado com FREQ=MONTHLY ou YEARLY e BYDAY ou BYMONTHDAY")
+	}
+	if rule.ByMonth != 0 && len(rule.ByMonthDay) > 0 && !rule.monthDayExists() {
+		return rule, fmt.Errorf("BYMONTHDAY não existe no mês %d", rule.ByMonth)
+	}
+	return rule, nil
+}
+
+// monthDayExists diz se algum dia de BYMONTHDAY existe no mês BYMONTH em
+// algum ano (29 de fevereiro conta)
+func (r RecurrenceRule) monthDayExists() bool {
+	days := 29
+	if time.Month(r.ByMonth) != time.February {
+		days = shortestMonth(time.Month(r.ByMonth))
+	}
+	for _, d := range r.ByMonthDay {
+		if d <= days && -d <= days {
+			return true
+		}
+	}
+	return false
+}
+
+func parseRRuleDate(value string) (time.Time, error) {
+	for _, layout := range []string{"20060102T150405Z", "20060102", "2006-01-02"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return dateOnly(t), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("data inválida: %s", value)
+}
+
+// String devolve a regra no formato RRULE
+func (r RecurrenceRule) String() string {
+	parts := []string{"FREQ=" + r.Freq}
+	if r.Interval > 1 {
+		parts = append(parts, "INTERVAL="+strconv.Itoa(r.Interval))
+	}
+	if r.ByMonth != 0 {
+		parts = append(parts, "BYMONTH="+strconv.Itoa(r.ByMonth))
+	}
+	if len(r.ByMonthDay) > 0 {
+		days := make([]string, len(r.ByMonthDay))
+		for i, d := range r.ByMonthDay {
+			days[i] = strconv.Itoa(d)
+		}
+		parts = append(parts, "BYMONTHDAY="+strings.Join(days, ","))
+	}
+	if len(r.ByDay) > 0 {
+		codes := make([]string, 0, len(r.ByDay))
+		for _, wd := range r.ByDay {
+			for code, d := range weekdayCodes {
+				if d == wd {
+					codes = append(codes, code)
+				}
+			}
+		}
+		parts = append(parts, "BYDAY="+strings.Join(codes, ","))
+	}
+	if r.BySetPos != 0 {
+		parts = append(parts, "BYSETPOS="+strconv.Itoa(r.BySetPos))
+	}
+	if r.Count > 0 {
+		parts = append(parts, "COUNT="+strconv.Itoa(r.Count))
+	}
+	if r.Until != nil {
+		parts = append(parts, "UNTIL="+r.Until.Format("20060102"))
+	}
+	return strings.Join(parts, ";")
+}
+
+// Pin torna explícitos os campos que a regra herdaria do DTSTART, para que
+// a série possa ser dividida ("esta e as próximas") sem mudar de dia.
+//
+// Um dia herdado que não existe em todos os meses (ex: 31) cai no último dia
+// dos meses mais curtos: vira BYMONTHDAY=28,...,31;BYSETPOS=-1, o maior dia
+// existente da lista, em vez de pular esses meses como um BYMONTHDAY=31.
+func (r RecurrenceRule) Pin(dtstart time.Time) RecurrenceRule {
+	switch r.Freq {
+	case FreqWeekly:
+		if len(r.ByDay) == 0 {
+			r.ByDay = []time.Weekday{dtstart.Weekday()}
+		}
+	case FreqMonthly:
+		if len(r.ByMonthDay) == 0 && len(r.ByDay) == 0 {
+			r.pinMonthDay(dtstart.Day(), 28)
+		}
+	case FreqYearly:
+		if r.ByMonth == 0 {
+			r.ByMonth = int(dtstart.Month())
+		}
+		if len(r.ByMonthDay) == 0 {
+			r.pinMonthDay(dtstart.Day(), shortestMonth(time.Month(r.ByMonth)))
+		}
+	}
+	return r
+}
+
+// pinMonthDay fixa o dia do mês; acima de shortest (o menor tamanho que o mês
+// pode ter), usa o maior dia existente entre shortest e day
+func (r *RecurrenceRule) pinMonthDay(day, shortest int) {
+	if day <= shortest {
+		r.ByMonthDay = []int{day}
+		return
+	}
+	r.ByMonthDay = nil
+	for d := shortest; d <= day; d++ {
+		r.ByMonthDay = append(r.ByMonthDay, d)
+	}
+	r.BySetPos = -1
+}
+
+// shortestMonth é o menor número de dias que o mês pode ter (fevereiro: 28)
+func shortestMonth(m time.Month) int {
+	if m == time.February {
+		return 28
+	}
+	return time.Date(2001, m+1, 0, 0, 0, 0, 0, time.UTC).Day()
+}
+
+// Between devolve as ocorrências da regra entre from e to (inclusive),
+// contando a partir de dtstart. Como no RFC 5545, meses sem o dia pedido em
+// BYMONTHDAY (ex: 31 em fevereiro) não têm ocorrência; o dia herdado do
+// dtstart cai no último dia desses meses (ver Pin).
+func (r RecurrenceRule) Between(dtstart, from, to time.Time) []time.Time {
+	dtstart, from, to = dateOnly(dtstart), dateOnly(from), dateOnly(to)
+	r = r.Pin(dtstart)
+	interval := r.Interval
+	if interval < 1 {
+		interval = 1
+	}
+
+	var result []time.Time
+	count := 0
+	for period := 0; period < maxRecurrencePeriods; period++ {
+		candidates := r.periodDates(dtstart, period*interval)
+		if len(candidates) == 0 {
+			continue
+		}
+		for _, d := range candidates {
+			if d.Before(dtstart) {
+				continue
+			}
+			if r.Until != nil && d.After(*r.Until) {
+				return result
+			}
+			if d.After(to) {
+				return result
+			}
+			count++
+			if r.Count > 0 && count > r.Count {
+				return result
+			}
+			if !d.Before(from) {
+				result = append(result, d)
+			}
+		}
+	}
+	return result
+}
+
+// periodDates devolve as datas candidatas do período de índice offset
+func (r RecurrenceRule) periodDates(dtstart time.Time, offset int) []time.Time {
+	switch r.Freq {
+	case FreqWeekly:
+		weekStart := dtstart.AddDate(0, 0, -int((dtstart.Weekday()+6)%7)+7*offset)
+		dates := make([]time.Time, 0, len(r.ByDay))
+		for _, wd := range r.ByDay {
+			dates = append(dates, weekStart.AddDate(0, 0, int((wd+6)%7)))
+		}
+		sort.Slice(dates, func(i, j int) bool { return dates[i].Before(dates[j]) })
+		return dates
+	case FreqMonthly:
+		first := time.Date(dtstart.Year(), dtstart.Month()+time.Month(offset), 1, 0, 0, 0, 0, time.UTC)
+		if len(r.ByDay) > 0 {
+			return r.monthWeekdays(first)
+		}
+		return r.monthDays(first)
+	case FreqYearly:
+		first := time.Date(dtstart.Year()+offset, time.Month(r.ByMonth), 1, 0, 0, 0, 0, time.UTC)
+		return r.monthDays(first)
+	}
+	return nil
+}
+
+// monthDays devolve os dias de BYMONTHDAY que existem no mês de first, em
+// ordem, filtrados por BYSETPOS
+func (r RecurrenceRule) monthDays(first time.Time) []time.Time {
+	var days []time.Time
+	for _, day := range r.ByMonthDay {
+		days = append(days, monthDay(first, day)...)
+	}
+	sort.Slice(days, func(i, j int) bool { return days[i].Before(days[j]) })
+	return setPos(days, r.BySetPos)
+}
+
+// monthWeekdays devolve os dias do mês que caem em BYDAY, filtrados por BYSETPOS
+func (r RecurrenceRule) monthWeekdays(first time.Time) []time.Time {
+	var days []time.Time
+	for d := first; d.Month() == first.Month(); d = d.AddDate(0, 0, 1) {
+		for _, wd := range r.ByDay {
+			if d.Weekday() == wd {
+				days = append(days, d)
+				break
+			}
+		}
+	}
+	return setPos(days, r.BySetPos)
+}
+
+// setPos escolhe a ocorrência BYSETPOS (1 = primeira, -1 = última) das datas do período
+func setPos(days []time.Time, pos int) []time.Time {
+	if pos == 0 {
+		return days
+	}
+	idx := pos - 1
+	if pos < 0 {
+		idx = len(days) + pos
+	}
+	if idx < 0 || idx >= len(days) {
+		return nil
+	}
+	return []time.Time{days[idx]}
+}
+
+// monthDay devolve o dia "day" do mês de first (valores negativos contam do
+// fim), ou nenhum se o mês não tem esse dia
+func monthDay(first time.Time, day int) []time.Time {
+	last := first.AddDate(0, 1, -1).Day()
+	if day < 0 {
+		day = last + day + 1
+	}
+	if day < 1 || day > last {
+		return nil
+	}
+	return []time.Time{time.Date(first.Year(), first.Month(), day, 0, 0, 0, 0, time.UTC)}
+}
+
+func dateOnly(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
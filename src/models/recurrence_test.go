@@ -0,0 +1,144 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func date(s string) time.Time {
+	t, err := time.Parse("2006-01-02", s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func TestParseRRuleErrors(t *testing.T) {
+	for _, rule := range []string{
+		"",
+		"FREQ=DAILY",
+		"BYMONTHDAY=10",
+		"FREQ=MONTHLY;BYMONTHDAY=0",
+		"FREQ=MONTHLY;BYMONTHDAY=32",
+		"FREQ=MONTHLY;BYMONTHDAY=-32",
+		"FREQ=MONTHLY;BYMONTHDAY=1,,2",
+		"FREQ=YEARLY;BYMONTH=13",
+		"FREQ=MONTHLY;INTERVAL=0",
+		"FREQ=MONTHLY;COUNT=0",
+		"FREQ=MONTHLY;COUNT=2;UNTIL=20270101",
+		"FREQ=WEEKLY;BYDAY=MO;BYSETPOS=1",
+		"FREQ=MONTHLY;BYSETPOS=1",
+		"FREQ=WEEKLY;BYMONTHDAY=10",
+		"FREQ=MONTHLY;BYMONTH=2;BYMONTHDAY=10",
+		"FREQ=YEARLY;BYMONTH=2;BYMONTHDAY=30",
+		"FREQ=YEARLY;BYMONTH=4;BYMONTHDAY=-31",
+		"FREQ=YEARLY;BYDAY=MO",
+		"FREQ=MONTHLY;BYDAY=MO;BYMONTHDAY=13",
+		"FREQ=WEEKLY;BYDAY=XX",
+		"FREQ=MONTHLY;UNTIL=amanha",
+		"FREQ=MONTHLY;BYHOUR=9",
+		"FREQ",
+	} {
+		if _, err := ParseRRule(rule); err == nil {
+			t.Errorf("ParseRRule(%q): esperava erro", rule)
+		}
+	}
+}
+
+func TestRecurrenceRuleString(t *testing.T) {
+	cases := []struct{ in, want string }{
+		{"FREQ=MONTHLY;BYMONTHDAY=10", "FREQ=MONTHLY;BYMONTHDAY=10"},
+		{"RRULE:freq=monthly;interval=2;bymonthday=-1;count=4", "FREQ=MONTHLY;INTERVAL=2;BYMONTHDAY=-1;COUNT=4"},
+		{"FREQ=YEARLY;BYMONTHDAY=1;BYMONTH=3;UNTIL=20300101", "FREQ=YEARLY;BYMONTH=3;BYMONTHDAY=1;UNTIL=20300101"},
+		{"FREQ=WEEKLY;INTERVAL=1;BYDAY=FR", "FREQ=WEEKLY;BYDAY=FR"},
+		{"FREQ=MONTHLY;BYDAY=MO;BYSETPOS=-1", "FREQ=MONTHLY;BYDAY=MO;BYSETPOS=-1"},
+		{"FREQ=MONTHLY;BYSETPOS=-1;BYMONTHDAY=28,29,30", "FREQ=MONTHLY;BYMONTHDAY=28,29,30;BYSETPOS=-1"},
+	}
+	for _, c := range cases {
+		rule, err := ParseRRule(c.in)
+		if err != nil {
+			t.Fatalf("ParseRRule(%q): %v", c.in, err)
+		}
+		if got := rule.String(); got != c.want {
+			t.Errorf("ParseRRule(%q).String() = %q, esperava %q", c.in, got, c.want)
+		}
+	}
+}
+
+func TestRecurrenceRuleBetween(t *testing.T) {
+	cases := []struct {
+		name                 string
+		rule                 string
+		dtstart, from, until string
+		want                 []string
+	}{
+		{"dia fixo do mês", "FREQ=MONTHLY;BYMONTHDAY=10", "2026-01-05", "2026-01-01", "2026-04-30",
+			[]string{"2026-01-10", "2026-02-10", "2026-03-10", "2026-04-10"}},
+		{"dia 31 pula meses mais curtos", "FREQ=MONTHLY;BYMONTHDAY=31", "2026-01-01", "2026-01-01", "2026-05-31",
+			[]string{"2026-01-31", "2026-03-31", "2026-05-31"}},
+		{"dia do DTSTART cai no último dia dos meses mais curtos", "FREQ=MONTHLY", "2026-01-31", "2026-01-01", "2026-04-30",
+			[]string{"2026-01-31", "2026-02-28", "2026-03-31", "2026-04-30"}},
+		{"dia 30 ou o último dia do mês", "FREQ=MONTHLY;BYMONTHDAY=28,29,30;BYSETPOS=-1", "2028-01-01", "2028-01-01", "2028-03-31",
+			[]string{"2028-01-30", "2028-02-29", "2028-03-30"}},
+		{"29 de fevereiro do DTSTART cai no dia 28", "FREQ=YEARLY", "2028-02-29", "2028-01-01", "2029-12-31",
+			[]string{"2028-02-29", "2029-02-28"}},
+		{"último dia do mês", "FREQ=MONTHLY;BYMONTHDAY=-1", "2026-01-01", "2026-01-01", "2026-03-31",
+			[]string{"2026-01-31", "2026-02-28", "2026-03-31"}},
+		{"29 de fevereiro só em ano bissexto", "FREQ=YEARLY;BYMONTH=2;BYMONTHDAY=29", "2026-01-01", "2026-01-01", "2029-12-31",
+			[]string{"2028-02-29"}},
+		{"a cada duas semanas", "FREQ=WEEKLY;INTERVAL=2;BYDAY=FR", "2026-01-02", "2026-01-01", "2026-01-31",
+			[]string{"2026-01-02", "2026-01-16", "2026-01-30"}},
+		{"vários dias da semana", "FREQ=WEEKLY;BYDAY=WE,MO", "2026-01-07", "2026-01-01", "2026-01-14",
+			[]string{"2026-01-07", "2026-01-12", "2026-01-14"}},
+		{"anual com COUNT", "FREQ=YEARLY;BYMONTH=1;BYMONTHDAY=15;COUNT=3", "2026-01-01", "2026-01-01", "2035-12-31",
+			[]string{"2026-01-15", "2027-01-15", "2028-01-15"}},
+		{"último dia útil", "FREQ=MONTHLY;BYDAY=MO,TU,WE,TH,FR;BYSETPOS=-1", "2026-01-01", "2026-01-01", "2026-03-31",
+			[]string{"2026-01-30", "2026-02-27", "2026-03-31"}},
+		{"quinto dia útil", "FREQ=MONTHLY;BYDAY=MO,TU,WE,TH,FR;BYSETPOS=5", "2026-01-01", "2026-01-01", "2026-02-28",
+			[]string{"2026-01-07", "2026-02-06"}},
+		{"UNTIL inclusivo", "FREQ=MONTHLY;BYMONTHDAY=5;UNTIL=20260305", "2026-01-01", "2026-01-01", "2026-12-31",
+			[]string{"2026-01-05", "2026-02-05", "2026-03-05"}},
+		{"COUNT conta desde o DTSTART", "FREQ=MONTHLY;BYMONTHDAY=10;COUNT=3", "2026-01-01", "2026-03-01", "2026-12-31",
+			[]string{"2026-03-10"}},
+		{"ocorrências antes do DTSTART ficam de fora", "FREQ=MONTHLY;BYMONTHDAY=10", "2026-01-20", "2026-01-01", "2026-02-28",
+			[]string{"2026-02-10"}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			rule, err := ParseRRule(c.rule)
+			if err != nil {
+				t.Fatalf("ParseRRule(%q): %v", c.rule, err)
+			}
+			got := rule.Between(date(c.dtstart), date(c.from), date(c.until))
+			if len(got) != len(c.want) {
+				t.Fatalf("Between = %v, esperava %v", got, c.want)
+			}
+			for i, d := range got {
+				if d.Format("2006-01-02") != c.want[i] {
+					t.Fatalf("Between = %v, esperava %v", got, c.want)
+				}
+			}
+		})
+	}
+}
+
+func TestRecurrenceRulePin(t *testing.T) {
+	rule, _ := ParseRRule("FREQ=YEARLY")
+	pinned := rule.Pin(date("2026-07-20"))
+	if pinned.String() != "FREQ=YEARLY;BYMONTH=7;BYMONTHDAY=20" {
+		t.Errorf("Pin = %q", pinned.String())
+	}
+
+	rule, _ = ParseRRule("FREQ=MONTHLY")
+	if pinned := rule.Pin(date("2026-01-31")); pinned.String() != "FREQ=MONTHLY;BYMONTHDAY=28,29,30,31;BYSETPOS=-1" {
+		t.Errorf("Pin = %q", pinned.String())
+	}
+	if pinned := rule.Pin(date("2026-01-28")); pinned.String() != "FREQ=MONTHLY;BYMONTHDAY=28" {
+		t.Errorf("Pin = %q", pinned.String())
+	}
+
+	rule, _ = ParseRRule("FREQ=MONTHLY;BYDAY=MO;BYSETPOS=1")
+	if pinned := rule.Pin(date("2026-07-20")); len(pinned.ByMonthDay) != 0 {
+		t.Errorf("Pin não deveria fixar BYMONTHDAY numa regra com BYDAY: %q", pinned.String())
+	}
+}
@@ -0,0 +1,23 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type RecurringExpense struct {
+	ID          uuid.UUID  `json:"id"`
+	UserID      uuid.UUID  `json:"user_id"`
+	Descricao   string     `json:"descricao"`
+	Valor       Money      `json:"valor"`
+	Moeda       string     `json:"moeda"`
+	Categoria   string     `json:"categoria"`
+	CategoriaID *uuid.UUID `json:"categoria_id,omitempty"`
+	Observacoes *string    `json:"observacoes,omitempty"`
+	Regra       string     `json:"regra"`
+	DataInicio  time.Time  `json:"data_inicio"`
+	GeradaAte   *time.Time `json:"gerada_ate,omitempty"`
+	Ativa       bool       `json:"ativa"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
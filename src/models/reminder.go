@@ -0,0 +1,82 @@
+package models
+
+import (
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Tipos de lembrete de vencimento
+const (
+	ReminderBefore   = "antecedencia"
+	ReminderDueToday = "no_dia"
+	ReminderOverdue  = "vencida"
+)
+
+// NotificationBillReminder é o tipo das notificações de vencimento
+const NotificationBillReminder = "lembrete_vencimento"
+
+// ReminderMaxDays limita a antecedência dos lembretes
+const ReminderMaxDays = 30
+
+// ReminderPreferences define quando e por quais canais o usuário é avisado
+// das despesas não pagas
+type ReminderPreferences struct {
+	UserID        uuid.UUID `json:"user_id"`
+	Ativo         bool      `json:"ativo"`
+	DiasAntes     []int     `json:"dias_antes"` // avisa uma vez quando faltam até N dias, para cada N
+	NoDia         bool      `json:"no_dia"`
+	Vencidas      bool      `json:"vencidas"`
+	Canais        []string  `json:"canais"`                   // email, webhook, inbox
+	Email         *string   `json:"email,omitempty"`          // padrão: e-mail do usuário
+	WebhookURL    *string   `json:"webhook_url,omitempty"`    // obrigatório com o canal webhook
+	WebhookSecret *string   `json:"webhook_secret,omitempty"` // assina as entregas do webhook; gerado pelo servidor
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+// DefaultReminderPreferences são as preferências de quem nunca as alterou
+func DefaultReminderPreferences(userID uuid.UUID) ReminderPreferences {
+	return ReminderPreferences{
+		UserID:    userID,
+		Ativo:     true,
+		DiasAntes: []int{3},
+		NoDia:     true,
+		Vencidas:  true,
+		Canais:    []string{"inbox"},
+	}
+}
+
+// Notification é uma mensagem da caixa de entrada do aplicativo
+type Notification struct {
+	ID           uuid.UUID  `json:"id"`
+	UserID       uuid.UUID  `json:"user_id"`
+	Tipo         string     `json:"tipo"`
+	Titulo       string     `json:"titulo"`
+	Mensagem     string     `json:"mensagem"`
+	ReferenciaID *uuid.UUID `json:"referencia_id,omitempty"`
+	Lida         bool       `json:"lida"`
+	CreatedAt    time.Time  `json:"created_at"`
+}
+
+// DueReminder decide qual lembrete cabe a uma despesa não paga hoje. Para a
+// antecedência vale a menor janela de DiasAntes que contém o vencimento, de
+// modo que um job que não rodou no dia exato ainda avise uma única vez.
+func DueReminder(p ReminderPreferences, vencimento, hoje time.Time) (string, int, bool) {
+	dias := int(vencimento.Sub(hoje).Hours() / 24)
+	switch {
+	case dias < 0:
+		return ReminderOverdue, 0, p.Vencidas
+	case dias == 0:
+		return ReminderDueToday, 0, p.NoDia
+	}
+
+	janelas := append([]int(nil), p.DiasAntes...)
+	sort.Ints(janelas)
+	for _, n := range janelas {
+		if n > 0 && dias <= n {
+			return ReminderBefore, n, true
+		}
+	}
+	return "", 0, false
+}
@@ -0,0 +1,39 @@
+package models
+
+import "testing"
+
+func TestDueReminder(t *testing.T) {
+	p := ReminderPreferences{DiasAntes: []int{7, 3}, NoDia: true, Vencidas: true}
+	hoje := date("2026-03-10")
+	cases := []struct {
+		vencimento string
+		tipo       string
+		dias       int
+		ok         bool
+	}{
+		{"2026-03-09", ReminderOverdue, 0, true},
+		{"2026-03-10", ReminderDueToday, 0, true},
+		{"2026-03-12", ReminderBefore, 3, true},
+		{"2026-03-13", ReminderBefore, 3, true},
+		{"2026-03-14", ReminderBefore, 7, true}, // a menor janela que contém o vencimento
+		{"2026-03-17", ReminderBefore, 7, true},
+		{"2026-03-18", "", 0, false},
+	}
+	for _, c := range cases {
+		tipo, dias, ok := DueReminder(p, date(c.vencimento), hoje)
+		if tipo != c.tipo || dias != c.dias || ok != c.ok {
+			t.Errorf("DueReminder(%s) = %q, %d, %v; esperava %q, %d, %v", c.vencimento, tipo, dias, ok, c.tipo, c.dias, c.ok)
+		}
+	}
+
+	desligado := ReminderPreferences{DiasAntes: []int{0}}
+	if _, _, ok := DueReminder(desligado, date("2026-03-09"), hoje); ok {
+		t.Error("lembrete de vencidas desligado")
+	}
+	if _, _, ok := DueReminder(desligado, hoje, hoje); ok {
+		t.Error("lembrete no dia desligado")
+	}
+	if _, _, ok := DueReminder(desligado, date("2026-03-11"), hoje); ok {
+		t.Error("janela 0 não avisa com antecedência")
+	}
+}
@@ -0,0 +1,34 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Situações de um lançamento na busca, comuns a despesas e receitas
+const (
+	SearchSettled = "quitado"  // despesa paga ou receita recebida
+	SearchPending = "pendente" // ainda não paga/recebida, dentro do prazo
+	SearchLate    = "atrasado" // ainda não paga/recebida, com a data já passada
+)
+
+// ValidSearchStatus indica se a situação pode ser usada no filtro da busca
+func ValidSearchStatus(s string) bool {
+	return s == SearchSettled || s == SearchPending || s == SearchLate
+}
+
+// SearchResult é um lançamento encontrado pela busca, do mais ao menos relevante
+type SearchResult struct {
+	Tipo        string    `json:"tipo"` // expenses ou incomes
+	ID          uuid.UUID `json:"id"`
+	Descricao   string    `json:"descricao"`
+	Categoria   string    `json:"categoria"`
+	Observacoes *string   `json:"observacoes,omitempty"`
+	Valor       Money     `json:"valor"`
+	Moeda       string    `json:"moeda"`
+	Data        time.Time `json:"data"` // vencimento da despesa ou data de recebimento da receita
+	Status      string    `json:"status"`
+	Relevancia  float64   `json:"relevancia"`
+	Trecho      string    `json:"trecho"` // descrição e observações com os termos encontrados entre <mark> e </mark>
+}
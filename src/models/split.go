@@ -0,0 +1,53 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/google/uuid"
+)
+
+// Split é uma linha da divisão de um lançamento entre categorias (ex: a
+// compra do supermercado dividida em alimentação, limpeza e presente)
+type Split struct {
+	ID          uuid.UUID  `json:"id"`
+	CategoriaID *uuid.UUID `json:"categoria_id,omitempty"`
+	Categoria   string     `json:"categoria"`
+	Valor       Money      `json:"valor"`
+	Observacao  *string    `json:"observacao,omitempty"`
+}
+
+// ValidateSplits confere se as linhas têm valor e somam exatamente total;
+// devolve a mensagem de erro ou "". Sem linhas o lançamento não é dividido.
+func ValidateSplits(splits []Split, total Money) string {
+	if len(splits) == 0 {
+		return ""
+	}
+	var sum Money
+	for _, s := range splits {
+		if s.Valor <= 0 {
+			return "Cada linha da divisão precisa de valor maior que zero"
+		}
+		sum += s.Valor
+	}
+	if sum != total {
+		return "A soma das linhas da divisão deve ser igual ao valor do lançamento"
+	}
+	return ""
+}
+
+// Splits lê o JSON agregado pelo banco (jsonb_agg) com as linhas da divisão
+type Splits []Split
+
+func (s *Splits) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		*s = nil
+		return nil
+	case []byte:
+		return json.Unmarshal(v, s)
+	case string:
+		return json.Unmarshal([]byte(v), s)
+	}
+	return fmt.Errorf("tipo não suportado para Splits: %T", src)
+}
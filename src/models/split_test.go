@@ -0,0 +1,46 @@
+package models
+
+import "testing"
+
+func TestValidateSplits(t *testing.T) {
+	cases := []struct {
+		name    string
+		valores []Money
+		total   Money
+		ok      bool
+	}{
+		{"sem divisão", nil, 1000, true},
+		{"soma exata", []Money{600, 300, 100}, 1000, true},
+		{"uma linha com o valor todo", []Money{1000}, 1000, true},
+		{"soma menor", []Money{600, 300}, 1000, false},
+		{"soma maior", []Money{600, 500}, 1000, false},
+		{"linha zerada", []Money{1000, 0}, 1000, false},
+		{"linha negativa compensada", []Money{1200, -200}, 1000, false},
+	}
+	for _, c := range cases {
+		var splits []Split
+		for _, v := range c.valores {
+			splits = append(splits, Split{Valor: v})
+		}
+		if msg := ValidateSplits(splits, c.total); (msg == "") != c.ok {
+			t.Errorf("%s: ValidateSplits = %q", c.name, msg)
+		}
+	}
+}
+
+func TestSplitsScan(t *testing.T) {
+	var s Splits
+	if err := s.Scan([]byte(`[{"categoria":"Mercado","valor":"12.50"},{"categoria":"Limpeza","valor":"7.50"}]`)); err != nil {
+		t.Fatalf("Scan: %v", err)
+	}
+	if len(s) != 2 || s[0].Categoria != "Mercado" || s[0].Valor != 1250 || s[1].Valor != 750 {
+		t.Errorf("Scan = %+v", s)
+	}
+
+	if err := s.Scan(nil); err != nil || s != nil {
+		t.Errorf("Scan(nil) = %+v, %v", s, err)
+	}
+	if err := s.Scan(42); err == nil {
+		t.Error("Scan(int): esperava erro")
+	}
+}
@@ -1,12 +1,20 @@
 package models
 
 type Summary struct {
-	TotalDespesas float64 `json:"total_despesas"`
-	TotalPagas    float64 `json:"total_pagas"`
-	Pendentes     float64 `json:"pendentes"`
-	TotalVencidas float64 `json:"total_vencidas"`
-	Receitas      float64 `json:"receitas"`
-	Saldo         float64 `json:"saldo"`
-	Mes           int     `json:"mes"`
-	Ano           int     `json:"ano"`
+	TotalDespesas     Money `json:"total_despesas"`
+	TotalPagas        Money `json:"total_pagas"`
+	Pendentes         Money `json:"pendentes"`
+	TotalVencidas     Money `json:"total_vencidas"`
+	Receitas          Money `json:"receitas"`           // receitas efetivamente recebidas
+	ReceitasPrevistas Money `json:"receitas_previstas"` // receitas previstas ainda não recebidas
+	ReceitasAtrasadas Money `json:"receitas_atrasadas"` // previstas com data já passada
+	Saldo             Money `json:"saldo"`
+	SaldoProjetado    Money `json:"saldo_projetado"` // saldo considerando também as receitas previstas
+	Mes               int   `json:"mes"`
+	Ano               int   `json:"ano"`
+
+	// Os totais acima estão na moeda base do usuário
+	MoedaBase       string    `json:"moeda_base"`
+	TaxasUtilizadas RatesUsed `json:"taxas_utilizadas"`
+	MoedasSemTaxa   []string  `json:"moedas_sem_taxa,omitempty"` // transações dessas moedas ficaram fora dos totais
 }
@@ -0,0 +1,40 @@
+package models
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Tag é um rótulo livre aplicado a despesas e receitas (ex: "viagem-2026", "reembolsável")
+type Tag struct {
+	ID        uuid.UUID `json:"id"`
+	UserID    uuid.UUID `json:"user_id"`
+	Nome      string    `json:"nome"`
+	Cor       *string   `json:"cor,omitempty"`
+	Usos      int       `json:"usos"` // quantidade de lançamentos com a tag
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// NormalizeTag padroniza o nome de uma tag: sem espaços nas pontas e em minúsculas
+func NormalizeTag(nome string) string {
+	return strings.ToLower(strings.TrimSpace(nome))
+}
+
+// NormalizeTags normaliza, remove vazias e duplicadas e ordena uma lista de tags
+func NormalizeTags(nomes []string) []string {
+	seen := make(map[string]bool, len(nomes))
+	out := make([]string, 0, len(nomes))
+	for _, nome := range nomes {
+		nome = NormalizeTag(nome)
+		if nome == "" || seen[nome] {
+			continue
+		}
+		seen[nome] = true
+		out = append(out, nome)
+	}
+	sort.Strings(out)
+	return out
+}
@@ -0,0 +1,16 @@
+package models
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNormalizeTags(t *testing.T) {
+	got := NormalizeTags([]string{" Viagem ", "casa", "VIAGEM", "", "  ", "Alimentação"})
+	if want := []string{"alimentação", "casa", "viagem"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("NormalizeTags = %q, esperava %q", got, want)
+	}
+	if got := NormalizeTags(nil); got == nil || len(got) != 0 {
+		t.Errorf("NormalizeTags(nil) = %#v, esperava lista vazia", got)
+	}
+}
@@ -0,0 +1,31 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Tipos de item da lixeira (o nome da tabela de origem)
+const (
+	TrashExpenses   = "expenses"
+	TrashIncomes    = "incomes"
+	TrashCategories = "categories"
+)
+
+// ValidTrashType indica se o tipo de item da lixeira é suportado
+func ValidTrashType(t string) bool {
+	return t == TrashExpenses || t == TrashIncomes || t == TrashCategories
+}
+
+// TrashItem é uma despesa, receita ou categoria excluída que ainda pode ser restaurada
+type TrashItem struct {
+	Tipo       string     `json:"tipo"` // expenses, incomes ou categories
+	ID         uuid.UUID  `json:"id"`
+	Descricao  string     `json:"descricao"` // nome, no caso das categorias
+	Valor      *Money     `json:"valor,omitempty"`
+	Moeda      *string    `json:"moeda,omitempty"`
+	Data       *time.Time `json:"data,omitempty"` // vencimento ou data de recebimento
+	ExcluidoEm time.Time  `json:"excluido_em"`
+	ExpiraEm   time.Time  `json:"expira_em"` // a partir daí o job de limpeza apaga o item de vez
+}
@@ -12,5 +12,10 @@ type User struct {
 	Email        string    `json:"email"`
 	Password     string    `json:"password,omitempty"`
 	PasswordHash string    `json:"-"`
+	MoedaBase    string    `json:"moeda_base"`
+	Locale       string    `json:"locale"` // pt-BR ou en; define as categorias padrão
 	CreatedAt    time.Time `json:"created_at"`
+
+	ModoOrcamento  string  `json:"modo_orcamento"`            // categorias ou envelopes
+	EnvelopeInicio *string `json:"envelope_inicio,omitempty"` // YYYY-MM: primeiro mês do modo envelope
 }
@@ -0,0 +1,57 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"mime"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// EmailOutbox grava cada e-mail como um arquivo .eml em Dir; um relay SMTP
+// (ou o próprio operador, em desenvolvimento) consome a caixa de saída
+type EmailOutbox struct {
+	Dir  string
+	From string
+}
+
+func (o *EmailOutbox) Send(ctx context.Context, n Notification) error {
+	if n.Email == "" {
+		return ErrNoDestination
+	}
+	if err := os.MkdirAll(o.Dir, 0o755); err != nil {
+		return err
+	}
+
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", o.From)
+	fmt.Fprintf(&msg, "To: %s\r\n", n.Email)
+	fmt.Fprintf(&msg, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", n.Titulo))
+	fmt.Fprintf(&msg, "Date: %s\r\n", n.CriadaEm.Format(time.RFC1123Z))
+	fmt.Fprintf(&msg, "Message-ID: <%s@saldozen>\r\n", uuid.New())
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	msg.WriteString("Content-Transfer-Encoding: 8bit\r\n\r\n")
+	msg.WriteString(n.Mensagem)
+	msg.WriteString("\r\n")
+
+	// grava num temporário e renomeia: o relay nunca lê um e-mail pela metade
+	tmp, err := os.CreateTemp(o.Dir, ".eml-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(msg.Bytes()); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	name := fmt.Sprintf("%s-%s.eml", n.CriadaEm.UTC().Format("20060102T150405Z"), uuid.New())
+	return os.Rename(tmp.Name(), filepath.Join(o.Dir, name))
+}
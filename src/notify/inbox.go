@@ -0,0 +1,21 @@
+package notify
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/google/uuid"
+)
+
+// Inbox guarda a notificação na caixa de entrada do aplicativo (tabela notifications)
+type Inbox struct {
+	DB *sql.DB
+}
+
+func (i *Inbox) Send(ctx context.Context, n Notification) error {
+	_, err := i.DB.ExecContext(ctx, `
+		INSERT INTO notifications (id, user_id, tipo, titulo, mensagem, referencia_id, lida, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, false, $7)
+	`, uuid.New(), n.UserID, n.Tipo, n.Titulo, n.Mensagem, n.ReferenciaID, n.CriadaEm)
+	return err
+}
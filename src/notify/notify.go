@@ -0,0 +1,75 @@
+package notify
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Canais de entrega disponíveis
+const (
+	ChannelEmail   = "email"
+	ChannelWebhook = "webhook"
+	ChannelInbox   = "inbox"
+)
+
+// ErrNoDestination é devolvido quando o usuário não configurou o destino do canal
+var ErrNoDestination = errors.New("destino não configurado para o canal")
+
+// Notification é uma mensagem a entregar a um usuário
+type Notification struct {
+	UserID        uuid.UUID
+	Email         string // destino do canal email
+	WebhookURL    string // destino do canal webhook
+	WebhookSecret string // segredo do usuário que assina o webhook
+	Tipo          string // ex: lembrete_vencimento
+	Titulo        string
+	Mensagem      string
+	ReferenciaID  *uuid.UUID             // ex: a despesa que originou o lembrete
+	Dados         map[string]interface{} // detalhes extras enviados no webhook
+	CriadaEm      time.Time
+}
+
+// Notifier entrega notificações por um canal
+type Notifier interface {
+	Send(ctx context.Context, n Notification) error
+}
+
+// Channels são os canais configurados em Init, pelo nome
+var Channels = map[string]Notifier{}
+
+// Init configura os canais. O e-mail é gravado como arquivo .eml em
+// NOTIFY_OUTBOX_DIR (padrão "outbox"), para ser despachado por um relay;
+// o webhook só chama endereços públicos, a menos que
+// NOTIFY_WEBHOOK_ALLOW_PRIVATE=true (desenvolvimento com um receptor local).
+func Init(database *sql.DB) {
+	dir := os.Getenv("NOTIFY_OUTBOX_DIR")
+	if dir == "" {
+		dir = "outbox"
+	}
+	from := os.Getenv("NOTIFY_EMAIL_FROM")
+	if from == "" {
+		from = "SaldoZen <lembretes@saldozen.local>"
+	}
+
+	Channels = map[string]Notifier{
+		ChannelEmail:   &EmailOutbox{Dir: dir, From: from},
+		ChannelWebhook: &Webhook{Client: NewWebhookClient(os.Getenv("NOTIFY_WEBHOOK_ALLOW_PRIVATE") == "true")},
+		ChannelInbox:   &Inbox{DB: database},
+	}
+	fmt.Println("Notificações: e-mails gravados em", dir)
+}
+
+// ValidChannel informa se o canal existe
+func ValidChannel(name string) bool {
+	switch name {
+	case ChannelEmail, ChannelWebhook, ChannelInbox:
+		return true
+	}
+	return false
+}
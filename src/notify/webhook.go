@@ -0,0 +1,130 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"syscall"
+	"time"
+)
+
+// errPrivateAddress recusa webhooks para a própria máquina ou para a rede
+// interna (ex: http://169.254.169.254/), que o servidor alcança e o usuário não
+var errPrivateAddress = errors.New("webhook para endereço privado ou local não permitido")
+
+// blockedNets são faixas internas que os métodos de net.IP não cobrem: NAT de
+// operadora (CGNAT) e os prefixos NAT64, que levam a endereços IPv4 quaisquer
+var blockedNets = func() []*net.IPNet {
+	var nets []*net.IPNet
+	for _, cidr := range []string{"100.64.0.0/10", "64:ff9b::/96", "64:ff9b:1::/48"} {
+		_, n, _ := net.ParseCIDR(cidr)
+		nets = append(nets, n)
+	}
+	return nets
+}()
+
+// publicOnly é o Control do dialer do webhook: confere o IP já resolvido,
+// o que também barra nomes que apontam para endereços internos
+func publicOnly(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return errPrivateAddress
+	}
+	if v4 := ip.To4(); v4 != nil {
+		ip = v4 // IPv4 mapeado em IPv6 (::ffff:a.b.c.d) é checado como IPv4
+	}
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() || ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() || ip.IsMulticast() {
+		return errPrivateAddress
+	}
+	for _, n := range blockedNets {
+		if n.Contains(ip) {
+			return errPrivateAddress
+		}
+	}
+	return nil
+}
+
+// NewWebhookClient devolve o cliente HTTP do webhook; sem allowPrivate, só
+// conecta em endereços públicos, e redirecionamentos passam pela mesma checagem
+func NewWebhookClient(allowPrivate bool) *http.Client {
+	dialer := &net.Dialer{Timeout: 5 * time.Second}
+	if !allowPrivate {
+		dialer.Control = publicOnly
+	}
+	return &http.Client{
+		Timeout:   10 * time.Second,
+		Transport: &http.Transport{DialContext: dialer.DialContext, TLSHandshakeTimeout: 5 * time.Second},
+	}
+}
+
+// Webhook envia a notificação como JSON por POST para a URL do usuário.
+// Com o segredo do usuário, o cabeçalho X-SaldoZen-Signature traz
+// "sha256=" + HMAC do corpo.
+type Webhook struct {
+	Client *http.Client
+}
+
+// NewWebhookSecret gera o segredo com que as entregas de um usuário são assinadas
+func NewWebhookSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func (h *Webhook) Send(ctx context.Context, n Notification) error {
+	if n.WebhookURL == "" {
+		return ErrNoDestination
+	}
+
+	body, err := json.Marshal(map[string]interface{}{
+		"user_id":       n.UserID,
+		"tipo":          n.Tipo,
+		"titulo":        n.Titulo,
+		"mensagem":      n.Mensagem,
+		"referencia_id": n.ReferenciaID,
+		"dados":         n.Dados,
+		"criada_em":     n.CriadaEm,
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.WebhookURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if n.WebhookSecret != "" {
+		mac := hmac.New(sha256.New, []byte(n.WebhookSecret))
+		mac.Write(body)
+		req.Header.Set("X-SaldoZen-Signature", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	}
+
+	resp, err := h.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook respondeu %s", resp.Status)
+	}
+	return nil
+}
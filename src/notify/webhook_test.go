@@ -0,0 +1,79 @@
+package notify
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestPublicOnly(t *testing.T) {
+	blocked := []string{
+		"127.0.0.1:80",
+		"10.1.2.3:443",
+		"192.168.0.10:80",
+		"169.254.169.254:80",
+		"100.64.0.1:80",
+		"0.0.0.0:80",
+		"[::1]:80",
+		"[fd00::1]:80",
+		"[fe80::1]:80",
+		"[::ffff:127.0.0.1]:80",
+		"[::ffff:10.0.0.1]:80",
+		"[64:ff9b::a00:1]:80",
+		"[64:ff9b:1::1]:80",
+	}
+	for _, addr := range blocked {
+		if err := publicOnly("tcp", addr, nil); err == nil {
+			t.Errorf("publicOnly(%s): esperava erro", addr)
+		}
+	}
+	for _, addr := range []string{"8.8.8.8:443", "[2001:4860:4860::8888]:443", "100.128.0.1:80"} {
+		if err := publicOnly("tcp", addr, nil); err != nil {
+			t.Errorf("publicOnly(%s): %v", addr, err)
+		}
+	}
+}
+
+func TestWebhookRefusesLocalAddress(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	h := &Webhook{Client: NewWebhookClient(false)}
+	if err := h.Send(context.Background(), Notification{WebhookURL: srv.URL}); err == nil {
+		t.Error("webhook para 127.0.0.1 deveria ser recusado")
+	}
+}
+
+func TestWebhookSignature(t *testing.T) {
+	secret, err := NewWebhookSecret()
+	if err != nil || len(secret) != 64 {
+		t.Fatalf("NewWebhookSecret = %q, %v", secret, err)
+	}
+
+	var body []byte
+	var signature string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ = io.ReadAll(r.Body)
+		signature = r.Header.Get("X-SaldoZen-Signature")
+	}))
+	defer srv.Close()
+
+	h := &Webhook{Client: NewWebhookClient(true)}
+	if err := h.Send(context.Background(), Notification{WebhookURL: srv.URL, WebhookSecret: secret, Titulo: "Conta de luz"}); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	if want := "sha256=" + hex.EncodeToString(mac.Sum(nil)); signature != want {
+		t.Errorf("assinatura %q, esperava %q", signature, want)
+	}
+
+	if err := h.Send(context.Background(), Notification{}); err != ErrNoDestination {
+		t.Errorf("Send sem URL = %v, esperava ErrNoDestination", err)
+	}
+}
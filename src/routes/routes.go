@@ -22,6 +22,8 @@ func SetupRoutes() http.Handler {
 	secure := middlewares.JWTAuth
 
 	r.Handle("/users/{id}", secure(http.HandlerFunc(controllers.GetUserById))).Methods("GET")
+	r.Handle("/users/{id}/currency", secure(http.HandlerFunc(controllers.UpdateUserCurrency))).Methods("PATCH")
+	r.Handle("/users/{id}/budget-mode", secure(http.HandlerFunc(controllers.UpdateUserBudgetMode))).Methods("PATCH")
 
 	r.Handle("/expenses", secure(http.HandlerFunc(controllers.CreateExpense))).Methods("POST")
 	// GET /users/{userId}?month=10&year=2023
@@ -33,6 +35,36 @@ func SetupRoutes() http.Handler {
 	r.Handle("/users/{userId}/expenses/{id}", secure(http.HandlerFunc(controllers.DeleteExpense))).Methods("DELETE")
 	r.Handle("/users/{userId}/expenses/{id}/pay", secure(http.HandlerFunc(controllers.PayExpense))).Methods("PATCH")
 	r.Handle("/users/{userId}/expenses/{id}/unpay", secure(http.HandlerFunc(controllers.UnpayExpense))).Methods("PATCH")
+	r.Handle("/users/{userId}/expenses/{id}/payments", secure(http.HandlerFunc(controllers.ListExpensePayments))).Methods("GET")
+	r.Handle("/users/{userId}/expenses/{id}/payments/{paymentId}", secure(http.HandlerFunc(controllers.DeleteExpensePayment))).Methods("DELETE")
+	r.Handle("/users/{userId}/expenses/{id}/charges", secure(http.HandlerFunc(controllers.GetExpenseCharges))).Methods("GET")
+	r.Handle("/users/{userId}/expenses/{id}/refunds", secure(http.HandlerFunc(controllers.CreateExpenseRefund))).Methods("POST")
+	r.Handle("/users/{userId}/expenses/{id}/refunds", secure(http.HandlerFunc(controllers.ListExpenseRefunds))).Methods("GET")
+	r.Handle("/users/{userId}/expenses/{id}/refunds/{refundId}", secure(http.HandlerFunc(controllers.DeleteExpenseRefund))).Methods("DELETE")
+	r.Handle("/users/{userId}/expenses/{id}/attachments", secure(http.HandlerFunc(controllers.UploadExpenseAttachment))).Methods("POST")
+	r.Handle("/users/{userId}/expenses/{id}/attachments", secure(http.HandlerFunc(controllers.ListExpenseAttachments))).Methods("GET")
+	r.Handle("/users/{userId}/expenses/{id}/attachments/{attachmentId}", secure(http.HandlerFunc(controllers.DownloadExpenseAttachment))).Methods("GET")
+	r.Handle("/users/{userId}/expenses/{id}/attachments/{attachmentId}", secure(http.HandlerFunc(controllers.DeleteExpenseAttachment))).Methods("DELETE")
+	r.Handle("/users/{userId}/expenses/{id}/recurrence", secure(http.HandlerFunc(controllers.UpdateRecurringOccurrence))).Methods("PUT")
+
+	// Rota para contas
+	r.Handle("/users/{userId}/accounts", secure(http.HandlerFunc(controllers.CreateAccount))).Methods("POST")
+	r.Handle("/users/{userId}/accounts", secure(http.HandlerFunc(controllers.ListAccounts))).Methods("GET")
+	r.Handle("/users/{userId}/accounts/{id}", secure(http.HandlerFunc(controllers.UpdateAccount))).Methods("PUT")
+	r.Handle("/users/{userId}/accounts/{id}", secure(http.HandlerFunc(controllers.DeleteAccount))).Methods("DELETE")
+
+	// Rota para despesas recorrentes
+	r.Handle("/users/{userId}/recurring-expenses", secure(http.HandlerFunc(controllers.CreateRecurringExpense))).Methods("POST")
+	r.Handle("/users/{userId}/recurring-expenses", secure(http.HandlerFunc(controllers.ListRecurringExpenses))).Methods("GET")
+	r.Handle("/users/{userId}/recurring-expenses/{id}", secure(http.HandlerFunc(controllers.GetRecurringExpense))).Methods("GET")
+	r.Handle("/users/{userId}/recurring-expenses/{id}", secure(http.HandlerFunc(controllers.UpdateRecurringExpense))).Methods("PUT")
+	r.Handle("/users/{userId}/recurring-expenses/{id}", secure(http.HandlerFunc(controllers.DeleteRecurringExpense))).Methods("DELETE")
+
+	// Rota para taxas de câmbio
+	r.Handle("/users/{userId}/exchange-rates", secure(http.HandlerFunc(controllers.CreateExchangeRate))).Methods("POST")
+	r.Handle("/users/{userId}/exchange-rates", secure(http.HandlerFunc(controllers.ListExchangeRates))).Methods("GET")
+	r.Handle("/users/{userId}/exchange-rates/import", secure(http.HandlerFunc(controllers.ImportExchangeRates))).Methods("POST")
+	r.Handle("/users/{userId}/exchange-rates/{id}", secure(http.HandlerFunc(controllers.DeleteExchangeRate))).Methods("DELETE")
 
 	// Rota para obter o resumo mensal
 	r.Handle("/summary/{userId}", secure(http.HandlerFunc(controllers.GetMonthlySummary))).Methods("GET")
@@ -40,20 +72,122 @@ func SetupRoutes() http.Handler {
 	// Rota categorias
 	r.Handle("/categories", secure(http.HandlerFunc(controllers.CreateCategory))).Methods("POST")
 	r.Handle("/categories/{userId}", secure(http.HandlerFunc(controllers.GetCategories))).Methods("GET")
+	r.Handle("/categories/{id}", secure(http.HandlerFunc(controllers.UpdateCategory))).Methods("PATCH")
 	r.Handle("/categories/{id}", secure(http.HandlerFunc(controllers.DeleteCategory))).Methods("DELETE")
+	r.Handle("/categories/{id}/move", secure(http.HandlerFunc(controllers.MoveCategory))).Methods("PATCH")
+	r.Handle("/categories/{id}/rename", secure(http.HandlerFunc(controllers.RenameCategory))).Methods("PATCH")
+	r.Handle("/categories/{id}/merge", secure(http.HandlerFunc(controllers.MergeCategory))).Methods("POST")
+
+	// Rota para tags
+	r.Handle("/users/{userId}/tags", secure(http.HandlerFunc(controllers.CreateTag))).Methods("POST")
+	r.Handle("/users/{userId}/tags", secure(http.HandlerFunc(controllers.ListTags))).Methods("GET")
+	r.Handle("/users/{userId}/tags/{id}", secure(http.HandlerFunc(controllers.UpdateTag))).Methods("PUT")
+	r.Handle("/users/{userId}/tags/{id}", secure(http.HandlerFunc(controllers.DeleteTag))).Methods("DELETE")
+
+	// Rota para orçamentos
+	r.Handle("/users/{userId}/budgets", secure(http.HandlerFunc(controllers.CreateBudget))).Methods("POST")
+	r.Handle("/users/{userId}/budgets", secure(http.HandlerFunc(controllers.ListBudgets))).Methods("GET")
+	r.Handle("/users/{userId}/budgets/progress", secure(http.HandlerFunc(controllers.GetBudgetProgress))).Methods("GET")
+	r.Handle("/users/{userId}/budgets/{id}", secure(http.HandlerFunc(controllers.UpdateBudget))).Methods("PUT")
+	r.Handle("/users/{userId}/budgets/{id}", secure(http.HandlerFunc(controllers.DeleteBudget))).Methods("DELETE")
+
+	// Rota para o orçamento base zero (envelopes)
+	r.Handle("/users/{userId}/envelopes", secure(http.HandlerFunc(controllers.GetEnvelopes))).Methods("GET")
+	r.Handle("/users/{userId}/envelopes/assign", secure(http.HandlerFunc(controllers.AssignEnvelope))).Methods("POST")
+	r.Handle("/users/{userId}/envelopes/move", secure(http.HandlerFunc(controllers.MoveEnvelope))).Methods("POST")
+	r.Handle("/users/{userId}/envelopes/movements", secure(http.HandlerFunc(controllers.ListEnvelopeMovements))).Methods("GET")
+	r.Handle("/users/{userId}/envelopes/movements/{id}", secure(http.HandlerFunc(controllers.DeleteEnvelopeMovement))).Methods("DELETE")
+
+	// Rota para metas de economia
+	r.Handle("/users/{userId}/goals", secure(http.HandlerFunc(controllers.CreateGoal))).Methods("POST")
+	r.Handle("/users/{userId}/goals", secure(http.HandlerFunc(controllers.ListGoals))).Methods("GET")
+	r.Handle("/users/{userId}/goals/{id}", secure(http.HandlerFunc(controllers.GetGoal))).Methods("GET")
+	r.Handle("/users/{userId}/goals/{id}", secure(http.HandlerFunc(controllers.UpdateGoal))).Methods("PUT")
+	r.Handle("/users/{userId}/goals/{id}", secure(http.HandlerFunc(controllers.DeleteGoal))).Methods("DELETE")
+	r.Handle("/users/{userId}/goals/{id}/contributions", secure(http.HandlerFunc(controllers.CreateGoalContribution))).Methods("POST")
+	r.Handle("/users/{userId}/goals/{id}/contributions", secure(http.HandlerFunc(controllers.ListGoalContributions))).Methods("GET")
+	r.Handle("/users/{userId}/goals/{id}/contributions/{contributionId}", secure(http.HandlerFunc(controllers.DeleteGoalContribution))).Methods("DELETE")
+
+	// Rota para empréstimos e financiamentos
+	r.Handle("/users/{userId}/loans", secure(http.HandlerFunc(controllers.CreateLoan))).Methods("POST")
+	r.Handle("/users/{userId}/loans", secure(http.HandlerFunc(controllers.ListLoans))).Methods("GET")
+	r.Handle("/users/{userId}/loans/{id}", secure(http.HandlerFunc(controllers.GetLoan))).Methods("GET")
+	r.Handle("/users/{userId}/loans/{id}", secure(http.HandlerFunc(controllers.DeleteLoan))).Methods("DELETE")
+	r.Handle("/users/{userId}/loans/{id}/simulate", secure(http.HandlerFunc(controllers.SimulateLoanPrepayment))).Methods("POST")
+	r.Handle("/users/{userId}/loans/{id}/prepayments", secure(http.HandlerFunc(controllers.CreateLoanPrepayment))).Methods("POST")
+
+	// Rota para investimentos e cotações
+	r.Handle("/users/{userId}/investments/transactions", secure(http.HandlerFunc(controllers.CreateInvestmentTransaction))).Methods("POST")
+	r.Handle("/users/{userId}/investments/transactions", secure(http.HandlerFunc(controllers.ListInvestmentTransactions))).Methods("GET")
+	r.Handle("/users/{userId}/investments/transactions/{id}", secure(http.HandlerFunc(controllers.DeleteInvestmentTransaction))).Methods("DELETE")
+	r.Handle("/users/{userId}/investments/portfolio", secure(http.HandlerFunc(controllers.GetPortfolio))).Methods("GET")
+	r.Handle("/users/{userId}/quotes", secure(http.HandlerFunc(controllers.CreateQuote))).Methods("POST")
+	r.Handle("/users/{userId}/quotes", secure(http.HandlerFunc(controllers.ListQuotes))).Methods("GET")
+	r.Handle("/users/{userId}/quotes/import", secure(http.HandlerFunc(controllers.ImportQuotes))).Methods("POST")
+
+	// Rota para o histórico do patrimônio líquido
+	r.Handle("/users/{userId}/net-worth", secure(http.HandlerFunc(controllers.GetNetWorthHistory))).Methods("GET")
+	r.Handle("/users/{userId}/net-worth/snapshots", secure(http.HandlerFunc(controllers.RefreshNetWorthSnapshot))).Methods("POST")
+
+	// Rota para lembretes de vencimento e caixa de entrada
+	r.Handle("/users/{userId}/reminder-preferences", secure(http.HandlerFunc(controllers.GetReminderPreferences))).Methods("GET")
+	r.Handle("/users/{userId}/reminder-preferences", secure(http.HandlerFunc(controllers.UpdateReminderPreferences))).Methods("PUT")
+	r.Handle("/users/{userId}/notifications", secure(http.HandlerFunc(controllers.ListNotifications))).Methods("GET")
+	r.Handle("/users/{userId}/notifications/read-all", secure(http.HandlerFunc(controllers.MarkAllNotificationsRead))).Methods("POST")
+	r.Handle("/users/{userId}/notifications/{id}/read", secure(http.HandlerFunc(controllers.MarkNotificationRead))).Methods("PATCH")
+
+	// Rota para a lixeira
+	r.Handle("/users/{userId}/trash", secure(http.HandlerFunc(controllers.ListTrash))).Methods("GET")
+	r.Handle("/users/{userId}/trash/{tipo}/{id}/restore", secure(http.HandlerFunc(controllers.RestoreTrashItem))).Methods("POST")
+
+	// Rota para o histórico de alterações
+	r.Handle("/users/{userId}/history", secure(http.HandlerFunc(controllers.ListAuditLog))).Methods("GET")
+	r.Handle("/users/{userId}/history/{entidade}/{id}", secure(http.HandlerFunc(controllers.GetEntityHistory))).Methods("GET")
+
+	// Rota para a busca de lançamentos
+	r.Handle("/users/{userId}/search", secure(http.HandlerFunc(controllers.SearchTransactions))).Methods("GET")
+
+	// Rota para campos personalizados
+	r.Handle("/users/{userId}/custom-fields", secure(http.HandlerFunc(controllers.CreateCustomField))).Methods("POST")
+	r.Handle("/users/{userId}/custom-fields", secure(http.HandlerFunc(controllers.ListCustomFields))).Methods("GET")
+	r.Handle("/users/{userId}/custom-fields/{id}", secure(http.HandlerFunc(controllers.UpdateCustomField))).Methods("PUT")
+	r.Handle("/users/{userId}/custom-fields/{id}", secure(http.HandlerFunc(controllers.DeleteCustomField))).Methods("DELETE")
+
+	// Rota para favorecidos
+	r.Handle("/users/{userId}/payees", secure(http.HandlerFunc(controllers.CreatePayee))).Methods("POST")
+	r.Handle("/users/{userId}/payees", secure(http.HandlerFunc(controllers.ListPayees))).Methods("GET")
+	r.Handle("/users/{userId}/payees/suggest", secure(http.HandlerFunc(controllers.SuggestPayee))).Methods("GET")
+	r.Handle("/users/{userId}/payees/apply", secure(http.HandlerFunc(controllers.ApplyPayees))).Methods("POST")
+	r.Handle("/users/{userId}/payees/{id}", secure(http.HandlerFunc(controllers.UpdatePayee))).Methods("PUT")
+	r.Handle("/users/{userId}/payees/{id}", secure(http.HandlerFunc(controllers.DeletePayee))).Methods("DELETE")
+	r.Handle("/users/{userId}/payees/{id}/aliases", secure(http.HandlerFunc(controllers.AddPayeeAlias))).Methods("POST")
+	r.Handle("/users/{userId}/payees/{id}/aliases/{aliasId}", secure(http.HandlerFunc(controllers.DeletePayeeAlias))).Methods("DELETE")
 
 	// Rota para gráficos
 	r.Handle("/charts/expenses-by-category/{userId}", secure(http.HandlerFunc(controllers.GetExpensesByCategory))).Methods("GET")
 	r.Handle("/charts/expenses-by-status/{userId}", secure(http.HandlerFunc(controllers.GetExpensesByStatus))).Methods("GET")
 	r.Handle("/charts/monthly-summary/{userId}", secure(http.HandlerFunc(controllers.GetMonthlySummaryChart))).Methods("GET")
 	r.Handle("/charts/incomes-by-category/{userId}", secure(http.HandlerFunc(controllers.GetIncomeByCategory))).Methods("GET")
+	r.Handle("/charts/expenses-by-tag/{userId}", secure(http.HandlerFunc(controllers.GetExpensesByTag))).Methods("GET")
+	r.Handle("/charts/incomes-by-tag/{userId}", secure(http.HandlerFunc(controllers.GetIncomesByTag))).Methods("GET")
+	r.Handle("/charts/expenses-by-field/{userId}", secure(http.HandlerFunc(controllers.GetExpensesByField))).Methods("GET")
+	r.Handle("/charts/incomes-by-field/{userId}", secure(http.HandlerFunc(controllers.GetIncomesByField))).Methods("GET")
+	r.Handle("/charts/top-payees/{userId}", secure(http.HandlerFunc(controllers.GetTopPayees))).Methods("GET")
 
 	// Rota para receitas
 	r.Handle("/incomes/{userId}", secure(http.HandlerFunc(controllers.CreateIncome))).Methods("POST")
 	r.Handle("/incomes/{userId}", secure(http.HandlerFunc(controllers.ListIncomes))).Methods("GET")
+	r.Handle("/incomes/{userId}/pending", secure(http.HandlerFunc(controllers.ListPendingIncomes))).Methods("GET")
 	r.Handle("/incomes/{userId}/{id}", secure(http.HandlerFunc(controllers.GetIncomeByID))).Methods("GET")
 	r.Handle("/incomes/{userId}/{id}", secure(http.HandlerFunc(controllers.UpdateIncome))).Methods("PUT")
 	r.Handle("/incomes/{userId}/{id}", secure(http.HandlerFunc(controllers.DeleteIncome))).Methods("DELETE")
+	r.Handle("/incomes/{userId}/{id}/confirm", secure(http.HandlerFunc(controllers.ConfirmIncome))).Methods("PATCH")
+
+	// Rota para receitas recorrentes
+	r.Handle("/users/{userId}/recurring-incomes", secure(http.HandlerFunc(controllers.CreateRecurringIncome))).Methods("POST")
+	r.Handle("/users/{userId}/recurring-incomes", secure(http.HandlerFunc(controllers.ListRecurringIncomes))).Methods("GET")
+	r.Handle("/users/{userId}/recurring-incomes/{id}", secure(http.HandlerFunc(controllers.UpdateRecurringIncome))).Methods("PUT")
+	r.Handle("/users/{userId}/recurring-incomes/{id}", secure(http.HandlerFunc(controllers.DeleteRecurringIncome))).Methods("DELETE")
 
 	r.PathPrefix("/swagger/").Handler(httpSwagger.WrapHandler)
 
@@ -0,0 +1,221 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// S3Store fala com qualquer serviço compatível com S3 (AWS, MinIO, R2...)
+// assinando as requisições com AWS Signature V4.
+type S3Store struct {
+	Endpoint  string // ex: https://s3.amazonaws.com ou http://localhost:9000
+	Bucket    string
+	Region    string
+	AccessKey string
+	SecretKey string
+	PathStyle bool // endpoint/bucket/chave em vez de bucket.endpoint/chave
+	Client    *http.Client
+}
+
+// NewS3StoreFromEnv monta o S3Store a partir das variáveis S3_*
+func NewS3StoreFromEnv() (*S3Store, error) {
+	s := &S3Store{
+		Endpoint:  os.Getenv("S3_ENDPOINT"),
+		Bucket:    os.Getenv("S3_BUCKET"),
+		Region:    os.Getenv("S3_REGION"),
+		AccessKey: os.Getenv("S3_ACCESS_KEY"),
+		SecretKey: os.Getenv("S3_SECRET_KEY"),
+		PathStyle: os.Getenv("S3_PATH_STYLE") != "false",
+		Client:    &http.Client{Timeout: 30 * time.Second},
+	}
+	if s.Endpoint == "" {
+		s.Endpoint = "https://s3.amazonaws.com"
+	}
+	if s.Region == "" {
+		s.Region = "us-east-1"
+	}
+	if s.Bucket == "" || s.AccessKey == "" || s.SecretKey == "" {
+		return nil, errors.New("S3_BUCKET, S3_ACCESS_KEY e S3_SECRET_KEY são obrigatórios")
+	}
+	if _, err := url.Parse(s.Endpoint); err != nil {
+		return nil, fmt.Errorf("S3_ENDPOINT inválido: %w", err)
+	}
+	return s, nil
+}
+
+func (s *S3Store) Put(ctx context.Context, key string, data []byte, contentType string) error {
+	resp, err := s.do(ctx, http.MethodPut, key, data, contentType)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	return s.check(resp)
+}
+
+func (s *S3Store) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	resp, err := s.do(ctx, http.MethodGet, key, nil, "")
+	if err != nil {
+		return nil, err
+	}
+	if err := s.check(resp); err != nil {
+		resp.Body.Close()
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+func (s *S3Store) Delete(ctx context.Context, key string) error {
+	resp, err := s.do(ctx, http.MethodDelete, key, nil, "")
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	// S3 responde 204 mesmo se o objeto não existir
+	if err := s.check(resp); err != nil && !errors.Is(err, ErrNotFound) {
+		return err
+	}
+	return nil
+}
+
+func (s *S3Store) check(resp *http.Response) error {
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return nil
+	case resp.StatusCode == http.StatusNotFound:
+		return ErrNotFound
+	}
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	return fmt.Errorf("s3: status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+}
+
+// objectURL monta a URL do objeto conforme o estilo de endereçamento
+func (s *S3Store) objectURL(key string) (*url.URL, error) {
+	u, err := url.Parse(strings.TrimRight(s.Endpoint, "/"))
+	if err != nil {
+		return nil, err
+	}
+	parts := strings.Split(key, "/")
+	escaped := make([]string, 0, len(parts))
+	for _, part := range parts {
+		escaped = append(escaped, uriEscape(part))
+	}
+	if s.PathStyle {
+		u.RawPath = u.Path + "/" + uriEscape(s.Bucket) + "/" + strings.Join(escaped, "/")
+	} else {
+		u.Host = s.Bucket + "." + u.Host
+		u.RawPath = u.Path + "/" + strings.Join(escaped, "/")
+	}
+	u.Path, err = url.PathUnescape(u.RawPath)
+	return u, err
+}
+
+func (s *S3Store) do(ctx context.Context, method, key string, body []byte, contentType string) (*http.Response, error) {
+	if err := validKey(key); err != nil {
+		return nil, err
+	}
+	u, err := s.objectURL(key)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	s.sign(req, body, time.Now().UTC())
+	return s.Client.Do(req)
+}
+
+// sign aplica a assinatura AWS Signature V4 à requisição
+func (s *S3Store) sign(req *http.Request, body []byte, now time.Time) {
+	amzDate := now.Format("20060102T150405Z")
+	day := now.Format("20060102")
+	payloadHash := sha256Hex(body)
+
+	req.Header.Set("x-amz-date", amzDate)
+	req.Header.Set("x-amz-content-sha256", payloadHash)
+
+	headers := []string{"host", "x-amz-content-sha256", "x-amz-date"}
+	values := map[string]string{
+		"host":                 req.URL.Host,
+		"x-amz-content-sha256": payloadHash,
+		"x-amz-date":           amzDate,
+	}
+	if ct := req.Header.Get("Content-Type"); ct != "" {
+		headers = []string{"content-type", "host", "x-amz-content-sha256", "x-amz-date"}
+		values["content-type"] = ct
+	}
+
+	var canonicalHeaders strings.Builder
+	for _, h := range headers {
+		canonicalHeaders.WriteString(h + ":" + strings.TrimSpace(values[h]) + "\n")
+	}
+	signedHeaders := strings.Join(headers, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := day + "/" + s.Region + "/s3/aws4_request"
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		amzDate,
+		scope,
+		sha256Hex([]byte(canonicalRequest)),
+	}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+s.SecretKey), day)
+	key = hmacSHA256(key, s.Region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf(
+		"AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.AccessKey, scope, signedHeaders, signature,
+	))
+}
+
+func sha256Hex(b []byte) string {
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// uriEscape codifica um segmento de caminho como o S3 espera:
+// tudo exceto letras, dígitos e "-._~" vira %XX
+func uriEscape(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || '0' <= c && c <= '9' || c == '-' || c == '.' || c == '_' || c == '~' {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,59 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// ErrNotFound é devolvido quando o objeto não existe no armazenamento
+var ErrNotFound = errors.New("objeto não encontrado")
+
+// BlobStore guarda arquivos binários (ex: comprovantes) identificados por chave.
+// As chaves usam "/" como separador, ex: "attachments/<user>/<sha256>".
+type BlobStore interface {
+	Put(ctx context.Context, key string, data []byte, contentType string) error
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	Delete(ctx context.Context, key string) error
+}
+
+// Blobs é o armazenamento configurado em Init
+var Blobs BlobStore
+
+// Init escolhe o armazenamento a partir de STORAGE_DRIVER ("local" ou "s3")
+func Init() {
+	switch driver := strings.ToLower(os.Getenv("STORAGE_DRIVER")); driver {
+	case "", "local":
+		dir := os.Getenv("STORAGE_LOCAL_DIR")
+		if dir == "" {
+			dir = "uploads"
+		}
+		Blobs = &LocalStore{Dir: dir}
+		fmt.Println("Armazenamento local em", dir)
+	case "s3":
+		s, err := NewS3StoreFromEnv()
+		if err != nil {
+			panic(err)
+		}
+		Blobs = s
+		fmt.Println("Armazenamento S3 no bucket", s.Bucket)
+	default:
+		panic("STORAGE_DRIVER inválido: " + driver)
+	}
+}
+
+// validKey rejeita chaves vazias ou que tentem sair do diretório base
+func validKey(key string) error {
+	if key == "" || strings.HasPrefix(key, "/") {
+		return fmt.Errorf("chave inválida: %q", key)
+	}
+	for _, part := range strings.Split(key, "/") {
+		if part == "" || part == "." || part == ".." {
+			return fmt.Errorf("chave inválida: %q", key)
+		}
+	}
+	return nil
+}